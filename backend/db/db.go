@@ -23,7 +23,7 @@ func InitDB() {
 	}
 
 	// モデルのスキーマを自動作成／更新
-	err = DB.AutoMigrate(&models.User{}, &models.MultiSig{})
+	err = DB.AutoMigrate(&models.User{}, &models.MultiSig{}, &models.Session{}, &models.SessionMessage{}, &models.Reshare{}, &models.Presignature{}, &models.PedersenSetup{})
 	if err != nil {
 		log.Fatalf("failed to migrate database schema: %v", err)
 	}
@@ -2,17 +2,26 @@ package handlers
 
 import (
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"sync"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"multisigservice/db"
 	"multisigservice/models"
+	"multisigservice/paillier"
+	pb "multisigservice/proto/paillierpb"
 )
 
 // challengeStore はアドレス毎のチャレンジ（nonce）を一時保存するストアです。
@@ -79,7 +88,7 @@ func LoginHandler(c *gin.Context) {
 	}
 
 	// 認証成功の場合、ユーザーをDBに登録（既存の場合は更新）
-	user := models.User{Address: req.Address}
+	user := models.User{Address: normalizeAddress(req.Address)}
 	// GORMのSaveはプライマリキーに基づいて更新・作成を行う
 	if err := db.DB.Save(&user).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Database error"})
@@ -94,54 +103,279 @@ func LoginHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Logged in", "address": req.Address})
 }
 
+// consumeChallenge は、アドレスのチャレンジを取り出してストアから削除します。
+// 取り出しと削除を1つのロックの中で行うため、同じチャレンジは1度しか使えません。
+func consumeChallenge(address string) (string, bool) {
+	challengeStore.Lock()
+	defer challengeStore.Unlock()
+	challenge, exists := challengeStore.m[strings.ToLower(address)]
+	delete(challengeStore.m, strings.ToLower(address))
+	return challenge, exists
+}
+
+// verifyChallengeSignature は、statement とチャレンジへの address の personal_sign 署名を検証し、チャレンジを消費します。
+// セッションIDが決まる前の操作（鍵更新の開始や再共有の提案）を参加者本人の要求に限るために用います。
+func verifyChallengeSignature(statement, signatureHex, address string) error {
+	challengeStore.Lock()
+	defer challengeStore.Unlock()
+	challenge, exists := challengeStore.m[strings.ToLower(address)]
+	if !exists {
+		return badRequest("No challenge found for address")
+	}
+	valid, err := verifySignature(statement+"\nChallenge: "+challenge, signatureHex, address)
+	if err != nil || !valid {
+		return badRequest("Signature verification failed")
+	}
+	// 使用済みチャレンジを削除
+	delete(challengeStore.m, strings.ToLower(address))
+	return nil
+}
+
+// serverPedersen は公開鍵登録時のΠ-fac検証に用いるサーバーのリングPedersenパラメータです。
+// 初回にsafe primeから生成してDBに保存し、再起動後も同じパラメータを使います（生成に使った秘密鍵は破棄します）。
+var serverPedersen struct {
+	once   sync.Once
+	params *paillier.PedersenParams
+	err    error
+}
+
+// serverPedersenParams はサーバーのリングPedersenパラメータを返します（初回呼び出し時に読み込みまたは生成）。
+func serverPedersenParams() (*paillier.PedersenParams, error) {
+	serverPedersen.once.Do(func() {
+		serverPedersen.params, serverPedersen.err = loadPedersenSetup()
+	})
+	return serverPedersen.params, serverPedersen.err
+}
+
+// InitPedersenParams は、サーバーのリングPedersenパラメータを起動時に読み込みます（未生成なら生成します）。
+func InitPedersenParams() error {
+	_, err := serverPedersenParams()
+	return err
+}
+
+// loadPedersenSetup は、保存済みのリングPedersenパラメータを読み込み、なければ生成して保存します。
+// 複数のサーバーが同時に生成した場合も、最初に保存されたものを全員が使います。
+func loadPedersenSetup() (*paillier.PedersenParams, error) {
+	var setup models.PedersenSetup
+	err := db.DB.Order("id").First(&setup).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Π-mod と同様に、Ñ はsafe primeの積とする
+		_, sk, err := paillier.GenerateKeyWithOptions(paillier.KeyOptions{BitLen: paillier.MinBitLen, SafePrimes: true})
+		if err != nil {
+			return nil, err
+		}
+		params, err := paillier.NewPedersenParams(sk)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := proto.Marshal(params.ToProto())
+		if err != nil {
+			return nil, err
+		}
+		setup = models.PedersenSetup{Model: gorm.Model{ID: 1}, Params: base64.StdEncoding.EncodeToString(raw)}
+		if err := db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&setup).Error; err != nil {
+			return nil, err
+		}
+		err = db.DB.Order("id").First(&setup).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	var msg pb.PedersenParams
+	if err := decodeProto(setup.Params, &msg); err != nil {
+		return nil, err
+	}
+	return paillier.PedersenParamsFromProto(&msg)
+}
+
+// PedersenParamsHandler はΠ-fac証明の作成に必要なサーバーのリングPedersenパラメータを返します。
+func PedersenParamsHandler(c *gin.Context) {
+	params, err := serverPedersenParams()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to generate pedersen parameters"})
+		return
+	}
+	raw, err := proto.Marshal(params.ToProto())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to encode pedersen parameters"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"pedersen": base64.StdEncoding.EncodeToString(raw)})
+}
+
 // RegisterPubkeyRequest はPaillier公開鍵登録時のリクエストデータです。
+// Pubkey は正規形（base64 protobuf）またはJSON形式（{"n": "0x..."}）の公開鍵、
+// ModProof, FacProof はそれぞれ pb.ModProof, pb.FacProof をbase64エンコードしたものです。
+// Pedersen は公開鍵と同じ法 N で作ったリングPedersenパラメータ（pb.PedersenParams）、
+// PrmProof はその pb.PrmProof をbase64エンコードしたものです（paillier.NewPedersenParamsWithProof）。
+// 署名では、他の署名者がこのパラメータに対して範囲証明を作成します。
+// Signature はチャレンジ・Pubkey・Pedersen を連結した文字列への personal_sign の署名です。
 type RegisterPubkeyRequest struct {
 	Address   string `json:"address"`
 	Pubkey    string `json:"pubkey"`
+	ModProof  string `json:"modProof"`
+	FacProof  string `json:"facProof"`
+	Pedersen  string `json:"pedersen"`
+	PrmProof  string `json:"prmProof"`
 	Signature string `json:"signature"`
 }
 
 // RegisterPubkeyHandler はチャレンジ署名方式により署名検証を行い、ユーザーのPaillier公開鍵をDBに登録します。
+// 計算量の大きいゼロ知識証明の検証は、署名を検証した後に行います。
+// 証明はアドレスとチャレンジに結び付けて作成する必要があります（registrationContext を参照してください）。
 func RegisterPubkeyHandler(c *gin.Context) {
 	var req RegisterPubkeyRequest
-	if err := c.ShouldBindJSON(&req); err != nil || req.Address == "" || req.Signature == "" || req.Pubkey == "" {
+	if err := c.ShouldBindJSON(&req); err != nil || req.Address == "" || req.Signature == "" || req.Pubkey == "" || req.Pedersen == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid request"})
 		return
 	}
 
-	// ストアからチャレンジを取得
-	challengeStore.RLock()
-	challenge, exists := challengeStore.m[strings.ToLower(req.Address)]
-	challengeStore.RUnlock()
-
+	// ストアからチャレンジを取り出して消費する。証明の検証に時間がかかるため、
+	// 検証の前に削除して同じチャレンジによる並行した登録を防ぐ（失敗した場合はチャレンジを取得し直す）
+	challenge, exists := consumeChallenge(req.Address)
 	if !exists {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "No challenge found for address"})
 		return
 	}
 
 	// 署名検証
-	valid, err := verifySignature(challenge + req.Pubkey, req.Signature, req.Address)
+	valid, err := verifySignature(challenge + req.Pubkey + req.Pedersen, req.Signature, req.Address)
 	if err != nil || !valid {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Signature verification failed"})
 		return
 	}
 
+	// Paillier公開鍵を解析し、ゼロ知識証明を検証
+	pub, err := parsePaillierPubkey(req.Pubkey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid Paillier key: " + err.Error()})
+		return
+	}
+	ctx := registrationContext(req.Address, challenge)
+	if err := verifyPaillierKey(pub, req.ModProof, req.FacProof, ctx); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid Paillier key: " + err.Error()})
+		return
+	}
+	pedersen, err := verifyPedersenParams(pub, req.Pedersen, req.PrmProof, ctx)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid Pedersen parameters: " + err.Error()})
+		return
+	}
+	// 正規形（base64 protobuf）に変換して保存する
+	normalized, err := pub.MarshalText()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to encode Paillier key"})
+		return
+	}
+
 	// 認証成功の場合、ユーザーをDBに登録（既存の場合は更新）
-	user := models.User{Address: req.Address, Pubkey: req.Pubkey}
+	user := models.User{Address: normalizeAddress(req.Address), Pubkey: string(normalized), Pedersen: pedersen}
 	// GORMのSaveはプライマリキーに基づいて更新・作成を行う
 	if err := db.DB.Save(&user).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Database error"})
 		return
 	}
 
-	// 使用済みチャレンジを削除
-	challengeStore.Lock()
-	delete(challengeStore.m, strings.ToLower(req.Address))
-	challengeStore.Unlock()
-
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Register Paillier Pubkey", "address": req.Address})
 }
 
+// registrationContext は、公開鍵登録の証明を結び付ける文脈（登録するアドレスとチャレンジ）です。
+// 他のアドレスの登録で証明を再利用できないよう、Fiat–Shamir変換のハッシュに含めます。
+func registrationContext(address, challenge string) []byte {
+	return []byte("paillier-register/" + strings.ToLower(address) + "/" + challenge)
+}
+
+// parsePaillierPubkey は、正規形またはJSON形式のPaillier公開鍵を解析し、形式を検証します。
+func parsePaillierPubkey(s string) (*paillier.PublicKey, error) {
+	var pub paillier.PublicKey
+	var err error
+	if strings.HasPrefix(strings.TrimSpace(s), "{") {
+		err = json.Unmarshal([]byte(s), &pub)
+	} else {
+		err = pub.UnmarshalText([]byte(s))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode pubkey: %v", err)
+	}
+	if err := pub.Validate(); err != nil {
+		return nil, err
+	}
+	return &pub, nil
+}
+
+// verifyPaillierKey は、公開鍵に対するΠ-mod（パイエ・ブラム整数）、Π-fac（小さな素因数なし）の証明を、文脈 ctx に対して検証します。
+func verifyPaillierKey(pub *paillier.PublicKey, modProof, facProof string, ctx []byte) error {
+	var modMsg pb.ModProof
+	if err := decodeProto(modProof, &modMsg); err != nil {
+		return fmt.Errorf("failed to decode modProof: %v", err)
+	}
+	mod, err := paillier.ModProofFromProto(&modMsg)
+	if err != nil {
+		return err
+	}
+	if !mod.Verify(pub.N, ctx) {
+		return errors.New("modulus proof verification failed")
+	}
+
+	var facMsg pb.FacProof
+	if err := decodeProto(facProof, &facMsg); err != nil {
+		return fmt.Errorf("failed to decode facProof: %v", err)
+	}
+	fac, err := paillier.FacProofFromProto(&facMsg)
+	if err != nil {
+		return err
+	}
+	params, err := serverPedersenParams()
+	if err != nil {
+		return err
+	}
+	if !fac.Verify(pub.N, params, ctx) {
+		return errors.New("factor proof verification failed")
+	}
+	return nil
+}
+
+// verifyPedersenParams は、リングPedersenパラメータが公開鍵と同じ法を持ち、Π-prm の証明が文脈 ctx に対して成り立つことを検証します。
+// 法が公開鍵と同じなので、Π-mod, Π-fac により法の性質も保証されます。保存用の正規形（base64 protobuf）を返します。
+func verifyPedersenParams(pub *paillier.PublicKey, params, prmProof string, ctx []byte) (string, error) {
+	var ppMsg pb.PedersenParams
+	if err := decodeProto(params, &ppMsg); err != nil {
+		return "", fmt.Errorf("failed to decode pedersen: %v", err)
+	}
+	pp, err := paillier.PedersenParamsFromProto(&ppMsg)
+	if err != nil {
+		return "", err
+	}
+	if pp.N.Cmp(pub.N) != 0 {
+		return "", errors.New("modulus does not match the Paillier key")
+	}
+	var prmMsg pb.PrmProof
+	if err := decodeProto(prmProof, &prmMsg); err != nil {
+		return "", fmt.Errorf("failed to decode prmProof: %v", err)
+	}
+	prm, err := paillier.PrmProofFromProto(&prmMsg)
+	if err != nil {
+		return "", err
+	}
+	if !prm.Verify(pp, ctx) {
+		return "", errors.New("parameter proof verification failed")
+	}
+	raw, err := proto.Marshal(pp.ToProto())
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// decodeProto は、base64文字列をProtobufメッセージにデコードします。
+func decodeProto(s string, msg proto.Message) error {
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(raw, msg)
+}
+
 // verifySignature は、チャレンジメッセージと署名から署名者のアドレスが一致するか検証します。
 // Ethereumのpersonal_signでは、メッセージの先頭に定型文字列が付加されます。
 func verifySignature(message, signatureHex, expectedAddress string) (bool, error) {
@@ -150,9 +384,12 @@ func verifySignature(message, signatureHex, expectedAddress string) (bool, error
 	if err != nil {
 		return false, fmt.Errorf("failed to decode signature: %v", err)
 	}
-	// リカバリIDの補正（27,28に合わせる）
-	if sig[64] != 27 && sig[64] != 28 {
-		sig[64] += 27
+	if len(sig) != 65 {
+		return false, fmt.Errorf("invalid signature length: %d", len(sig))
+	}
+	// リカバリIDの補正（SigToPub は 0,1 のみを受け付けるため、27,28 を変換する）
+	if sig[64] >= 27 {
+		sig[64] -= 27
 	}
 
 	// Ethereum仕様に基づくメッセージの前処理
@@ -175,3 +412,12 @@ func verifySignature(message, signatureHex, expectedAddress string) (bool, error
 
 	return true, nil
 }
+
+// normalizeAddress は、アドレスをEIP-55のチェックサム形式に揃えます。
+// ユーザーとマルチシグのアドレスはこの形式で保存し、大文字小文字の違いで別のユーザーにならないようにします。
+func normalizeAddress(address string) string {
+	if !common.IsHexAddress(address) {
+		return address
+	}
+	return common.HexToAddress(address).Hex()
+}
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"multisigservice/db"
+	"multisigservice/models"
+	"multisigservice/protocol/additive"
+	"multisigservice/protocol/cmp"
+)
+
+// cmpRoundResult は、参加者の結果報告を保存するラウンド番号です。
+const cmpRoundResult = -1
+
+// CMPResultReport は、CMPのプロトコルを終えた参加者が報告する結果です。
+// 秘密鍵の情報は含まず、サーバーが検証できる公開情報のみを送ります。
+type CMPResultReport struct {
+	PublicKey    *additive.Point            `json:"publicKey,omitempty"`    // keygen, refresh
+	PublicShares map[string]*additive.Point `json:"publicShares,omitempty"` // keygen, refresh
+	Signature    hexutil.Bytes              `json:"signature,omitempty"`    // sign（r || s || v の65バイト）
+}
+
+// StartCMPSessionHandler は、CMPマルチシグの事前署名（presign）のセッションを開始します。
+// signers で署名者を指定でき、省略時は参加者全員とします。
+// 鍵更新は開始者の署名と他の鍵更新との排他が必要なため、StartRefreshHandler で開始します。
+func StartCMPSessionHandler(c *gin.Context) {
+	kind := c.Param("kind")
+	if kind != "presign" {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Unknown session kind: " + kind})
+		return
+	}
+	var req struct {
+		Signers []string `json:"signers"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+			return
+		}
+	}
+
+	var session *models.Session
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		ms, err := loadMultiSig(tx, c.Param("address"))
+		if err != nil {
+			return err
+		}
+		if ms.Scheme != "cmp" {
+			return badRequest("MultiSig does not use CMP")
+		}
+		participants, err := signerSet(ms, req.Signers)
+		if err != nil {
+			return err
+		}
+		session, err = newSession(tx, "cmp", kind, ms, participants, struct{}{})
+		return err
+	})
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Session created", "session": session})
+}
+
+// RelayCMPMessageHandler は、CMPの protocol.Message を中継のため保存します。
+// サーバーは内容を復号できないため、送信者の personal_sign 署名とヘッダ（プロトコル・送信者・宛先）のみを検証します。
+// 署名はヘッダのラウンドに対して検証するため、中断（ラウンド0）も参加者本人しか通知できません。
+// 宛先の参加者はセッション取得APIでメッセージを受け取ります。
+func RelayCMPMessageHandler(c *gin.Context) {
+	var req SessionMessageRequest
+	var raw []byte
+	if err := c.ShouldBindJSON(&req); err != nil || json.Unmarshal(req.Message, &raw) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid message"})
+		return
+	}
+	header, err := cmp.ParseMessage(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid message: " + err.Error()})
+		return
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		session, participants, err := lockSession(tx, c.Param("session"), "cmp", "")
+		if err != nil {
+			return err
+		}
+		if session.Status != "active" {
+			return conflict("Session is " + session.Status)
+		}
+		from, err := participantAddress(participants, req.From)
+		if err != nil {
+			return err
+		}
+		protocolID, err := cmpProtocolID(session)
+		if err != nil {
+			return err
+		}
+		if err := header.Check(protocolID, from, participants); err != nil {
+			return badRequest(err.Error())
+		}
+		signed, err := verifyMessage(session, header.Round, from, &req)
+		if err != nil {
+			return err
+		}
+		if err := storeSigned(tx, signed, header.To, raw); err != nil {
+			return err
+		}
+		// ラウンド0は送信者による中断の通知
+		if header.Round == 0 {
+			return tx.Model(session).Update("status", "aborted").Error
+		}
+		return nil
+	})
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Message relayed"})
+}
+
+// ReportCMPResultHandler は、CMPのプロトコルを終えた参加者の署名付きの結果報告を受け付けます。
+// 鍵生成・鍵更新では全員の報告が一致し、公開シェアが公開鍵と整合した時点で、署名では検証できる署名が報告された時点でセッションを完了します。
+// 事前署名では全員の報告が揃った時点で、事前署名を使用可能な状態で保存します（秘密の素材は各参加者が保持します）。
+func ReportCMPResultHandler(c *gin.Context) {
+	var req SessionMessageRequest
+	var report CMPResultReport
+	if err := c.ShouldBindJSON(&req); err != nil || json.Unmarshal(req.Message, &report) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid result"})
+		return
+	}
+
+	var completed bool
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		session, participants, err := lockSession(tx, c.Param("session"), "cmp", "")
+		if err != nil {
+			return err
+		}
+		if session.Status != "active" {
+			return conflict("Session is " + session.Status)
+		}
+		from, err := participantAddress(participants, req.From)
+		if err != nil {
+			return err
+		}
+		signed, err := verifyMessage(session, cmpRoundResult, from, &req)
+		if err != nil {
+			return err
+		}
+
+		if session.Kind == "sign" {
+			completed, err = completeCMPSigning(tx, session, &report)
+			return err
+		}
+		if err := storeSigned(tx, signed, "", &report); err != nil {
+			return err
+		}
+		msgs, err := roundMessages(tx, session.SessionID, cmpRoundResult)
+		if err != nil || len(msgs) < len(participants) {
+			return err
+		}
+		reports, err := decodeMessages[CMPResultReport](msgs)
+		if err != nil {
+			return err
+		}
+		if session.Kind != "presign" {
+			if err := agreeOnPublicKey(reports); err != nil {
+				return err
+			}
+		}
+
+		var result interface{} = struct{}{}
+		switch session.Kind {
+		case "keygen":
+			var data KeygenData
+			if err := json.Unmarshal(session.Data, &data); err != nil {
+				return err
+			}
+			// CMPのライブラリの閾値は t-1
+			if err := cmp.CheckPublicShares(report.PublicKey, report.PublicShares, participants, data.Threshold-1); err != nil {
+				return badRequest(err.Error())
+			}
+			result = &KeygenResult{
+				PublicKey:    report.PublicKey,
+				Address:      additive.EthereumAddress(report.PublicKey),
+				PublicShares: report.PublicShares,
+			}
+		case "refresh":
+			ms, err := lockMultiSig(tx, session.MultiSig)
+			if err != nil {
+				return err
+			}
+			Q, err := multisigPublicKey(ms)
+			if err != nil {
+				return err
+			}
+			if !Q.Equal(report.PublicKey) {
+				return badRequest("Refresh changed the public key")
+			}
+			if err := cmp.CheckPublicShares(Q, report.PublicShares, participants, ms.Threshold-1); err != nil {
+				return badRequest(err.Error())
+			}
+			if _, err := advanceEpoch(tx, ms, session, report.PublicShares); err != nil {
+				return err
+			}
+			result = &report
+		case "presign":
+			if err := tx.Create(&models.Presignature{
+				PresignID: session.SessionID,
+				MultiSig:  session.MultiSig,
+				Scheme:    "cmp",
+				Signers:   session.Participants,
+				Epoch:     session.Epoch,
+				Status:    "available",
+			}).Error; err != nil {
+				return err
+			}
+		}
+		completed = true
+		return tx.Model(session).Updates(map[string]interface{}{
+			"status": "completed",
+			"result": datatypes.JSON([]byte(mustMarshal(result))),
+		}).Error
+	})
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	if completed {
+		c.JSON(http.StatusOK, gin.H{"message": "Session completed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Result accepted"})
+}
+
+// agreeOnPublicKey は、全員が同じ公開鍵と公開シェアを報告したか確認します。
+func agreeOnPublicKey(reports map[string]*CMPResultReport) error {
+	var first *CMPResultReport
+	for _, r := range reports {
+		if r.PublicKey == nil || r.PublicKey.IsIdentity() {
+			return badRequest("Public key is missing")
+		}
+		if first == nil {
+			first = r
+			continue
+		}
+		if !first.PublicKey.Equal(r.PublicKey) || mustMarshal(first.PublicShares) != mustMarshal(r.PublicShares) {
+			return badRequest("Participants reported different public keys")
+		}
+	}
+	return nil
+}
+
+// completeCMPSigning は、報告された署名をマルチシグの公開鍵で検証し、正しければ署名セッションを完了します。
+func completeCMPSigning(tx *gorm.DB, session *models.Session, report *CMPResultReport) (bool, error) {
+	var data SignData
+	if err := json.Unmarshal(session.Data, &data); err != nil {
+		return false, err
+	}
+	ms, err := loadMultiSig(tx, session.MultiSig)
+	if err != nil {
+		return false, err
+	}
+	Q, err := multisigPublicKey(ms)
+	if err != nil {
+		return false, err
+	}
+	sig, err := additive.ParseSignature(data.Hash, Q, report.Signature)
+	if err != nil {
+		return false, badRequest("Invalid signature: " + err.Error())
+	}
+	return true, completeSigning(tx, session, ms, data.Hash, &SignResult{Signature: sig})
+}
+
+// cmpProtocolID は、セッションで中継するCMPメッセージのプロトコルIDを返します。
+func cmpProtocolID(session *models.Session) (string, error) {
+	switch session.Kind {
+	case "keygen":
+		return cmp.ProtocolKeygen, nil
+	case "refresh":
+		return cmp.ProtocolRefresh, nil
+	case "presign":
+		return cmp.ProtocolPresign, nil
+	case "sign":
+		var data SignData
+		if err := json.Unmarshal(session.Data, &data); err != nil {
+			return "", err
+		}
+		if data.Presign != "" {
+			return cmp.ProtocolPresignOnline, nil
+		}
+		return cmp.ProtocolSign, nil
+	}
+	return "", errors.New("unknown session kind: " + session.Kind)
+}
@@ -0,0 +1,421 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"multisigservice/db"
+	"multisigservice/models"
+	"multisigservice/protocol/additive"
+)
+
+// 鍵生成のラウンド（メッセージの署名対象となる番号は additive パッケージと共通）
+const (
+	keygenRoundCommit    = additive.KeygenRoundCommit
+	keygenRoundReveal    = additive.KeygenRoundReveal
+	keygenRoundConfirm   = additive.KeygenRoundConfirm   // 受け取ったシェアの確認
+	keygenRoundComplaint = additive.KeygenRoundComplaint // 確認の代わりに送る苦情（セッションは確認のラウンドのまま）
+)
+
+// KeygenResult は鍵生成セッションの結果として Session.Result に保存される内容です。
+type KeygenResult struct {
+	PublicKey    *additive.Point            `json:"publicKey"`
+	Address      string                     `json:"address"`
+	PublicShares map[string]*additive.Point `json:"publicShares"`
+}
+
+// KeygenData は鍵生成セッションの入力として Session.Data に保存される内容です。
+type KeygenData struct {
+	Threshold int          `json:"threshold"`      // 署名に必要な人数 t（CMPのライブラリには t-1 を渡す）
+	Keys      *SessionKeys `json:"keys,omitempty"` // 加法的方式でシェアを配る参加者の開始時点の公開鍵（サーバーが設定）
+}
+
+// MultiSigMembers は、マルチシグの参加者と閾値の指定です。
+type MultiSigMembers struct {
+	Owner        string   `json:"owner"`        // ログイン済みのユーザーアドレス
+	Participants []string `json:"participants"` // 参加者のEthereumアドレス
+	IncludeOwner bool     `json:"includeOwner"` // 作成者を参加者に含めるか
+	Threshold    int      `json:"threshold"`    // 署名に必要な人数 t
+}
+
+// normalize は、参加者をチェックサム付きアドレスに揃えて返します。
+// IncludeOwner の場合は作成者を先頭に加え、参加者の重複と 1 ≤ t ≤ n を検証します。
+func (m *MultiSigMembers) normalize() ([]string, error) {
+	addresses := m.Participants
+	if m.IncludeOwner {
+		if !common.IsHexAddress(m.Owner) {
+			return nil, badRequest("Invalid owner address: " + m.Owner)
+		}
+		addresses = append([]string{m.Owner}, addresses...)
+	}
+	participants := make([]string, 0, len(addresses))
+	for _, p := range addresses {
+		if !common.IsHexAddress(p) {
+			return nil, badRequest("Invalid participant address: " + p)
+		}
+		p = common.HexToAddress(p).Hex()
+		for _, q := range participants {
+			if p == q {
+				return nil, badRequest("Duplicate participant address: " + p)
+			}
+		}
+		participants = append(participants, p)
+	}
+	if len(participants) < 2 {
+		return nil, badRequest("Require at least 2 participants")
+	}
+	if m.Threshold < 1 || m.Threshold > len(participants) {
+		return nil, badRequest(fmt.Sprintf("Threshold must be between 1 and %d", len(participants)))
+	}
+	return participants, nil
+}
+
+// StartKeygenHandler は、指定参加者による t-of-n の鍵生成セッションを開始します。
+// scheme で組み込みの加法的方式（"additive"、既定）かCMP（"cmp"）を選択します。
+// 加法的方式ではシェアをPaillier暗号で配るため、参加者全員がPaillier公開鍵を登録済みである必要があります。
+func StartKeygenHandler(c *gin.Context) {
+	var req struct {
+		MultiSigMembers
+		Scheme string `json:"scheme"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+		return
+	}
+	if req.Scheme == "" {
+		req.Scheme = "additive"
+	}
+	if req.Scheme != "additive" && req.Scheme != "cmp" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Unknown scheme: " + req.Scheme})
+		return
+	}
+
+	var session *models.Session
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		participants, err := req.normalize()
+		if err != nil {
+			return err
+		}
+		data := &KeygenData{Threshold: req.Threshold}
+		if req.Scheme == "additive" {
+			// 苦情の検証に使う公開鍵が途中で登録し直されないよう、開始時点の値をセッションに保存する
+			if data.Keys, err = snapshotKeys(tx, participants); err != nil {
+				return err
+			}
+		}
+		session, err = newSession(tx, req.Scheme, "keygen", nil, participants, data)
+		return err
+	})
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Keygen session created", "session": session})
+}
+
+// additiveSession は、鍵生成・鍵更新セッションの参加者・閾値と開始時点のPaillier公開鍵を読み込みます。
+func additiveSession(tx *gorm.DB, session *models.Session, participants []string) (*additive.Session, error) {
+	var data KeygenData
+	if err := json.Unmarshal(session.Data, &data); err != nil {
+		return nil, err
+	}
+	if data.Keys == nil {
+		return nil, conflict("Session has no snapshot of the participants' keys")
+	}
+	keys, _, err := data.Keys.load()
+	if err != nil {
+		return nil, err
+	}
+	return &additive.Session{
+		ID:           session.SessionID,
+		Parties:      participants,
+		Threshold:    data.Threshold,
+		PaillierKeys: keys,
+	}, nil
+}
+
+// KeygenCommitHandler は、参加者の多項式の係数へのコミットメントを受け付けます。
+// メッセージは送信者の personal_sign 署名を検証してから受け付けます。全員分が揃うと公開ラウンドに進みます。
+func KeygenCommitHandler(c *gin.Context) {
+	var req SessionMessageRequest
+	var commit additive.KeygenCommit
+	if err := c.ShouldBindJSON(&req); err != nil || json.Unmarshal(req.Message, &commit) != nil || len(commit.Commitment) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid commitment"})
+		return
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		session, participants, err := lockSession(tx, c.Param("session"), "additive", "keygen")
+		if err != nil {
+			return err
+		}
+		if err := expectRound(session, keygenRoundCommit); err != nil {
+			return err
+		}
+		from, err := participantAddress(participants, req.From)
+		if err != nil {
+			return err
+		}
+		signed, err := verifyMessage(session, keygenRoundCommit, from, &req)
+		if err != nil {
+			return err
+		}
+		if err := storeSigned(tx, signed, "", &commit); err != nil {
+			return err
+		}
+		msgs, err := roundMessages(tx, session.SessionID, keygenRoundCommit)
+		if err != nil {
+			return err
+		}
+		if len(msgs) < len(participants) {
+			return nil
+		}
+		return tx.Model(session).Update("round", keygenRoundReveal).Error
+	})
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Commitment accepted"})
+}
+
+// KeygenRevealHandler は、送信者の署名と、Feldmanコミットメント・Schnorr証明・暗号化されたシェアを検証して受け付けます。
+// 全員分が揃うと Q と各参加者の公開シェアを導出できることを確認し、確認のラウンドに進みます。
+func KeygenRevealHandler(c *gin.Context) {
+	var req SessionMessageRequest
+	var reveal additive.KeygenReveal
+	if err := c.ShouldBindJSON(&req); err != nil || json.Unmarshal(req.Message, &reveal) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid reveal"})
+		return
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		session, participants, err := lockSession(tx, c.Param("session"), "additive", "keygen")
+		if err != nil {
+			return err
+		}
+		if err := expectRound(session, keygenRoundReveal); err != nil {
+			return err
+		}
+		from, err := participantAddress(participants, req.From)
+		if err != nil {
+			return err
+		}
+		signed, err := verifyMessage(session, keygenRoundReveal, from, &req)
+		if err != nil {
+			return err
+		}
+
+		msgs, err := roundMessages(tx, session.SessionID, keygenRoundCommit)
+		if err != nil {
+			return err
+		}
+		commits, err := decodeMessages[additive.KeygenCommit](msgs)
+		if err != nil {
+			return err
+		}
+		ks, err := additiveSession(tx, session, participants)
+		if err != nil {
+			return err
+		}
+		if err := ks.VerifyKeygenReveal(from, commits[from], &reveal); err != nil {
+			return badRequest(err.Error())
+		}
+		if err := storeSigned(tx, signed, "", &reveal); err != nil {
+			return err
+		}
+
+		msgs, err = roundMessages(tx, session.SessionID, keygenRoundReveal)
+		if err != nil {
+			return err
+		}
+		if len(msgs) < len(participants) {
+			return nil
+		}
+		if _, err := keygenResult(tx, session, ks); err != nil {
+			return err
+		}
+		return tx.Model(session).Update("round", keygenRoundConfirm).Error
+	})
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Reveal accepted"})
+}
+
+// KeygenConfirmHandler は、参加者のシェアの知識のSchnorr証明を、送信者の署名と共に検証して受け付けます。
+// 参加者は受け取ったシェアがすべてディーラーのコミットメントと一致した場合にのみ確認を送ります。
+// 全員分が揃うと Q と各参加者の公開シェア、Ethereumアドレスを結果として保存し、セッションを完了します。
+func KeygenConfirmHandler(c *gin.Context) {
+	var req SessionMessageRequest
+	var confirm additive.KeygenConfirm
+	if err := c.ShouldBindJSON(&req); err != nil || json.Unmarshal(req.Message, &confirm) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid confirmation"})
+		return
+	}
+
+	var result *KeygenResult
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		session, participants, from, err := keygenSession(tx, c, req.From)
+		if err != nil {
+			return err
+		}
+		signed, err := verifyMessage(session, keygenRoundConfirm, from, &req)
+		if err != nil {
+			return err
+		}
+		ks, err := additiveSession(tx, session, participants)
+		if err != nil {
+			return err
+		}
+		res, err := keygenResult(tx, session, ks)
+		if err != nil {
+			return err
+		}
+		if err := ks.VerifyKeygenConfirm(from, res.PublicShares[from], &confirm); err != nil {
+			return badRequest(err.Error())
+		}
+		if err := storeSigned(tx, signed, "", &confirm); err != nil {
+			return err
+		}
+
+		msgs, err := roundMessages(tx, session.SessionID, keygenRoundConfirm)
+		if err != nil || len(msgs) < len(participants) {
+			return err
+		}
+		result = res
+		return tx.Model(session).Updates(map[string]interface{}{
+			"status": "completed",
+			"result": datatypes.JSON([]byte(mustMarshal(result))),
+		}).Error
+	})
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	if result != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Keygen completed", "result": result})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Confirmation accepted"})
+}
+
+// KeygenComplaintHandler は、コミットメントと一致しないシェアを配ったディーラーへの苦情を受け付けます。
+// 苦情は送信者宛ての暗号文の開示を含み、サーバーはディーラーの公開に照らして検証します。
+// 正当な苦情であればディーラーを不正者として記録し、署名済みの公開と苦情を証拠として鍵生成を中断します。
+// 証拠は誰でも additive.KeygenEvidence.Verify で再検証できます。正当でない苦情は拒否します。
+func KeygenComplaintHandler(c *gin.Context) {
+	var req SessionMessageRequest
+	var complaint additive.KeygenComplaint
+	if err := c.ShouldBindJSON(&req); err != nil || json.Unmarshal(req.Message, &complaint) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid complaint"})
+		return
+	}
+
+	var fault *additive.Fault
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		session, participants, from, err := keygenSession(tx, c, req.From)
+		if err != nil {
+			return err
+		}
+		signed, err := verifyMessage(session, keygenRoundComplaint, from, &req)
+		if err != nil {
+			return err
+		}
+		dealer, err := participantAddress(participants, complaint.Dealer)
+		if err != nil {
+			return badRequest("Dealer is not a participant of the session")
+		}
+		complaint.Dealer = dealer
+		var msg models.SessionMessage
+		if err := tx.First(&msg, "session_id = ? AND round = ? AND \"from\" = ?", session.SessionID, keygenRoundReveal, dealer).Error; err != nil {
+			return err
+		}
+		var reveal additive.KeygenReveal
+		if err := json.Unmarshal(msg.Payload, &reveal); err != nil {
+			return err
+		}
+		ks, err := additiveSession(tx, session, participants)
+		if err != nil {
+			return err
+		}
+		if err := ks.VerifyKeygenComplaint(from, &reveal, &complaint); err != nil {
+			return badRequest(err.Error())
+		}
+		if err := storeSigned(tx, signed, "", &complaint); err != nil {
+			return err
+		}
+
+		sig, err := hexutil.Decode(msg.Signature)
+		if err != nil {
+			return err
+		}
+		fault = &additive.Fault{Culprit: dealer, Reason: "dealt a share inconsistent with its commitments"}
+		ev := &additive.KeygenEvidence{
+			SessionID:   session.SessionID,
+			Parties:     participants,
+			Threshold:   ks.Threshold,
+			PaillierKey: ks.PaillierKeys[from],
+			Reveal: &additive.SignedMessage{
+				Session:   session.SessionID,
+				Round:     keygenRoundReveal,
+				From:      dealer,
+				Payload:   msg.Signed,
+				Signature: sig,
+			},
+			Complaint: signed,
+			Fault:     fault,
+		}
+		return tx.Model(session).Updates(map[string]interface{}{
+			"status":   "aborted",
+			"culprit":  dealer,
+			"evidence": datatypes.JSON([]byte(mustMarshal(ev))),
+		}).Error
+	})
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Keygen aborted", "fault": fault})
+}
+
+// keygenSession は、加法的方式の鍵生成セッションをロックし、確認のラウンドと送信者を確認します。
+func keygenSession(tx *gorm.DB, c *gin.Context, sender string) (*models.Session, []string, string, error) {
+	session, participants, err := lockSession(tx, c.Param("session"), "additive", "keygen")
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if err := expectRound(session, keygenRoundConfirm); err != nil {
+		return nil, nil, "", err
+	}
+	from, err := participantAddress(participants, sender)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return session, participants, from, nil
+}
+
+// keygenResult は、全員の公開から公開鍵・アドレス・各参加者の公開シェアを導出します。
+func keygenResult(tx *gorm.DB, session *models.Session, ks *additive.Session) (*KeygenResult, error) {
+	msgs, err := roundMessages(tx, session.SessionID, keygenRoundReveal)
+	if err != nil {
+		return nil, err
+	}
+	reveals, err := decodeMessages[additive.KeygenReveal](msgs)
+	if err != nil {
+		return nil, err
+	}
+	Q, shares, err := ks.CombineKeygen(reveals)
+	if err != nil {
+		return nil, badRequest(err.Error())
+	}
+	return &KeygenResult{PublicKey: Q, Address: additive.EthereumAddress(Q), PublicShares: shares}, nil
+}
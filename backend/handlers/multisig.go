@@ -1,64 +1,128 @@
 package handlers
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
 	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/gin-gonic/gin"
 	"multisigservice/db"
 	"multisigservice/models"
 
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
-	"time"
 )
 
-// CreateMultiSigHandler は、ログインユーザー（Owner）と2名の参加者からマルチシグを作成しDBに登録します。
+// CreateMultiSigHandler は、完了した鍵生成セッションから t-of-n のマルチシグを作成しDBに登録します。
+// アドレスはサーバーが鍵生成の結果から導出したものを用い、クライアントの申告と一致しない場合は拒否します。
+// 所有者には ChallengeHandler のチャレンジを付けた作成メッセージへの personal_sign 署名を要求します。
 func CreateMultiSigHandler(c *gin.Context) {
 	var req struct {
-		Owner        string   `json:"owner"`        // ログイン済みのユーザーアドレス
-		Participants []string `json:"participants"` // 参加者のEthereumアドレス（2名）
-		Address      string   `json:"address"`        // マルチシグ公開鍵のアドレス
+		MultiSigMembers
+		Address   string        `json:"address"`   // マルチシグ公開鍵のアドレス
+		Session   string        `json:"session"`   // 完了した鍵生成セッションのID
+		Signature hexutil.Bytes `json:"signature"` // createMultiSigMessage とチャレンジへの所有者の署名
 	}
-	if err := c.ShouldBindJSON(&req); err != nil || len(req.Participants) != 2 {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Require owner and exactly 2 participants"})
+	if err := c.ShouldBindJSON(&req); err != nil || req.Session == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Require owner, participants, threshold and a keygen session"})
 		return
 	}
 
-	// マルチシグIDを生成し、初期状態を設定
-	newMultiSig := models.MultiSig{
-		Address:      req.Address,
-		Owner:        req.Owner,
-		Participants: datatypes.JSON([]byte(mustMarshal(req.Participants))),
-		Status:       "awaiting",
-		Data:         datatypes.JSON([]byte(`{}`)),
-	}
+	var newMultiSig models.MultiSig
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		members, err := req.normalize()
+		if err != nil {
+			return err
+		}
+		if !common.IsHexAddress(req.Owner) {
+			return badRequest("Invalid owner address: " + req.Owner)
+		}
+		owner := common.HexToAddress(req.Owner).Hex()
+		session, participants, err := lockSession(tx, req.Session, "", "keygen")
+		if err != nil {
+			return err
+		}
+		if session.Status != "completed" {
+			return conflict("Keygen session is not completed")
+		}
+		if session.MultiSig != "" {
+			return conflict("Keygen session is already used by " + session.MultiSig)
+		}
+		if len(participants) != len(members) {
+			return badRequest("Participants do not match the keygen session")
+		}
+		for _, p := range members {
+			if _, err := participantAddress(participants, p); err != nil {
+				return badRequest("Participants do not match the keygen session")
+			}
+		}
+		var data KeygenData
+		if err := json.Unmarshal(session.Data, &data); err != nil {
+			return err
+		}
+		if data.Threshold != req.Threshold {
+			return badRequest("Threshold does not match the keygen session")
+		}
+		var result KeygenResult
+		if err := json.Unmarshal(session.Result, &result); err != nil {
+			return err
+		}
+		if !strings.EqualFold(result.Address, req.Address) {
+			return badRequest("Address does not match the generated public key")
+		}
+		if err := verifyChallengeSignature(createMultiSigMessage(result.Address, session.SessionID), hexutil.Encode(req.Signature), owner); err != nil {
+			return err
+		}
 
-	if err := db.DB.Create(&newMultiSig).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Database error on create"})
+		// マルチシグを登録し、初期状態を設定
+		newMultiSig = models.MultiSig{
+			Address:          result.Address,
+			Owner:            owner,
+			Participants:     session.Participants,
+			Status:           "awaiting",
+			Data:             datatypes.JSON([]byte(`{}`)),
+			Scheme:           session.Scheme,
+			PublicKey:        hex.EncodeToString(result.PublicKey.Bytes()),
+			PublicShares:     datatypes.JSON([]byte(mustMarshal(result.PublicShares))),
+			Threshold:        data.Threshold,
+			ParticipantCount: len(participants),
+		}
+		if err := tx.Create(&newMultiSig).Error; err != nil {
+			return err
+		}
+		return tx.Model(session).Update("multi_sig", result.Address).Error
+	})
+	if err != nil {
+		respondSessionError(c, err)
 		return
 	}
 
-	for _, address := range req.Participants {
-    	var user models.User
-    	if err := db.DB.First(&user, "address = ?", address).Error; err != nil {
-        	if errors.Is(err, gorm.ErrRecordNotFound) {
-            	continue
-        	} else {
-            	c.JSON(http.StatusInternalServerError, gin.H{"message": "Database error fetching user"})
+	for _, address := range participantsOf(newMultiSig) {
+		var user models.User
+		if err := db.DB.First(&user, "LOWER(address) = LOWER(?)", address).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"message": "Database error fetching user"})
 				return
-        	}
-    	}
-
-    	var msAddresses []string
-    	if err := json.Unmarshal(user.MultiSigs, &msAddresses); err != nil {
-        	c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to parse user's multisigs list"})
-        	return
-    	}
-		msAddresses = append(msAddresses, req.Address)
-		
-		user = models.User{Address: address, MultiSigs: datatypes.JSON([]byte(mustMarshal(msAddresses)))}
+			}
+		}
+
+		var msAddresses []string
+		if len(user.MultiSigs) > 0 {
+			if err := json.Unmarshal(user.MultiSigs, &msAddresses); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to parse user's multisigs list"})
+				return
+			}
+		}
+		msAddresses = append(msAddresses, normalizeAddress(newMultiSig.Address))
+
+		user.MultiSigs = datatypes.JSON([]byte(mustMarshal(msAddresses)))
 		if err := db.DB.Save(&user).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Database error"})
 			return
@@ -68,6 +132,18 @@ func CreateMultiSigHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "MultiSig created", "multisig": newMultiSig})
 }
 
+// createMultiSigMessage は、所有者がマルチシグの作成時に署名するメッセージを返します。
+func createMultiSigMessage(address, session string) string {
+	return fmt.Sprintf("Create multisig %s\nSession: %s", address, session)
+}
+
+// participantsOf は、マルチシグの参加者アドレスを返します。
+func participantsOf(ms models.MultiSig) []string {
+	var participants []string
+	json.Unmarshal(ms.Participants, &participants)
+	return participants
+}
+
 // GetMultiSigListHandler は、指定ユーザーが参加しているマルチシグの一覧を返します。
 func GetMultiSigListHandler(c *gin.Context) {
     userAddress := c.Query("address")
@@ -76,8 +152,9 @@ func GetMultiSigListHandler(c *gin.Context) {
         return
     }
 
+    // 保存済みのアドレスは大文字小文字が揃っていない場合があるため、小文字で比較する
     var user models.User
-    if err := db.DB.First(&user, "address = ?", userAddress).Error; err != nil {
+    if err := db.DB.First(&user, "LOWER(address) = LOWER(?)", normalizeAddress(userAddress)).Error; err != nil {
         if errors.Is(err, gorm.ErrRecordNotFound) {
             c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
         } else {
@@ -97,9 +174,12 @@ func GetMultiSigListHandler(c *gin.Context) {
         return
     }
 
+    for i, address := range msAddresses {
+        msAddresses[i] = strings.ToLower(address)
+    }
     var multisigs []models.MultiSig
     if err := db.DB.
-        Where("address IN ?", msAddresses).
+        Where("LOWER(address) IN ?", msAddresses).
         Find(&multisigs).
         Error; err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"message": "Database error on list"})
@@ -110,60 +190,6 @@ func GetMultiSigListHandler(c *gin.Context) {
     c.JSON(http.StatusOK, multisigs)
 }
 
-// GetMultiSigDataHandler は、指定マルチシグの署名用データ（例としてプレースホルダー）を生成し返します。
-func GetMultiSigDataHandler(c *gin.Context) {
-	address := c.Param("address")
-	var ms models.MultiSig
-	if err := db.DB.First(&ms, "address = ?", address).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "MultiSig not found"})
-		return
-	}
-	// 状態に応じたデータ（ここではシンプルにタイムスタンプ付きの文字列を例示）
-	dataToSign := "data-placeholder-" + time.Now().String()
-	dataJSON, _ := json.Marshal(map[string]string{"dataToSign": dataToSign})
-	ms.Data = datatypes.JSON(dataJSON)
-
-	// DB上も更新
-	db.DB.Save(&ms)
-	c.JSON(http.StatusOK, gin.H{"dataToSign": dataToSign})
-}
-
-// UpdateMultiSigDataHandler は、クライアントから送信された署名情報をDB上のマルチシグに反映し状態更新します。
-func UpdateMultiSigDataHandler(c *gin.Context) {
-	address := c.Param("address")
-	var payload struct {
-		Signature string `json:"signature"`
-	}
-	if err := c.ShouldBindJSON(&payload); err != nil || payload.Signature == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid signature payload"})
-		return
-	}
-
-	var ms models.MultiSig
-	if err := db.DB.First(&ms, "address = ?", address).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "MultiSig not found"})
-		return
-	}
-
-	// 受信した署名情報をJSON形式で保存（必要に応じたプロトコルロジックの実装を行う）
-	dataMap := map[string]interface{}{}
-	// 既存のDataを読み込む
-	json.Unmarshal(ms.Data, &dataMap)
-	dataMap["signature"] = payload.Signature
-	updatedData, _ := json.Marshal(dataMap)
-	ms.Data = datatypes.JSON(updatedData)
-
-	// 状態更新（例：一方の署名を受領した場合）
-	ms.Status = "partial"
-
-	if err := db.DB.Save(&ms).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Database error on update"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Signature data updated", "multisig": ms})
-}
-
 // mustMarshal は、JSON変換に失敗した場合にpanicする簡易関数です。
 func mustMarshal(v interface{}) string {
 	b, err := json.Marshal(v)
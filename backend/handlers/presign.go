@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"multisigservice/db"
+	"multisigservice/models"
+	"multisigservice/protocol/additive"
+)
+
+// StartPresignHandler は、マルチシグの事前署名セッションを開始します。
+// 事前署名では署名対象に依存しないラウンド（加法的方式ではラウンド1〜4）を先に済ませ、結果を事前署名として保存します。
+// 署名の開始時に事前署名を指定すると、各署名者は部分署名を1回送るだけで済みます。
+// 加法的方式では最初にコミットした t 人が署名者となり、CMPでは signers で署名者を指定します（省略時は参加者全員）。
+func StartPresignHandler(c *gin.Context) {
+	var req struct {
+		Signers []string `json:"signers"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+			return
+		}
+	}
+
+	var session *models.Session
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		ms, err := loadMultiSig(tx, c.Param("address"))
+		if err != nil {
+			return err
+		}
+		participants := participantsOf(*ms)
+		if ms.Scheme == "cmp" {
+			if participants, err = signerSet(ms, req.Signers); err != nil {
+				return err
+			}
+			session, err = newSession(tx, "cmp", "presign", ms, participants, struct{}{})
+			return err
+		}
+		if len(req.Signers) > 0 {
+			return badRequest("Signers are chosen by the first commitments")
+		}
+		// 全員がPaillier公開鍵とリングPedersenパラメータを登録済みでなければMtAを行えない。
+		// 途中で登録し直されても検証と証拠が変わらないよう、開始時点の値をセッションに保存する
+		keys, err := snapshotKeys(tx, participants)
+		if err != nil {
+			return err
+		}
+		session, err = newSession(tx, "additive", "presign", ms, participants, &SignData{Keys: keys})
+		return err
+	})
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Presigning session created", "session": session})
+}
+
+// PresignRevealHandler は、事前署名セッションのラウンド3の公開と、送信者のPaillier公開鍵で暗号化した k_i, σ_i を受け付けます。
+// 全員分が揃うと R を導出し、ラウンド4（SignCheckHandler）の確認に進みます。
+func PresignRevealHandler(c *gin.Context) {
+	var msg additive.PresignRound3
+	signStep(c, signRoundReveal, &msg, func(tx *gorm.DB, st *signState) error {
+		if st.session.Kind != "presign" {
+			return notFound("Session not found")
+		}
+		if err := additive.VerifyPresignShares(st.keys[st.from], msg.Shares); err != nil {
+			return st.reject(tx, err)
+		}
+		return st.acceptReveal(tx, &msg.SignRound3, &msg)
+	})
+}
+
+// completePresign は、ラウンド4の確認を終えた事前署名を使用可能な状態で保存し、セッションを完了します。
+func (st *signState) completePresign(tx *gorm.DB) error {
+	var result SignResult
+	if err := json.Unmarshal(st.session.Result, &result); err != nil {
+		return err
+	}
+	msgs, err := st.messages(tx, signRoundReveal)
+	if err != nil {
+		return err
+	}
+	reveals, err := decodeMessages[additive.PresignRound3](msgs)
+	if err != nil {
+		return err
+	}
+	shares := make(map[string]*additive.PresignShares, len(reveals))
+	for from, r := range reveals {
+		shares[from] = r.Shares
+	}
+	if err := tx.Create(&models.Presignature{
+		PresignID: st.session.SessionID,
+		MultiSig:  st.session.MultiSig,
+		Scheme:    "additive",
+		Signers:   st.session.Participants,
+		R:         hex.EncodeToString(result.R.Bytes()),
+		Shares:    datatypes.JSON([]byte(mustMarshal(shares))),
+		Epoch:     st.session.Epoch,
+		Status:    "available",
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Model(st.session).Update("status", "completed").Error
+}
+
+// ListPresignaturesHandler は、マルチシグの事前署名の一覧を返します。
+// status を指定するとその状態の事前署名のみを返します（例: status=available）。
+func ListPresignaturesHandler(c *gin.Context) {
+	query := db.DB.Where("multi_sig = ?", c.Param("address"))
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var presignatures []models.Presignature
+	if err := query.Order("id").Find(&presignatures).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Database error fetching presignatures"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"presignatures": presignatures})
+}
+
+// consumePresign は、マルチシグの使用可能な事前署名をロックして使用済みにし、その事前署名と署名者を返します。
+// 事前署名を2度使うと秘密鍵が漏洩するため、1度しか使えないようにします。
+// 使用済みにする前に、事前署名の署名者 initiator による presignSignMessage とチャレンジへの署名を検証します。
+func consumePresign(tx *gorm.DB, ms *models.MultiSig, presignID string, hash []byte, initiator string, signature hexutil.Bytes) (*models.Presignature, []string, error) {
+	var presig models.Presignature
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&presig, "presign_id = ? AND multi_sig = ?", presignID, ms.Address).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, notFound("Presignature not found")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if presig.Status != "available" {
+		return nil, nil, conflict("Presignature is " + presig.Status)
+	}
+	if presig.Epoch != ms.Epoch {
+		return nil, nil, conflict("Presignature was generated before the latest refresh")
+	}
+	var signers []string
+	if err := json.Unmarshal(presig.Signers, &signers); err != nil {
+		return nil, nil, err
+	}
+	initiator, err = participantAddress(signers, initiator)
+	if err != nil {
+		return nil, nil, badRequest("Initiator is not a signer of the presignature")
+	}
+	if err := verifyChallengeSignature(presignSignMessage(ms.Address, presignID, hash), hexutil.Encode(signature), initiator); err != nil {
+		return nil, nil, err
+	}
+	return &presig, signers, tx.Model(&presig).Update("status", "consumed").Error
+}
+
+// presignSignMessage は、事前署名を使って署名を開始する署名者がチャレンジと共に署名するメッセージを返します。
+func presignSignMessage(address, presignID string, hash []byte) string {
+	return fmt.Sprintf("Sign %s with presignature %s of multisig %s", hexutil.Encode(hash), presignID, address)
+}
+
+// presignNonce は、加法的方式の事前署名に保存された R を復元します。
+func presignNonce(presig *models.Presignature) (*additive.Point, error) {
+	raw, err := hex.DecodeString(presig.R)
+	if err != nil {
+		return nil, err
+	}
+	return additive.PointFromBytes(raw)
+}
@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"multisigservice/db"
+	"multisigservice/models"
+	"multisigservice/protocol/additive"
+)
+
+// 鍵更新のラウンド
+const (
+	refreshRoundCommit  = 1
+	refreshRoundReveal  = 2
+	refreshRoundConfirm = 3 // 新しいシェアの知識の証明による受領の確認
+)
+
+// RefreshResult は加法的方式の鍵更新セッションの結果として Session.Result に保存される内容です。
+type RefreshResult struct {
+	Epoch        int                        `json:"epoch"`
+	PublicShares map[string]*additive.Point `json:"publicShares"`
+}
+
+// StartRefreshHandler は、マルチシグの参加者全員による鍵更新セッションを開始します。
+// 開始できるのは参加者のみで、ChallengeHandler のチャレンジを付けた開始メッセージへの personal_sign 署名を要求します。
+// 公開鍵とアドレスは変わらず、完了すると各参加者のシェアとマルチシグのエポックが更新されます。
+// CMPのマルチシグではCMPの鍵更新セッションを開始し、メッセージは RelayCMPMessageHandler で中継します。
+func StartRefreshHandler(c *gin.Context) {
+	var req struct {
+		Initiator string        `json:"initiator"` // 鍵更新を開始する参加者
+		Signature hexutil.Bytes `json:"signature"` // refreshStartMessage とチャレンジへの署名
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+		return
+	}
+
+	var session *models.Session
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		ms, err := lockMultiSig(tx, c.Param("address"))
+		if err != nil {
+			return err
+		}
+		participants := participantsOf(*ms)
+		initiator, err := participantAddress(participants, req.Initiator)
+		if err != nil {
+			return badRequest("Initiator is not a participant of the multisig")
+		}
+		if err := verifyChallengeSignature(refreshStartMessage(ms.Address), hexutil.Encode(req.Signature), initiator); err != nil {
+			return err
+		}
+		if err := checkNoKeyUpdate(tx, ms); err != nil {
+			return err
+		}
+
+		if ms.Scheme == "cmp" {
+			session, err = newSession(tx, "cmp", "refresh", ms, participants, struct{}{})
+			return err
+		}
+		if ms.Threshold < 2 {
+			return badRequest("Refresh requires a threshold of at least 2")
+		}
+		// シェアをPaillier暗号で配るため、開始時点の全員の公開鍵を保存する
+		keys, err := snapshotKeys(tx, participants)
+		if err != nil {
+			return err
+		}
+		session, err = newSession(tx, "additive", "refresh", ms, participants, &KeygenData{Threshold: ms.Threshold, Keys: keys})
+		return err
+	})
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Refresh session created", "session": session})
+}
+
+// refreshStartMessage は、鍵更新を開始する参加者がチャレンジと共に署名するメッセージを返します。
+func refreshStartMessage(address string) string {
+	return fmt.Sprintf("Start refresh of multisig %s", address)
+}
+
+// RefreshCommitHandler は、参加者のゼロシェアリングの係数へのコミットメントを受け付けます。
+// メッセージは送信者の personal_sign 署名を検証してから受け付けます。全員分が揃うと公開ラウンドに進みます。
+func RefreshCommitHandler(c *gin.Context) {
+	var req SessionMessageRequest
+	var commit additive.KeygenCommit
+	if err := c.ShouldBindJSON(&req); err != nil || json.Unmarshal(req.Message, &commit) != nil || len(commit.Commitment) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid commitment"})
+		return
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		session, participants, from, err := refreshSession(tx, c, refreshRoundCommit, req.From)
+		if err != nil {
+			return err
+		}
+		signed, err := verifyMessage(session, refreshRoundCommit, from, &req)
+		if err != nil {
+			return err
+		}
+		if err := storeSigned(tx, signed, "", &commit); err != nil {
+			return err
+		}
+		msgs, err := roundMessages(tx, session.SessionID, refreshRoundCommit)
+		if err != nil || len(msgs) < len(participants) {
+			return err
+		}
+		return tx.Model(session).Update("round", refreshRoundReveal).Error
+	})
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Commitment accepted"})
+}
+
+// RefreshRevealHandler は、送信者の署名と、Feldmanコミットメント・暗号化されたシェアを検証して受け付けます。
+// 全員分が揃うと確認ラウンドに進みます。公開シェアはまだ更新しません。
+func RefreshRevealHandler(c *gin.Context) {
+	var req SessionMessageRequest
+	var reveal additive.RefreshReveal
+	if err := c.ShouldBindJSON(&req); err != nil || json.Unmarshal(req.Message, &reveal) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid reveal"})
+		return
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		session, participants, from, err := refreshSession(tx, c, refreshRoundReveal, req.From)
+		if err != nil {
+			return err
+		}
+		signed, err := verifyMessage(session, refreshRoundReveal, from, &req)
+		if err != nil {
+			return err
+		}
+		msgs, err := roundMessages(tx, session.SessionID, refreshRoundCommit)
+		if err != nil {
+			return err
+		}
+		commits, err := decodeMessages[additive.KeygenCommit](msgs)
+		if err != nil {
+			return err
+		}
+		rs, err := additiveSession(tx, session, participants)
+		if err != nil {
+			return err
+		}
+		if err := rs.VerifyRefreshReveal(from, commits[from], &reveal); err != nil {
+			return badRequest(err.Error())
+		}
+		if err := storeSigned(tx, signed, "", &reveal); err != nil {
+			return err
+		}
+
+		msgs, err = roundMessages(tx, session.SessionID, refreshRoundReveal)
+		if err != nil || len(msgs) < len(participants) {
+			return err
+		}
+		// 全員の公開から新しい公開シェアを計算できることを確認してから確認ラウンドに進む
+		if _, _, err := refreshedShares(tx, session, rs); err != nil {
+			return err
+		}
+		return tx.Model(session).Update("round", refreshRoundConfirm).Error
+	})
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Reveal accepted"})
+}
+
+// RefreshConfirmHandler は、参加者の新しいシェアの知識のSchnorr証明を、送信者の署名と共に検証して受け付けます。
+// 公開シェアは公開された記録から誰でも計算できるため、証明によってシェアを受け取れたことを確認します。
+// 全員分が揃うと公開シェアを更新してエポックを進め、古いエポックの進行中のセッションを中断します。
+func RefreshConfirmHandler(c *gin.Context) {
+	var req SessionMessageRequest
+	var confirm additive.RefreshConfirm
+	if err := c.ShouldBindJSON(&req); err != nil || json.Unmarshal(req.Message, &confirm) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid confirmation"})
+		return
+	}
+
+	var result *RefreshResult
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		session, participants, from, err := refreshSession(tx, c, refreshRoundConfirm, req.From)
+		if err != nil {
+			return err
+		}
+		signed, err := verifyMessage(session, refreshRoundConfirm, from, &req)
+		if err != nil {
+			return err
+		}
+		rs, err := additiveSession(tx, session, participants)
+		if err != nil {
+			return err
+		}
+		ms, refreshed, err := refreshedShares(tx, session, rs)
+		if err != nil {
+			return err
+		}
+		if err := rs.VerifyRefreshConfirm(from, refreshed[from], &confirm); err != nil {
+			return badRequest(err.Error())
+		}
+		if err := storeSigned(tx, signed, "", &confirm); err != nil {
+			return err
+		}
+
+		msgs, err := roundMessages(tx, session.SessionID, refreshRoundConfirm)
+		if err != nil || len(msgs) < len(participants) {
+			return err
+		}
+		epoch, err := advanceEpoch(tx, ms, session, refreshed)
+		if err != nil {
+			return err
+		}
+		result = &RefreshResult{Epoch: epoch, PublicShares: refreshed}
+		return tx.Model(session).Updates(map[string]interface{}{
+			"status": "completed",
+			"result": datatypes.JSON([]byte(mustMarshal(result))),
+		}).Error
+	})
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	if result != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Refresh completed", "result": result})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Confirmation accepted"})
+}
+
+// RefreshAbort は、鍵更新を中断した参加者とその署名として Session.Evidence に保存される内容です。
+type RefreshAbort struct {
+	AbortedBy string        `json:"abortedBy"`
+	Signature hexutil.Bytes `json:"signature"` // refreshAbortMessage への署名
+}
+
+// RefreshAbortHandler は、参加者の中断メッセージへの署名を検証して進行中の鍵更新を中断し、署名を証拠として残します。
+// 参加者が応答しない場合や、受け取ったシェアが検証できず確認を送れない場合に用います。CMPの鍵更新も中断できます。
+func RefreshAbortHandler(c *gin.Context) {
+	var req struct {
+		From      string        `json:"from"`
+		Signature hexutil.Bytes `json:"signature"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+		return
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		session, participants, err := lockSession(tx, c.Param("session"), "", "refresh")
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(session.MultiSig, c.Param("address")) {
+			return notFound("Session not found")
+		}
+		if session.Status != "active" {
+			return conflict("Session is " + session.Status)
+		}
+		from, err := participantAddress(participants, req.From)
+		if err != nil {
+			return err
+		}
+		abort := refreshAbortMessage(session.MultiSig, session.SessionID)
+		if valid, err := verifySignature(abort, hexutil.Encode(req.Signature), from); err != nil || !valid {
+			return badRequest("Abort signature verification failed")
+		}
+		return tx.Model(session).Updates(map[string]interface{}{
+			"status":   "aborted",
+			"evidence": datatypes.JSON([]byte(mustMarshal(&RefreshAbort{AbortedBy: from, Signature: req.Signature}))),
+		}).Error
+	})
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Refresh aborted"})
+}
+
+// refreshAbortMessage は、鍵更新を中断する参加者が署名するメッセージを返します。
+func refreshAbortMessage(address, sessionID string) string {
+	return fmt.Sprintf("Abort refresh of multisig %s\nSession: %s", address, sessionID)
+}
+
+// refreshedShares は、マルチシグをロックし、全員の公開を現在の公開シェアに適用した新しい公開シェアを返します。
+func refreshedShares(tx *gorm.DB, session *models.Session, rs *additive.Session) (*models.MultiSig, map[string]*additive.Point, error) {
+	msgs, err := roundMessages(tx, session.SessionID, refreshRoundReveal)
+	if err != nil {
+		return nil, nil, err
+	}
+	reveals, err := decodeMessages[additive.RefreshReveal](msgs)
+	if err != nil {
+		return nil, nil, err
+	}
+	ms, err := lockMultiSig(tx, session.MultiSig)
+	if err != nil {
+		return nil, nil, err
+	}
+	var shares map[string]*additive.Point
+	if err := json.Unmarshal(ms.PublicShares, &shares); err != nil {
+		return nil, nil, err
+	}
+	refreshed, err := rs.RefreshPublicShares(shares, reveals)
+	if err != nil {
+		return nil, nil, badRequest(err.Error())
+	}
+	return ms, refreshed, nil
+}
+
+// refreshSession は、加法的方式の鍵更新セッションをロックし、ラウンドと送信者を確認します。
+func refreshSession(tx *gorm.DB, c *gin.Context, round int, sender string) (*models.Session, []string, string, error) {
+	session, participants, err := lockSession(tx, c.Param("session"), "additive", "refresh")
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if !strings.EqualFold(session.MultiSig, c.Param("address")) {
+		return nil, nil, "", notFound("Session not found")
+	}
+	if err := expectRound(session, round); err != nil {
+		return nil, nil, "", err
+	}
+	from, err := participantAddress(participants, sender)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return session, participants, from, nil
+}
+
+// advanceEpoch は、鍵更新の完了時にマルチシグの公開シェアを更新してエポックを進め、新しいエポックを返します。
+// 古いエポックで開始された進行中のセッションと未使用の事前署名は古いシェアを前提とするため、中断・失効させます。
+func advanceEpoch(tx *gorm.DB, ms *models.MultiSig, refresh *models.Session, shares interface{}) (int, error) {
+	epoch := ms.Epoch + 1
+	updates := map[string]interface{}{
+		"public_shares": datatypes.JSON([]byte(mustMarshal(shares))),
+		"epoch":         epoch,
+	}
+	if ms.Status == "partial" {
+		updates["status"] = "awaiting"
+	}
+	if err := tx.Model(ms).Updates(updates).Error; err != nil {
+		return 0, err
+	}
+	err := tx.Model(&models.Session{}).
+		Where("multi_sig = ? AND epoch < ? AND status = ? AND session_id <> ?", ms.Address, epoch, "active", refresh.SessionID).
+		Update("status", "aborted").Error
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Model(&models.Presignature{}).
+		Where("multi_sig = ? AND epoch < ? AND status = ?", ms.Address, epoch, "available").
+		Update("status", "expired").Error
+	return epoch, err
+}
+
+// lockMultiSig は、トランザクション内でマルチシグを排他ロックして読み込みます。
+func lockMultiSig(tx *gorm.DB, address string) (*models.MultiSig, error) {
+	return loadMultiSig(tx.Clauses(clause.Locking{Strength: "UPDATE"}), address)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"multisigservice/models"
+	"multisigservice/protocol/additive"
+)
+
+// startRefresh は initiator の署名で鍵更新セッションを開始し、IDを返します。
+func (e *testEnv) startRefresh(ms *testMultiSig, initiator *testParty) string {
+	e.t.Helper()
+	var started struct {
+		Session models.Session `json:"session"`
+	}
+	e.post("/multisig/"+ms.address+"/refresh", map[string]interface{}{
+		"initiator": initiator.address,
+		"signature": e.signChallenge(initiator, refreshStartMessage(ms.address)),
+	}, &started)
+	return started.Session.SessionID
+}
+
+// runRefresh は参加者全員で鍵更新をハンドラー経由で実行し、更新後のシェアを返します。
+func (e *testEnv) runRefresh(ms *testMultiSig) *testMultiSig {
+	e.t.Helper()
+	sid := e.startRefresh(ms, e.parties[0])
+	path := "/multisig/" + ms.address + "/refresh/" + sid
+	threshold := ms.shares[e.parties[0].address].Threshold
+	session := &additive.Session{ID: sid, Parties: addresses(e.parties), Threshold: threshold, PaillierKeys: paillierPubs(e.parties)}
+
+	players := make(map[string]*additive.RefreshParty)
+	commits := make(map[string]*additive.KeygenCommit)
+	for _, p := range e.parties {
+		player, err := additive.NewRefreshParty(session, ms.shares[p.address], p.paillier)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		players[p.address], commits[p.address] = player, player.Commit()
+		e.post(path+"/commit", p.message(e.t, sid, refreshRoundCommit, commits[p.address]), nil)
+	}
+	reveals := make(map[string]*additive.RefreshReveal)
+	for _, p := range e.parties {
+		reveals[p.address] = players[p.address].Reveal()
+		e.post(path+"/reveal", p.message(e.t, sid, refreshRoundReveal, reveals[p.address]), nil)
+	}
+	refreshed := &testMultiSig{address: ms.address, shares: make(map[string]*additive.KeyShare)}
+	for _, p := range e.parties {
+		share, err := players[p.address].Finalize(commits, reveals)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		confirm, err := players[p.address].Confirm(share)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		e.post(path+"/confirm", p.message(e.t, sid, refreshRoundConfirm, confirm), nil)
+		refreshed.shares[p.address] = share
+	}
+	if got := e.session(sid).Status; got != "completed" {
+		e.t.Fatalf("got refresh status %q\nwant completed", got)
+	}
+	return refreshed
+}
+
+func TestRefreshSession(t *testing.T) {
+	e := newTestEnv(t)
+	ms := e.createMultiSig(2)
+	refreshed := e.runRefresh(ms)
+
+	// 公開鍵は変わらず、公開シェアとエポックが更新される
+	updated := e.multisig(ms.address)
+	if updated.Epoch != 1 {
+		t.Errorf("got epoch %d\nwant 1", updated.Epoch)
+	}
+	var shares map[string]*additive.Point
+	if err := json.Unmarshal(updated.PublicShares, &shares); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range e.parties {
+		share := refreshed.shares[p.address]
+		if !share.PublicKey.Equal(ms.shares[p.address].PublicKey) {
+			t.Errorf("%s: public key changed", p.address)
+		}
+		if share.Secret.Cmp(ms.shares[p.address].Secret) == 0 {
+			t.Errorf("%s: share was not refreshed", p.address)
+		}
+		if !shares[p.address].Equal(additive.ScalarBaseMult(share.Secret)) {
+			t.Errorf("%s: stored public share does not match the refreshed share", p.address)
+		}
+	}
+
+	// 参加者以外は鍵更新を開始できない
+	outsider := &testParty{key: e.parties[0].key, address: "0x000000000000000000000000000000000000dEaD"}
+	body := map[string]interface{}{"initiator": outsider.address, "signature": hexutil.Bytes(make([]byte, 65))}
+	if code := e.request(http.MethodPost, "/multisig/"+ms.address+"/refresh", body, nil); code != http.StatusBadRequest {
+		t.Errorf("got %d\nwant %d", code, http.StatusBadRequest)
+	}
+}
+
+func TestRefreshSessionAbort(t *testing.T) {
+	e := newTestEnv(t)
+	ms := e.createMultiSig(2)
+	sid := e.startRefresh(ms, e.parties[0])
+	path := "/multisig/" + ms.address + "/refresh/" + sid
+
+	// 進行中の鍵更新があるうちは、次の鍵更新を開始できない
+	body := map[string]interface{}{
+		"initiator": e.parties[1].address,
+		"signature": e.signChallenge(e.parties[1], refreshStartMessage(ms.address)),
+	}
+	if code := e.request(http.MethodPost, "/multisig/"+ms.address+"/refresh", body, nil); code != http.StatusConflict {
+		t.Errorf("got %d\nwant %d", code, http.StatusConflict)
+	}
+
+	// 他の参加者の署名では中断できない
+	abort := refreshAbortMessage(ms.address, sid)
+	forged := map[string]interface{}{"from": e.parties[2].address, "signature": e.parties[1].sign(t, abort)}
+	if code := e.request(http.MethodPost, path+"/abort", forged, nil); code != http.StatusBadRequest {
+		t.Errorf("got %d\nwant %d", code, http.StatusBadRequest)
+	}
+
+	// 参加者の署名で中断すると、署名が証拠として残る
+	e.post(path+"/abort", map[string]interface{}{"from": e.parties[2].address, "signature": e.parties[2].sign(t, abort)}, nil)
+	session := e.session(sid)
+	if session.Status != "aborted" {
+		t.Fatalf("got status %q\nwant aborted", session.Status)
+	}
+	var ev RefreshAbort
+	if err := json.Unmarshal(session.Evidence, &ev); err != nil {
+		t.Fatal(err)
+	}
+	if valid, err := verifySignature(abort, hexutil.Encode(ev.Signature), ev.AbortedBy); ev.AbortedBy != e.parties[2].address || err != nil || !valid {
+		t.Errorf("got evidence %+v\nwant a signature of %s", ev, e.parties[2].address)
+	}
+	msg := e.parties[0].message(t, sid, refreshRoundCommit, &additive.KeygenCommit{Commitment: []byte{1}})
+	if code := e.request(http.MethodPost, path+"/commit", msg, nil); code != http.StatusConflict {
+		t.Errorf("got %d\nwant %d", code, http.StatusConflict)
+	}
+
+	// 中断後は鍵更新をやり直せる。エポックは変わらない
+	e.runRefresh(ms)
+	if got := e.multisig(ms.address).Epoch; got != 1 {
+		t.Errorf("got epoch %d\nwant 1", got)
+	}
+}
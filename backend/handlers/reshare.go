@@ -0,0 +1,507 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"multisigservice/db"
+	"multisigservice/models"
+	"multisigservice/protocol/additive"
+)
+
+// keyUpdateTimeout は、鍵更新・再共有のセッションが他の鍵更新を妨げられる期間です。
+// これを過ぎても完了しないセッションは、次の鍵更新の開始時に中断します。
+const keyUpdateTimeout = 24 * time.Hour
+
+// 再共有のラウンド
+const (
+	reshareRoundApprove = 1 // 旧参加者の承認
+	reshareRoundDeal    = 2 // 承認した旧参加者（ディーラー）によるシェアの配布
+	reshareRoundConfirm = 3 // 新しい参加者による受領の確認
+)
+
+// ReshareData は再共有セッションの入力として Session.Data に保存される内容です。
+type ReshareData struct {
+	OldParticipants []string `json:"oldParticipants"`
+	OldThreshold    int      `json:"oldThreshold"`
+	Participants    []string `json:"participants"` // 変更後の参加者
+	Threshold       int      `json:"threshold"`    // 変更後の閾値
+	Approval        string   `json:"approval"`     // 旧参加者が personal_sign で署名する承認メッセージ
+	// 開始時点の新しい参加者の公開鍵。配布と確認はこの鍵で検証する
+	Keys *SessionKeys `json:"keys,omitempty"`
+}
+
+// ReshareApproval は旧参加者の承認です。Signature は ReshareData.Approval への personal_sign の署名です。
+type ReshareApproval struct {
+	Address    string        `json:"address"`
+	Signature  hexutil.Bytes `json:"signature"`
+	ApprovedAt time.Time     `json:"approvedAt"`
+}
+
+// StartReshareHandler は、マルチシグの参加者と閾値を変更する再共有セッションを開始します。
+// 公開鍵とアドレスは変わりません。旧閾値の人数の旧参加者が承認すると、承認した旧参加者がシェアを配り、
+// 新しい参加者全員が受領を確認した時点でマルチシグの参加者・閾値・エポックを更新します。
+// 提案できるのは旧参加者のみで、ChallengeHandler のチャレンジを付けた提案メッセージへの personal_sign 署名を要求します。
+func StartReshareHandler(c *gin.Context) {
+	var req struct {
+		Proposer     string        `json:"proposer"`     // 変更を提案する旧参加者
+		Participants []string      `json:"participants"` // 変更後の参加者のEthereumアドレス
+		Threshold    int           `json:"threshold"`    // 変更後の閾値
+		Signature    hexutil.Bytes `json:"signature"`    // 提案メッセージとチャレンジへの提案者の署名
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request"})
+		return
+	}
+
+	var session *models.Session
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		ms, err := lockMultiSig(tx, c.Param("address"))
+		if err != nil {
+			return err
+		}
+		if ms.Scheme != "additive" {
+			return badRequest("Resharing is only supported for the additive scheme")
+		}
+		if err := checkNoKeyUpdate(tx, ms); err != nil {
+			return err
+		}
+		old := participantsOf(*ms)
+		proposer, err := participantAddress(old, req.Proposer)
+		if err != nil {
+			return badRequest("Proposer is not a participant of the multisig")
+		}
+		members := MultiSigMembers{Participants: req.Participants, Threshold: req.Threshold}
+		participants, err := members.normalize()
+		if err != nil {
+			return err
+		}
+		proposal := reshareProposalMessage(ms.Address, participants, req.Threshold)
+		if err := verifyChallengeSignature(proposal, hexutil.Encode(req.Signature), proposer); err != nil {
+			return err
+		}
+		// セッションの参加者は旧参加者と新しい参加者の和集合。両方に属する参加者は旧参加者の表記に揃える
+		all := append([]string{}, old...)
+		for i, p := range participants {
+			if existing, err := participantAddress(old, p); err == nil {
+				participants[i] = existing
+			} else {
+				all = append(all, p)
+			}
+		}
+		// 新しい参加者にはシェアをPaillier暗号で配るため、開始時点の公開鍵を保存する
+		keys, err := snapshotKeys(tx, participants)
+		if err != nil {
+			return err
+		}
+		data := &ReshareData{
+			OldParticipants: old,
+			OldThreshold:    ms.Threshold,
+			Participants:    participants,
+			Threshold:       req.Threshold,
+			Keys:            keys,
+		}
+		session, err = newSession(tx, "additive", "reshare", ms, all, data)
+		if err != nil {
+			return err
+		}
+		// 承認メッセージはセッションIDを含むため作成後に設定する
+		data.Approval = reshareApprovalMessage(ms.Address, session.SessionID, participants, req.Threshold)
+		session.Data = datatypes.JSON([]byte(mustMarshal(data)))
+		if err := tx.Model(session).Update("data", session.Data).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.Reshare{
+			SessionID:         session.SessionID,
+			MultiSig:          ms.Address,
+			Proposer:          proposer,
+			ProposerSignature: hexutil.Encode(req.Signature),
+			OldParticipants:   ms.Participants,
+			OldThreshold:      ms.Threshold,
+			NewParticipants:   datatypes.JSON([]byte(mustMarshal(participants))),
+			NewThreshold:      req.Threshold,
+			Approvals:         datatypes.JSON([]byte(`[]`)),
+			Status:            "pending",
+		}).Error
+	})
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Reshare session created", "session": session})
+}
+
+// reshareProposalMessage は、再共有を提案する旧参加者がチャレンジと共に署名するメッセージを返します。
+// 参加者はチェックサム付きアドレスで、リクエストの順に並べます。
+func reshareProposalMessage(address string, participants []string, threshold int) string {
+	return fmt.Sprintf("Propose resharing of multisig %s\nParticipants: %s\nThreshold: %d",
+		address, strings.Join(participants, ","), threshold)
+}
+
+// reshareAbortMessage は、再共有を中断する参加者が署名する中断メッセージを返します。
+func reshareAbortMessage(address, sessionID string) string {
+	return fmt.Sprintf("Abort resharing of multisig %s\nSession: %s", address, sessionID)
+}
+
+// reshareApprovalMessage は、旧参加者が署名する承認メッセージを返します。
+func reshareApprovalMessage(address, sessionID string, participants []string, threshold int) string {
+	return fmt.Sprintf("Approve resharing of multisig %s\nSession: %s\nParticipants: %s\nThreshold: %d",
+		address, sessionID, strings.Join(participants, ","), threshold)
+}
+
+// ReshareApproveHandler は、旧参加者の承認を署名を検証して受け付け、監査記録に追加します。
+// 旧閾値の人数が揃うと、承認した旧参加者をディーラーとして配布ラウンドに進みます。
+func ReshareApproveHandler(c *gin.Context) {
+	var msg struct {
+		Signature hexutil.Bytes `json:"signature"`
+	}
+	reshareStep(c, reshareRoundApprove, &msg, func(tx *gorm.DB, st *reshareState) error {
+		if _, err := participantAddress(st.data.OldParticipants, st.from); err != nil {
+			return badRequest("Only old participants can approve")
+		}
+		if valid, err := verifySignature(st.data.Approval, hexutil.Encode(msg.Signature), st.from); err != nil || !valid {
+			return badRequest("Approval signature verification failed")
+		}
+		approval := ReshareApproval{Address: st.from, Signature: msg.Signature, ApprovedAt: time.Now().UTC()}
+		if err := storeMessage(tx, st.session, reshareRoundApprove, st.from, "", &approval); err != nil {
+			return err
+		}
+		msgs, err := roundMessages(tx, st.session.SessionID, reshareRoundApprove)
+		if err != nil {
+			return err
+		}
+		approvals, err := decodeMessages[ReshareApproval](msgs)
+		if err != nil {
+			return err
+		}
+		list := make([]*ReshareApproval, 0, len(approvals))
+		for _, p := range st.data.OldParticipants {
+			if a := approvals[p]; a != nil {
+				list = append(list, a)
+			}
+		}
+		if err := tx.Model(st.record).Update("approvals", datatypes.JSON([]byte(mustMarshal(list)))).Error; err != nil {
+			return err
+		}
+		if len(list) < st.data.OldThreshold {
+			return nil
+		}
+		return tx.Model(st.session).Update("round", reshareRoundDeal).Error
+	})
+}
+
+// ReshareDealHandler は、ディーラーの署名付きのシェアの配布を受け付けます。
+// Feldmanコミットメントの定数項がディーラーの旧公開シェアと一致するかを検証します。
+func ReshareDealHandler(c *gin.Context) {
+	var deal additive.ReshareDeal
+	reshareStep(c, reshareRoundDeal, &deal, func(tx *gorm.DB, st *reshareState) error {
+		rs, err := st.reshareSession(tx)
+		if err != nil {
+			return err
+		}
+		if err := rs.VerifyDeal(st.from, &deal); err != nil {
+			return badRequest(err.Error())
+		}
+		if err := storeSigned(tx, st.signed, "", &deal); err != nil {
+			return err
+		}
+		msgs, err := roundMessages(tx, st.session.SessionID, reshareRoundDeal)
+		if err != nil || len(msgs) < len(rs.Dealers) {
+			return err
+		}
+		return tx.Model(st.session).Update("round", reshareRoundConfirm).Error
+	})
+}
+
+// ReshareConfirmHandler は、新しい参加者の署名付きの受領確認を受け付けます。
+// 公開シェアは配布から誰でも計算できるため、新しいシェアの知識のSchnorr証明を検証します。
+// 全員が確認すると、マルチシグの参加者・閾値・公開シェアを更新してエポックを進めます。
+func ReshareConfirmHandler(c *gin.Context) {
+	var confirm additive.ReshareConfirm
+	reshareStep(c, reshareRoundConfirm, &confirm, func(tx *gorm.DB, st *reshareState) error {
+		if _, err := participantAddress(st.data.Participants, st.from); err != nil {
+			return badRequest("Only new participants can confirm")
+		}
+		rs, err := st.reshareSession(tx)
+		if err != nil {
+			return err
+		}
+		msgs, err := roundMessages(tx, st.session.SessionID, reshareRoundDeal)
+		if err != nil {
+			return err
+		}
+		deals, err := decodeMessages[additive.ReshareDeal](msgs)
+		if err != nil {
+			return err
+		}
+		Q, shares, err := rs.Combine(deals)
+		if err != nil {
+			return err
+		}
+		if err := rs.VerifyConfirm(st.from, shares[st.from], &confirm); err != nil {
+			return badRequest(err.Error())
+		}
+		if err := storeSigned(tx, st.signed, "", &confirm); err != nil {
+			return err
+		}
+		msgs, err = roundMessages(tx, st.session.SessionID, reshareRoundConfirm)
+		if err != nil || len(msgs) < len(st.data.Participants) {
+			return err
+		}
+		return completeReshare(tx, st, Q, shares)
+	})
+}
+
+// ReshareAbortHandler は、参加者の中断メッセージへの署名を検証して進行中の再共有を中断し、監査記録に残します。
+// 承認が集まらない場合や、配られたシェアが検証できず確認を送れない場合に用います。
+func ReshareAbortHandler(c *gin.Context) {
+	var msg struct {
+		Signature hexutil.Bytes `json:"signature"`
+	}
+	reshareStep(c, 0, &msg, func(tx *gorm.DB, st *reshareState) error {
+		abort := reshareAbortMessage(st.session.MultiSig, st.session.SessionID)
+		if valid, err := verifySignature(abort, hexutil.Encode(msg.Signature), st.from); err != nil || !valid {
+			return badRequest("Abort signature verification failed")
+		}
+		if err := tx.Model(st.session).Update("status", "aborted").Error; err != nil {
+			return err
+		}
+		return tx.Model(st.record).Updates(map[string]interface{}{
+			"status":          "aborted",
+			"aborted_by":      st.from,
+			"abort_signature": hexutil.Encode(msg.Signature),
+		}).Error
+	})
+}
+
+// completeReshare は、マルチシグを新しい参加者と閾値に更新し、参加者のマルチシグ一覧と監査記録を更新します。
+func completeReshare(tx *gorm.DB, st *reshareState, Q *additive.Point, shares map[string]*additive.Point) error {
+	ms, err := lockMultiSig(tx, st.session.MultiSig)
+	if err != nil {
+		return err
+	}
+	current, err := multisigPublicKey(ms)
+	if err != nil {
+		return err
+	}
+	if !current.Equal(Q) {
+		return badRequest("Resharing changed the public key")
+	}
+	epoch, err := advanceEpoch(tx, ms, st.session, shares)
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(ms).Updates(map[string]interface{}{
+		"participants":      datatypes.JSON([]byte(mustMarshal(st.data.Participants))),
+		"threshold":         st.data.Threshold,
+		"participant_count": len(st.data.Participants),
+	}).Error; err != nil {
+		return err
+	}
+
+	// 外れた参加者の一覧からは削除し、加わった参加者の一覧には追加する
+	for _, p := range st.data.OldParticipants {
+		if _, err := participantAddress(st.data.Participants, p); err != nil {
+			if err := updateUserMultiSigs(tx, p, ms.Address, false); err != nil {
+				return err
+			}
+		}
+	}
+	for _, p := range st.data.Participants {
+		if _, err := participantAddress(st.data.OldParticipants, p); err != nil {
+			if err := updateUserMultiSigs(tx, p, ms.Address, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Model(st.record).Updates(map[string]interface{}{"status": "completed", "epoch": epoch}).Error; err != nil {
+		return err
+	}
+	return tx.Model(st.session).Updates(map[string]interface{}{
+		"status": "completed",
+		"result": datatypes.JSON([]byte(mustMarshal(&RefreshResult{Epoch: epoch, PublicShares: shares}))),
+	}).Error
+}
+
+// updateUserMultiSigs は、ユーザーの参加マルチシグ一覧にマルチシグを追加または削除します。
+// 未登録のユーザーは何もしません。
+func updateUserMultiSigs(tx *gorm.DB, address, multisig string, add bool) error {
+	var user models.User
+	if err := tx.First(&user, "LOWER(address) = LOWER(?)", address).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	var list []string
+	if len(user.MultiSigs) > 0 {
+		if err := json.Unmarshal(user.MultiSigs, &list); err != nil {
+			return err
+		}
+	}
+	updated := list[:0]
+	for _, a := range list {
+		if !strings.EqualFold(a, multisig) {
+			updated = append(updated, a)
+		}
+	}
+	if add {
+		updated = append(updated, multisig)
+	}
+	return tx.Model(&user).Update("multi_sigs", datatypes.JSON([]byte(mustMarshal(updated)))).Error
+}
+
+// reshareState は再共有ラウンドの処理中に共有する状態です。
+type reshareState struct {
+	session *models.Session
+	from    string
+	signed  *additive.SignedMessage // 配布・確認ラウンドの署名済みメッセージ
+	data    ReshareData
+	record  *models.Reshare
+}
+
+// reshareSession は、承認した旧参加者をディーラーとする再共有の記述を組み立てます。
+func (st *reshareState) reshareSession(tx *gorm.DB) (*additive.ReshareSession, error) {
+	msgs, err := roundMessages(tx, st.session.SessionID, reshareRoundApprove)
+	if err != nil {
+		return nil, err
+	}
+	approved := make(map[string]bool, len(msgs))
+	for _, m := range msgs {
+		approved[m.From] = true
+	}
+	var dealers []string
+	for _, p := range st.data.OldParticipants {
+		if approved[p] {
+			dealers = append(dealers, p)
+		}
+	}
+	if st.session.Round == reshareRoundDeal {
+		if _, err := participantAddress(dealers, st.from); err != nil {
+			return nil, badRequest("Only approving old participants can deal")
+		}
+	}
+
+	ms, err := loadMultiSig(tx, st.session.MultiSig)
+	if err != nil {
+		return nil, err
+	}
+	var oldShares map[string]*additive.Point
+	if err := json.Unmarshal(ms.PublicShares, &oldShares); err != nil {
+		return nil, err
+	}
+	if st.data.Keys == nil {
+		return nil, conflict("Session has no snapshot of the participants' keys")
+	}
+	keys, _, err := st.data.Keys.load()
+	if err != nil {
+		return nil, err
+	}
+	return &additive.ReshareSession{
+		OldParties:      st.data.OldParticipants,
+		OldThreshold:    st.data.OldThreshold,
+		OldPublicShares: oldShares,
+		Dealers:         dealers,
+		New: &additive.Session{
+			ID:           st.session.SessionID,
+			Parties:      st.data.Participants,
+			Threshold:    st.data.Threshold,
+			PaillierKeys: keys,
+		},
+	}, nil
+}
+
+// reshareStep は、再共有ラウンドのリクエストを解析し、セッションをロックして step を実行します。
+// round が0の場合はラウンドを問いません。配布・確認ラウンドでは送信者の personal_sign 署名を検証します
+// （承認と中断はメッセージ自体が署名です）。
+func reshareStep(c *gin.Context, round int, msg interface{}, step func(tx *gorm.DB, st *reshareState) error) {
+	var req SessionMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (len(req.Message) > 0 && json.Unmarshal(req.Message, msg) != nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid message"})
+		return
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var st reshareState
+		session, participants, err := lockSession(tx, c.Param("session"), "additive", "reshare")
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(session.MultiSig, c.Param("address")) {
+			return notFound("Session not found")
+		}
+		if round == 0 && session.Status != "active" {
+			return conflict("Session is " + session.Status)
+		}
+		if round != 0 {
+			if err := expectRound(session, round); err != nil {
+				return err
+			}
+		}
+		st.session = session
+		if st.from, err = participantAddress(participants, req.From); err != nil {
+			return err
+		}
+		if round == reshareRoundDeal || round == reshareRoundConfirm {
+			if st.signed, err = verifyMessage(session, round, st.from, &req); err != nil {
+				return err
+			}
+		}
+		if err := json.Unmarshal(session.Data, &st.data); err != nil {
+			return err
+		}
+		st.record = &models.Reshare{}
+		if err := tx.First(st.record, "session_id = ?", session.SessionID).Error; err != nil {
+			return err
+		}
+		return step(tx, &st)
+	})
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Message accepted"})
+}
+
+// checkNoKeyUpdate は、マルチシグで鍵更新・再共有が進行中でないことを確認します。
+// 期限を過ぎた進行中のセッションは中断し、参加者が応答しなくなっても鍵更新を続けられるようにします。
+func checkNoKeyUpdate(tx *gorm.DB, ms *models.MultiSig) error {
+	kinds := []string{"refresh", "reshare"}
+	var expired []string
+	if err := tx.Model(&models.Session{}).
+		Where("multi_sig = ? AND kind IN ? AND status = ? AND created_at < ?", ms.Address, kinds, "active", time.Now().Add(-keyUpdateTimeout)).
+		Pluck("session_id", &expired).Error; err != nil {
+		return err
+	}
+	if len(expired) > 0 {
+		if err := tx.Model(&models.Session{}).Where("session_id IN ?", expired).Update("status", "aborted").Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Reshare{}).
+			Where("session_id IN ? AND status = ?", expired, "pending").
+			Update("status", "aborted").Error; err != nil {
+			return err
+		}
+	}
+
+	var active int64
+	if err := tx.Model(&models.Session{}).
+		Where("multi_sig = ? AND kind IN ? AND status = ?", ms.Address, kinds, "active").
+		Count(&active).Error; err != nil {
+		return err
+	}
+	if active > 0 {
+		return conflict("Refresh or resharing is already in progress")
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"multisigservice/db"
+	"multisigservice/models"
+	"multisigservice/protocol/additive"
+)
+
+// startReshare は proposer の提案で再共有セッションを開始し、IDと承認メッセージを返します。
+func (e *testEnv) startReshare(ms *testMultiSig, proposer *testParty, participants []*testParty, threshold int) (string, *ReshareData) {
+	e.t.Helper()
+	var started struct {
+		Session models.Session `json:"session"`
+	}
+	e.post("/multisig/"+ms.address+"/reshare", map[string]interface{}{
+		"proposer":     proposer.address,
+		"participants": addresses(participants),
+		"threshold":    threshold,
+		"signature":    e.signChallenge(proposer, reshareProposalMessage(ms.address, addresses(participants), threshold)),
+	}, &started)
+	var data ReshareData
+	if err := json.Unmarshal(started.Session.Data, &data); err != nil {
+		e.t.Fatal(err)
+	}
+	return started.Session.SessionID, &data
+}
+
+// reshareSession は、テスト用の再共有の記述を組み立てます。
+func (e *testEnv) reshareSession(ms *testMultiSig, sid string, dealers, participants []*testParty, threshold int) *additive.ReshareSession {
+	old := ms.shares[e.parties[0].address]
+	return &additive.ReshareSession{
+		OldParties:      old.Parties,
+		OldThreshold:    old.Threshold,
+		OldPublicShares: old.PublicShares,
+		Dealers:         addresses(dealers),
+		New:             &additive.Session{ID: sid, Parties: addresses(participants), Threshold: threshold, PaillierKeys: paillierPubs(participants)},
+	}
+}
+
+// reshareRecord は再共有の監査記録を読み込みます。
+func (e *testEnv) reshareRecord(sid string) *models.Reshare {
+	e.t.Helper()
+	var record models.Reshare
+	if err := db.DB.First(&record, "session_id = ?", sid).Error; err != nil {
+		e.t.Fatal(err)
+	}
+	return &record
+}
+
+func TestReshareSession(t *testing.T) {
+	e := newTestEnv(t)
+	ms := e.createMultiSig(2)
+	hash := crypto.Keccak256([]byte("reshare handler test"))
+	stale := e.startSign(ms, hash)
+
+	// 3人目を外して 2-of-2 に変更する
+	next := e.parties[:2]
+	sid, data := e.startReshare(ms, e.parties[0], next, 2)
+	path := "/multisig/" + ms.address + "/reshare/" + sid
+	for _, p := range e.parties[:2] {
+		e.post(path+"/approve", map[string]interface{}{"from": p.address, "message": map[string]interface{}{"signature": p.sign(t, data.Approval)}}, nil)
+	}
+	if got := e.session(sid).Round; got != reshareRoundDeal {
+		t.Fatalf("got round %d\nwant %d", got, reshareRoundDeal)
+	}
+
+	rs := e.reshareSession(ms, sid, e.parties[:2], next, 2)
+	players := make(map[string]*additive.ReshareParty)
+	deals := make(map[string]*additive.ReshareDeal)
+	for _, p := range e.parties[:2] {
+		player, err := additive.NewReshareParty(rs, p.address, ms.shares[p.address], p.paillier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		players[p.address], deals[p.address] = player, player.Deal()
+		e.post(path+"/deal", p.message(t, sid, reshareRoundDeal, deals[p.address]), nil)
+	}
+	reshared := &testMultiSig{address: ms.address, shares: make(map[string]*additive.KeyShare)}
+	for _, p := range next {
+		share, err := players[p.address].Finalize(deals)
+		if err != nil {
+			t.Fatal(err)
+		}
+		confirm, err := players[p.address].Confirm(share)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.post(path+"/confirm", p.message(t, sid, reshareRoundConfirm, confirm), nil)
+		reshared.shares[p.address] = share
+	}
+
+	// マルチシグの参加者・閾値・エポックが更新され、監査記録が完了となる
+	updated := e.multisig(ms.address)
+	if got := participantsOf(*updated); len(got) != 2 || got[0] != next[0].address || got[1] != next[1].address {
+		t.Errorf("got participants %v\nwant %v", got, addresses(next))
+	}
+	if updated.Threshold != 2 || updated.Epoch != 1 {
+		t.Errorf("got threshold %d, epoch %d\nwant 2, 1", updated.Threshold, updated.Epoch)
+	}
+	if record := e.reshareRecord(sid); record.Status != "completed" || record.Epoch != 1 {
+		t.Errorf("got record status %q, epoch %d\nwant completed, 1", record.Status, record.Epoch)
+	}
+
+	// 再共有の前に開始した署名セッションは中断され、新しいシェアで署名できる
+	if got := e.session(stale).Status; got != "aborted" {
+		t.Errorf("got status %q\nwant aborted", got)
+	}
+	sign := e.startSign(reshared, hash)
+	if code := e.runSign(reshared, sign, next, hash, nil); code != http.StatusOK {
+		t.Fatalf("got %d\nwant %d", code, http.StatusOK)
+	}
+}
+
+func TestReshareSessionRejectsInvalidMessages(t *testing.T) {
+	e := newTestEnv(t)
+	ms := e.createMultiSig(2)
+	sid, data := e.startReshare(ms, e.parties[0], e.parties[:2], 2)
+	path := "/multisig/" + ms.address + "/reshare/" + sid
+
+	// 承認メッセージ以外への署名は承認として受け付けない
+	p := e.parties[1]
+	bad := map[string]interface{}{"from": p.address, "message": map[string]interface{}{"signature": p.sign(t, data.Approval+"\n")}}
+	if code := e.request(http.MethodPost, path+"/approve", bad, nil); code != http.StatusBadRequest {
+		t.Errorf("got %d\nwant %d", code, http.StatusBadRequest)
+	}
+	for _, p := range e.parties[:2] {
+		e.post(path+"/approve", map[string]interface{}{"from": p.address, "message": map[string]interface{}{"signature": p.sign(t, data.Approval)}}, nil)
+	}
+
+	// 承認していない旧参加者は配布できない
+	rs := e.reshareSession(ms, sid, e.parties[:2], e.parties[:2], 2)
+	dealer, err := additive.NewReshareParty(rs, p.address, ms.shares[p.address], p.paillier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deal := dealer.Deal()
+	if code := e.request(http.MethodPost, path+"/deal", e.parties[2].message(t, sid, reshareRoundDeal, deal), nil); code != http.StatusBadRequest {
+		t.Errorf("got %d\nwant %d", code, http.StatusBadRequest)
+	}
+
+	// 定数項が旧公開シェアと一致しない配布は受け付けない
+	tampered := *deal
+	tampered.Commitments = append([]*additive.Point{additive.ScalarBaseMult(ms.shares[p.address].Secret)}, deal.Commitments[1:]...)
+	if code := e.request(http.MethodPost, path+"/deal", p.message(t, sid, reshareRoundDeal, &tampered), nil); code != http.StatusBadRequest {
+		t.Errorf("got %d\nwant %d", code, http.StatusBadRequest)
+	}
+	e.post(path+"/deal", p.message(t, sid, reshareRoundDeal, deal), nil)
+}
+
+func TestReshareSessionAbort(t *testing.T) {
+	e := newTestEnv(t)
+	ms := e.createMultiSig(2)
+	sid, _ := e.startReshare(ms, e.parties[0], e.parties[:2], 2)
+	path := "/multisig/" + ms.address + "/reshare/" + sid
+
+	// 他の参加者の署名では中断できない
+	abort := reshareAbortMessage(ms.address, sid)
+	forged := map[string]interface{}{"from": e.parties[2].address, "message": map[string]interface{}{"signature": e.parties[1].sign(t, abort)}}
+	if code := e.request(http.MethodPost, path+"/abort", forged, nil); code != http.StatusBadRequest {
+		t.Errorf("got %d\nwant %d", code, http.StatusBadRequest)
+	}
+
+	// 参加者の署名で中断すると、監査記録に中断した参加者が残る
+	e.post(path+"/abort", map[string]interface{}{"from": e.parties[2].address, "message": map[string]interface{}{"signature": e.parties[2].sign(t, abort)}}, nil)
+	if got := e.session(sid).Status; got != "aborted" {
+		t.Fatalf("got status %q\nwant aborted", got)
+	}
+	if record := e.reshareRecord(sid); record.Status != "aborted" || record.AbortedBy != e.parties[2].address {
+		t.Errorf("got record status %q, aborted by %q\nwant aborted, %s", record.Status, record.AbortedBy, e.parties[2].address)
+	}
+	approve := map[string]interface{}{"from": e.parties[0].address, "message": map[string]interface{}{"signature": e.parties[0].sign(t, abort)}}
+	if code := e.request(http.MethodPost, path+"/approve", approve, nil); code != http.StatusConflict {
+		t.Errorf("got %d\nwant %d", code, http.StatusConflict)
+	}
+
+	// 中断後は鍵更新を開始でき、マルチシグは変わらない
+	e.runRefresh(ms)
+	if updated := e.multisig(ms.address); len(participantsOf(*updated)) != 3 || updated.Threshold != 2 {
+		t.Errorf("got %d participants, threshold %d\nwant 3, 2", len(participantsOf(*updated)), updated.Threshold)
+	}
+}
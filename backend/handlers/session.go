@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"multisigservice/db"
+	"multisigservice/models"
+	"multisigservice/protocol/additive"
+)
+
+// SessionMessageRequest は参加者がセッションにメッセージを送る際のリクエストデータです。
+// Message の中身はラウンド毎に異なります。
+// 加法的方式の鍵生成・署名・事前署名では、Message を送信者のEthereum鍵で personal_sign した Signature が必須です
+// （署名する文面は additive.SignedMessage.Statement を参照してください）。
+type SessionMessageRequest struct {
+	From      string          `json:"from"`
+	Message   json.RawMessage `json:"message"`
+	Signature hexutil.Bytes   `json:"signature,omitempty"`
+}
+
+// sessionError はセッション処理中に発生した、クライアントに返すべきエラーです。
+type sessionError struct {
+	status  int
+	message string
+}
+
+func (e *sessionError) Error() string { return e.message }
+
+func badRequest(message string) error { return &sessionError{http.StatusBadRequest, message} }
+func conflict(message string) error   { return &sessionError{http.StatusConflict, message} }
+func notFound(message string) error   { return &sessionError{http.StatusNotFound, message} }
+
+// respondSessionError は、エラーを対応するHTTPステータスで返します。
+func respondSessionError(c *gin.Context, err error) {
+	var se *sessionError
+	if errors.As(err, &se) {
+		c.JSON(se.status, gin.H{"message": se.message})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"message": "Database error"})
+}
+
+// newSession は新しいセッションを作成します。
+// ms を指定した場合は、そのマルチシグの現在のエポックのセッションとします（鍵生成では nil）。
+func newSession(tx *gorm.DB, scheme, kind string, ms *models.MultiSig, participants []string, data interface{}) (*models.Session, error) {
+	session := models.Session{
+		SessionID:    uuid.NewString(),
+		Kind:         kind,
+		Scheme:       scheme,
+		Participants: datatypes.JSON([]byte(mustMarshal(participants))),
+		Round:        1,
+		Status:       "active",
+		Data:         datatypes.JSON([]byte(mustMarshal(data))),
+		Result:       datatypes.JSON([]byte(`{}`)),
+	}
+	if ms != nil {
+		session.MultiSig = ms.Address
+		session.Epoch = ms.Epoch
+	}
+	if err := tx.Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// lockSession は、トランザクション内でセッションを排他ロックして読み込みます。
+// 同じラウンドに複数の参加者が同時に送信しても、ラウンドの進行が一度だけ行われるようにします。
+// scheme, kind が空の場合はそれぞれ問いません。
+func lockSession(tx *gorm.DB, sessionID, scheme, kind string) (*models.Session, []string, error) {
+	var session models.Session
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("session_id = ?", sessionID)
+	if scheme != "" {
+		query = query.Where("scheme = ?", scheme)
+	}
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	err := query.First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, notFound("Session not found")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var participants []string
+	if err := json.Unmarshal(session.Participants, &participants); err != nil {
+		return nil, nil, err
+	}
+	return &session, participants, nil
+}
+
+// expectRound は、セッションが進行中で指定ラウンドを受け付けているか確認します。
+func expectRound(session *models.Session, round int) error {
+	if session.Status != "active" {
+		return conflict("Session is " + session.Status)
+	}
+	if session.Round != round {
+		return conflict("Session is not accepting this round")
+	}
+	return nil
+}
+
+// participantAddress は、参加者リスト中の from と一致するアドレスを返します（大文字小文字は区別しません）。
+func participantAddress(participants []string, from string) (string, error) {
+	for _, p := range participants {
+		if strings.EqualFold(p, from) {
+			return p, nil
+		}
+	}
+	return "", badRequest("Sender is not a participant of the session")
+}
+
+// roundMessages は、指定ラウンドで受信済みのメッセージを返します。
+func roundMessages(tx *gorm.DB, sessionID string, round int) ([]models.SessionMessage, error) {
+	var msgs []models.SessionMessage
+	err := tx.Where("session_id = ? AND round = ?", sessionID, round).Order("id").Find(&msgs).Error
+	return msgs, err
+}
+
+// storeMessage は、検証済みのメッセージを保存します。同じ送信者からの重複送信は拒否します。
+func storeMessage(tx *gorm.DB, session *models.Session, round int, from, to string, payload interface{}) error {
+	return createMessage(tx, &models.SessionMessage{
+		SessionID: session.SessionID,
+		Round:     round,
+		From:      from,
+		To:        to,
+		Payload:   datatypes.JSON([]byte(mustMarshal(payload))),
+	})
+}
+
+// verifyMessage は、req.Message が送信者 from の personal_sign で署名されていることを検証します。
+// 署名する文面はセッションIDとラウンドを含むため、他のセッションやラウンドには流用できません。
+func verifyMessage(session *models.Session, round int, from string, req *SessionMessageRequest) (*additive.SignedMessage, error) {
+	signed := &additive.SignedMessage{
+		Session:   session.SessionID,
+		Round:     round,
+		From:      from,
+		Payload:   string(req.Message),
+		Signature: req.Signature,
+	}
+	if err := signed.Verify(); err != nil {
+		return nil, badRequest("Invalid message signature: " + err.Error())
+	}
+	return signed, nil
+}
+
+// storeSigned は、署名済みメッセージを送信者の署名と共に保存します。
+func storeSigned(tx *gorm.DB, signed *additive.SignedMessage, to string, payload interface{}) error {
+	return createMessage(tx, &models.SessionMessage{
+		SessionID: signed.Session,
+		Round:     signed.Round,
+		From:      signed.From,
+		To:        to,
+		Payload:   datatypes.JSON([]byte(mustMarshal(payload))),
+		Signed:    signed.Payload,
+		Signature: hexutil.Encode(signed.Signature),
+	})
+}
+
+// createMessage は、メッセージを保存します。同じ送信者から同じ宛先への重複送信は拒否します。
+func createMessage(tx *gorm.DB, msg *models.SessionMessage) error {
+	var count int64
+	err := tx.Model(&models.SessionMessage{}).
+		Where("session_id = ? AND round = ? AND \"from\" = ? AND \"to\" = ?", msg.SessionID, msg.Round, msg.From, msg.To).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return conflict("Message already submitted for this round")
+	}
+	return tx.Create(msg).Error
+}
+
+// decodeMessages は、ラウンドのブロードキャストメッセージを送信者毎にデコードします。
+func decodeMessages[T any](msgs []models.SessionMessage) (map[string]*T, error) {
+	out := make(map[string]*T, len(msgs))
+	for _, m := range msgs {
+		if m.To != "" {
+			continue
+		}
+		var v T
+		if err := json.Unmarshal(m.Payload, &v); err != nil {
+			return nil, err
+		}
+		out[m.From] = &v
+	}
+	return out, nil
+}
+
+// GetSessionHandler は、セッションの状態と中継済みのメッセージを返します。
+func GetSessionHandler(c *gin.Context) {
+	var session models.Session
+	if err := db.DB.First(&session, "session_id = ?", c.Param("session")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Session not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Database error fetching session"})
+		}
+		return
+	}
+	var msgs []models.SessionMessage
+	if err := db.DB.Where("session_id = ?", session.SessionID).Order("id").Find(&msgs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Database error fetching messages"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"session": session, "messages": msgs})
+}
@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"multisigservice/db"
+	"multisigservice/models"
+	"multisigservice/paillier"
+	"multisigservice/protocol/additive"
+)
+
+// testParty はテスト用の参加者のEthereum鍵とPaillier鍵です。
+type testParty struct {
+	key      *ecdsa.PrivateKey
+	address  string
+	paillier *paillier.PrivateKey
+	pedersen *paillier.PedersenParams
+}
+
+// partyFixture はテスト全体で共有する参加者です（Paillier鍵の生成に時間がかかるため）。
+var partyFixture struct {
+	once    sync.Once
+	parties []*testParty
+	err     error
+}
+
+func testParties(t *testing.T) []*testParty {
+	t.Helper()
+	partyFixture.once.Do(func() {
+		for i := 0; i < 3; i++ {
+			key, err := crypto.GenerateKey()
+			if err != nil {
+				partyFixture.err = err
+				return
+			}
+			_, sk, err := paillier.GenerateKey(2048)
+			if err != nil {
+				partyFixture.err = err
+				return
+			}
+			pp, err := paillier.NewPedersenParams(sk)
+			if err != nil {
+				partyFixture.err = err
+				return
+			}
+			partyFixture.parties = append(partyFixture.parties, &testParty{
+				key:      key,
+				address:  crypto.PubkeyToAddress(key.PublicKey).Hex(),
+				paillier: sk,
+				pedersen: pp,
+			})
+		}
+	})
+	if partyFixture.err != nil {
+		t.Fatal(partyFixture.err)
+	}
+	return partyFixture.parties
+}
+
+// sign は message への personal_sign 署名を返します（MetaMask と同じく v は 27, 28）。
+func (p *testParty) sign(t *testing.T, message string) hexutil.Bytes {
+	t.Helper()
+	hash := crypto.Keccak256([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(message), message)))
+	sig, err := crypto.Sign(hash, p.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig[64] += 27
+	return sig
+}
+
+// message は msg をセッションのラウンドのメッセージとして署名したリクエストを返します。
+func (p *testParty) message(t *testing.T, sessionID string, round int, msg interface{}) *SessionMessageRequest {
+	t.Helper()
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := &additive.SignedMessage{Session: sessionID, Round: round, From: p.address, Payload: string(raw)}
+	return &SessionMessageRequest{From: p.address, Message: raw, Signature: p.sign(t, signed.Statement())}
+}
+
+// testEnv はテスト毎のDBとルーターです。
+type testEnv struct {
+	t       *testing.T
+	router  *gin.Engine
+	parties []*testParty
+}
+
+// newTestEnv は一時ファイルのSQLiteに参加者を登録し、ハンドラーのルーターを用意します。
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	parties := testParties(t)
+	conn, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.AutoMigrate(&models.User{}, &models.MultiSig{}, &models.Session{}, &models.SessionMessage{}, &models.Reshare{}, &models.Presignature{}, &models.PedersenSetup{}); err != nil {
+		t.Fatal(err)
+	}
+	prev := db.DB
+	db.DB = conn
+	t.Cleanup(func() {
+		db.DB = prev
+		if sqlDB, err := conn.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	// 公開鍵の登録は証明の生成に時間がかかるため、検証済みの形式で直接保存する
+	for _, p := range parties {
+		pub, err := p.paillier.PublicKey.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pp, err := proto.Marshal(p.pedersen.ToProto())
+		if err != nil {
+			t.Fatal(err)
+		}
+		user := models.User{Address: p.address, Pubkey: string(pub), Pedersen: base64.StdEncoding.EncodeToString(pp)}
+		if err := conn.Create(&user).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api := router.Group("/api")
+	api.GET("/auth/challenge", ChallengeHandler)
+	api.POST("/multisig/keygen", StartKeygenHandler)
+	api.POST("/multisig/keygen/:session/commit", KeygenCommitHandler)
+	api.POST("/multisig/keygen/:session/reveal", KeygenRevealHandler)
+	api.POST("/multisig/keygen/:session/confirm", KeygenConfirmHandler)
+	api.POST("/multisig/create", CreateMultiSigHandler)
+	api.POST("/multisig/:address/sign", StartSignHandler)
+	api.GET("/multisig/:address/sign/:session", GetSessionHandler)
+	api.POST("/multisig/:address/sign/:session/commit", SignCommitHandler)
+	api.POST("/multisig/:address/sign/:session/mta", SignMtAHandler)
+	api.POST("/multisig/:address/sign/:session/reveal", SignRevealHandler)
+	api.POST("/multisig/:address/sign/:session/check", SignCheckHandler)
+	api.POST("/multisig/:address/sign/:session/partial", SignPartialHandler)
+	api.POST("/multisig/:address/refresh", StartRefreshHandler)
+	api.POST("/multisig/:address/refresh/:session/commit", RefreshCommitHandler)
+	api.POST("/multisig/:address/refresh/:session/reveal", RefreshRevealHandler)
+	api.POST("/multisig/:address/refresh/:session/confirm", RefreshConfirmHandler)
+	api.POST("/multisig/:address/refresh/:session/abort", RefreshAbortHandler)
+	api.POST("/multisig/:address/reshare", StartReshareHandler)
+	api.POST("/multisig/:address/reshare/:session/approve", ReshareApproveHandler)
+	api.POST("/multisig/:address/reshare/:session/deal", ReshareDealHandler)
+	api.POST("/multisig/:address/reshare/:session/confirm", ReshareConfirmHandler)
+	api.POST("/multisig/:address/reshare/:session/abort", ReshareAbortHandler)
+	return &testEnv{t: t, router: router, parties: parties}
+}
+
+// request はルーターにリクエストを送り、ステータスを返します。out を指定するとレスポンスをデコードします。
+func (e *testEnv) request(method, path string, body, out interface{}) int {
+	e.t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, "/api"+path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, req)
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			e.t.Fatalf("%s %s: %v: %s", method, path, err, w.Body.String())
+		}
+	}
+	return w.Code
+}
+
+// post は成功を期待してリクエストを送ります。
+func (e *testEnv) post(path string, body, out interface{}) {
+	e.t.Helper()
+	var res json.RawMessage
+	if code := e.request(http.MethodPost, path, body, &res); code != http.StatusOK {
+		e.t.Fatalf("POST %s: got %d\nwant %d: %s", path, code, http.StatusOK, res)
+	}
+	if out != nil {
+		if err := json.Unmarshal(res, out); err != nil {
+			e.t.Fatal(err)
+		}
+	}
+}
+
+// signChallenge はチャレンジを取得し、statement とチャレンジへの p の署名を返します。
+func (e *testEnv) signChallenge(p *testParty, statement string) hexutil.Bytes {
+	e.t.Helper()
+	var res struct {
+		Challenge string `json:"challenge"`
+	}
+	if code := e.request(http.MethodGet, "/auth/challenge?address="+p.address, nil, &res); code != http.StatusOK {
+		e.t.Fatalf("challenge: got %d", code)
+	}
+	return p.sign(e.t, statement+"\nChallenge: "+res.Challenge)
+}
+
+// session はDBからセッションを読み込みます。
+func (e *testEnv) session(sessionID string) *models.Session {
+	e.t.Helper()
+	var session models.Session
+	if err := db.DB.First(&session, "session_id = ?", sessionID).Error; err != nil {
+		e.t.Fatal(err)
+	}
+	return &session
+}
+
+// multisig はDBからマルチシグを読み込みます。
+func (e *testEnv) multisig(address string) *models.MultiSig {
+	e.t.Helper()
+	ms, err := loadMultiSig(db.DB, address)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return ms
+}
+
+// addresses は参加者のアドレスを返します。
+func addresses(parties []*testParty) []string {
+	out := make([]string, len(parties))
+	for i, p := range parties {
+		out[i] = p.address
+	}
+	return out
+}
+
+// paillierPubs は参加者のPaillier公開鍵を返します。
+func paillierPubs(parties []*testParty) map[string]*paillier.PublicKey {
+	out := make(map[string]*paillier.PublicKey, len(parties))
+	for _, p := range parties {
+		out[p.address] = &p.paillier.PublicKey
+	}
+	return out
+}
+
+// testMultiSig はハンドラー経由の鍵生成で作成したマルチシグと各参加者のシェアです。
+type testMultiSig struct {
+	address string
+	shares  map[string]*additive.KeyShare
+}
+
+// createMultiSig は参加者全員で t-of-n の鍵生成を行い、1人目を所有者としてマルチシグを作成します。
+func (e *testEnv) createMultiSig(threshold int) *testMultiSig {
+	e.t.Helper()
+	owner := e.parties[0]
+	members := MultiSigMembers{Owner: owner.address, Participants: addresses(e.parties), Threshold: threshold}
+	var started struct {
+		Session models.Session `json:"session"`
+	}
+	e.post("/multisig/keygen", &members, &started)
+	sid := started.Session.SessionID
+
+	session := &additive.Session{ID: sid, Parties: addresses(e.parties), Threshold: threshold, PaillierKeys: paillierPubs(e.parties)}
+	players := make(map[string]*additive.KeygenParty)
+	commits := make(map[string]*additive.KeygenCommit)
+	for _, p := range e.parties {
+		player, err := additive.NewKeygenParty(session, p.address, p.paillier)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		players[p.address], commits[p.address] = player, player.Commit()
+		e.post("/multisig/keygen/"+sid+"/commit", p.message(e.t, sid, keygenRoundCommit, commits[p.address]), nil)
+	}
+	reveals := make(map[string]*additive.KeygenReveal)
+	for _, p := range e.parties {
+		reveals[p.address] = players[p.address].Reveal()
+		e.post("/multisig/keygen/"+sid+"/reveal", p.message(e.t, sid, keygenRoundReveal, reveals[p.address]), nil)
+	}
+	ms := &testMultiSig{shares: make(map[string]*additive.KeyShare)}
+	var confirmed struct {
+		Result *KeygenResult `json:"result"`
+	}
+	for _, p := range e.parties {
+		share, err := players[p.address].Finalize(commits, reveals)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		confirm, err := players[p.address].Confirm(share)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		e.post("/multisig/keygen/"+sid+"/confirm", p.message(e.t, sid, keygenRoundConfirm, confirm), &confirmed)
+		ms.shares[p.address] = share
+	}
+	if confirmed.Result == nil {
+		e.t.Fatal("keygen did not complete")
+	}
+	ms.address = confirmed.Result.Address
+
+	e.post("/multisig/create", map[string]interface{}{
+		"owner":        owner.address,
+		"participants": members.Participants,
+		"threshold":    threshold,
+		"address":      ms.address,
+		"session":      sid,
+		"signature":    e.signChallenge(owner, createMultiSigMessage(ms.address, sid)),
+	}, nil)
+	return ms
+}
+
+func TestParticipantAddress(t *testing.T) {
+	participants := []string{"0xAbC0000000000000000000000000000000000001", "0x00000000000000000000000000000000000000Ef"}
+	got, err := participantAddress(participants, "0xabc0000000000000000000000000000000000001")
+	if err != nil || got != participants[0] {
+		t.Errorf("got %q, %v\nwant %q", got, err, participants[0])
+	}
+	if _, err := participantAddress(participants, "0x0000000000000000000000000000000000000002"); err == nil {
+		t.Error("non-participant accepted")
+	}
+}
@@ -0,0 +1,753 @@
+package handlers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"multisigservice/db"
+	"multisigservice/models"
+	"multisigservice/paillier"
+	pb "multisigservice/proto/paillierpb"
+	"multisigservice/protocol/additive"
+)
+
+// 署名のラウンド（メッセージの署名対象となる番号は additive パッケージと共通）
+const (
+	signRoundCommit  = additive.SignRoundCommit  // Γ_i へのコミットメントと Enc(k_i)
+	signRoundMtA     = additive.SignRoundMtA     // MtA の応答（個別メッセージ）
+	signRoundReveal  = additive.SignRoundReveal  // δ_i と Γ_i の公開
+	signRoundCheck   = additive.SignRoundCheck   // k_i·R と σ_i·R
+	signRoundPartial = additive.SignRoundPartial // 部分署名 s_i
+	signRoundOpen    = additive.SignRoundOpen    // 確認に失敗した場合の開示
+)
+
+// SignData は署名セッションの入力として Session.Data に保存される内容です。
+// 事前署名セッションでは Keys のみを保存します。
+type SignData struct {
+	Hash    hexutil.Bytes `json:"hash"`              // 署名対象の32バイトのハッシュ
+	Presign string        `json:"presign,omitempty"` // 事前署名を用いる場合の事前署名セッションID
+	Signers []string      `json:"signers,omitempty"` // CMPまたは事前署名の署名者（省略時は参加者全員）
+	Keys    *SessionKeys  `json:"keys,omitempty"`    // 加法的方式でMtAを行うセッションの開始時点の公開鍵（サーバーが設定）
+}
+
+// SignResult は署名セッションの結果として Session.Result に保存される内容です。
+type SignResult struct {
+	R         *additive.Point     `json:"R,omitempty"`
+	Signature *additive.Signature `json:"signature,omitempty"`
+	Encoded   hexutil.Bytes       `json:"encoded,omitempty"` // r || s || v の65バイト
+	Failure   string              `json:"failure,omitempty"` // ラウンド3・4の確認に失敗した理由（開示のラウンドに進んだ場合）
+}
+
+// StartSignHandler は、マルチシグの署名セッションを開始します。
+// セッションの方式はマルチシグの方式に従います。CMPのメッセージは RelayCMPMessageHandler で中継します。
+// 加法的方式では最初にコミットした t 人が署名者となり、CMPでは開始時に署名者を指定します。
+// 事前署名を指定した場合は事前署名の署名者で署名し、加法的方式では部分署名のラウンドから始めます。
+// 事前署名は1度しか使えないため、事前署名の署名者によるハッシュと事前署名IDへのチャレンジ付きの署名を要求します。
+func StartSignHandler(c *gin.Context) {
+	var req struct {
+		SignData
+		Initiator string        `json:"initiator,omitempty"` // 事前署名を使う場合に署名を開始する署名者
+		Signature hexutil.Bytes `json:"signature,omitempty"` // presignSignMessage とチャレンジへの開始者の署名
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Hash) != 32 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Require a 32-byte hash to sign"})
+		return
+	}
+	req.Keys = nil
+
+	var session *models.Session
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		ms, err := loadMultiSig(tx, c.Param("address"))
+		if err != nil {
+			return err
+		}
+		participants := participantsOf(*ms)
+		var presig *models.Presignature
+		switch {
+		case req.Presign != "":
+			// 事前署名と同じ署名者で署名する
+			if presig, participants, err = consumePresign(tx, ms, req.Presign, req.Hash, req.Initiator, req.Signature); err != nil {
+				return err
+			}
+			req.Signers = participants
+		case ms.Scheme == "cmp":
+			if participants, err = signerSet(ms, req.Signers); err != nil {
+				return err
+			}
+			req.Signers = participants
+		case len(req.Signers) > 0:
+			return badRequest("Signers are chosen by the first commitments")
+		default:
+			// 全員がPaillier公開鍵とリングPedersenパラメータを登録済みでなければMtAを行えない。
+			// 途中で登録し直されても検証と証拠が変わらないよう、開始時点の値をセッションに保存する
+			if req.Keys, err = snapshotKeys(tx, participants); err != nil {
+				return err
+			}
+		}
+		session, err = newSession(tx, ms.Scheme, "sign", ms, participants, &req.SignData)
+		if err != nil {
+			return err
+		}
+		if presig != nil {
+			if err := tx.Model(presig).Update("sign_session", session.SessionID).Error; err != nil {
+				return err
+			}
+		}
+		if presig != nil && ms.Scheme == "additive" {
+			R, err := presignNonce(presig)
+			if err != nil {
+				return err
+			}
+			session.Round = signRoundPartial
+			session.Result = datatypes.JSON([]byte(mustMarshal(&SignResult{R: R})))
+			if err := tx.Save(session).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(ms).Update("status", "partial").Error
+	})
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Signing session created", "session": session})
+}
+
+// SignCommitHandler は、ラウンド1のメッセージを受け付けます。
+// Enc(k_i) の範囲証明を、セッション開始時点の送信者のPaillier公開鍵と、証明を受け取る各参加者のリングPedersenパラメータで検証してから保存します。
+// t 人分が揃うと、コミットした参加者を署名者として次のラウンドに進みます。
+func SignCommitHandler(c *gin.Context) {
+	var msg additive.SignRound1
+	signStep(c, signRoundCommit, &msg, func(tx *gorm.DB, st *signState) error {
+		if err := additive.VerifySignRound1(st.keys[st.from], st.pedersen, st.from, &msg); err != nil {
+			return st.reject(tx, err)
+		}
+		if err := st.store(tx, "", &msg); err != nil {
+			return err
+		}
+		msgs, err := st.messages(tx, signRoundCommit)
+		if err != nil || len(msgs) < st.ms.Threshold {
+			return err
+		}
+		// 以降のラウンドはコミットした署名者のみで行う（順序はマルチシグの参加者順）
+		committed := make(map[string]bool, len(msgs))
+		for _, m := range msgs {
+			committed[m.From] = true
+		}
+		var signers []string
+		for _, p := range st.participants {
+			if committed[p] {
+				signers = append(signers, p)
+			}
+		}
+		return tx.Model(st.session).Updates(map[string]interface{}{
+			"round":        signRoundMtA,
+			"participants": datatypes.JSON([]byte(mustMarshal(signers))),
+		}).Error
+	})
+}
+
+// SignMtAHandler は、ラウンド2のMtA応答を受け付けます。
+// メッセージは宛先アドレスをキーとするオブジェクトで、他の参加者全員宛ての応答を含む必要があります。
+// 各応答のアフィン証明を、宛先のラウンド1の Enc(k_i) と宛先のリングPedersenパラメータで検証してから中継します。
+func SignMtAHandler(c *gin.Context) {
+	var msgs map[string]*additive.SignRound2
+	signStep(c, signRoundMtA, &msgs, func(tx *gorm.DB, st *signState) error {
+		if len(msgs) != len(st.participants)-1 {
+			return st.reject(tx, errors.New("require a message for every other participant"))
+		}
+		commits, err := st.messages(tx, signRoundCommit)
+		if err != nil {
+			return err
+		}
+		round1, err := decodeMessages[additive.SignRound1](commits)
+		if err != nil {
+			return err
+		}
+		for to, msg := range msgs {
+			recipient, err := participantAddress(st.participants, to)
+			if err != nil || recipient == st.from {
+				return st.reject(tx, errors.New("invalid recipient: "+to))
+			}
+			if err := additive.VerifySignRound2(st.keys[recipient], st.pedersen[recipient], round1[recipient], msg); err != nil {
+				return st.reject(tx, err)
+			}
+		}
+		for to, msg := range msgs {
+			recipient, _ := participantAddress(st.participants, to)
+			if err := st.store(tx, recipient, msg); err != nil {
+				return err
+			}
+		}
+		n := len(st.participants)
+		return st.advanceWhen(tx, n*(n-1))
+	})
+}
+
+// SignRevealHandler は、ラウンド3の δ_i と Γ_i の公開を受け付けます。
+// 全員分が揃うと R = δ⁻¹·ΣΓ_i を導出します。
+func SignRevealHandler(c *gin.Context) {
+	var msg additive.SignRound3
+	signStep(c, signRoundReveal, &msg, func(tx *gorm.DB, st *signState) error {
+		if st.session.Kind != "sign" {
+			return notFound("Session not found")
+		}
+		return st.acceptReveal(tx, &msg, &msg)
+	})
+}
+
+// SignCheckHandler は、署名・事前署名セッションのラウンド4の k_i·R と σ_i·R を受け付けます。
+// 全員分が揃うと Σk_i·R = G と Σσ_i·R = Q を確認し、署名では部分署名のラウンドに進み、事前署名ではセッションを完了します。
+// 確認に失敗した場合は開示のラウンドに進みます。
+func SignCheckHandler(c *gin.Context) {
+	var msg additive.SignRound4
+	signStep(c, signRoundCheck, &msg, func(tx *gorm.DB, st *signState) error {
+		if err := additive.VerifySignRound4(&msg); err != nil {
+			return st.reject(tx, err)
+		}
+		if err := st.store(tx, "", &msg); err != nil {
+			return err
+		}
+		msgs, err := st.messages(tx, signRoundCheck)
+		if err != nil || len(msgs) < len(st.participants) {
+			return err
+		}
+		checks, err := decodeMessages[additive.SignRound4](msgs)
+		if err != nil {
+			return err
+		}
+		Q, err := multisigPublicKey(st.ms)
+		if err != nil {
+			return err
+		}
+		if err := additive.CheckSignRound4(Q, checks); err != nil {
+			return st.requestOpening(tx, err)
+		}
+		if st.session.Kind == "presign" {
+			return st.completePresign(tx)
+		}
+		return tx.Model(st.session).Update("round", signRoundPartial).Error
+	})
+}
+
+// SignPartialHandler は、ラウンド5の部分署名を受け付けます。
+// 部分署名は送信者のラウンド4のメッセージに対して検証し、式を満たさなければ送信者を不正者としてセッションを中断します。
+// 全員分が揃うと署名を組み立て、マルチシグのアドレスに対して検証します。
+func SignPartialHandler(c *gin.Context) {
+	var msg additive.SignRound5
+	signStep(c, signRoundPartial, &msg, func(tx *gorm.DB, st *signState) error {
+		var data SignData
+		var result SignResult
+		if err := json.Unmarshal(st.session.Data, &data); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(st.session.Result, &result); err != nil {
+			return err
+		}
+		// 事前署名を用いる場合、ラウンド4は事前署名セッションで行っている
+		checkSession := st.session.SessionID
+		if data.Presign != "" {
+			checkSession = data.Presign
+		}
+		round4, err := roundMessages(tx, checkSession, signRoundCheck)
+		if err != nil {
+			return err
+		}
+		checks, err := decodeMessages[additive.SignRound4](round4)
+		if err != nil {
+			return err
+		}
+		if err := additive.VerifySignRound5(data.Hash, result.R, checks[st.from], &msg); err != nil {
+			return st.reject(tx, err)
+		}
+		if err := st.store(tx, "", &msg); err != nil {
+			return err
+		}
+		msgs, err := st.messages(tx, signRoundPartial)
+		if err != nil || len(msgs) < len(st.participants) {
+			return err
+		}
+		partials, err := decodeMessages[additive.SignRound5](msgs)
+		if err != nil {
+			return err
+		}
+
+		Q, err := multisigPublicKey(st.ms)
+		if err != nil {
+			return err
+		}
+		sig, err := additive.CombineSignature(data.Hash, Q, result.R, partials)
+		if err != nil {
+			return st.abort(tx, err)
+		}
+		result.Signature = sig
+		return completeSigning(tx, st.session, st.ms, data.Hash, &result)
+	})
+}
+
+// SignOpenHandler は、ラウンド3・4の確認に失敗した後の開示（k_i と自分宛てのMtA応答の平文と乱数）を受け付けます。
+// 開示は部分署名より前にしか求めないため、開示しても秘密鍵は漏れません。
+// 不正者を特定できた時点、または全員分が揃った時点でセッションを中断します。
+func SignOpenHandler(c *gin.Context) {
+	var msg additive.SignOpening
+	signStep(c, signRoundOpen, &msg, func(tx *gorm.DB, st *signState) error {
+		if err := st.store(tx, "", &msg); err != nil {
+			return err
+		}
+		ev, err := st.blame(tx)
+		if err != nil {
+			return err
+		}
+		msgs, err := st.messages(tx, signRoundOpen)
+		if err != nil || ev.Fault == nil && len(msgs) < len(st.participants) {
+			return err
+		}
+		var result SignResult
+		if err := json.Unmarshal(st.session.Result, &result); err != nil {
+			return err
+		}
+		return st.abortWith(tx, ev, errors.New(result.Failure))
+	})
+}
+
+// completeSigning は、検証済みの署名で署名セッションを完了し、マルチシグに記録します。
+func completeSigning(tx *gorm.DB, session *models.Session, ms *models.MultiSig, hash []byte, result *SignResult) error {
+	result.Encoded = result.Signature.Bytes()
+	if err := tx.Model(session).Updates(map[string]interface{}{
+		"status": "completed",
+		"result": datatypes.JSON([]byte(mustMarshal(result))),
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Model(ms).Updates(map[string]interface{}{
+		"status": "completed",
+		"data": datatypes.JSON([]byte(mustMarshal(gin.H{
+			"session":   session.SessionID,
+			"hash":      hexutil.Bytes(hash),
+			"signature": result.Encoded,
+		}))),
+	}).Error
+}
+
+// signState は署名ラウンドの処理中に共有する状態です。
+type signState struct {
+	session      *models.Session
+	participants []string
+	from         string
+	signed       *additive.SignedMessage // 受信した署名済みメッセージ
+	ms           *models.MultiSig
+	keys         map[string]*paillier.PublicKey
+	pedersen     map[string]*paillier.PedersenParams
+	aborted      error
+}
+
+// messages は、指定ラウンドで受信済みのメッセージを返します。
+func (st *signState) messages(tx *gorm.DB, round int) ([]models.SessionMessage, error) {
+	return roundMessages(tx, st.session.SessionID, round)
+}
+
+// store は、受信したメッセージを送信者の署名と共に保存します。
+func (st *signState) store(tx *gorm.DB, to string, payload interface{}) error {
+	return storeSigned(tx, st.signed, to, payload)
+}
+
+// advanceWhen は、現在のラウンドのメッセージが count 件揃っていれば次のラウンドに進めます。
+func (st *signState) advanceWhen(tx *gorm.DB, count int) error {
+	msgs, err := st.messages(tx, st.session.Round)
+	if err != nil || len(msgs) < count {
+		return err
+	}
+	return tx.Model(st.session).Update("round", st.session.Round+1).Error
+}
+
+// acceptReveal は、ラウンド3の公開 reveal を検証して stored を保存します。
+// 全員分が揃うと R = δ⁻¹·ΣΓ_i を導出して確認のラウンドに進みます。R を導出できなければ開示のラウンドに進みます。
+func (st *signState) acceptReveal(tx *gorm.DB, reveal *additive.SignRound3, stored interface{}) error {
+	round1, err := st.messages(tx, signRoundCommit)
+	if err != nil {
+		return err
+	}
+	commits, err := decodeMessages[additive.SignRound1](round1)
+	if err != nil {
+		return err
+	}
+	if err := additive.VerifySignRound3(st.session.SessionID, st.from, commits[st.from], reveal); err != nil {
+		return st.reject(tx, err)
+	}
+	if err := st.store(tx, "", stored); err != nil {
+		return err
+	}
+
+	msgs, err := st.messages(tx, signRoundReveal)
+	if err != nil || len(msgs) < len(st.participants) {
+		return err
+	}
+	reveals, err := decodeMessages[additive.SignRound3](msgs)
+	if err != nil {
+		return err
+	}
+	R, _, err := additive.ComputeR(reveals)
+	if err != nil {
+		return st.requestOpening(tx, err)
+	}
+	return tx.Model(st.session).Updates(map[string]interface{}{
+		"round":  signRoundCheck,
+		"result": datatypes.JSON([]byte(mustMarshal(&SignResult{R: R}))),
+	}).Error
+}
+
+// requestOpening は、ラウンド3・4の確認に失敗したセッションを開示のラウンドに進めます。
+func (st *signState) requestOpening(tx *gorm.DB, cause error) error {
+	var result SignResult
+	if err := json.Unmarshal(st.session.Result, &result); err != nil {
+		return err
+	}
+	result.Failure = cause.Error()
+	return tx.Model(st.session).Updates(map[string]interface{}{
+		"round":  signRoundOpen,
+		"result": datatypes.JSON([]byte(mustMarshal(&result))),
+	}).Error
+}
+
+// reject は、検証に失敗したメッセージを保存し、送信者の署名を証拠としてセッションを中断します。
+// 記録から不正を再現できない場合は、メッセージを保存せずに拒否します。
+func (st *signState) reject(tx *gorm.DB, cause error) error {
+	if err := st.store(tx, "", json.RawMessage(st.signed.Payload)); err != nil {
+		return err
+	}
+	ev, err := st.blame(tx)
+	if err != nil {
+		return err
+	}
+	if ev.Fault == nil {
+		return badRequest(cause.Error())
+	}
+	return st.abortWith(tx, ev, cause)
+}
+
+// blame は、セッションの公開入力と署名済みメッセージ一式から証拠を作り、不正者を特定します。
+// 事前署名を用いた署名では、事前署名セッションのメッセージも含めます。
+func (st *signState) blame(tx *gorm.DB) (*additive.SignEvidence, error) {
+	var data SignData
+	if err := json.Unmarshal(st.session.Data, &data); err != nil {
+		return nil, err
+	}
+	Q, err := multisigPublicKey(st.ms)
+	if err != nil {
+		return nil, err
+	}
+	var shares map[string]*additive.Point
+	if err := json.Unmarshal(st.ms.PublicShares, &shares); err != nil {
+		return nil, err
+	}
+	ev := &additive.SignEvidence{
+		SessionID:    st.session.SessionID,
+		Parties:      st.participants,
+		KeyParties:   participantsOf(*st.ms),
+		PublicKey:    Q,
+		PublicShares: shares,
+		PaillierKeys: st.keys,
+		Pedersen:     st.pedersen,
+		Hash:         data.Hash,
+		Presign:      st.session.Kind == "presign" || data.Presign != "",
+	}
+	sessions := []string{st.session.SessionID}
+	if data.Presign != "" {
+		ev.SessionID, ev.SignSession = data.Presign, st.session.SessionID
+		sessions = append(sessions, data.Presign)
+	}
+
+	var msgs []models.SessionMessage
+	if err := tx.Where("session_id IN ? AND signed <> ''", sessions).Order("id").Find(&msgs).Error; err != nil {
+		return nil, err
+	}
+	// ラウンド2は宛先毎に保存しているが、署名は送信者毎に1つ
+	seen := make(map[string]bool, len(msgs))
+	for _, m := range msgs {
+		key := fmt.Sprintf("%s/%d/%s", m.SessionID, m.Round, m.From)
+		if _, err := participantAddress(st.participants, m.From); err != nil || seen[key] {
+			continue
+		}
+		seen[key] = true
+		sig, err := hexutil.Decode(m.Signature)
+		if err != nil {
+			return nil, err
+		}
+		ev.Messages = append(ev.Messages, &additive.SignedMessage{
+			Session:   m.SessionID,
+			Round:     m.Round,
+			From:      m.From,
+			Payload:   m.Signed,
+			Signature: sig,
+		})
+	}
+	transcript, err := ev.Transcript()
+	if err != nil {
+		return nil, err
+	}
+	ev.Fault = transcript.Blame()
+	return ev, nil
+}
+
+// abort は、記録から不正者を特定してセッションを中断状態にします。中断はトランザクションと共にコミットされます。
+func (st *signState) abort(tx *gorm.DB, cause error) error {
+	ev, err := st.blame(tx)
+	if err != nil {
+		return err
+	}
+	return st.abortWith(tx, ev, cause)
+}
+
+// abortWith は、証拠 ev と共にセッションを中断状態にします。
+// 不正者を特定できた場合は Session.Culprit に記録し、証拠は誰でも additive.SignEvidence.Transcript から再検証できます。
+func (st *signState) abortWith(tx *gorm.DB, ev *additive.SignEvidence, cause error) error {
+	ev.Reason = cause.Error()
+	st.aborted = cause
+	culprit := ""
+	if ev.Fault != nil {
+		st.aborted = ev.Fault
+		culprit = ev.Fault.Culprit
+	}
+	if err := tx.Model(st.session).Updates(map[string]interface{}{
+		"status":   "aborted",
+		"culprit":  culprit,
+		"evidence": datatypes.JSON([]byte(mustMarshal(ev))),
+	}).Error; err != nil {
+		return err
+	}
+	if st.session.Kind != "sign" {
+		return nil
+	}
+	return tx.Model(st.ms).Update("status", "awaiting").Error
+}
+
+// signStep は、署名・事前署名ラウンドのリクエストを解析し、セッションをロックして step を実行します。
+// メッセージは送信者の personal_sign 署名を検証してから受け付けます。
+func signStep(c *gin.Context, round int, msg interface{}, step func(tx *gorm.DB, st *signState) error) {
+	var req SessionMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil || json.Unmarshal(req.Message, msg) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid message"})
+		return
+	}
+
+	var st signState
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		st.session, st.participants, err = lockSession(tx, c.Param("session"), "additive", "")
+		if err != nil {
+			return err
+		}
+		if st.session.Kind != "sign" && st.session.Kind != "presign" || !strings.EqualFold(st.session.MultiSig, c.Param("address")) {
+			return notFound("Session not found")
+		}
+		if err := expectRound(st.session, round); err != nil {
+			return err
+		}
+		if st.from, err = participantAddress(st.participants, req.From); err != nil {
+			return err
+		}
+		if st.signed, err = verifyMessage(st.session, round, st.from, &req); err != nil {
+			return err
+		}
+		if st.ms, err = loadMultiSig(tx, st.session.MultiSig); err != nil {
+			return err
+		}
+		if st.session.Epoch != st.ms.Epoch {
+			return conflict("Session was started before the latest refresh")
+		}
+		if st.keys, st.pedersen, err = sessionKeys(tx, st.session); err != nil {
+			return err
+		}
+		return step(tx, &st)
+	})
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	if st.aborted != nil {
+		c.JSON(http.StatusConflict, gin.H{"message": "Signing aborted: " + st.aborted.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Message accepted"})
+}
+
+// loadMultiSig は、アドレスで指定したマルチシグを読み込みます。
+func loadMultiSig(tx *gorm.DB, address string) (*models.MultiSig, error) {
+	var ms models.MultiSig
+	if err := tx.First(&ms, "address = ?", address).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, notFound("MultiSig not found")
+		}
+		return nil, err
+	}
+	return &ms, nil
+}
+
+// multisigPublicKey は、マルチシグに保存された公開鍵 Q を復元します。
+func multisigPublicKey(ms *models.MultiSig) (*additive.Point, error) {
+	raw, err := hex.DecodeString(ms.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return additive.PointFromBytes(raw)
+}
+
+// signerSet は、指定された署名者がマルチシグの参加者で重複がなく、閾値以上であることを確認します。
+// 省略時は参加者全員を署名者とします。
+func signerSet(ms *models.MultiSig, requested []string) ([]string, error) {
+	participants := participantsOf(*ms)
+	if len(requested) == 0 {
+		return participants, nil
+	}
+	signers := make([]string, 0, len(requested))
+	for _, r := range requested {
+		p, err := participantAddress(participants, r)
+		if err != nil {
+			return nil, badRequest("Signer is not a participant: " + r)
+		}
+		for _, s := range signers {
+			if s == p {
+				return nil, badRequest("Duplicate signer: " + p)
+			}
+		}
+		signers = append(signers, p)
+	}
+	if len(signers) < ms.Threshold {
+		return nil, badRequest(fmt.Sprintf("Require at least %d signers", ms.Threshold))
+	}
+	return signers, nil
+}
+
+// paillierKeys は、参加者が登録したPaillier公開鍵を読み込みます。
+func paillierKeys(tx *gorm.DB, participants []string) (map[string]*paillier.PublicKey, error) {
+	keys := make(map[string]*paillier.PublicKey, len(participants))
+	for _, address := range participants {
+		user, err := registeredUser(tx, address)
+		if err != nil {
+			return nil, err
+		}
+		if user.Pubkey == "" {
+			return nil, badRequest("Participant has not registered a Paillier pubkey: " + address)
+		}
+		if keys[address], err = storedPaillierKey(user.Pubkey); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// SessionKeys は、鍵生成・鍵更新・署名・事前署名・再共有セッションの開始時点で参加者が登録していたPaillier公開鍵とリングPedersenパラメータです。
+// 途中で公開鍵を登録し直されても、セッションの検証と不正者の証拠には開始時点の値を使います。
+// Enc(k_i) の範囲証明は受け取る側のパラメータに対して作成・検証するため、サーバーはトラップドアを持ちません。
+type SessionKeys struct {
+	Paillier map[string]string `json:"paillier"` // User.Pubkey の形式
+	Pedersen map[string]string `json:"pedersen"` // User.Pedersen の形式
+}
+
+// snapshotKeys は、参加者全員のPaillier公開鍵とリングPedersenパラメータを読み込み、セッションに保存する形で返します。
+func snapshotKeys(tx *gorm.DB, participants []string) (*SessionKeys, error) {
+	keys := &SessionKeys{
+		Paillier: make(map[string]string, len(participants)),
+		Pedersen: make(map[string]string, len(participants)),
+	}
+	for _, address := range participants {
+		user, err := registeredUser(tx, address)
+		if err != nil {
+			return nil, err
+		}
+		if user.Pubkey == "" {
+			return nil, badRequest("Participant has not registered a Paillier pubkey: " + address)
+		}
+		if user.Pedersen == "" {
+			return nil, badRequest("Participant has not registered Pedersen parameters: " + address)
+		}
+		keys.Paillier[address], keys.Pedersen[address] = user.Pubkey, user.Pedersen
+	}
+	if _, _, err := keys.load(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// load は、保存された公開鍵とパラメータを解析します。
+func (k *SessionKeys) load() (map[string]*paillier.PublicKey, map[string]*paillier.PedersenParams, error) {
+	keys := make(map[string]*paillier.PublicKey, len(k.Paillier))
+	for address, s := range k.Paillier {
+		pub, err := storedPaillierKey(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys[address] = pub
+	}
+	params := make(map[string]*paillier.PedersenParams, len(k.Pedersen))
+	for address, s := range k.Pedersen {
+		pp, err := storedPedersenParams(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		params[address] = pp
+	}
+	return keys, params, nil
+}
+
+// sessionKeys は、署名・事前署名セッションの開始時に保存した公開鍵とパラメータを返します。
+// 事前署名を用いた署名では、MtAを行った事前署名セッションのものを返します。
+func sessionKeys(tx *gorm.DB, session *models.Session) (map[string]*paillier.PublicKey, map[string]*paillier.PedersenParams, error) {
+	var data SignData
+	if err := json.Unmarshal(session.Data, &data); err != nil {
+		return nil, nil, err
+	}
+	if data.Presign != "" {
+		var presign models.Session
+		if err := tx.First(&presign, "session_id = ?", data.Presign).Error; err != nil {
+			return nil, nil, err
+		}
+		data = SignData{}
+		if err := json.Unmarshal(presign.Data, &data); err != nil {
+			return nil, nil, err
+		}
+	}
+	if data.Keys == nil {
+		return nil, nil, conflict("Session has no snapshot of the participants' keys")
+	}
+	return data.Keys.load()
+}
+
+// registeredUser は、参加者のユーザー情報を読み込みます。
+func registeredUser(tx *gorm.DB, address string) (*models.User, error) {
+	var user models.User
+	if err := tx.First(&user, "LOWER(address) = LOWER(?)", address).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, badRequest("Participant has not registered: " + address)
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// storedPaillierKey は、User.Pubkey に保存した正規形のPaillier公開鍵を解析します。
+func storedPaillierKey(s string) (*paillier.PublicKey, error) {
+	var pub paillier.PublicKey
+	if err := pub.UnmarshalText([]byte(s)); err != nil {
+		return nil, err
+	}
+	return &pub, nil
+}
+
+// storedPedersenParams は、User.Pedersen に保存したリングPedersenパラメータを解析します。
+func storedPedersenParams(s string) (*paillier.PedersenParams, error) {
+	var msg pb.PedersenParams
+	if err := decodeProto(s, &msg); err != nil {
+		return nil, err
+	}
+	return paillier.PedersenParamsFromProto(&msg)
+}
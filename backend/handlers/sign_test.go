@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"multisigservice/paillier"
+	"multisigservice/protocol/additive"
+)
+
+// startSign は署名セッションを開始してIDを返します。
+func (e *testEnv) startSign(ms *testMultiSig, hash []byte) string {
+	e.t.Helper()
+	var started struct {
+		Session struct {
+			SessionID string `json:"sessionId"`
+		} `json:"session"`
+	}
+	e.post("/multisig/"+ms.address+"/sign", map[string]interface{}{"hash": hexutil.Bytes(hash)}, &started)
+	return started.Session.SessionID
+}
+
+// runSign は signers でラウンド1〜5をハンドラー経由で実行し、最後の部分署名のステータスを返します。
+// tamper を指定すると、部分署名を送信前に書き換えます。
+func (e *testEnv) runSign(ms *testMultiSig, sid string, signers []*testParty, hash []byte, tamper func(from string, msg *additive.SignRound5)) int {
+	e.t.Helper()
+	path := "/multisig/" + ms.address + "/sign/" + sid
+	// 範囲証明はセッション開始時点の参加者全員のパラメータに対して作る
+	pedersen := make(map[string]*paillier.PedersenParams)
+	for _, p := range e.parties {
+		pedersen[p.address] = p.pedersen
+	}
+	parties := make(map[string]*additive.SignParty)
+	for _, p := range signers {
+		party, err := additive.NewSignParty(additive.SignConfig{
+			SessionID:    sid,
+			Self:         p.address,
+			Parties:      addresses(signers),
+			Share:        ms.shares[p.address],
+			Hash:         hash,
+			Paillier:     p.paillier,
+			PaillierKeys: paillierPubs(e.parties),
+			Pedersen:     pedersen,
+		})
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		parties[p.address] = party
+	}
+
+	round1 := make(map[string]*additive.SignRound1)
+	for _, p := range signers {
+		msg, err := parties[p.address].Round1()
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		round1[p.address] = msg
+		e.post(path+"/commit", p.message(e.t, sid, signRoundCommit, msg), nil)
+	}
+	inbox := make(map[string]map[string]*additive.SignRound2)
+	for _, p := range signers {
+		inbox[p.address] = make(map[string]*additive.SignRound2)
+	}
+	for _, p := range signers {
+		out, err := parties[p.address].Round2(round1)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		for to, msg := range out {
+			inbox[to][p.address] = msg
+		}
+		e.post(path+"/mta", p.message(e.t, sid, signRoundMtA, out), nil)
+	}
+	round3 := make(map[string]*additive.SignRound3)
+	for _, p := range signers {
+		msg, err := parties[p.address].Round3(inbox[p.address])
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		round3[p.address] = msg
+		e.post(path+"/reveal", p.message(e.t, sid, signRoundReveal, msg), nil)
+	}
+	round4 := make(map[string]*additive.SignRound4)
+	for _, p := range signers {
+		msg, err := parties[p.address].Round4(round3)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		round4[p.address] = msg
+		e.post(path+"/check", p.message(e.t, sid, signRoundCheck, msg), nil)
+	}
+	code := http.StatusOK
+	for _, p := range signers {
+		msg, err := parties[p.address].Round5(round4)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		if tamper != nil {
+			tamper(p.address, msg)
+		}
+		if code = e.request(http.MethodPost, path+"/partial", p.message(e.t, sid, signRoundPartial, msg), nil); code != http.StatusOK {
+			return code
+		}
+	}
+	return code
+}
+
+func TestSignSession(t *testing.T) {
+	e := newTestEnv(t)
+	ms := e.createMultiSig(2)
+	hash := crypto.Keccak256([]byte("sign handler test"))
+
+	// 最初にコミットした t 人が署名者となる
+	sid := e.startSign(ms, hash)
+	if code := e.runSign(ms, sid, e.parties[:2], hash, nil); code != http.StatusOK {
+		t.Fatalf("got %d\nwant %d", code, http.StatusOK)
+	}
+
+	session := e.session(sid)
+	if session.Status != "completed" {
+		t.Fatalf("got status %q\nwant completed", session.Status)
+	}
+	var result SignResult
+	if err := json.Unmarshal(session.Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	pub, err := crypto.SigToPub(hash, result.Encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := crypto.PubkeyToAddress(*pub).Hex(); got != ms.address {
+		t.Errorf("got signer %s\nwant %s", got, ms.address)
+	}
+	if got := e.multisig(ms.address).Status; got != "completed" {
+		t.Errorf("got multisig status %q\nwant completed", got)
+	}
+
+	// 完了したセッションには送信できない
+	msg := e.parties[0].message(t, sid, signRoundPartial, &additive.SignRound5{S: (*hexutil.Big)(big.NewInt(1))})
+	if code := e.request(http.MethodPost, "/multisig/"+ms.address+"/sign/"+sid+"/partial", msg, nil); code != http.StatusConflict {
+		t.Errorf("got %d\nwant %d", code, http.StatusConflict)
+	}
+}
+
+func TestSignSessionBlame(t *testing.T) {
+	e := newTestEnv(t)
+	ms := e.createMultiSig(2)
+	hash := crypto.Keccak256([]byte("sign handler blame test"))
+	signers := e.parties[:2]
+
+	// 不正な部分署名を送った署名者が特定され、セッションは証拠と共に中断される
+	cheater := signers[1].address
+	sid := e.startSign(ms, hash)
+	code := e.runSign(ms, sid, signers, hash, func(from string, msg *additive.SignRound5) {
+		if from == cheater {
+			msg.S = (*hexutil.Big)(new(big.Int).Add(msg.S.ToInt(), big.NewInt(1)))
+		}
+	})
+	if code != http.StatusConflict {
+		t.Fatalf("got %d\nwant %d", code, http.StatusConflict)
+	}
+	session := e.session(sid)
+	if session.Status != "aborted" || session.Culprit != cheater {
+		t.Fatalf("got status %q, culprit %q\nwant aborted, %s", session.Status, session.Culprit, cheater)
+	}
+
+	// 証拠は誰でも再検証でき、同じ署名者が特定される
+	var ev additive.SignEvidence
+	if err := json.Unmarshal(session.Evidence, &ev); err != nil {
+		t.Fatal(err)
+	}
+	transcript, err := ev.Transcript()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := transcript.Blame(); f == nil || f.Culprit != cheater {
+		t.Errorf("got %v\nwant culprit %s", f, cheater)
+	}
+	if got := e.multisig(ms.address).Status; got != "awaiting" {
+		t.Errorf("got multisig status %q\nwant awaiting", got)
+	}
+
+	// 署名の送信者を偽ったメッセージは受け付けない
+	sid = e.startSign(ms, hash)
+	forged := e.parties[1].message(t, sid, signRoundCommit, &additive.SignRound1{Commitment: []byte{1}})
+	forged.From = e.parties[0].address
+	if code := e.request(http.MethodPost, "/multisig/"+ms.address+"/sign/"+sid+"/commit", forged, nil); code != http.StatusBadRequest {
+		t.Errorf("got %d\nwant %d", code, http.StatusBadRequest)
+	}
+}
+
+func TestSignSessionEpochChange(t *testing.T) {
+	e := newTestEnv(t)
+	ms := e.createMultiSig(2)
+	hash := crypto.Keccak256([]byte("sign handler epoch test"))
+
+	// 鍵更新の前に開始した署名セッションは、鍵更新の完了で中断される
+	stale := e.startSign(ms, hash)
+	refreshed := e.runRefresh(ms)
+	if got := e.session(stale).Status; got != "aborted" {
+		t.Fatalf("got status %q\nwant aborted", got)
+	}
+	msg := e.parties[0].message(t, stale, signRoundCommit, &additive.SignRound1{Commitment: []byte{1}})
+	if code := e.request(http.MethodPost, "/multisig/"+ms.address+"/sign/"+stale+"/commit", msg, nil); code != http.StatusConflict {
+		t.Errorf("got %d\nwant %d", code, http.StatusConflict)
+	}
+
+	// 更新後のシェアで署名できる
+	sid := e.startSign(refreshed, hash)
+	if code := e.runSign(refreshed, sid, e.parties[1:], hash, nil); code != http.StatusOK {
+		t.Fatalf("got %d\nwant %d", code, http.StatusOK)
+	}
+	if got := e.session(sid).Status; got != "completed" {
+		t.Errorf("got status %q\nwant completed", got)
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"multisigservice/db"
+	"multisigservice/models"
+	"multisigservice/paillier"
+)
+
+// GetUserPubkeyHandler は、指定ユーザーが登録したPaillier公開鍵をデコードし、リングPedersenパラメータと共に返します。
+func GetUserPubkeyHandler(c *gin.Context) {
+	address := c.Param("address")
+
+	var user models.User
+	if err := db.DB.First(&user, "LOWER(address) = LOWER(?)", normalizeAddress(address)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Database error fetching user"})
+		}
+		return
+	}
+	if user.Pubkey == "" {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Pubkey not registered"})
+		return
+	}
+
+	var pub paillier.PublicKey
+	if err := pub.UnmarshalText([]byte(user.Pubkey)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to decode stored pubkey"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"address":  user.Address,
+		"pubkey":   &pub,        // JSON形式（{"n": "0x..."}）
+		"encoded":  user.Pubkey, // 正規形（base64 protobuf）
+		"bitLen":   pub.N.BitLen(),
+		"pedersen": user.Pedersen, // リングPedersenパラメータ（base64 protobuf）。署名ではこれに対して範囲証明を作成する
+	})
+}
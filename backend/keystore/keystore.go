@@ -0,0 +1,284 @@
+// Package keystore stores Paillier private keys encrypted at rest.
+//
+// A key file is a JSON document modelled on Ethereum's keystore v3 format:
+// the pb.PrivateKey protobuf is sealed with AES-256-GCM under a key derived
+// from the passphrase with scrypt.
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/scrypt"
+	"google.golang.org/protobuf/proto"
+
+	"multisigservice/paillier"
+	pb "multisigservice/proto/paillierpb"
+)
+
+const (
+	// Version is the key file format version written by this package.
+	Version = 1
+
+	// StandardScryptN and StandardScryptP are the scrypt parameters for key
+	// files kept by long-running signer daemons.
+	StandardScryptN = 1 << 18
+	StandardScryptP = 1
+
+	// LightScryptN and LightScryptP use less memory and CPU, e.g. for tests.
+	LightScryptN = 1 << 12
+	LightScryptP = 6
+
+	scryptR     = 8
+	scryptDKLen = 32
+	cipherName  = "aes-256-gcm"
+	kdfName     = "scrypt"
+)
+
+var (
+	// ErrDecrypt is returned when the passphrase is wrong or the file has been tampered with.
+	ErrDecrypt = errors.New("could not decrypt key with given passphrase")
+	// ErrVersion is returned for key files of an unsupported format version.
+	ErrVersion = errors.New("unsupported key file version")
+	// ErrScryptParams is returned for scrypt parameters costlier than StandardScryptN,
+	// StandardScryptP and r = 8, which would let a crafted key file exhaust memory or CPU.
+	ErrScryptParams = errors.New("scrypt parameters exceed the supported maximum")
+)
+
+type keyFile struct {
+	Version int        `json:"version"`
+	ID      string     `json:"id"`
+	Crypto  cryptoJSON `json:"crypto"`
+}
+
+type cryptoJSON struct {
+	Cipher       string           `json:"cipher"`
+	CipherText   string           `json:"ciphertext"`
+	CipherParams cipherParamsJSON `json:"cipherparams"`
+	KDF          string           `json:"kdf"`
+	KDFParams    scryptParamsJSON `json:"kdfparams"`
+}
+
+type cipherParamsJSON struct {
+	Nonce string `json:"nonce"`
+}
+
+type scryptParamsJSON struct {
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	DKLen int    `json:"dklen"`
+	Salt  string `json:"salt"`
+}
+
+// EncryptKey seals key with passphrase and returns the JSON key file.
+func EncryptKey(key *paillier.PrivateKey, passphrase string, scryptN, scryptP int) ([]byte, error) {
+	if err := checkScryptParams(scryptN, scryptR, scryptP); err != nil {
+		return nil, err
+	}
+	plaintext, err := proto.Marshal(key.ToProto())
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	derived, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(derived)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	kf := keyFile{
+		Version: Version,
+		ID:      id,
+		Crypto: cryptoJSON{
+			Cipher:       cipherName,
+			CipherText:   hex.EncodeToString(aead.Seal(nil, nonce, plaintext, additionalData(id))),
+			CipherParams: cipherParamsJSON{Nonce: hex.EncodeToString(nonce)},
+			KDF:          kdfName,
+			KDFParams: scryptParamsJSON{
+				N:     scryptN,
+				R:     scryptR,
+				P:     scryptP,
+				DKLen: scryptDKLen,
+				Salt:  hex.EncodeToString(salt),
+			},
+		},
+	}
+	return json.MarshalIndent(kf, "", "  ")
+}
+
+// DecryptKey opens a JSON key file produced by EncryptKey.
+func DecryptKey(data []byte, passphrase string) (*paillier.PrivateKey, error) {
+	var kf keyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return nil, fmt.Errorf("invalid key file: %v", err)
+	}
+	if kf.Version != Version {
+		return nil, ErrVersion
+	}
+	if kf.Crypto.Cipher != cipherName || kf.Crypto.KDF != kdfName {
+		return nil, fmt.Errorf("unsupported cipher %q or kdf %q", kf.Crypto.Cipher, kf.Crypto.KDF)
+	}
+
+	kp := kf.Crypto.KDFParams
+	if kp.DKLen != scryptDKLen {
+		return nil, errors.New("invalid key length")
+	}
+	// ファイル中のコストをそのまま使うとメモリやCPUを使い果たせるため上限を設ける
+	if err := checkScryptParams(kp.N, kp.R, kp.P); err != nil {
+		return nil, err
+	}
+	salt, err := hex.DecodeString(kp.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %v", err)
+	}
+	nonce, err := hex.DecodeString(kf.Crypto.CipherParams.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce: %v", err)
+	}
+	ciphertext, err := hex.DecodeString(kf.Crypto.CipherText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext: %v", err)
+	}
+
+	derived, err := scrypt.Key([]byte(passphrase), salt, kp.N, kp.R, kp.P, kp.DKLen)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(derived)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce length")
+	}
+	// GCMの認証タグで誤ったパスフレーズと改ざんの両方を検出する
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData(kf.ID))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	var msg pb.PrivateKey
+	if err := proto.Unmarshal(plaintext, &msg); err != nil {
+		return nil, fmt.Errorf("invalid private key: %v", err)
+	}
+	if msg.PublicKey == nil {
+		return nil, errors.New("invalid private key: public key is missing")
+	}
+	key, err := paillier.PrivateKeyFromProto(&msg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %v", err)
+	}
+	return key, nil
+}
+
+// StoreKey encrypts key and writes it to path with owner-only permissions.
+func StoreKey(path string, key *paillier.PrivateKey, passphrase string, scryptN, scryptP int) error {
+	data, err := EncryptKey(key, passphrase, scryptN, scryptP)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// LoadKey reads and decrypts the key file at path.
+func LoadKey(path, passphrase string) (*paillier.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptKey(data, passphrase)
+}
+
+// ChangePassphrase re-encrypts the key file at path under newPassphrase,
+// keeping the scrypt cost parameters of the existing file.
+func ChangePassphrase(path, oldPassphrase, newPassphrase string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	key, err := DecryptKey(data, oldPassphrase)
+	if err != nil {
+		return err
+	}
+	var kf keyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return err
+	}
+	return StoreKey(path, key, newPassphrase, kf.Crypto.KDFParams.N, kf.Crypto.KDFParams.P)
+}
+
+// checkScryptParams bounds the scrypt cost. Memory grows with N·r and time
+// with N·r·p, so N and r are capped at the standard values and p may only
+// exceed StandardScryptP when N is correspondingly smaller (LightScryptN/P).
+func checkScryptParams(n, r, p int) error {
+	if n <= 1 || r <= 0 || p <= 0 {
+		return errors.New("invalid scrypt parameters")
+	}
+	if n > StandardScryptN || r > scryptR || n*p > StandardScryptN*StandardScryptP {
+		return ErrScryptParams
+	}
+	return nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData binds the ciphertext to the format version and key id.
+func additionalData(id string) []byte {
+	return []byte(fmt.Sprintf("paillier-keystore-v%d:%s", Version, id))
+}
+
+// writeFileAtomic writes data to a temporary file and renames it over path,
+// so a crash never leaves a truncated key file behind.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	// リネーム前に内容をディスクへ書き出さないと、クラッシュ後に空のファイルが残り得る
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
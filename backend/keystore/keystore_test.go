@@ -0,0 +1,151 @@
+package keystore
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"multisigservice/paillier"
+)
+
+func newKey(t *testing.T) *paillier.PrivateKey {
+	t.Helper()
+	_, priv, err := paillier.GenerateKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+func TestStoreAndLoad(t *testing.T) {
+	priv := newKey(t)
+	path := filepath.Join(t.TempDir(), "keys", "signer.json")
+
+	if err := StoreKey(path, priv, "correct horse", LightScryptN, LightScryptP); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("key file permissions %v, want 0600", info.Mode().Perm())
+	}
+
+	loaded, err := LoadKey(path, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.N.Cmp(priv.N) != 0 || loaded.Lambda.Cmp(priv.Lambda) != 0 || loaded.P.Cmp(priv.P) != 0 {
+		t.Fatal("loaded key differs from stored key")
+	}
+
+	m := big.NewInt(2024)
+	ct, err := priv.PublicKey.Encrypt(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := loaded.Decrypt(ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Cmp(m) != 0 {
+		t.Errorf("got %v\nwant %v", got, m)
+	}
+}
+
+func TestWrongPassphrase(t *testing.T) {
+	data, err := EncryptKey(newKey(t), "secret", LightScryptN, LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptKey(data, "not the secret"); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("got %v, want ErrDecrypt", err)
+	}
+}
+
+func TestCorruptedFile(t *testing.T) {
+	data, err := EncryptKey(newKey(t), "secret", LightScryptN, LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modify := func(f func(kf *keyFile)) []byte {
+		var kf keyFile
+		if err := json.Unmarshal(data, &kf); err != nil {
+			t.Fatal(err)
+		}
+		f(&kf)
+		out, err := json.Marshal(kf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	flip := func(s string) string {
+		b := []byte(s)
+		if b[0] == '0' {
+			b[0] = '1'
+		} else {
+			b[0] = '0'
+		}
+		return string(b)
+	}
+
+	cases := map[string][]byte{
+		"truncated":       data[:len(data)/2],
+		"not json":        []byte("not a key file"),
+		"flipped bit":     modify(func(kf *keyFile) { kf.Crypto.CipherText = flip(kf.Crypto.CipherText) }),
+		"changed salt":    modify(func(kf *keyFile) { kf.Crypto.KDFParams.Salt = flip(kf.Crypto.KDFParams.Salt) }),
+		"changed id":      modify(func(kf *keyFile) { kf.ID = "00000000-0000-0000-0000-000000000000" }),
+		"bad hex":         modify(func(kf *keyFile) { kf.Crypto.CipherText = "zz" }),
+		"short nonce":     modify(func(kf *keyFile) { kf.Crypto.CipherParams.Nonce = "00" }),
+		"unknown cipher":  modify(func(kf *keyFile) { kf.Crypto.Cipher = "aes-128-ctr" }),
+		"unknown version": modify(func(kf *keyFile) { kf.Version = 3 }),
+	}
+	for name, input := range cases {
+		if _, err := DecryptKey(input, "secret"); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	// 過大なscryptパラメータは鍵導出の前に拒否されること
+	costly := map[string][]byte{
+		"large n": modify(func(kf *keyFile) { kf.Crypto.KDFParams.N = StandardScryptN << 1 }),
+		"large r": modify(func(kf *keyFile) { kf.Crypto.KDFParams.R = 1 << 20 }),
+		"large p": modify(func(kf *keyFile) { kf.Crypto.KDFParams.P = 1 << 20 }),
+	}
+	for name, input := range costly {
+		if _, err := DecryptKey(input, "secret"); !errors.Is(err, ErrScryptParams) {
+			t.Errorf("%s: got %v\nwant %v", name, err, ErrScryptParams)
+		}
+	}
+}
+
+func TestChangePassphrase(t *testing.T) {
+	priv := newKey(t)
+	path := filepath.Join(t.TempDir(), "signer.json")
+	if err := StoreKey(path, priv, "old", LightScryptN, LightScryptP); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ChangePassphrase(path, "wrong", "new"); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("got %v, want ErrDecrypt", err)
+	}
+	if err := ChangePassphrase(path, "old", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKey(path, "old"); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("old passphrase still works: %v", err)
+	}
+	loaded, err := LoadKey(path, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.N.Cmp(priv.N) != 0 {
+		t.Error("key changed after passphrase change")
+	}
+}
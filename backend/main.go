@@ -1,8 +1,13 @@
 package main
 
 import (
+	"log"
+	"net"
+	"os"
+
 	"multisigservice/db"
 	"multisigservice/handlers"
+	"multisigservice/paillierrpc"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,6 +15,10 @@ import (
 func main() {
 	// DB初期化：PostgreSQLへ接続し、テーブルを自動マイグレーション
 	db.InitDB()
+	// 公開鍵登録に用いるリングPedersenパラメータを読み込む（初回起動時は生成する）
+	if err := handlers.InitPedersenParams(); err != nil {
+		log.Fatalf("failed to load pedersen parameters: %v", err)
+	}
 
 	router := gin.Default()
 
@@ -18,13 +27,79 @@ func main() {
 		// 認証関連エンドポイント
 		api.GET("/auth/challenge", handlers.ChallengeHandler)
 		api.POST("/auth/login", handlers.LoginHandler)
+		api.GET("/auth/pedersen", handlers.PedersenParamsHandler)
 		api.POST("/auth/registerPubkey", handlers.RegisterPubkeyHandler)
 
+		// ユーザー関連エンドポイント
+		api.GET("/users/:address/pubkey", handlers.GetUserPubkeyHandler)
+
 		// マルチシグ関連エンドポイント
+		api.POST("/multisig/keygen", handlers.StartKeygenHandler)
+		api.GET("/multisig/keygen/:session", handlers.GetSessionHandler)
+		api.POST("/multisig/keygen/:session/commit", handlers.KeygenCommitHandler)
+		api.POST("/multisig/keygen/:session/reveal", handlers.KeygenRevealHandler)
+		api.POST("/multisig/keygen/:session/confirm", handlers.KeygenConfirmHandler)
+		api.POST("/multisig/keygen/:session/complaint", handlers.KeygenComplaintHandler)
 		api.POST("/multisig/create", handlers.CreateMultiSigHandler)
+		api.POST("/multisig/:address/cmp/:kind", handlers.StartCMPSessionHandler)
 		api.GET("/multisig/list", handlers.GetMultiSigListHandler)
-		api.GET("/multisig/:id/data", handlers.GetMultiSigDataHandler)
-		api.POST("/multisig/:id/data", handlers.UpdateMultiSigDataHandler)
+		api.POST("/multisig/:address/sign", handlers.StartSignHandler)
+		api.GET("/multisig/:address/sign/:session", handlers.GetSessionHandler)
+		api.POST("/multisig/:address/sign/:session/commit", handlers.SignCommitHandler)
+		api.POST("/multisig/:address/sign/:session/mta", handlers.SignMtAHandler)
+		api.POST("/multisig/:address/sign/:session/reveal", handlers.SignRevealHandler)
+		api.POST("/multisig/:address/sign/:session/check", handlers.SignCheckHandler)
+		api.POST("/multisig/:address/sign/:session/partial", handlers.SignPartialHandler)
+		api.POST("/multisig/:address/sign/:session/open", handlers.SignOpenHandler)
+		api.POST("/multisig/:address/presign", handlers.StartPresignHandler)
+		api.GET("/multisig/:address/presignatures", handlers.ListPresignaturesHandler)
+		api.GET("/multisig/:address/presign/:session", handlers.GetSessionHandler)
+		api.POST("/multisig/:address/presign/:session/commit", handlers.SignCommitHandler)
+		api.POST("/multisig/:address/presign/:session/mta", handlers.SignMtAHandler)
+		api.POST("/multisig/:address/presign/:session/reveal", handlers.PresignRevealHandler)
+		api.POST("/multisig/:address/presign/:session/check", handlers.SignCheckHandler)
+		api.POST("/multisig/:address/presign/:session/open", handlers.SignOpenHandler)
+		api.POST("/multisig/:address/refresh", handlers.StartRefreshHandler)
+		api.GET("/multisig/:address/refresh/:session", handlers.GetSessionHandler)
+		api.POST("/multisig/:address/refresh/:session/commit", handlers.RefreshCommitHandler)
+		api.POST("/multisig/:address/refresh/:session/reveal", handlers.RefreshRevealHandler)
+		api.POST("/multisig/:address/refresh/:session/confirm", handlers.RefreshConfirmHandler)
+		api.POST("/multisig/:address/refresh/:session/abort", handlers.RefreshAbortHandler)
+		api.POST("/multisig/:address/reshare", handlers.StartReshareHandler)
+		api.GET("/multisig/:address/reshare/:session", handlers.GetSessionHandler)
+		api.POST("/multisig/:address/reshare/:session/approve", handlers.ReshareApproveHandler)
+		api.POST("/multisig/:address/reshare/:session/deal", handlers.ReshareDealHandler)
+		api.POST("/multisig/:address/reshare/:session/confirm", handlers.ReshareConfirmHandler)
+		api.POST("/multisig/:address/reshare/:session/abort", handlers.ReshareAbortHandler)
+
+		// CMPセッションの中継エンドポイント（鍵生成・鍵更新・事前署名・署名で共通）
+		api.GET("/sessions/:session", handlers.GetSessionHandler)
+		api.POST("/sessions/:session/messages", handlers.RelayCMPMessageHandler)
+		api.POST("/sessions/:session/result", handlers.ReportCMPResultHandler)
+	}
+
+	// gRPCサーバー：Go以外の署名者向けにPaillier演算を提供
+	// 秘密鍵で復号できるため PAILLIER_GRPC_ADDR を設定した場合のみ起動し、トークン認証を必須とする
+	// （ループバック以外で待ち受ける場合はTLSも必須）
+	if addr := os.Getenv("PAILLIER_GRPC_ADDR"); addr != "" {
+		grpcServer, err := paillierrpc.NewGRPCServer(paillierrpc.Config{
+			Addr:     addr,
+			Token:    os.Getenv("PAILLIER_GRPC_TOKEN"),
+			CertFile: os.Getenv("PAILLIER_GRPC_TLS_CERT"),
+			KeyFile:  os.Getenv("PAILLIER_GRPC_TLS_KEY"),
+		})
+		if err != nil {
+			log.Fatalf("failed to configure gRPC: %v", err)
+		}
+		lis, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatalf("failed to listen for gRPC: %v", err)
+		}
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatalf("gRPC server stopped: %v", err)
+			}
+		}()
 	}
 
 	router.Run(":8080")
@@ -13,4 +13,10 @@ type MultiSig struct {
 	Participants datatypes.JSON `gorm:"type:jsonb" json:"participants"` // 参加者アドレスのJSON配列
 	Status       string         `gorm:"not null" json:"status"`           // "awaiting", "partial", "completed"
 	Data         datatypes.JSON `gorm:"type:jsonb" json:"data"`           // 署名に必要な中間データ
+	Scheme       string         `gorm:"not null;default:additive" json:"scheme"` // 署名方式 "additive", "cmp"
+	PublicKey    string         `json:"publicKey"`                        // 鍵生成で導出した公開鍵 Q（圧縮形式のhex）
+	PublicShares datatypes.JSON `gorm:"type:jsonb" json:"publicShares"`   // 参加者アドレス毎の公開シェア X_i
+	Threshold        int `gorm:"not null;default:2" json:"threshold"`        // 署名に必要な人数 t
+	ParticipantCount int `gorm:"not null;default:2" json:"participantCount"` // 参加者数 n
+	Epoch            int `gorm:"not null;default:0" json:"epoch"`            // 鍵更新の回数。更新の度にシェアが変わる
 }
@@ -0,0 +1,11 @@
+package models
+
+import "gorm.io/gorm"
+
+// PedersenSetup はサーバーのリングPedersenパラメータ（Ñ, s, t）です。
+// 公開鍵登録時のΠ-fac検証に用いるため、一度生成したものを保存して使い続けます。
+// 生成に使ったPaillier秘密鍵（トラップドア）は保存しません。
+type PedersenSetup struct {
+	gorm.Model
+	Params string `gorm:"type:text;not null" json:"params"` // pb.PedersenParams のbase64
+}
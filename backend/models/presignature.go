@@ -0,0 +1,21 @@
+package models
+
+import (
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Presignature は事前署名セッションで生成された、署名対象に依存しない署名素材です。
+// 署名時に1度だけ使用でき、同じ事前署名を2度使うと秘密鍵が漏洩するため、使用状況を記録します。
+type Presignature struct {
+	gorm.Model
+	PresignID   string         `gorm:"uniqueIndex;not null" json:"presignId"` // 事前署名セッションのID
+	MultiSig    string         `gorm:"index;not null" json:"multisig"`        // 対象マルチシグのアドレス
+	Scheme      string         `gorm:"not null" json:"scheme"`                // "additive", "cmp"
+	Signers     datatypes.JSON `gorm:"type:jsonb" json:"signers"`             // 署名者アドレスのJSON配列
+	R           string         `json:"R,omitempty"`                           // ナンス点 R（圧縮形式のhex、加法的方式のみ）
+	Shares      datatypes.JSON `gorm:"type:jsonb" json:"shares,omitempty"`    // 署名者毎の k_i, σ_i（各自のPaillier公開鍵で暗号化、加法的方式のみ）
+	Epoch       int            `gorm:"not null" json:"epoch"`                 // 生成時のマルチシグのエポック
+	Status      string         `gorm:"not null;index" json:"status"`          // "available", "consumed", "expired"
+	SignSession string         `json:"signSession,omitempty"`                 // 使用した署名セッションのID
+}
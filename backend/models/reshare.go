@@ -0,0 +1,25 @@
+package models
+
+import (
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Reshare は参加者の変更（再共有）の監査記録です。
+// 変更前後の参加者と閾値、提案・承認・中断した参加者とその署名を保持します。
+type Reshare struct {
+	gorm.Model
+	SessionID         string         `gorm:"uniqueIndex;not null" json:"sessionId"` // 再共有セッションのID
+	MultiSig          string         `gorm:"index;not null" json:"multisig"`        // 対象マルチシグのアドレス
+	Proposer          string         `gorm:"not null" json:"proposer"`              // 変更を提案した旧参加者
+	ProposerSignature string         `gorm:"type:text" json:"proposerSignature"`    // 提案メッセージへの提案者の署名
+	OldParticipants   datatypes.JSON `gorm:"type:jsonb" json:"oldParticipants"`     // 変更前の参加者アドレスのJSON配列
+	OldThreshold      int            `gorm:"not null" json:"oldThreshold"`
+	NewParticipants   datatypes.JSON `gorm:"type:jsonb" json:"newParticipants"` // 変更後の参加者アドレスのJSON配列
+	NewThreshold      int            `gorm:"not null" json:"newThreshold"`
+	Approvals         datatypes.JSON `gorm:"type:jsonb" json:"approvals"`               // 承認（アドレス・署名・日時）のJSON配列
+	Status            string         `gorm:"not null" json:"status"`                    // "pending", "completed", "aborted"
+	AbortedBy         string         `json:"abortedBy,omitempty"`                       // 中断した参加者
+	AbortSignature    string         `gorm:"type:text" json:"abortSignature,omitempty"` // 中断メッセージへの署名
+	Epoch             int            `json:"epoch"`                                     // 完了後のマルチシグのエポック
+}
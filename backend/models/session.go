@@ -0,0 +1,38 @@
+package models
+
+import (
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Session は参加者間で実行する多者間プロトコル（鍵生成・署名など）のセッションです。
+// サーバーはメッセージの中継と検証のみを行い、秘密のシェアは保持しません。
+type Session struct {
+	gorm.Model
+	SessionID    string         `gorm:"uniqueIndex;not null" json:"sessionId"`
+	Kind         string         `gorm:"not null" json:"kind"`                    // "keygen", "sign", "refresh", "presign"
+	Scheme       string         `gorm:"not null;default:additive" json:"scheme"` // "additive", "cmp"
+	MultiSig     string         `gorm:"index" json:"multisig"`                   // 対象マルチシグのアドレス（鍵生成では作成後に設定）
+	Participants datatypes.JSON `gorm:"type:jsonb" json:"participants"`          // 参加者アドレスのJSON配列
+	Round        int            `gorm:"not null" json:"round"`                   // 現在受け付けているラウンド
+	Status       string         `gorm:"not null" json:"status"`                  // "active", "completed", "aborted"
+	Data         datatypes.JSON `gorm:"type:jsonb" json:"data"`                  // セッション開始時の入力（署名対象のハッシュなど）
+	Result       datatypes.JSON `gorm:"type:jsonb" json:"result"`                // 導出した公開鍵などの結果
+	Epoch        int            `gorm:"not null;default:0" json:"epoch"`         // 開始時のマルチシグのエポック（鍵更新で古いセッションを無効にする）
+	Culprit      string         `json:"culprit,omitempty"`                       // 中断の原因となった参加者のアドレス（特定できた場合）
+	Evidence     datatypes.JSON `gorm:"type:jsonb" json:"evidence,omitempty"`    // 中断の証拠（署名済みメッセージ一式など、誰でも再検証できる内容）
+}
+
+// SessionMessage はセッション内で中継される1つのメッセージです。
+// CMPでは Payload はシリアライズされた protocol.Message（base64）で、Round はそのラウンド番号です。
+// 加法的方式の署名では、送信者が personal_sign したメッセージ本文と署名を Signed, Signature に保存します。
+type SessionMessage struct {
+	gorm.Model
+	SessionID string         `gorm:"uniqueIndex:idx_session_message;not null" json:"sessionId"`
+	Round     int            `gorm:"uniqueIndex:idx_session_message;not null" json:"round"`
+	From      string         `gorm:"uniqueIndex:idx_session_message;not null" json:"from"`
+	To        string         `gorm:"uniqueIndex:idx_session_message" json:"to"` // 空の場合はブロードキャスト
+	Payload   datatypes.JSON `gorm:"type:jsonb" json:"payload"`
+	Signed    string         `gorm:"type:text" json:"signed,omitempty"` // 署名されたメッセージ本文（受信したJSONそのまま）
+	Signature string         `json:"signature,omitempty"`               // 送信者の personal_sign 署名（hex）
+}
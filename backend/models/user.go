@@ -10,5 +10,6 @@ type User struct {
 	gorm.Model
 	Address string `gorm:"uniqueIndex;not null" json:"address"` // ユーザーアドレス
 	Pubkey string  `gorm:"not null" json:"pubkey"` // paillier公開鍵
+	Pedersen string `gorm:"type:text" json:"pedersen"` // 公開鍵の法で作ったリングPedersenパラメータ（pb.PedersenParamsのbase64、Π-prmで検証済み）
 	MultiSigs datatypes.JSON `gorm:"type:jsonb" json:"multisigs"` // 参加マルチシグアドレスリスト
 }
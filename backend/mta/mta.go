@@ -0,0 +1,231 @@
+// Package mta implements the Paillier-based Multiplicative-to-Additive share
+// conversion used by additive ECDSA: Alice holds a, Bob holds b, and after two
+// messages they hold α and β with α + β = a·b mod q over secp256k1.
+package mta
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"multisigservice/paillier"
+	pb "multisigservice/proto/paillierpb"
+)
+
+// RangeBits is ℓ of the range proof attached to Enc_A(a); secp256k1 scalars fit in 2^256.
+const RangeBits = 256
+
+// MaskBits is ℓ' of the affine proof for Bob's mask β' ∈ Z_{q^5}; q^5 < 2^1280.
+const MaskBits = 5 * RangeBits
+
+// Order returns the order q of the secp256k1 group.
+func Order() *big.Int {
+	return new(big.Int).Set(crypto.S256().Params().N)
+}
+
+// Alice is the party who owns the Paillier key and the secret a.
+type Alice struct {
+	priv  *paillier.PrivateKey
+	pp    *paillier.PedersenParams
+	bobPP *paillier.PedersenParams
+	a     *big.Int
+	ca    *paillier.Ciphertext
+}
+
+// NewAlice creates Alice's side of an MtA instance. pp are Alice's own
+// ring-Pedersen parameters, used to verify Bob's affine proof, and bobPP are
+// Bob's, against which Alice proves that a is in range.
+func NewAlice(priv *paillier.PrivateKey, pp, bobPP *paillier.PedersenParams, a *big.Int) (*Alice, error) {
+	if err := checkScalar(a); err != nil {
+		return nil, err
+	}
+	if err := checkModulus(&priv.PublicKey); err != nil {
+		return nil, err
+	}
+	if err := pp.Validate(); err != nil {
+		return nil, err
+	}
+	if err := bobPP.Validate(); err != nil {
+		return nil, err
+	}
+	return &Alice{priv: priv, pp: pp, bobPP: bobPP, a: a}, nil
+}
+
+// Round1 returns Enc_A(a) and a range proof for a for Bob.
+func (al *Alice) Round1() (*pb.MtARound1, error) {
+	pub := &al.priv.PublicKey
+	// 証明で nonce を再利用するため明示的に生成する
+	rho, err := paillier.SampleUnit(pub.N)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := pub.EncryptWithNonce(al.a, rho)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := paillier.ProveEnc(pub, al.bobPP, ct, al.a, rho, RangeBits)
+	if err != nil {
+		return nil, err
+	}
+	al.ca = ct
+	return &pb.MtARound1{CA: ct.ToProto(), RangeProof: proof.ToProto()}, nil
+}
+
+// VerifyRound1 checks that Enc_A(a) is a valid ciphertext under Alice's key
+// and that its range proof verifies against Bob's ring-Pedersen parameters.
+// Bob calls it before answering, and the server calls it before relaying.
+func VerifyRound1(alicePub *paillier.PublicKey, bobPP *paillier.PedersenParams, msg *pb.MtARound1) (*paillier.Ciphertext, error) {
+	if msg == nil {
+		return nil, errors.New("round 1 message is missing")
+	}
+	ca, err := paillier.CiphertextFromProto(alicePub, msg.CA)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := paillier.EncProofFromProto(msg.RangeProof)
+	if err != nil {
+		return nil, err
+	}
+	if !proof.Verify(alicePub, bobPP, ca, RangeBits) {
+		return nil, errors.New("range proof verification failed")
+	}
+	return ca, nil
+}
+
+// Finalize verifies Bob's reply to Round1 and returns Alice's additive share α.
+func (al *Alice) Finalize(msg *pb.MtARound2) (*big.Int, error) {
+	if al.ca == nil {
+		return nil, errors.New("round 1 has not been run")
+	}
+	return OpenRound2(al.priv, al.pp, al.ca, msg)
+}
+
+// VerifyRound2 checks that Bob's reply is a valid ciphertext under Alice's
+// key and that its affine proof shows it was formed as ca^b·Enc_A(β') with b
+// and β' in range, against Alice's ring-Pedersen parameters. Alice calls it
+// before decrypting, and the server calls it before relaying.
+func VerifyRound2(alicePub *paillier.PublicKey, alicePP *paillier.PedersenParams, ca *paillier.Ciphertext, msg *pb.MtARound2) (*paillier.Ciphertext, error) {
+	if msg == nil {
+		return nil, errors.New("round 2 message is missing")
+	}
+	cb, err := paillier.CiphertextFromProto(alicePub, msg.CB)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := paillier.AffProofFromProto(msg.AffineProof)
+	if err != nil {
+		return nil, err
+	}
+	if !proof.Verify(alicePub, alicePP, ca, cb, RangeBits, MaskBits) {
+		return nil, errors.New("affine proof verification failed")
+	}
+	return cb, nil
+}
+
+// OpenRound2 verifies Bob's reply to ca and decrypts Alice's additive share
+// α. It is used by parties who sent Enc_A(a) without an Alice, such as
+// signers who prove the same ciphertext to several Bobs.
+func OpenRound2(priv *paillier.PrivateKey, pp *paillier.PedersenParams, ca *paillier.Ciphertext, msg *pb.MtARound2) (*big.Int, error) {
+	cb, err := VerifyRound2(&priv.PublicKey, pp, ca, msg)
+	if err != nil {
+		return nil, err
+	}
+	// α = Dec(c_b) mod q
+	alpha, err := priv.Decrypt(cb)
+	if err != nil {
+		return nil, err
+	}
+	return alpha.Mod(alpha, Order()), nil
+}
+
+// Bob is the party who knows only Alice's public key and the secret b.
+type Bob struct {
+	pub     *paillier.PublicKey
+	pp      *paillier.PedersenParams
+	alicePP *paillier.PedersenParams
+	b       *big.Int
+}
+
+// NewBob creates Bob's side of an MtA instance. pp are Bob's own
+// ring-Pedersen parameters, used to verify Alice's range proof, and alicePP
+// are Alice's, against which Bob proves that his reply is affine in b.
+func NewBob(pub *paillier.PublicKey, pp, alicePP *paillier.PedersenParams, b *big.Int) (*Bob, error) {
+	if err := checkScalar(b); err != nil {
+		return nil, err
+	}
+	if err := checkModulus(pub); err != nil {
+		return nil, err
+	}
+	if err := pp.Validate(); err != nil {
+		return nil, err
+	}
+	if err := alicePP.Validate(); err != nil {
+		return nil, err
+	}
+	return &Bob{pub: pub, pp: pp, alicePP: alicePP, b: b}, nil
+}
+
+// Round2 answers Alice's Enc_A(a) with Enc_A(a·b + β') and an affine proof
+// for it, and returns Bob's additive share β = −β' mod q.
+func (bob *Bob) Round2(msg *pb.MtARound1) (*pb.MtARound2, *big.Int, error) {
+	ca, err := VerifyRound1(bob.pub, bob.pp, msg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 1. β' ← Z_{q^5}（a·b を統計的に隠すのに十分な幅）
+	q := Order()
+	betaPrime, err := rand.Int(rand.Reader, new(big.Int).Exp(q, big.NewInt(5), nil))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 2. c_b = c_a^b * Enc_A(β') mod n^2
+	cab, err := ca.MulScalar(bob.pub, bob.b)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 証明で nonce を再利用するため明示的に生成する
+	rho, err := paillier.SampleUnit(bob.pub.N)
+	if err != nil {
+		return nil, nil, err
+	}
+	encBeta, err := bob.pub.EncryptWithNonce(betaPrime, rho)
+	if err != nil {
+		return nil, nil, err
+	}
+	cb, err := cab.Add(bob.pub, encBeta)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 3. c_b が c_a^b·Enc_A(β') の形であることを Alice のパラメータに対して証明する
+	proof, err := paillier.ProveAff(bob.pub, bob.alicePP, ca, cb, bob.b, betaPrime, rho, RangeBits, MaskBits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 4. β = −β' mod q
+	beta := new(big.Int).Neg(betaPrime)
+	beta.Mod(beta, q)
+	return &pb.MtARound2{CB: cb.ToProto(), AffineProof: proof.ToProto()}, beta, nil
+}
+
+// checkScalar reports an error unless 0 <= x < q.
+func checkScalar(x *big.Int) error {
+	if x == nil || x.Sign() < 0 || x.Cmp(crypto.S256().Params().N) >= 0 {
+		return errors.New("scalar must be in [0, q)")
+	}
+	return nil
+}
+
+// checkModulus reports an error unless a·b + β' cannot wrap around n, even
+// with b and β' anywhere in the ranges the affine proof allows.
+func checkModulus(pub *paillier.PublicKey) error {
+	if pub.N == nil || pub.N.BitLen() < MaskBits+paillier.EncSlackBits+2 {
+		return errors.New("paillier modulus is too small for MtA")
+	}
+	return nil
+}
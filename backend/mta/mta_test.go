@@ -0,0 +1,214 @@
+package mta
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"multisigservice/paillier"
+	pb "multisigservice/proto/paillierpb"
+)
+
+func runMtA(t *testing.T, priv *paillier.PrivateKey, alicePP, bobPP *paillier.PedersenParams, a, b *big.Int) (*big.Int, *big.Int) {
+	t.Helper()
+	alice, err := NewAlice(priv, alicePP, bobPP, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := NewBob(&priv.PublicKey, bobPP, alicePP, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// メッセージはサーバー経由で中継されるため、シリアライズを挟んで往復させる
+	msg1, err := alice.Round1()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw1, err := proto.Marshal(msg1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var relayed1 pb.MtARound1
+	if err := proto.Unmarshal(raw1, &relayed1); err != nil {
+		t.Fatal(err)
+	}
+
+	msg2, beta, err := bob.Round2(&relayed1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw2, err := proto.Marshal(msg2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var relayed2 pb.MtARound2
+	if err := proto.Unmarshal(raw2, &relayed2); err != nil {
+		t.Fatal(err)
+	}
+
+	alpha, err := alice.Finalize(&relayed2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return alpha, beta
+}
+
+// setup returns Alice's Paillier key and both parties' ring-Pedersen parameters.
+func setup(t *testing.T) (*paillier.PrivateKey, *paillier.PedersenParams, *paillier.PedersenParams) {
+	t.Helper()
+	_, alice, err := paillier.GenerateKey(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alicePP, err := paillier.NewPedersenParams(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, bob, err := paillier.GenerateKey(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobPP, err := paillier.NewPedersenParams(bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return alice, alicePP, bobPP
+}
+
+func TestMtA(t *testing.T) {
+	priv, alicePP, bobPP := setup(t)
+	q := Order()
+	qMinus1 := new(big.Int).Sub(q, big.NewInt(1))
+
+	random := func() *big.Int {
+		x, err := rand.Int(rand.Reader, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return x
+	}
+
+	cases := []struct{ a, b *big.Int }{
+		{random(), random()},
+		{random(), random()},
+		{big.NewInt(0), random()},
+		{qMinus1, qMinus1},
+	}
+	for _, tc := range cases {
+		alpha, beta := runMtA(t, priv, alicePP, bobPP, tc.a, tc.b)
+
+		sum := new(big.Int).Add(alpha, beta)
+		sum.Mod(sum, q)
+		want := new(big.Int).Mul(tc.a, tc.b)
+		want.Mod(want, q)
+		if sum.Cmp(want) != 0 {
+			t.Errorf("α+β = %v\nwant a·b = %v", sum, want)
+		}
+	}
+}
+
+func TestMtARejectsInvalidInput(t *testing.T) {
+	priv, alicePP, bobPP := setup(t)
+
+	_, small, err := paillier.GenerateKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewAlice(small, alicePP, bobPP, big.NewInt(1)); err == nil {
+		t.Error("modulus too small for the affine proof accepted")
+	}
+	if _, err := NewBob(&priv.PublicKey, bobPP, alicePP, Order()); err == nil {
+		t.Error("scalar q accepted")
+	}
+	if _, err := NewAlice(priv, alicePP, bobPP, big.NewInt(-1)); err == nil {
+		t.Error("negative scalar accepted")
+	}
+
+	bob, err := NewBob(&priv.PublicKey, bobPP, alicePP, big.NewInt(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, err := NewAlice(priv, alicePP, bobPP, big.NewInt(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := alice.Round1()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bad := &pb.MtARound1{CA: &pb.Ciphertext{C: priv.N.Bytes()}, RangeProof: msg.RangeProof}
+	if _, _, err := bob.Round2(bad); err == nil {
+		t.Error("invalid ciphertext accepted")
+	}
+
+	// 範囲証明なし、または別の暗号文に対する証明は拒否される
+	if _, _, err := bob.Round2(&pb.MtARound1{CA: msg.CA}); err == nil {
+		t.Error("missing range proof accepted")
+	}
+	other, err := priv.PublicKey.Encrypt(big.NewInt(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	swapped := &pb.MtARound1{CA: other.ToProto(), RangeProof: msg.RangeProof}
+	if _, err := VerifyRound1(&priv.PublicKey, bobPP, swapped); err == nil {
+		t.Error("proof for a different ciphertext accepted")
+	}
+}
+
+func TestMtARejectsTamperedReply(t *testing.T) {
+	priv, alicePP, bobPP := setup(t)
+	pub := &priv.PublicKey
+	b := big.NewInt(5)
+
+	alice, err := NewAlice(priv, alicePP, bobPP, big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.Finalize(&pb.MtARound2{}); err == nil {
+		t.Error("finalize before round 1 accepted")
+	}
+	msg1, err := alice.Round1()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := NewBob(pub, bobPP, alicePP, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg2, _, err := bob.Round2(msg1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := VerifyRound1(pub, bobPP, msg1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyRound2(pub, alicePP, ca, msg2); err != nil {
+		t.Fatalf("valid reply rejected: %v", err)
+	}
+
+	// 選択的失敗攻撃: c_b に平文を加えると、証明は c_b と一致しなくなる
+	cb, err := paillier.CiphertextFromProto(pub, msg2.CB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shifted, err := cb.AddScalar(pub, new(big.Int).Lsh(big.NewInt(1), 2000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := &pb.MtARound2{CB: shifted.ToProto(), AffineProof: msg2.AffineProof}
+	if _, err := alice.Finalize(tampered); err == nil {
+		t.Error("tampered reply accepted")
+	}
+	if _, err := alice.Finalize(&pb.MtARound2{CB: msg2.CB}); err == nil {
+		t.Error("reply without an affine proof accepted")
+	}
+	// 別の検証者パラメータに対しては検証に失敗する
+	if _, err := VerifyRound2(pub, bobPP, ca, msg2); err == nil {
+		t.Error("proof accepted for other pedersen parameters")
+	}
+}
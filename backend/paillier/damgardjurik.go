@@ -0,0 +1,238 @@
+package paillier
+
+import (
+	"errors"
+	"math/big"
+
+	pb "multisigservice/proto/paillierpb"
+)
+
+// MaxDJExponent is the largest supported Damgård–Jurik parameter s. The cost
+// of every operation grows with s, so decoded keys must not choose it freely.
+const MaxDJExponent = 8
+
+// DJPublicKey is a Damgård–Jurik public key: the Paillier key extended with
+// the parameter s, so that plaintexts live in Z_{n^s} and ciphertexts in
+// Z*_{n^(s+1)}. With s = 1 it is exactly Paillier.
+type DJPublicKey struct {
+	PublicKey
+	S   int
+	NS  *big.Int // n^s（平文空間）
+	NS1 *big.Int // n^(s+1)（暗号文空間）
+}
+
+// DJPrivateKey is a Damgård–Jurik private key.
+type DJPrivateKey struct {
+	DJPublicKey
+	Lambda *big.Int
+	Mu     *big.Int // λ^-1 mod n^s
+}
+
+// DJCiphertext is a Damgård–Jurik ciphertext.
+type DJCiphertext struct {
+	c *big.Int
+}
+
+// NewDJPublicKey extends a Paillier public key to Damgård–Jurik with parameter 1 <= s <= MaxDJExponent.
+func NewDJPublicKey(pub *PublicKey, s int) (*DJPublicKey, error) {
+	if s < 1 {
+		return nil, errors.New("s must be at least 1")
+	}
+	if s > MaxDJExponent {
+		return nil, errors.New("s exceeds MaxDJExponent")
+	}
+	if pub.N == nil {
+		return nil, errors.New("public key is incomplete")
+	}
+	return &DJPublicKey{
+		PublicKey: *newPublicKey(pub.N),
+		S:         s,
+		NS:        new(big.Int).Exp(pub.N, big.NewInt(int64(s)), nil),
+		NS1:       new(big.Int).Exp(pub.N, big.NewInt(int64(s+1)), nil),
+	}, nil
+}
+
+// NewDJPrivateKey extends a Paillier private key to Damgård–Jurik with parameter 1 <= s <= MaxDJExponent.
+func NewDJPrivateKey(priv *PrivateKey, s int) (*DJPrivateKey, error) {
+	pub, err := NewDJPublicKey(&priv.PublicKey, s)
+	if err != nil {
+		return nil, err
+	}
+	return newDJPrivateKey(pub, priv.Lambda)
+}
+
+func newDJPrivateKey(pub *DJPublicKey, lambda *big.Int) (*DJPrivateKey, error) {
+	mu := new(big.Int).ModInverse(lambda, pub.NS)
+	if mu == nil {
+		return nil, errors.New("failed to compute modular inverse for mu")
+	}
+	return &DJPrivateKey{DJPublicKey: *pub, Lambda: lambda, Mu: mu}, nil
+}
+
+// GenerateDJKey generates a Damgård–Jurik keypair with a modulus of bitLen bits.
+func GenerateDJKey(bitLen, s int) (*DJPublicKey, *DJPrivateKey, error) {
+	_, priv, err := GenerateKey(bitLen)
+	if err != nil {
+		return nil, nil, err
+	}
+	sk, err := NewDJPrivateKey(priv, s)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &sk.DJPublicKey, sk, nil
+}
+
+// Encrypt encrypts plaintext m ∈ [0, n^s) with a fresh nonce.
+func (pub *DJPublicKey) Encrypt(m *big.Int) (*DJCiphertext, error) {
+	r, err := SampleUnit(pub.N)
+	if err != nil {
+		return nil, err
+	}
+	return pub.EncryptWithNonce(m, r)
+}
+
+// EncryptWithNonce computes c = (1+n)^m · r^(n^s) mod n^(s+1) for r ∈ Z*_n.
+func (pub *DJPublicKey) EncryptWithNonce(m, r *big.Int) (*DJCiphertext, error) {
+	if m.Sign() < 0 || m.Cmp(pub.NS) >= 0 {
+		return nil, errors.New("plaintext out of range")
+	}
+	if r.Sign() <= 0 || r.Cmp(pub.N) >= 0 || new(big.Int).GCD(nil, nil, r, pub.N).Cmp(big.NewInt(1)) != 0 {
+		return nil, errors.New("nonce is not in Z*_n")
+	}
+	gm := new(big.Int).Exp(pub.G, m, pub.NS1)
+	rn := new(big.Int).Exp(r, pub.NS, pub.NS1)
+	return &DJCiphertext{c: gm.Mod(gm.Mul(gm, rn), pub.NS1)}, nil
+}
+
+// IsValidCiphertext reports whether 0 < c < n^(s+1) and gcd(c, n) = 1.
+func (pub *DJPublicKey) IsValidCiphertext(ct *DJCiphertext) bool {
+	if ct == nil || ct.c == nil || ct.c.Sign() <= 0 || ct.c.Cmp(pub.NS1) >= 0 {
+		return false
+	}
+	return new(big.Int).GCD(nil, nil, ct.c, pub.N).Cmp(big.NewInt(1)) == 0
+}
+
+// Decrypt recovers m ∈ [0, n^s) from ct.
+func (priv *DJPrivateKey) Decrypt(ct *DJCiphertext) (*big.Int, error) {
+	if !priv.IsValidCiphertext(ct) {
+		return nil, errors.New("invalid ciphertext")
+	}
+	// c^λ = (1+n)^(mλ mod n^s) mod n^(s+1)
+	a := new(big.Int).Exp(ct.c, priv.Lambda, priv.NS1)
+	i := priv.dlog(a)
+	return i.Mod(i.Mul(i, priv.Mu), priv.NS), nil
+}
+
+// dlog returns i ∈ Z_{n^s} with a = (1+n)^i mod n^(s+1), using the
+// recursive algorithm of Damgård and Jurik.
+func (pub *DJPublicKey) dlog(a *big.Int) *big.Int {
+	n := pub.N
+	i := new(big.Int)
+	nj := new(big.Int).Set(n) // n^j
+	for j := 1; j <= pub.S; j++ {
+		nj1 := new(big.Int).Mul(nj, n) // n^(j+1)
+		t1 := L(new(big.Int).Mod(a, nj1), n)
+		t2 := new(big.Int).Set(i)
+		nk := big.NewInt(1) // n^(k-1)
+		kFact := big.NewInt(1)
+		for k := 2; k <= j; k++ {
+			i.Sub(i, big.NewInt(1))
+			t2.Mod(t2.Mul(t2, i), nj)
+			nk.Mul(nk, n)
+			kFact.Mul(kFact, big.NewInt(int64(k)))
+			// t1 = t1 − t2·n^(k−1)/k! mod n^j
+			term := new(big.Int).Mul(t2, nk)
+			term.Mul(term, new(big.Int).ModInverse(kFact, nj))
+			t1.Sub(t1, term)
+			t1.Mod(t1, nj)
+		}
+		i = t1
+		nj = nj1
+	}
+	return i
+}
+
+// Add returns Enc(m1 + m2).
+func (ct *DJCiphertext) Add(pub *DJPublicKey, ct2 *DJCiphertext) (*DJCiphertext, error) {
+	cNew := new(big.Int).Mul(ct.c, ct2.c)
+	return &DJCiphertext{c: cNew.Mod(cNew, pub.NS1)}, nil
+}
+
+// AddScalar returns Enc(m1 + m) for a signed scalar m.
+func (ct *DJCiphertext) AddScalar(pub *DJPublicKey, m *big.Int) (*DJCiphertext, error) {
+	gm := new(big.Int).Exp(pub.G, new(big.Int).Mod(m, pub.NS), pub.NS1)
+	return &DJCiphertext{c: gm.Mod(gm.Mul(gm, ct.c), pub.NS1)}, nil
+}
+
+// MulScalar returns Enc(m1 * k) for a signed scalar k.
+func (ct *DJCiphertext) MulScalar(pub *DJPublicKey, k *big.Int) (*DJCiphertext, error) {
+	cNew := new(big.Int).Exp(ct.c, new(big.Int).Abs(k), pub.NS1)
+	if k.Sign() < 0 {
+		if cNew.ModInverse(cNew, pub.NS1) == nil {
+			return nil, errors.New("ciphertext is not invertible")
+		}
+	}
+	return &DJCiphertext{c: cNew}, nil
+}
+
+// DJPublicKey → Protobuf
+func (pub *DJPublicKey) ToProto() *pb.DJPublicKey {
+	return &pb.DJPublicKey{
+		N: pub.N.Bytes(),
+		S: uint32(pub.S),
+	}
+}
+
+// Protobuf → DJPublicKey
+func DJPublicKeyFromProto(msg *pb.DJPublicKey) (*DJPublicKey, error) {
+	if msg == nil {
+		return nil, errors.New("public key is missing")
+	}
+	// int へ変換する前に検査する（32ビット環境で負になるのを防ぐ）
+	if msg.S > MaxDJExponent {
+		return nil, errors.New("s exceeds MaxDJExponent")
+	}
+	n := new(big.Int).SetBytes(msg.N)
+	if n.Sign() == 0 {
+		return nil, errors.New("modulus is missing")
+	}
+	return NewDJPublicKey(&PublicKey{N: n}, int(msg.S))
+}
+
+// DJPrivateKey → Protobuf
+func (priv *DJPrivateKey) ToProto() *pb.DJPrivateKey {
+	return &pb.DJPrivateKey{
+		PublicKey: priv.DJPublicKey.ToProto(),
+		Lambda:    priv.Lambda.Bytes(),
+	}
+}
+
+// Protobuf → DJPrivateKey
+func DJPrivateKeyFromProto(msg *pb.DJPrivateKey) (*DJPrivateKey, error) {
+	if msg == nil {
+		return nil, errors.New("private key is missing")
+	}
+	pub, err := DJPublicKeyFromProto(msg.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return newDJPrivateKey(pub, new(big.Int).SetBytes(msg.Lambda))
+}
+
+// DJCiphertext → Protobuf
+func (ct *DJCiphertext) ToProto() *pb.DJCiphertext {
+	return &pb.DJCiphertext{C: ct.c.Bytes()}
+}
+
+// Protobuf → DJCiphertext
+// The ciphertext is checked against pub and rejected unless it is a valid element of Z*_{n^(s+1)}.
+func DJCiphertextFromProto(pub *DJPublicKey, msg *pb.DJCiphertext) (*DJCiphertext, error) {
+	if msg == nil {
+		return nil, errors.New("ciphertext is missing")
+	}
+	ct := &DJCiphertext{c: new(big.Int).SetBytes(msg.C)}
+	if !pub.IsValidCiphertext(ct) {
+		return nil, errors.New("invalid ciphertext")
+	}
+	return ct, nil
+}
@@ -0,0 +1,161 @@
+package paillier
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	pb "multisigservice/proto/paillierpb"
+)
+
+func TestDamgardJurikS1MatchesPaillier(t *testing.T) {
+	pub, priv, err := GenerateKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	djPriv, err := NewDJPrivateKey(priv, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	djPub := &djPriv.DJPublicKey
+
+	for i := 0; i < 5; i++ {
+		m, err := rand.Int(rand.Reader, pub.N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := SampleUnit(pub.N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct, err := pub.EncryptWithNonce(m, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		djct, err := djPub.EncryptWithNonce(m, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ct.c.Cmp(djct.c) != 0 {
+			t.Fatal("s=1 ciphertext differs from Paillier")
+		}
+
+		// 相互に復号できる
+		got, err := djPriv.Decrypt(&DJCiphertext{c: ct.c})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Cmp(m) != 0 {
+			t.Errorf("got %v\nwant %v", got, m)
+		}
+		got, err = priv.Decrypt(&Ciphertext{c: djct.c})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Cmp(m) != 0 {
+			t.Errorf("got %v\nwant %v", got, m)
+		}
+	}
+}
+
+func TestDamgardJurikLargePlaintext(t *testing.T) {
+	for _, s := range []int{2, 3} {
+		pub, priv, err := GenerateDJKey(512, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// n より大きな平文
+		a, err := rand.Int(rand.Reader, pub.NS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := rand.Int(rand.Reader, pub.NS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k := big.NewInt(-12345)
+
+		ctA, err := pub.Encrypt(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctB, err := pub.Encrypt(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := priv.Decrypt(ctA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Cmp(a) != 0 {
+			t.Fatalf("s=%d: got %v\nwant %v", s, got, a)
+		}
+
+		sum, _ := ctA.Add(pub, ctB)
+		plus, _ := ctA.AddScalar(pub, b)
+		prod, err := ctA.MulScalar(pub, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantSum := new(big.Int).Mod(new(big.Int).Add(a, b), pub.NS)
+		wantProd := new(big.Int).Mod(new(big.Int).Mul(a, k), pub.NS)
+		for _, tc := range []struct {
+			name string
+			ct   *DJCiphertext
+			want *big.Int
+		}{
+			{"Add", sum, wantSum},
+			{"AddScalar", plus, wantSum},
+			{"MulScalar", prod, wantProd},
+		} {
+			got, err := priv.Decrypt(tc.ct)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Cmp(tc.want) != 0 {
+				t.Errorf("s=%d %s: got %v\nwant %v", s, tc.name, got, tc.want)
+			}
+		}
+
+		// protobuf 往復
+		restored, err := DJPrivateKeyFromProto(priv.ToProto())
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DJCiphertextFromProto(&restored.DJPublicKey, ctA.ToProto())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err = restored.Decrypt(decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Cmp(a) != 0 {
+			t.Errorf("s=%d proto: got %v\nwant %v", s, got, a)
+		}
+
+		if _, err := pub.Encrypt(pub.NS); err == nil {
+			t.Errorf("s=%d: plaintext n^s accepted", s)
+		}
+	}
+}
+
+func TestDamgardJurikExponentBound(t *testing.T) {
+	pub, _, err := GenerateKey(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewDJPublicKey(pub, MaxDJExponent); err != nil {
+		t.Errorf("s=%d rejected: %v", MaxDJExponent, err)
+	}
+	if _, err := NewDJPublicKey(pub, MaxDJExponent+1); err == nil {
+		t.Errorf("s=%d accepted", MaxDJExponent+1)
+	}
+	// 外部から受け取った巨大な s で n^s を計算しないこと
+	for _, s := range []uint32{0, MaxDJExponent + 1, 1 << 31, ^uint32(0)} {
+		if _, err := DJPublicKeyFromProto(&pb.DJPublicKey{N: pub.N.Bytes(), S: s}); err == nil {
+			t.Errorf("s=%d accepted from protobuf", s)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package paillier
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "multisigservice/proto/paillierpb"
+)
+
+// The canonical text encoding of PublicKey and Ciphertext is the standard
+// base64 encoding of their protobuf messages. The JSON encoding is an object
+// with the hex-encoded ("0x"-prefixed) modulus or ciphertext value:
+//
+//	{"n": "0x..."}
+//	{"c": "0x..."}
+
+// MarshalText encodes pk as base64 protobuf.
+func (pk *PublicKey) MarshalText() ([]byte, error) {
+	if pk.N == nil {
+		return nil, errors.New("public key is incomplete")
+	}
+	full := newPublicKey(pk.N)
+	return marshalProtoText(full.ToProto())
+}
+
+// UnmarshalText decodes a base64 protobuf public key. NSquare and G may be
+// omitted, in which case they are derived from N; if present they must match.
+func (pk *PublicKey) UnmarshalText(text []byte) error {
+	var msg pb.PublicKey
+	if err := unmarshalProtoText(text, &msg); err != nil {
+		return err
+	}
+	decoded := newPublicKey(new(big.Int).SetBytes(msg.N))
+	if len(msg.NSquare) > 0 && new(big.Int).SetBytes(msg.NSquare).Cmp(decoded.NSquare) != 0 {
+		return errors.New("NSquare does not equal N^2")
+	}
+	if len(msg.G) > 0 && new(big.Int).SetBytes(msg.G).Cmp(decoded.G) != 0 {
+		return errors.New("generator must be n+1")
+	}
+	return pk.set(decoded)
+}
+
+type publicKeyJSON struct {
+	N string `json:"n"`
+}
+
+// MarshalJSON encodes pk as {"n": "0x..."}.
+func (pk *PublicKey) MarshalJSON() ([]byte, error) {
+	if pk.N == nil {
+		return nil, errors.New("public key is incomplete")
+	}
+	return json.Marshal(publicKeyJSON{N: encodeHex(pk.N)})
+}
+
+// UnmarshalJSON decodes {"n": "0x..."} and derives NSquare and G from N.
+func (pk *PublicKey) UnmarshalJSON(data []byte) error {
+	var v publicKeyJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	n, err := decodeHex(v.N)
+	if err != nil {
+		return err
+	}
+	return pk.set(newPublicKey(n))
+}
+
+// newPublicKey builds the public key with g = n+1 for modulus n.
+func newPublicKey(n *big.Int) *PublicKey {
+	return &PublicKey{
+		N:       new(big.Int).Set(n),
+		NSquare: new(big.Int).Mul(n, n),
+		G:       new(big.Int).Add(n, big.NewInt(1)),
+	}
+}
+
+func (pk *PublicKey) set(decoded *PublicKey) error {
+	if decoded.N.Sign() <= 0 {
+		return errors.New("modulus is missing")
+	}
+	*pk = *decoded
+	return nil
+}
+
+// errPrivateKeyEncoding is returned by the text and JSON methods of
+// PrivateKey. Without them PrivateKey would inherit the PublicKey methods
+// and silently drop the secret; private keys are stored with the keystore
+// package or as protobuf via ToProto.
+var errPrivateKeyEncoding = errors.New("private keys have no text or JSON encoding; use the keystore package")
+
+// MarshalText always fails; see errPrivateKeyEncoding.
+func (sk *PrivateKey) MarshalText() ([]byte, error) {
+	return nil, errPrivateKeyEncoding
+}
+
+// UnmarshalText always fails; see errPrivateKeyEncoding.
+func (sk *PrivateKey) UnmarshalText([]byte) error {
+	return errPrivateKeyEncoding
+}
+
+// MarshalJSON always fails; see errPrivateKeyEncoding.
+func (sk *PrivateKey) MarshalJSON() ([]byte, error) {
+	return nil, errPrivateKeyEncoding
+}
+
+// UnmarshalJSON always fails; see errPrivateKeyEncoding.
+func (sk *PrivateKey) UnmarshalJSON([]byte) error {
+	return errPrivateKeyEncoding
+}
+
+// MarshalText encodes ct as base64 protobuf.
+func (ct *Ciphertext) MarshalText() ([]byte, error) {
+	if ct.c == nil {
+		return nil, errors.New("ciphertext is empty")
+	}
+	return marshalProtoText(ct.ToProto())
+}
+
+// UnmarshalText decodes a base64 protobuf ciphertext. Callers must still
+// check it with PublicKey.IsValidCiphertext before use.
+func (ct *Ciphertext) UnmarshalText(text []byte) error {
+	var msg pb.Ciphertext
+	if err := unmarshalProtoText(text, &msg); err != nil {
+		return err
+	}
+	return ct.set(new(big.Int).SetBytes(msg.C))
+}
+
+type ciphertextJSON struct {
+	C string `json:"c"`
+}
+
+// MarshalJSON encodes ct as {"c": "0x..."}.
+func (ct *Ciphertext) MarshalJSON() ([]byte, error) {
+	if ct.c == nil {
+		return nil, errors.New("ciphertext is empty")
+	}
+	return json.Marshal(ciphertextJSON{C: encodeHex(ct.c)})
+}
+
+// UnmarshalJSON decodes {"c": "0x..."}.
+func (ct *Ciphertext) UnmarshalJSON(data []byte) error {
+	var v ciphertextJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	c, err := decodeHex(v.C)
+	if err != nil {
+		return err
+	}
+	return ct.set(c)
+}
+
+func (ct *Ciphertext) set(c *big.Int) error {
+	if c.Sign() <= 0 {
+		return errors.New("ciphertext is empty")
+	}
+	ct.c = c
+	return nil
+}
+
+func marshalProtoText(msg proto.Message) ([]byte, error) {
+	raw, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, base64.StdEncoding.EncodedLen(len(raw)))
+	base64.StdEncoding.Encode(out, raw)
+	return out, nil
+}
+
+func unmarshalProtoText(text []byte, msg proto.Message) error {
+	raw := make([]byte, base64.StdEncoding.DecodedLen(len(text)))
+	n, err := base64.StdEncoding.Decode(raw, text)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(raw[:n], msg)
+}
+
+func encodeHex(x *big.Int) string {
+	return "0x" + hex.EncodeToString(x.Bytes())
+}
+
+func decodeHex(s string) (*big.Int, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if len(s)%2 == 1 {
+		s = "0" + s
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// MarshalSigned encodes x as a sign byte (0: non-negative, 1: negative)
+// followed by its big-endian magnitude, the format of signed fields in
+// paillier.proto.
+func MarshalSigned(x *big.Int) []byte {
+	return signedToBytes(x)
+}
+
+// UnmarshalSigned decodes the output of MarshalSigned.
+func UnmarshalSigned(b []byte) (*big.Int, error) {
+	return signedFromBytes(b)
+}
@@ -0,0 +1,150 @@
+package paillier
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	pb "multisigservice/proto/paillierpb"
+)
+
+func TestPublicKeyEncoding(t *testing.T) {
+	pub, _, err := GenerateKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := pub.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromText PublicKey
+	if err := fromText.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if fromText.N.Cmp(pub.N) != 0 || fromText.NSquare.Cmp(pub.NSquare) != 0 || fromText.G.Cmp(pub.G) != 0 {
+		t.Error("text round trip changed the key")
+	}
+
+	data, err := json.Marshal(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), `{"n":"0x`) {
+		t.Errorf("unexpected JSON form %s", data)
+	}
+	var fromJSON PublicKey
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if fromJSON.N.Cmp(pub.N) != 0 || fromJSON.NSquare.Cmp(pub.NSquare) != 0 || fromJSON.G.Cmp(pub.G) != 0 {
+		t.Error("JSON round trip changed the key")
+	}
+
+	// N のみのprotobufは正規形に補完される
+	short, err := marshalProtoText(&pb.PublicKey{N: pub.N.Bytes()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var normalized PublicKey
+	if err := normalized.UnmarshalText(short); err != nil {
+		t.Fatal(err)
+	}
+	canonical, err := normalized.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(canonical) != string(text) {
+		t.Error("normalized key does not match canonical encoding")
+	}
+
+	bad, err := marshalProtoText(&pb.PublicKey{N: pub.N.Bytes(), G: big.NewInt(2).Bytes()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, input := range map[string][]byte{
+		"bad generator": bad,
+		"not base64":    []byte("!!!"),
+		"empty":         []byte(""),
+	} {
+		var pk PublicKey
+		if err := pk.UnmarshalText(input); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	var pk PublicKey
+	if err := json.Unmarshal([]byte(`{"n":"0xzz"}`), &pk); err == nil {
+		t.Error("invalid hex accepted")
+	}
+}
+
+func TestPrivateKeyEncoding(t *testing.T) {
+	_, priv, err := GenerateKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 秘密鍵は公開鍵のメソッドを継承して秘密を落とさず、エラーになること
+	if data, err := json.Marshal(priv); err == nil {
+		t.Errorf("private key was encoded as %s", data)
+	}
+	if _, err := priv.MarshalText(); err == nil {
+		t.Error("private key was encoded as text")
+	}
+	pubJSON, err := json.Marshal(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded PrivateKey
+	if err := json.Unmarshal(pubJSON, &decoded); err == nil {
+		t.Error("public key was decoded as a private key")
+	}
+}
+
+func TestCiphertextEncoding(t *testing.T) {
+	pub, priv, err := GenerateKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := big.NewInt(777)
+	ct, err := pub.Encrypt(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := ct.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromText Ciphertext
+	if err := fromText.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON Ciphertext
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, decoded := range []*Ciphertext{&fromText, &fromJSON} {
+		if !pub.IsValidCiphertext(decoded) {
+			t.Fatal("decoded ciphertext is invalid")
+		}
+		got, err := priv.Decrypt(decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Cmp(m) != 0 {
+			t.Errorf("got %v\nwant %v", got, m)
+		}
+	}
+
+	var empty Ciphertext
+	if err := json.Unmarshal([]byte(`{"c":"0x"}`), &empty); err == nil {
+		t.Error("empty ciphertext accepted")
+	}
+}
@@ -0,0 +1,191 @@
+package paillier
+
+import (
+	"math/big"
+	"testing"
+	"testing/quick"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "multisigservice/proto/paillierpb"
+)
+
+// 不正なProtobufを与えてもパニックせず、受理した暗号文は正当であること
+func FuzzCiphertextFromProto(f *testing.F) {
+	priv := vectorKey(f)
+	ct, err := priv.Encrypt(big.NewInt(42))
+	if err != nil {
+		f.Fatal(err)
+	}
+	seed, _ := proto.Marshal(ct.ToProto())
+	f.Add(seed)
+	f.Add([]byte{})
+	f.Add([]byte{0x0a, 0x01, 0x00})
+	f.Add([]byte{0x0a, 0xff})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var msg pb.Ciphertext
+		if proto.Unmarshal(data, &msg) != nil {
+			return
+		}
+		ct, err := CiphertextFromProto(&priv.PublicKey, &msg)
+		if err != nil {
+			return
+		}
+		if !priv.IsValidCiphertext(ct) {
+			t.Fatalf("accepted invalid ciphertext %x", msg.C)
+		}
+		if _, err := priv.Decrypt(ct); err != nil {
+			t.Fatalf("valid ciphertext failed to decrypt: %v", err)
+		}
+	})
+}
+
+func FuzzPublicKeyFromProto(f *testing.F) {
+	seed, _ := proto.Marshal(vectorKey(f).PublicKey.ToProto())
+	f.Add(seed)
+	f.Add([]byte{})
+	f.Add([]byte{0x0a, 0x01, 0x02, 0x12, 0x01, 0x04, 0x1a, 0x01, 0x03})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var msg pb.PublicKey
+		if proto.Unmarshal(data, &msg) != nil {
+			return
+		}
+		pub := PublicKeyFromProto(&msg)
+		if pub.Validate() != nil {
+			// 不正な鍵でも暗号文の検査や演算がパニックしないこと
+			ct := &Ciphertext{c: big.NewInt(2)}
+			pub.IsValidCiphertext(ct)
+			pub.Encrypt(big.NewInt(1))
+			ct.Add(pub, ct)
+			ct.MulScalar(pub, big.NewInt(-3))
+			return
+		}
+		if _, err := pub.Encrypt(big.NewInt(1)); err != nil {
+			t.Fatalf("valid key failed to encrypt: %v", err)
+		}
+	})
+}
+
+func FuzzPrivateKeyFromProto(f *testing.F) {
+	seed, _ := proto.Marshal(vectorKey(f).ToProto())
+	f.Add(seed)
+	f.Add([]byte{})
+	f.Add([]byte{0x12, 0x01, 0x05, 0x22, 0x01, 0x03, 0x2a, 0x01, 0x05})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var msg pb.PrivateKey
+		if proto.Unmarshal(data, &msg) != nil {
+			return
+		}
+		priv, err := PrivateKeyFromProto(&msg)
+		if err != nil {
+			return
+		}
+		for _, c := range []int64{0, 1, 2, 7} {
+			priv.Decrypt(&Ciphertext{c: big.NewInt(c)})
+		}
+	})
+}
+
+func FuzzDecrypt(f *testing.F) {
+	priv := vectorKey(f)
+	ct, err := priv.Encrypt(big.NewInt(7))
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(ct.c.Bytes())
+	f.Add([]byte{})
+	f.Add([]byte{1})
+	f.Add(priv.NSquare.Bytes())
+	f.Add(priv.N.Bytes())
+	legacy := &PrivateKey{PublicKey: priv.PublicKey, Lambda: priv.Lambda, Mu: priv.Mu}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ct := &Ciphertext{c: new(big.Int).SetBytes(data)}
+		m, err := priv.Decrypt(ct)
+		if err != nil {
+			return
+		}
+		if m.Sign() < 0 || m.Cmp(priv.N) >= 0 {
+			t.Fatalf("plaintext %v out of range", m)
+		}
+		if !priv.IsValidCiphertext(ct) {
+			return
+		}
+		// 正当な暗号文ではCRTとλによる復号が一致すること
+		want, err := legacy.Decrypt(ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Cmp(want) != 0 {
+			t.Fatalf("crt %v, lambda %v", m, want)
+		}
+	})
+}
+
+// plaintextFrom は任意のバイト列を [0, n) の平文に写します。
+func plaintextFrom(b []byte, n *big.Int) *big.Int {
+	return new(big.Int).Mod(new(big.Int).SetBytes(b), n)
+}
+
+// Dec(Add(Enc(a), Enc(b))) = a + b mod n
+func TestAddProperty(t *testing.T) {
+	priv := vectorKey(t)
+	pub := &priv.PublicKey
+	property := func(x, y []byte) bool {
+		a, b := plaintextFrom(x, pub.N), plaintextFrom(y, pub.N)
+		ca, err := pub.Encrypt(a)
+		if err != nil {
+			return false
+		}
+		cb, err := pub.Encrypt(b)
+		if err != nil {
+			return false
+		}
+		sum, err := ca.Add(pub, cb)
+		if err != nil {
+			return false
+		}
+		got, err := priv.Decrypt(sum)
+		if err != nil {
+			return false
+		}
+		want := new(big.Int).Add(a, b)
+		return got.Cmp(want.Mod(want, pub.N)) == 0
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 30}); err != nil {
+		t.Error(err)
+	}
+}
+
+// Dec(MulScalar(Enc(a), k)) = a·k mod n（k は負の値も含む）
+func TestMulScalarProperty(t *testing.T) {
+	priv := vectorKey(t)
+	pub := &priv.PublicKey
+	property := func(x, y []byte, negative bool) bool {
+		a := plaintextFrom(x, pub.N)
+		k := new(big.Int).SetBytes(y)
+		if negative {
+			k.Neg(k)
+		}
+		ca, err := pub.Encrypt(a)
+		if err != nil {
+			return false
+		}
+		prod, err := ca.MulScalar(pub, k)
+		if err != nil {
+			return false
+		}
+		got, err := priv.Decrypt(prod)
+		if err != nil {
+			return false
+		}
+		want := new(big.Int).Mul(a, k)
+		return got.Cmp(want.Mod(want, pub.N)) == 0
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 30}); err != nil {
+		t.Error(err)
+	}
+}
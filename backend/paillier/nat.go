@@ -0,0 +1,102 @@
+package paillier
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/cronokirby/saferith"
+)
+
+// このファイルは saferith による定数時間演算をまとめたものです。
+// math/big の Exp は指数や底の値によって処理時間が変わるため、
+// λ や平文・乱数などの秘密値を扱う演算はすべてここを経由します。
+// 公開 API は従来通り *big.Int を受け取り、境界で変換だけを行います。
+
+// natFromBig converts x to a Nat announced at bits bits, or at the length of x
+// if that is larger. Later operations leak only the announced length.
+func natFromBig(x *big.Int, bits int) *saferith.Nat {
+	if x.BitLen() > bits {
+		bits = x.BitLen()
+	}
+	return new(saferith.Nat).SetBig(x, bits)
+}
+
+// modulusFromBig converts a public modulus to a saferith.Modulus.
+func modulusFromBig(m *big.Int) *saferith.Modulus {
+	return saferith.ModulusFromNat(new(saferith.Nat).SetBig(m, m.BitLen()))
+}
+
+// natPublicKey is the saferith form of a PublicKey.
+type natPublicKey struct {
+	n       *saferith.Modulus
+	nSquare *saferith.Modulus
+	g       *saferith.Nat
+	bits    int  // |n|
+	simpleG bool // g = n + 1
+}
+
+// errMalformedKey is returned when a key cannot be used for arithmetic.
+var errMalformedKey = errors.New("public key is malformed")
+
+// nat converts pub to its saferith form. Unlike Validate it does not check the
+// key's strength, only that n is odd and greater than one and n^2 matches n.
+func (pub *PublicKey) nat() (*natPublicKey, error) {
+	if pub.N == nil || pub.NSquare == nil || pub.G == nil {
+		return nil, errMalformedKey
+	}
+	if pub.N.Cmp(big.NewInt(1)) <= 0 || pub.N.Bit(0) == 0 || pub.NSquare.Cmp(new(big.Int).Mul(pub.N, pub.N)) != 0 {
+		return nil, errMalformedKey
+	}
+	return &natPublicKey{
+		n:       modulusFromBig(pub.N),
+		nSquare: modulusFromBig(pub.NSquare),
+		g:       natFromBig(pub.G, pub.NSquare.BitLen()),
+		bits:    pub.N.BitLen(),
+		simpleG: pub.G.Cmp(new(big.Int).Add(pub.N, big.NewInt(1))) == 0,
+	}, nil
+}
+
+// plaintext converts a plaintext in [0, n) announced at |n| bits.
+func (k *natPublicKey) plaintext(m *big.Int) *saferith.Nat {
+	return natFromBig(m, k.bits)
+}
+
+// ciphertext converts a ciphertext announced at |n^2| bits.
+func (k *natPublicKey) ciphertext(ct *Ciphertext) (*saferith.Nat, error) {
+	if ct == nil || ct.c == nil || ct.c.Sign() < 0 {
+		return nil, errors.New("ciphertext is missing")
+	}
+	return natFromBig(ct.c, k.nSquare.BitLen()), nil
+}
+
+// encrypt computes g^m * r^n mod n^2.
+func (k *natPublicKey) encrypt(m, r *saferith.Nat) *saferith.Nat {
+	return k.encryptMasked(m, k.mask(r))
+}
+
+// encryptMasked computes g^m * rn mod n^2 for m ∈ [0, n) and a precomputed
+// rn = r^n mod n^2.
+func (k *natPublicKey) encryptMasked(m, rn *saferith.Nat) *saferith.Nat {
+	var gm *saferith.Nat
+	if k.simpleG {
+		// g = n+1 のとき g^m = 1 + m*n mod n^2 なので冪乗は不要
+		bits := k.nSquare.BitLen()
+		gm = new(saferith.Nat).Mul(m, k.n.Nat(), bits)
+		gm.Add(gm, new(saferith.Nat).SetUint64(1), bits)
+	} else {
+		gm = new(saferith.Nat).Exp(k.g, m, k.nSquare)
+	}
+	return gm.ModMul(gm, rn, k.nSquare)
+}
+
+// mask computes r^n mod n^2.
+func (k *natPublicKey) mask(r *saferith.Nat) *saferith.Nat {
+	return new(saferith.Nat).Exp(r, k.n.Nat(), k.nSquare)
+}
+
+// natL computes L(u) = (u - 1) / d for u ≡ 1 mod d, where u < d^2.
+func natL(u *saferith.Nat, d *saferith.Modulus) *saferith.Nat {
+	one := new(saferith.Nat).SetUint64(1)
+	u1 := new(saferith.Nat).Sub(u, one, u.AnnouncedLen())
+	return u1.Div(u1, d, d.BitLen())
+}
@@ -0,0 +1,248 @@
+package paillier
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+// 以下は math/big による参照実装です。saferith による実装と同じ値を返すことを確認します。
+
+func refEncrypt(pub *PublicKey, m, r *big.Int) *big.Int {
+	gm := new(big.Int).Exp(pub.G, m, pub.NSquare)
+	rn := new(big.Int).Exp(r, pub.N, pub.NSquare)
+	return gm.Mod(gm.Mul(gm, rn), pub.NSquare)
+}
+
+func refDecrypt(priv *PrivateKey, c *big.Int) *big.Int {
+	l := L(new(big.Int).Exp(c, priv.Lambda, priv.NSquare), priv.N)
+	return l.Mod(l.Mul(l, priv.Mu), priv.N)
+}
+
+func refAdd(pub *PublicKey, c1, c2 *big.Int) *big.Int {
+	return new(big.Int).Mod(new(big.Int).Mul(c1, c2), pub.NSquare)
+}
+
+func refAddScalar(pub *PublicKey, c, m *big.Int) *big.Int {
+	gm := new(big.Int).Exp(pub.G, new(big.Int).Mod(m, pub.N), pub.NSquare)
+	return gm.Mod(gm.Mul(gm, c), pub.NSquare)
+}
+
+func refMulScalar(pub *PublicKey, c, k *big.Int) *big.Int {
+	res := new(big.Int).Exp(c, new(big.Int).Abs(k), pub.NSquare)
+	if k.Sign() < 0 {
+		res.ModInverse(res, pub.NSquare)
+	}
+	return res
+}
+
+func hexInt(t testing.TB, s string) *big.Int {
+	t.Helper()
+	x, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		t.Fatalf("invalid hex %q", s)
+	}
+	return x
+}
+
+// vectorKey は固定の素数から512ビットのテスト用鍵を組み立てます。
+func vectorKey(t testing.TB) *PrivateKey {
+	t.Helper()
+	p := hexInt(t, "e56f3c8a90bb20b684bf1de77e406ecf435af3e5097c952ecb0fface71b5528b")
+	q := hexInt(t, "ed8d0749e5637ff65bfc6920f9b19d9c911d4014a3162d9cb76d37a86499989d")
+	n := new(big.Int).Mul(p, q)
+	nSquare := new(big.Int).Mul(n, n)
+	g := new(big.Int).Add(n, big.NewInt(1))
+
+	p1 := new(big.Int).Sub(p, big.NewInt(1))
+	q1 := new(big.Int).Sub(q, big.NewInt(1))
+	lambda := new(big.Int).Mul(p1, q1)
+	lambda.Div(lambda, new(big.Int).GCD(nil, nil, p1, q1))
+	mu := new(big.Int).ModInverse(L(new(big.Int).Exp(g, lambda, nSquare), n), n)
+
+	priv := &PrivateKey{
+		PublicKey: PublicKey{N: n, NSquare: nSquare, G: g},
+		Lambda:    lambda,
+		Mu:        mu,
+		P:         p,
+		Q:         q,
+	}
+	if err := priv.Precompute(); err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+// encVectors は (m, r) に対する期待暗号文 g^m * r^n mod n^2 です。
+var encVectors = []struct {
+	m, r, c string
+}{
+	{
+		m: "0",
+		r: "c089df2b1468e1698b738772eff8471865f15b91a2b71934826284cff851b82a850bf69e44fbc04d24728e7c84058a332b5c8825665599cc0e4654b8e17d5368",
+		c: "5efb6d065737b9be8a05c884c94517288695d1d18cc9ea6cfd49f0a91ac540fb8fd5bdab326780586d9c43403cce05adf5375cb56e1c1190990e49adfbbc05d65d9396dc2394655e419360e843d959a98d7d39268f0b62f8403ffcf37ddc588bf409a2a3965d5a1743d07bd7b8215cf37a0f14a36799849b18a3d1be5a516301",
+	},
+	{
+		m: "1",
+		r: "7d186f2e4f6f0aae02a258f52eba354462637a6682311c15120b066085128f78541bc2cb900180440b7e138e917e69b10cb74b61725172aa8b69ed5520118fc6",
+		c: "58f06d41e3c25943b4464639bec225ae4d15c4f70950c3f4a66b2efbfda86d97c9dac8b128b1e00f651987d1f0fdb8e12bd4e575f8467b1ce676e606b0b2a7bda4b183b10d32914ae21bdf79d011e6c05f8d0c7035ae64dd0947c4bded5c7b6c8e992fc709332e0d5df7fad384eaf7b61a6423696bfaa2cd80856625f39173ef",
+	},
+	{
+		m: "2a",
+		r: "9141bcd2b28fb8c0701dace51d24a3fd2523b604daa68fdeb8e125624b114adab6afdd9841c7b694bd563628584ffa1715d104bf5eb728b3b91d230861ea0fcc",
+		c: "2123cb3a81539da1d2ffc62970bc5643449dd4e732b4dc7d2e743a89a3b4a03f7bde926d7eeb0592fff0ecf4366805247f9413ca3a61adca893ac808752c8da1249376f4de7798f5f0bfacfee7a4f636b019a35a8ee059fd7dec112337d68d8d20b20f9579e83ef416e95e048b87b8dc91c3421c4c407879b61faf1f72f7431d",
+	},
+	{
+		// m = n - 1
+		m: "d4e65fd8e544afadb8186771b8711701ca1255b30fcdf457287deb3e5fe1e42244c26570bf57715c55b98b12724c41975996490b0bc5969ba56d35f40649273e",
+		r: "c5c1a824e836f2fca5d1070d402c530d75bb0df9dfa4515506dedcecf094a42d51f0cc8faa6a637e748381b8b62e9bdc17ff54b89ca17bb3f08bb8aba83946bc",
+		c: "79e737edf5214db0cd9616903151936f99b5dae75f1c20152b255dee636c13f02fa43d93188a6dbe5e326e766e041afd9a78c18249fd1745fe56c071dbaa45ab20455f90f971ca74a6fe5f53d3840aa9b432925c56cb727f523e0fec6907b9e74bf40001b4a167edf94be9052a25be75016fcd2e50d9bd234adba5f50c46733",
+	},
+	{
+		m: "a0647744e0b6f6ace4d29e07d4646a27988b46c6eef83e5308c991ff21c7171a8a49ec59c7190715d031035a132387356531c913b05e88e48f0caaf08359008",
+		r: "b012eadde5d81e846080f2e584a58eb5dff316cd8a73fa5c92c067ab4ca5d9d96692e1e6263c0aa0777860433ea5f85a7ff382ef9a89bed22524aec697354fae",
+		c: "46c774f95a24279857cae5df0e5a018e153cfba1d7332f771deeb29fd1b5988f56186ecbf565183eff1fffdf970e7c87e5797dbf3398d012f0e7af9fa288a4b1d31d183f53d7261d601e3344e8a6ae5c770eddacccc56c927eaded848f26ee375faa51bbb26a8da5a54cb210ea2c20a7257907c39a529d425700efb3521c8060",
+	},
+}
+
+func TestEncryptVectors(t *testing.T) {
+	priv := vectorKey(t)
+	legacy := &PrivateKey{PublicKey: priv.PublicKey, Lambda: priv.Lambda, Mu: priv.Mu}
+
+	for i, v := range encVectors {
+		m, r, want := hexInt(t, v.m), hexInt(t, v.r), hexInt(t, v.c)
+
+		if ref := refEncrypt(&priv.PublicKey, m, r); ref.Cmp(want) != 0 {
+			t.Fatalf("vector %d: reference got %x\nwant %x", i, ref, want)
+		}
+		ct, err := priv.EncryptWithNonce(m, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ct.c.Cmp(want) != 0 {
+			t.Errorf("vector %d: got %x\nwant %x", i, ct.c, want)
+		}
+
+		for name, sk := range map[string]*PrivateKey{"crt": priv, "lambda": legacy} {
+			got, err := sk.Decrypt(ct)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Cmp(m) != 0 {
+				t.Errorf("vector %d (%s): got %x\nwant %x", i, name, got, m)
+			}
+		}
+	}
+}
+
+func TestHomomorphicVectors(t *testing.T) {
+	priv := vectorKey(t)
+	pub := &priv.PublicKey
+	c1 := &Ciphertext{c: hexInt(t, encVectors[2].c)}
+	c2 := &Ciphertext{c: hexInt(t, encVectors[4].c)}
+	k := hexInt(t, "bdda3492e5a1daae22faf9b932b831350d045044b0662af2eb")
+
+	tests := []struct {
+		name string
+		op   func() (*Ciphertext, error)
+		want string
+	}{
+		{
+			name: "Add",
+			op:   func() (*Ciphertext, error) { return c1.Add(pub, c2) },
+			want: "49faae73e9db6052a38d89a06b2d7e59567178f3740beb0630bf22c7e9d7b5ff9edbd7da544afa8874c7ac9475cb04fcaf7a474a98250eb48e6616987922d57bbddd2f4e1971068c1a361d8097e387a40fe78b42364884695ae1a53495b348803192e4deb2d1d64a34de5eaa66e7846bca1be3cc26c3f5e258fa80948fe0c851",
+		},
+		{
+			name: "AddScalar",
+			op:   func() (*Ciphertext, error) { return c1.AddScalar(pub, k) },
+			want: "8e35212f07e83786d63c6c5899c767fd562055656048828b3594b00bacd5dba9c1ec084a052c8dad610606ea5436455dd3fcd7dce2795276be29fccf2b667bed8acd6d880d912cfdb0f734f0282b3c1290377a7417fbb3f37b856dd82356e1c56d1d8669bd8721fe62e8b9d3ea39abf2d1d129ae9325b6a048615b14155a5575",
+		},
+		{
+			name: "MulScalar",
+			op:   func() (*Ciphertext, error) { return c2.MulScalar(pub, k) },
+			want: "8974653d9b3169647412c77a315812d4a3fdd66ad847106c062a6baf4ba1253043d429cfbbe19c2058b546ad9f45edb367b5c504a7973880343ce75d45a031efec9e690d44cf93dd5969449c6846f312cb4912771c35fe628dd7116ff6fa3e4aad8fe92f2b28c929e6c89a41b9834a9be6c62fad4161c4d2c1e496e103afae13",
+		},
+		{
+			name: "MulScalarNegative",
+			op:   func() (*Ciphertext, error) { return c2.MulScalar(pub, new(big.Int).Neg(k)) },
+			want: "989417713df25bafe6f10c94f88a7b30878029026821502aaada53b460d1c646fd6d00afe0d6064c149e9340a2b2de59e1d8400e6a4c76963852a6273c2bf5e71158a05f45aebf7125db28bb1d2379db2b759c808cdd9d83e966597ed8550570766e355c8ae1fed827eb6d52b765f2e70e110e11a8c1903d17c28ab29e86f5a9",
+		},
+		{
+			name: "Neg",
+			op:   func() (*Ciphertext, error) { return c1.Neg(pub) },
+			want: "55635b0456822371a489bfaa49a0a2df7472295902e9df770de03b7d45cebff57fc222c314aa9e4ae46073f77de32f891224cc6d8fca61fbea405e46c373776ce8f6050b5286ba50e10fea016b2d773d4c11e62611d44c25bf26040d7e6dfe6c4c8c64dc04cbd2cc3ac18f1da87aaec13842f71ddfea7126fc0c1e77ee7564fe",
+		},
+	}
+	for _, tt := range tests {
+		got, err := tt.op()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if want := hexInt(t, tt.want); got.c.Cmp(want) != 0 {
+			t.Errorf("%s: got %x\nwant %x", tt.name, got.c, want)
+		}
+	}
+}
+
+// TestBackendsAgree はランダムな入力に対して saferith 実装と参照実装の結果が一致することを確認します。
+func TestBackendsAgree(t *testing.T) {
+	pub, priv, err := GenerateKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := &PrivateKey{PublicKey: priv.PublicKey, Lambda: priv.Lambda, Mu: priv.Mu}
+
+	for i := 0; i < 8; i++ {
+		m1, _ := rand.Int(rand.Reader, pub.N)
+		m2, _ := rand.Int(rand.Reader, pub.N)
+		r1, err := SampleUnit(pub.N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r2, err := SampleUnit(pub.N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 256))
+		if i%2 == 1 {
+			k.Neg(k)
+		}
+
+		ct1, err := pub.EncryptWithNonce(m1, r1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct2, err := pub.EncryptWithNonce(m2, r2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := refEncrypt(pub, m1, r1); ct1.c.Cmp(want) != 0 {
+			t.Fatalf("Encrypt: got %x\nwant %x", ct1.c, want)
+		}
+
+		sum, _ := ct1.Add(pub, ct2)
+		if want := refAdd(pub, ct1.c, ct2.c); sum.c.Cmp(want) != 0 {
+			t.Errorf("Add: got %x\nwant %x", sum.c, want)
+		}
+		shifted, _ := ct1.AddScalar(pub, k)
+		if want := refAddScalar(pub, ct1.c, k); shifted.c.Cmp(want) != 0 {
+			t.Errorf("AddScalar: got %x\nwant %x", shifted.c, want)
+		}
+		scaled, err := ct1.MulScalar(pub, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := refMulScalar(pub, ct1.c, k); scaled.c.Cmp(want) != 0 {
+			t.Errorf("MulScalar: got %x\nwant %x", scaled.c, want)
+		}
+
+		for _, ct := range []*Ciphertext{sum, shifted, scaled} {
+			want := refDecrypt(priv, ct.c)
+			got, _ := priv.Decrypt(ct)
+			gotLambda, _ := legacy.Decrypt(ct)
+			if got.Cmp(want) != 0 || gotLambda.Cmp(want) != 0 {
+				t.Errorf("Decrypt: crt %x, lambda %x\nwant %x", got, gotLambda, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package paillier
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Packer places several bounded, non-negative values ("slots") into a single
+// Paillier plaintext: slot i occupies bits [i·w, (i+1)·w) where the slot
+// width w is the value size plus padding bits that absorb carries from
+// homomorphic additions and scalar multiplications.
+type Packer struct {
+	ValueBits int // bit length of a freshly packed value
+	SlotWidth int // ValueBits + padding
+	Slots     int // number of slots that fit in one plaintext
+}
+
+// PackedCiphertext is an encryption of packed slots. It tracks an upper
+// bound on the bit length of every slot so that operations which could
+// overflow into the neighbouring slot are rejected.
+type PackedCiphertext struct {
+	ct    *Ciphertext
+	count int
+	bits  int
+}
+
+// NewPacker returns a packer for pub whose slots hold valueBits-bit values
+// with paddingBits bits of headroom.
+func NewPacker(pub *PublicKey, valueBits, paddingBits int) (*Packer, error) {
+	if valueBits <= 0 || paddingBits < 0 {
+		return nil, errors.New("slot sizes must be positive")
+	}
+	width := valueBits + paddingBits
+	// 最上位スロットも n 未満に収まるよう1ビット余らせる
+	slots := (pub.N.BitLen() - 1) / width
+	if slots < 1 {
+		return nil, errors.New("slot width exceeds plaintext space")
+	}
+	return &Packer{ValueBits: valueBits, SlotWidth: width, Slots: slots}, nil
+}
+
+// Pack encodes values into one plaintext.
+func (pk *Packer) Pack(values []*big.Int) (*big.Int, error) {
+	if len(values) == 0 || len(values) > pk.Slots {
+		return nil, fmt.Errorf("can pack 1 to %d values, got %d", pk.Slots, len(values))
+	}
+	m := new(big.Int)
+	for i := len(values) - 1; i >= 0; i-- {
+		v := values[i]
+		if v.Sign() < 0 || v.BitLen() > pk.ValueBits {
+			return nil, fmt.Errorf("value %d out of range", i)
+		}
+		m.Lsh(m, uint(pk.SlotWidth))
+		m.Add(m, v)
+	}
+	return m, nil
+}
+
+// Unpack splits a plaintext into count slot values.
+func (pk *Packer) Unpack(m *big.Int, count int) []*big.Int {
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(pk.SlotWidth)), big.NewInt(1))
+	rest := new(big.Int).Set(m)
+	values := make([]*big.Int, count)
+	for i := 0; i < count; i++ {
+		values[i] = new(big.Int).And(rest, mask)
+		rest.Rsh(rest, uint(pk.SlotWidth))
+	}
+	return values
+}
+
+// Encrypt packs and encrypts values.
+func (pk *Packer) Encrypt(pub *PublicKey, values []*big.Int) (*PackedCiphertext, error) {
+	m, err := pk.Pack(values)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := pub.Encrypt(m)
+	if err != nil {
+		return nil, err
+	}
+	return &PackedCiphertext{ct: ct, count: len(values), bits: pk.ValueBits}, nil
+}
+
+// Decrypt decrypts and unpacks p.
+func (pk *Packer) Decrypt(priv *PrivateKey, p *PackedCiphertext) ([]*big.Int, error) {
+	m, err := priv.Decrypt(p.ct)
+	if err != nil {
+		return nil, err
+	}
+	return pk.Unpack(m, p.count), nil
+}
+
+// Add adds two packed ciphertexts slot by slot.
+func (pk *Packer) Add(pub *PublicKey, a, b *PackedCiphertext) (*PackedCiphertext, error) {
+	bits := maxInt(a.bits, b.bits) + 1
+	if err := pk.checkBits(bits); err != nil {
+		return nil, err
+	}
+	ct, err := a.ct.Add(pub, b.ct)
+	if err != nil {
+		return nil, err
+	}
+	return &PackedCiphertext{ct: ct, count: maxInt(a.count, b.count), bits: bits}, nil
+}
+
+// AddScalars adds values[i] to slot i.
+func (pk *Packer) AddScalars(pub *PublicKey, a *PackedCiphertext, values []*big.Int) (*PackedCiphertext, error) {
+	bits := maxInt(a.bits, pk.ValueBits) + 1
+	if err := pk.checkBits(bits); err != nil {
+		return nil, err
+	}
+	m, err := pk.Pack(values)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := a.ct.AddScalar(pub, m)
+	if err != nil {
+		return nil, err
+	}
+	return &PackedCiphertext{ct: ct, count: maxInt(a.count, len(values)), bits: bits}, nil
+}
+
+// MulScalar multiplies every slot by the non-negative scalar k.
+func (pk *Packer) MulScalar(pub *PublicKey, a *PackedCiphertext, k *big.Int) (*PackedCiphertext, error) {
+	if k.Sign() < 0 {
+		return nil, errors.New("packed slots only support non-negative scalars")
+	}
+	bits := a.bits + k.BitLen()
+	if err := pk.checkBits(bits); err != nil {
+		return nil, err
+	}
+	ct, err := a.ct.MulScalar(pub, k)
+	if err != nil {
+		return nil, err
+	}
+	return &PackedCiphertext{ct: ct, count: a.count, bits: bits}, nil
+}
+
+// checkBits rejects results whose slots could carry into the next slot.
+func (pk *Packer) checkBits(bits int) error {
+	if bits > pk.SlotWidth {
+		return fmt.Errorf("slot overflow: %d bits exceed slot width %d", bits, pk.SlotWidth)
+	}
+	return nil
+}
+
+// NewPackedCiphertext wraps a ciphertext received from a peer that holds
+// count slots whose values have at most bits bits.
+func NewPackedCiphertext(ct *Ciphertext, count, bits int) *PackedCiphertext {
+	return &PackedCiphertext{ct: ct, count: count, bits: bits}
+}
+
+// Ciphertext returns the underlying Paillier ciphertext, e.g. for transmission.
+func (p *PackedCiphertext) Ciphertext() *Ciphertext {
+	return p.ct
+}
+
+// Count returns the number of slots in use.
+func (p *PackedCiphertext) Count() int {
+	return p.count
+}
+
+// Bits returns the current upper bound on the bit length of each slot.
+func (p *PackedCiphertext) Bits() int {
+	return p.bits
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,115 @@
+package paillier
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestPacking(t *testing.T) {
+	pub, priv, err := GenerateKey(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packer, err := NewPacker(pub, 256, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packer.Slots != 6 {
+		t.Fatalf("got %d slots, want 6", packer.Slots)
+	}
+
+	random := func(n int) []*big.Int {
+		values := make([]*big.Int, n)
+		for i := range values {
+			v, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 256))
+			if err != nil {
+				t.Fatal(err)
+			}
+			values[i] = v
+		}
+		return values
+	}
+	a, b, c := random(packer.Slots), random(packer.Slots), random(packer.Slots)
+	k := big.NewInt(1 << 20)
+
+	encA, err := packer.Encrypt(pub, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encB, err := packer.Encrypt(pub, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// (a + b) · k + c をスロット毎に計算
+	sum, err := packer.Add(pub, encA, encB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prod, err := packer.MulScalar(pub, sum, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := packer.AddScalars(pub, prod, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := packer.Decrypt(priv, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != packer.Slots {
+		t.Fatalf("got %d slots, want %d", len(got), packer.Slots)
+	}
+	for i := range got {
+		want := new(big.Int).Add(a[i], b[i])
+		want.Mul(want, k)
+		want.Add(want, c[i])
+		if got[i].Cmp(want) != 0 {
+			t.Errorf("slot %d: got %v\nwant %v", i, got[i], want)
+		}
+	}
+
+	// 部分的に埋めたスロットも復元できる
+	few, err := packer.Encrypt(pub, a[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = packer.Decrypt(priv, few)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Cmp(a[0]) != 0 || got[1].Cmp(a[1]) != 0 {
+		t.Errorf("got %v\nwant %v", got, a[:2])
+	}
+}
+
+func TestPackingRejectsOverflow(t *testing.T) {
+	pub, _, err := GenerateKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packer, err := NewPacker(pub, 256, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := []*big.Int{big.NewInt(1), big.NewInt(2)}
+	enc, err := packer.Encrypt(pub, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := packer.MulScalar(pub, enc, big.NewInt(1<<9)); err == nil {
+		t.Error("multiplication overflowing the padding accepted")
+	}
+	if _, err := packer.MulScalar(pub, enc, big.NewInt(-1)); err == nil {
+		t.Error("negative scalar accepted")
+	}
+	if _, err := packer.Pack(make([]*big.Int, packer.Slots+1)); err == nil {
+		t.Error("too many values accepted")
+	}
+	if _, err := packer.Pack([]*big.Int{new(big.Int).Lsh(big.NewInt(1), 256)}); err == nil {
+		t.Error("oversized value accepted")
+	}
+}
@@ -2,9 +2,11 @@ package paillier
 
 import (
 	"math/big"
-	"encoding/json"
 	"crypto/rand"
 	"errors"
+
+    "github.com/cronokirby/saferith"
+
     pb "multisigservice/proto/paillierpb"
 )
 
@@ -20,6 +22,39 @@ type PublicKey struct {
     G       *big.Int
 }
 
+// MinBitLen is the smallest modulus accepted by Validate.
+const MinBitLen = 2048
+
+// Validate reports whether pk is a well-formed Paillier public key.
+// It should be called before encrypting to a key received from a peer.
+func (pk *PublicKey) Validate() error {
+    if pk.N == nil || pk.NSquare == nil || pk.G == nil {
+        return errors.New("public key is incomplete")
+    }
+    if pk.N.BitLen() < MinBitLen {
+        return errors.New("modulus is too small")
+    }
+    if pk.N.Bit(0) == 0 {
+        return errors.New("modulus is even")
+    }
+    if isSquare(pk.N) {
+        return errors.New("modulus is a perfect square")
+    }
+    if pk.G.Cmp(new(big.Int).Add(pk.N, big.NewInt(1))) != 0 {
+        return errors.New("generator must be n+1")
+    }
+    if pk.NSquare.Cmp(new(big.Int).Mul(pk.N, pk.N)) != 0 {
+        return errors.New("NSquare does not equal N^2")
+    }
+    return nil
+}
+
+// isSquare reports whether x is a perfect square.
+func isSquare(x *big.Int) bool {
+    r := new(big.Int).Sqrt(x)
+    return r.Mul(r, r).Cmp(x) == 0
+}
+
 // PublicKey → Protobuf
 func (pk *PublicKey) ToProto() *pb.PublicKey {
     return &pb.PublicKey{
@@ -30,11 +65,12 @@ func (pk *PublicKey) ToProto() *pb.PublicKey {
 }
 
 // Protobuf → PublicKey
+// The result is not validated; call Validate before using a key from a peer.
 func PublicKeyFromProto(msg *pb.PublicKey) *PublicKey {
     return &PublicKey{
-        N:       new(big.Int).SetBytes(msg.N),
-        NSquare: new(big.Int).SetBytes(msg.NSquare),
-        G:       new(big.Int).SetBytes(msg.G),
+        N:       new(big.Int).SetBytes(msg.GetN()),
+        NSquare: new(big.Int).SetBytes(msg.GetNSquare()),
+        G:       new(big.Int).SetBytes(msg.GetG()),
     }
 }
 
@@ -42,24 +78,117 @@ type PrivateKey struct {
     PublicKey
     Lambda   *big.Int
     Mu       *big.Int
+    // P, Q はnの素因数です。旧形式の鍵では nil のままで、その場合はλによる復号にフォールバックします。
+    P        *big.Int
+    Q        *big.Int
+
+    precomputed *precomputedValues
+}
+
+// precomputedValues holds the CRT constants used by Decrypt, in saferith form.
+type precomputedValues struct {
+    p, q    *saferith.Modulus
+    pSquare *saferith.Modulus // p^2
+    qSquare *saferith.Modulus // q^2
+    p1      *saferith.Nat     // p - 1
+    q1      *saferith.Nat     // q - 1
+    hp      *saferith.Nat     // L_p(g^(p-1) mod p^2)^-1 mod p
+    hq      *saferith.Nat     // L_q(g^(q-1) mod q^2)^-1 mod q
+    qInv    *saferith.Nat     // q^-1 mod p
 }
 
 // PrivateKey → Protobuf
 func (sk *PrivateKey) ToProto() *pb.PrivateKey {
-    return &pb.PrivateKey{
+    msg := &pb.PrivateKey{
         PublicKey: sk.PublicKey.ToProto(),
         Lambda:    sk.Lambda.Bytes(),
         Mu:        sk.Mu.Bytes(),
     }
+    if sk.P != nil && sk.Q != nil {
+        msg.P = sk.P.Bytes()
+        msg.Q = sk.Q.Bytes()
+    }
+    return msg
 }
 
 // Protobuf → PrivateKey
-func PrivateKeyFromProto(msg *pb.PrivateKey) *PrivateKey {
-    return &PrivateKey{
-        PublicKey: *PublicKeyFromProto(msg.PublicKey),
-        Lambda:    new(big.Int).SetBytes(msg.Lambda),
-        Mu:        new(big.Int).SetBytes(msg.Mu),
+// The key is rejected unless 0 < λ, μ < n with λμ ≡ 1 mod n (which holds for
+// g = n+1), and unless P and Q, when present, are the factors of n.
+func PrivateKeyFromProto(msg *pb.PrivateKey) (*PrivateKey, error) {
+    if msg == nil || msg.GetPublicKey() == nil {
+        return nil, errors.New("public key is missing")
+    }
+    sk := &PrivateKey{
+        PublicKey: *PublicKeyFromProto(msg.GetPublicKey()),
+        Lambda:    new(big.Int).SetBytes(msg.GetLambda()),
+        Mu:        new(big.Int).SetBytes(msg.GetMu()),
+    }
+    one := big.NewInt(1)
+    if sk.N.Cmp(one) <= 0 || sk.N.Bit(0) == 0 {
+        return nil, errors.New("modulus is invalid")
     }
+    if sk.G.Cmp(new(big.Int).Add(sk.N, one)) != 0 || sk.NSquare.Cmp(new(big.Int).Mul(sk.N, sk.N)) != 0 {
+        return nil, errors.New("public key does not match the modulus")
+    }
+    if sk.Lambda.Sign() == 0 || sk.Lambda.Cmp(sk.N) >= 0 || sk.Mu.Sign() == 0 || sk.Mu.Cmp(sk.N) >= 0 {
+        return nil, errors.New("lambda or mu is out of range")
+    }
+    // g = n+1 のとき L(g^λ mod n^2) = λ mod n なので、μ = λ^-1 mod n でなければならない
+    lm := new(big.Int).Mul(sk.Lambda, sk.Mu)
+    if lm.Mod(lm, sk.N).Cmp(one) != 0 {
+        return nil, errors.New("mu is not the inverse of lambda")
+    }
+    if len(msg.GetP()) > 0 || len(msg.GetQ()) > 0 {
+        sk.P = new(big.Int).SetBytes(msg.GetP())
+        sk.Q = new(big.Int).SetBytes(msg.GetQ())
+        if err := sk.Precompute(); err != nil {
+            return nil, err
+        }
+    }
+    return sk, nil
+}
+
+// Precompute computes the CRT constants used to speed up Decrypt.
+// It requires P and Q to be set and to be the factors of N.
+func (sk *PrivateKey) Precompute() error {
+    if sk.P == nil || sk.Q == nil {
+        return errors.New("prime factors are not available")
+    }
+    if _, err := sk.nat(); err != nil {
+        return err
+    }
+    if new(big.Int).Mul(sk.P, sk.Q).Cmp(sk.N) != 0 {
+        return errors.New("prime factors do not match modulus")
+    }
+    one := big.NewInt(1)
+    if sk.P.Cmp(one) <= 0 || sk.Q.Cmp(one) <= 0 || sk.P.Bit(0) == 0 || sk.Q.Bit(0) == 0 {
+        return errors.New("prime factors must be odd and greater than one")
+    }
+
+    pv := &precomputedValues{
+        p:       modulusFromBig(sk.P),
+        q:       modulusFromBig(sk.Q),
+        pSquare: modulusFromBig(new(big.Int).Mul(sk.P, sk.P)),
+        qSquare: modulusFromBig(new(big.Int).Mul(sk.Q, sk.Q)),
+        p1:      natFromBig(new(big.Int).Sub(sk.P, one), sk.P.BitLen()),
+        q1:      natFromBig(new(big.Int).Sub(sk.Q, one), sk.Q.BitLen()),
+    }
+    g := natFromBig(sk.G, sk.NSquare.BitLen())
+    // hp = L_p(g^(p-1) mod p^2)^-1 mod p
+    lp := natL(new(saferith.Nat).Exp(g, pv.p1, pv.pSquare), pv.p)
+    // hq = L_q(g^(q-1) mod q^2)^-1 mod q
+    lq := natL(new(saferith.Nat).Exp(g, pv.q1, pv.qSquare), pv.q)
+    // qInv = q^-1 mod p
+    qModP := new(saferith.Nat).Mod(natFromBig(sk.Q, sk.Q.BitLen()), pv.p)
+    if lp.IsUnit(pv.p) != 1 || lq.IsUnit(pv.q) != 1 || qModP.IsUnit(pv.p) != 1 {
+        return errors.New("failed to compute CRT constants")
+    }
+    pv.hp = new(saferith.Nat).ModInverse(lp, pv.p)
+    pv.hq = new(saferith.Nat).ModInverse(lq, pv.q)
+    pv.qInv = new(saferith.Nat).ModInverse(qModP, pv.p)
+
+    sk.precomputed = pv
+    return nil
 }
 
 type Ciphertext struct {
@@ -75,22 +204,104 @@ func (ct *Ciphertext) ToProto() *pb.Ciphertext {
 }
 
 // Protobuf → Ciphertext
-func CiphertextFromProto(msg *pb.Ciphertext) *Ciphertext {
-    return &Ciphertext{
+// The ciphertext is checked against pub and rejected unless it is a valid element of Z*_{n^2}.
+func CiphertextFromProto(pub *PublicKey, msg *pb.Ciphertext) (*Ciphertext, error) {
+    if msg == nil {
+        return nil, errors.New("ciphertext is missing")
+    }
+    ct := &Ciphertext{
         c: new(big.Int).SetBytes(msg.C),
     }
+    if !pub.IsValidCiphertext(ct) {
+        return nil, errors.New("invalid ciphertext")
+    }
+    return ct, nil
 }
 
-// GenerateKey generates a Paillier keypair of specified bit length (e.g., 2048 or 3072)
-func GenerateKey(bitLen int) (*PublicKey, *PrivateKey, error) {
-    // 1. 素数p, qの生成
-    p, err := rand.Prime(rand.Reader, bitLen/2)
+// IsValidCiphertext reports whether 0 < c < n^2 and gcd(c, n) = 1.
+func (pub *PublicKey) IsValidCiphertext(ct *Ciphertext) bool {
+    if ct == nil || ct.c == nil {
+        return false
+    }
+    if ct.c.Sign() <= 0 || ct.c.Cmp(pub.NSquare) >= 0 {
+        return false
+    }
+    return new(big.Int).GCD(nil, nil, ct.c, pub.N).Cmp(big.NewInt(1)) == 0
+}
+
+// Rerandomize returns a fresh ciphertext of the same plaintext by multiplying
+// in r^n for a new random r, so that forwarded ciphertexts cannot be linked.
+func (ct *Ciphertext) Rerandomize(pub *PublicKey) (*Ciphertext, error) {
+    k, err := pub.nat()
     if err != nil {
-        return nil, nil, err
+        return nil, err
     }
-    q, err := rand.Prime(rand.Reader, bitLen/2)
+    c, err := k.ciphertext(ct)
     if err != nil {
-        return nil, nil, err
+        return nil, err
+    }
+    r, err := SampleUnit(pub.N)
+    if err != nil {
+        return nil, err
+    }
+    // c' = c * r^n mod n^2
+    cNew := k.mask(k.plaintext(r))
+    cNew.ModMul(c, cNew, k.nSquare)
+    return &Ciphertext{c: cNew.Big()}, nil
+}
+
+// KeyOptions controls how GenerateKeyWithOptions chooses the prime factors.
+type KeyOptions struct {
+    // BitLen is the bit length of the modulus n (e.g., 2048 or 3072).
+    BitLen     int
+    // SafePrimes requests p = 2p'+1 and q = 2q'+1 with p', q' prime,
+    // which makes n a Blum integer as required by the ZK proofs.
+    SafePrimes bool
+}
+
+// MinSafePrimeBitLen is the smallest modulus GenerateKeyWithOptions accepts
+// with SafePrimes. Smaller sizes do not have two distinct safe primes of
+// equal length.
+const MinSafePrimeBitLen = 20
+
+// GenerateKey generates a Paillier keypair of specified bit length (e.g., 2048 or 3072)
+func GenerateKey(bitLen int) (*PublicKey, *PrivateKey, error) {
+    return GenerateKeyWithOptions(KeyOptions{BitLen: bitLen})
+}
+
+// GenerateKeyWithOptions generates a Paillier keypair whose factors have
+// equal bit lengths, are distinct and satisfy gcd(n, φ(n)) = 1.
+func GenerateKeyWithOptions(opts KeyOptions) (*PublicKey, *PrivateKey, error) {
+    if opts.BitLen < 16 || opts.BitLen%2 != 0 {
+        return nil, nil, errors.New("modulus bit length must be an even number of at least 16 bits")
+    }
+    // 8ビット以下の安全素数は1つしかなく p ≠ q を満たせないため、安全素数は10ビット以上とする
+    if opts.SafePrimes && opts.BitLen < MinSafePrimeBitLen {
+        return nil, nil, errors.New("modulus with safe primes must be at least 20 bits")
+    }
+    primeBits := opts.BitLen / 2
+
+    // 1. 素数p, qの生成（|p| = |q|、p ≠ q、gcd(n, φ(n)) = 1 となるまで繰り返す）
+    var p, q *big.Int
+    for {
+        var err error
+        if p, err = generatePrime(primeBits, opts.SafePrimes); err != nil {
+            return nil, nil, err
+        }
+        if q, err = generatePrime(primeBits, opts.SafePrimes); err != nil {
+            return nil, nil, err
+        }
+        if p.Cmp(q) == 0 {
+            continue
+        }
+        n := new(big.Int).Mul(p, q)
+        if n.BitLen() != opts.BitLen {
+            continue
+        }
+        phi := new(big.Int).Mul(new(big.Int).Sub(p, big.NewInt(1)), new(big.Int).Sub(q, big.NewInt(1)))
+        if new(big.Int).GCD(nil, nil, n, phi).Cmp(big.NewInt(1)) == 0 {
+            break
+        }
     }
     n := new(big.Int).Mul(p, q)
     nSquare := new(big.Int).Mul(n, n)
@@ -125,62 +336,254 @@ func GenerateKey(bitLen int) (*PublicKey, *PrivateKey, error) {
         PublicKey: *pub,
         Lambda:    lambda,
         Mu:        mu,
+        P:         p,
+        Q:         q,
+    }
+
+    // 5. CRT復号用の定数を事前計算
+    if err := priv.Precompute(); err != nil {
+        return nil, nil, err
     }
 
     return pub, priv, nil
 }
 
-// Encrypt encrypts plaintext m ∈ [0, n)
+// generatePrime returns a random prime of exactly bits bits.
+func generatePrime(bits int, safe bool) (*big.Int, error) {
+    if safe {
+        return safePrime(rand.Reader, bits)
+    }
+    return rand.Prime(rand.Reader, bits)
+}
+
+// SampleUnit draws r uniformly at random from Z*_n.
+func SampleUnit(n *big.Int) (*big.Int, error) {
+    one := big.NewInt(1)
+    if n.Cmp(one) <= 0 {
+        return nil, errors.New("modulus must be greater than 1")
+    }
+    for {
+        r, err := rand.Int(rand.Reader, n)
+        if err != nil {
+            return nil, err
+        }
+        if r.Sign() > 0 && new(big.Int).GCD(nil, nil, r, n).Cmp(one) == 0 {
+            return r, nil
+        }
+    }
+}
+
+// Encrypt encrypts plaintext m ∈ [0, n) with a fresh nonce
 func (pub *PublicKey) Encrypt(m *big.Int) (*Ciphertext, error) {
-    if m.Cmp(pub.N) >= 0 {
-        return nil, errors.New("plaintext too large")
+    if _, err := pub.nat(); err != nil {
+        return nil, err
     }
-    // 1. 乱数 r ∈ [1, n), gcd(r,n)=1
-    r, err := rand.Prime(rand.Reader, pub.N.BitLen())
+    // 1. 乱数 r ∈ Z*_n
+    r, err := SampleUnit(pub.N)
     if err != nil {
         return nil, err
     }
+    return pub.EncryptWithNonce(m, r)
+}
+
+// EncryptWithNonce encrypts plaintext m ∈ [0, n) using the caller-provided
+// nonce r ∈ Z*_n, so that the nonce can be reused in proofs.
+func (pub *PublicKey) EncryptWithNonce(m, r *big.Int) (*Ciphertext, error) {
+    k, err := pub.nat()
+    if err != nil {
+        return nil, err
+    }
+    if m.Sign() < 0 || m.Cmp(pub.N) >= 0 {
+        return nil, errors.New("plaintext out of range")
+    }
+    rr := k.plaintext(r)
+    if r.Sign() <= 0 || r.Cmp(pub.N) >= 0 || rr.IsUnit(k.n) != 1 {
+        return nil, errors.New("nonce is not in Z*_n")
+    }
     // 2. c = g^m * r^n mod n^2
-    gm := new(big.Int).Exp(pub.G, m, pub.NSquare)
-    rn := new(big.Int).Exp(r, pub.N, pub.NSquare)
-    ct := &Ciphertext{new(big.Int).Mod(new(big.Int).Mul(gm, rn), pub.NSquare)}
-    return ct, nil
+    c := k.encrypt(k.plaintext(m), rr)
+    return &Ciphertext{c.Big()}, nil
 }
 
-// Decrypt recovers plaintext from ciphertext c
+// Decrypt recovers plaintext from ciphertext c.
+// When the prime factors are known it uses two half-size exponentiations (CRT),
+// otherwise it falls back to the full c^λ mod n^2 exponentiation.
 func (priv *PrivateKey) Decrypt(ct *Ciphertext) (*big.Int, error) {
+    k, err := priv.nat()
+    if err != nil {
+        return nil, err
+    }
+    c, err := k.ciphertext(ct)
+    if err != nil {
+        return nil, err
+    }
     if ct.c.Cmp(priv.NSquare) >= 0 {
         return nil, errors.New("ciphertext too large")
     }
-    // m = L(c^λ mod n^2) * μ mod n
-    u := new(big.Int).Exp(ct.c, priv.Lambda, priv.NSquare)
-    l := L(u, priv.N)
-    m := new(big.Int).Mod(new(big.Int).Mul(l, priv.Mu), priv.N)
+    if priv.precomputed != nil {
+        return priv.decryptCRT(c), nil
+    }
+    if priv.Lambda == nil || priv.Mu == nil {
+        return nil, errors.New("private key is incomplete")
+    }
+    return priv.decryptLambda(k, c), nil
+}
+
+// Open decrypts ct and also recovers its nonce r, so that anyone can check
+// the plaintext with VerifyOpening without the private key.
+func (priv *PrivateKey) Open(ct *Ciphertext) (*big.Int, *big.Int, error) {
+    m, err := priv.Decrypt(ct)
+    if err != nil {
+        return nil, nil, err
+    }
+    if priv.Lambda == nil {
+        return nil, nil, errors.New("private key is incomplete")
+    }
+    // c ≡ r^n (mod n) なので r = c^(n^-1 mod λ) mod n
+    nInv := new(big.Int).ModInverse(priv.N, priv.Lambda)
+    if nInv == nil {
+        return nil, nil, errors.New("modulus is not invertible modulo lambda")
+    }
+    r := new(big.Int).Mod(ct.c, priv.N)
+    return m, r.Exp(r, nInv, priv.N), nil
+}
+
+// VerifyOpening reports whether ct = Enc(m; r) under pub.
+func (pub *PublicKey) VerifyOpening(ct *Ciphertext, m, r *big.Int) bool {
+    if !pub.IsValidCiphertext(ct) || m == nil || r == nil {
+        return false
+    }
+    expected, err := pub.EncryptWithNonce(m, r)
+    return err == nil && expected.c.Cmp(ct.c) == 0
+}
+
+// DecryptSigned decrypts ct and maps plaintexts in (n/2, n) to the negative
+// integers m - n, matching the signed encoding used by AddScalar and MulScalar.
+func (priv *PrivateKey) DecryptSigned(ct *Ciphertext) (*big.Int, error) {
+    m, err := priv.Decrypt(ct)
+    if err != nil {
+        return nil, err
+    }
+    if m.Cmp(new(big.Int).Rsh(priv.N, 1)) > 0 {
+        m.Sub(m, priv.N)
+    }
     return m, nil
 }
 
+// decryptLambda computes m = L(c^λ mod n^2) * μ mod n.
+func (priv *PrivateKey) decryptLambda(k *natPublicKey, c *saferith.Nat) *big.Int {
+    // λ は実際の長さではなく |n| ビットとして扱い、長さも漏らさない
+    u := new(saferith.Nat).Exp(c, k.plaintext(priv.Lambda), k.nSquare)
+    l := natL(u, k.n)
+    return l.ModMul(l, k.plaintext(priv.Mu), k.n).Big()
+}
+
+// decryptCRT computes m mod p and m mod q separately and recombines them.
+func (priv *PrivateKey) decryptCRT(c *saferith.Nat) *big.Int {
+    pv := priv.precomputed
+    // mp = L_p(c^(p-1) mod p^2) * hp mod p
+    mp := natL(new(saferith.Nat).Exp(c, pv.p1, pv.pSquare), pv.p)
+    mp.ModMul(mp, pv.hp, pv.p)
+    // mq = L_q(c^(q-1) mod q^2) * hq mod q
+    mq := natL(new(saferith.Nat).Exp(c, pv.q1, pv.qSquare), pv.q)
+    mq.ModMul(mq, pv.hq, pv.q)
+    // m = mq + q * ((mp - mq) * qInv mod p)
+    h := new(saferith.Nat).ModSub(mp, new(saferith.Nat).Mod(mq, pv.p), pv.p)
+    h.ModMul(h, pv.qInv, pv.p)
+    bits := priv.N.BitLen()
+    m := new(saferith.Nat).Mul(h, pv.q.Nat(), bits)
+    return m.Add(m, mq, bits).Big()
+}
+
+// AddScalar returns Enc(m1 + m) for a signed scalar m.
 func (ct *Ciphertext) AddScalar(pub *PublicKey, m *big.Int) (*Ciphertext, error) {
-    if m.Sign() < 0 {
-        return nil, errors.New("scalar must be non-negative")
+    k, err := pub.nat()
+    if err != nil {
+        return nil, err
     }
+    c, err := k.ciphertext(ct)
+    if err != nil {
+        return nil, err
+    }
+    // 負のスカラーは m mod n として扱う
+    mm := new(big.Int).Mod(m, pub.N)
     // g^m mod n^2
-    gm := new(big.Int).Exp(pub.G, m, pub.NSquare)
+    gm := new(saferith.Nat).Exp(k.g, k.plaintext(mm), k.nSquare)
     // c' = c * g^m mod n^2
-    cNew := new(big.Int).Mod(new(big.Int).Mul(ct.c, gm), pub.NSquare,)
-    return &Ciphertext{c: cNew}, nil
+    cNew := gm.ModMul(c, gm, k.nSquare)
+    return &Ciphertext{c: cNew.Big()}, nil
+}
+
+// SubScalar returns Enc(m1 - m) for a signed scalar m.
+func (ct *Ciphertext) SubScalar(pub *PublicKey, m *big.Int) (*Ciphertext, error) {
+    return ct.AddScalar(pub, new(big.Int).Neg(m))
 }
 
 func (ct *Ciphertext) Add(pub *PublicKey, ct2 *Ciphertext) (*Ciphertext, error) {
+    k, err := pub.nat()
+    if err != nil {
+        return nil, err
+    }
+    c1, err := k.ciphertext(ct)
+    if err != nil {
+        return nil, err
+    }
+    c2, err := k.ciphertext(ct2)
+    if err != nil {
+        return nil, err
+    }
     // c' = c1 * c2 mod n^2
-    cNew := new(big.Int).Mod(new(big.Int).Mul(ct.c, ct2.c), pub.NSquare)
-    return &Ciphertext{c: cNew}, nil
+    cNew := new(saferith.Nat).ModMul(c1, c2, k.nSquare)
+    return &Ciphertext{c: cNew.Big()}, nil
+}
+
+// Neg returns Enc(-m1).
+func (ct *Ciphertext) Neg(pub *PublicKey) (*Ciphertext, error) {
+    k, err := pub.nat()
+    if err != nil {
+        return nil, err
+    }
+    c, err := k.ciphertext(ct)
+    if err != nil {
+        return nil, err
+    }
+    c.Mod(c, k.nSquare)
+    if c.IsUnit(k.nSquare) != 1 {
+        return nil, errors.New("ciphertext is not invertible")
+    }
+    // c' = c^-1 mod n^2
+    return &Ciphertext{c: c.ModInverse(c, k.nSquare).Big()}, nil
+}
+
+// Sub returns Enc(m1 - m2).
+func (ct *Ciphertext) Sub(pub *PublicKey, ct2 *Ciphertext) (*Ciphertext, error) {
+    neg, err := ct2.Neg(pub)
+    if err != nil {
+        return nil, err
+    }
+    return ct.Add(pub, neg)
 }
 
+// MulScalar returns Enc(m1 * k) for a signed scalar k.
+// The exponentiation leaks only the sign and bit length of k.
 func (ct *Ciphertext) MulScalar(pub *PublicKey, k *big.Int) (*Ciphertext, error) {
+    nk, err := pub.nat()
+    if err != nil {
+        return nil, err
+    }
+    c, err := nk.ciphertext(ct)
+    if err != nil {
+        return nil, err
+    }
+    abs := new(big.Int).Abs(k)
+    // c' = c^|k| mod n^2
+    cNew := new(saferith.Nat).Exp(c, natFromBig(abs, abs.BitLen()), nk.nSquare)
     if k.Sign() < 0 {
-        return nil, errors.New("scalar must be non-negative")
+        // k < 0 の場合は逆元をとる
+        if cNew.IsUnit(nk.nSquare) != 1 {
+            return nil, errors.New("ciphertext is not invertible")
+        }
+        cNew.ModInverse(cNew, nk.nSquare)
     }
-    // c' = c^k mod n^2
-    cNew := new(big.Int).Exp(ct.c, k, pub.NSquare)
-    return &Ciphertext{c: cNew}, nil
-}
\ No newline at end of file
+    return &Ciphertext{c: cNew.Big()}, nil
+}
@@ -1,22 +1,470 @@
 package paillier
 
 import (
+	"crypto/rand"
 	"math/big"
 	"testing"
+
+	pb "multisigservice/proto/paillierpb"
 )
 
-func TestEncryptDecrypt(t *testing.T) () {
+func TestEncryptDecrypt(t *testing.T) {
 	plaintext := big.NewInt(int64(123456789))
 
-	pub := &PublicKey{}
-	priv := &PrivateKey{}
-	pub, priv, _ = GenerateKey(2048)
+	pub, priv, err := GenerateKey(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	ciphertext, _ := pub.Encrypt(plaintext)
-	
-	result, _ := priv.Decrypt(ciphertext)
+	ciphertext, err := pub.Encrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	if plaintext.Int64() != result.Int64() {
+	result, err := priv.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plaintext.Cmp(result) != 0 {
 		t.Errorf("got %v\nwant %v", result, plaintext)
 	}
-}
\ No newline at end of file
+}
+
+func TestDecryptCRTMatchesLambda(t *testing.T) {
+	_, priv, err := GenerateKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := &PrivateKey{PublicKey: priv.PublicKey, Lambda: priv.Lambda, Mu: priv.Mu}
+
+	for _, v := range []int64{0, 1, 42, 123456789} {
+		m := big.NewInt(v)
+		ct, err := priv.Encrypt(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := priv.Decrypt(ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := legacy.Decrypt(ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Cmp(m) != 0 || want.Cmp(m) != 0 {
+			t.Errorf("crt %v, lambda %v, want %v", got, want, m)
+		}
+	}
+}
+
+func TestPrivateKeyProtoWithoutFactors(t *testing.T) {
+	_, priv, err := GenerateKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := priv.ToProto()
+
+	restored, err := PrivateKeyFromProto(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.P == nil || restored.precomputed == nil {
+		t.Fatal("factors were not restored from protobuf")
+	}
+
+	// 旧形式（素因数なし）の鍵でも復号できること
+	msg.P, msg.Q = nil, nil
+	old, err := PrivateKeyFromProto(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old.P != nil || old.precomputed != nil {
+		t.Fatal("legacy key unexpectedly has factors")
+	}
+
+	m := big.NewInt(987654321)
+	ct, err := priv.Encrypt(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sk := range []*PrivateKey{restored, old} {
+		got, err := sk.Decrypt(ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Cmp(m) != 0 {
+			t.Errorf("got %v\nwant %v", got, m)
+		}
+	}
+}
+
+func TestPrivateKeyFromProtoValidation(t *testing.T) {
+	_, priv, err := GenerateKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	one := big.NewInt(1)
+	for name, tamper := range map[string]func(msg *pb.PrivateKey){
+		"no public key": func(msg *pb.PrivateKey) { msg.PublicKey = nil },
+		"empty lambda":  func(msg *pb.PrivateKey) { msg.Lambda = nil },
+		"empty mu":      func(msg *pb.PrivateKey) { msg.Mu = nil },
+		"lambda >= n":   func(msg *pb.PrivateKey) { msg.Lambda = new(big.Int).Add(priv.N, priv.Lambda).Bytes() },
+		"wrong mu":      func(msg *pb.PrivateKey) { msg.Mu = new(big.Int).Add(priv.Mu, one).Bytes() },
+		"wrong p":       func(msg *pb.PrivateKey) { msg.P = new(big.Int).Add(priv.P, big.NewInt(2)).Bytes() },
+		"only p":        func(msg *pb.PrivateKey) { msg.Q = nil },
+		"wrong g":       func(msg *pb.PrivateKey) { msg.PublicKey.G = one.Bytes() },
+	} {
+		msg := priv.ToProto()
+		tamper(msg)
+		if _, err := PrivateKeyFromProto(msg); err == nil {
+			t.Errorf("%s: invalid key accepted", name)
+		}
+	}
+}
+
+func benchmarkDecrypt(b *testing.B, bitLen int, crt bool) {
+	_, priv, err := GenerateKey(bitLen)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if !crt {
+		priv = &PrivateKey{PublicKey: priv.PublicKey, Lambda: priv.Lambda, Mu: priv.Mu}
+	}
+	ct, err := priv.Encrypt(big.NewInt(123456789))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := priv.Decrypt(ct); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecryptLambda2048(b *testing.B) { benchmarkDecrypt(b, 2048, false) }
+func BenchmarkDecryptCRT2048(b *testing.B)    { benchmarkDecrypt(b, 2048, true) }
+func BenchmarkDecryptLambda3072(b *testing.B) { benchmarkDecrypt(b, 3072, false) }
+func BenchmarkDecryptCRT3072(b *testing.B)    { benchmarkDecrypt(b, 3072, true) }
+
+func TestGenerateKeyWithSafePrimes(t *testing.T) {
+	_, priv, err := GenerateKeyWithOptions(KeyOptions{BitLen: 512, SafePrimes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if priv.N.BitLen() != 512 || priv.P.BitLen() != priv.Q.BitLen() {
+		t.Fatalf("unexpected sizes: n=%d p=%d q=%d", priv.N.BitLen(), priv.P.BitLen(), priv.Q.BitLen())
+	}
+	if priv.P.Cmp(priv.Q) == 0 {
+		t.Fatal("p equals q")
+	}
+	for _, f := range []*big.Int{priv.P, priv.Q} {
+		half := new(big.Int).Rsh(f, 1)
+		if !f.ProbablyPrime(20) || !half.ProbablyPrime(20) {
+			t.Errorf("%v is not a safe prime", f)
+		}
+		if f.Bit(0) != 1 || f.Bit(1) != 1 {
+			t.Errorf("%v is not 3 mod 4", f)
+		}
+	}
+
+	m := big.NewInt(31337)
+	ct, err := priv.Encrypt(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := priv.Decrypt(ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Cmp(m) != 0 {
+		t.Errorf("got %v\nwant %v", got, m)
+	}
+}
+
+func TestGenerateKeyWithSmallSafePrimes(t *testing.T) {
+	// ふるいに使う素数より小さな安全素数も見つかること
+	for bits := 6; bits <= 14; bits++ {
+		p, err := safePrime(rand.Reader, bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.BitLen() != bits || !p.ProbablyPrime(20) || !new(big.Int).Rsh(p, 1).ProbablyPrime(20) {
+			t.Errorf("%d bits: %v is not a safe prime of the requested size", bits, p)
+		}
+	}
+	for _, bitLen := range []int{MinSafePrimeBitLen, 24, 32} {
+		_, priv, err := GenerateKeyWithOptions(KeyOptions{BitLen: bitLen, SafePrimes: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if priv.N.BitLen() != bitLen || priv.P.Cmp(priv.Q) == 0 {
+			t.Errorf("%d bits: unexpected key n=%v p=%v q=%v", bitLen, priv.N, priv.P, priv.Q)
+		}
+	}
+	if _, _, err := GenerateKeyWithOptions(KeyOptions{BitLen: 16, SafePrimes: true}); err == nil {
+		t.Error("safe-prime modulus below the minimum was accepted")
+	}
+}
+
+func TestPublicKeyValidate(t *testing.T) {
+	pub, _, err := GenerateKey(MinBitLen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pub.Validate(); err != nil {
+		t.Fatalf("valid key rejected: %v", err)
+	}
+
+	smallPub, _, err := GenerateKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	evenN := new(big.Int).Lsh(pub.N, 1)
+
+	cases := map[string]*PublicKey{
+		"too small": smallPub,
+		"even": {
+			N:       evenN,
+			NSquare: new(big.Int).Mul(evenN, evenN),
+			G:       new(big.Int).Add(evenN, big.NewInt(1)),
+		},
+		"bad generator": {
+			N:       pub.N,
+			NSquare: pub.NSquare,
+			G:       big.NewInt(2),
+		},
+		"bad nsquare": {
+			N:       pub.N,
+			NSquare: new(big.Int).Add(pub.NSquare, big.NewInt(1)),
+			G:       pub.G,
+		},
+		"incomplete": {N: pub.N},
+	}
+	for name, pk := range cases {
+		if err := pk.Validate(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestEncryptWithNonce(t *testing.T) {
+	pub, priv, err := GenerateKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := big.NewInt(555)
+
+	for i := 0; i < 5; i++ {
+		r, err := SampleUnit(pub.N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Sign() <= 0 || r.Cmp(pub.N) >= 0 {
+			t.Fatalf("nonce %v out of range", r)
+		}
+		ct, err := pub.EncryptWithNonce(m, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := priv.Decrypt(ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Cmp(m) != 0 {
+			t.Errorf("got %v\nwant %v", got, m)
+		}
+
+		// 同じ nonce なら同じ暗号文になる
+		again, err := pub.EncryptWithNonce(m, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.c.Cmp(ct.c) != 0 {
+			t.Error("encryption with the same nonce is not deterministic")
+		}
+	}
+
+	// r = 1 も Z*_n の元として有効
+	ct, err := pub.EncryptWithNonce(m, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := priv.Decrypt(ct); got.Cmp(m) != 0 {
+		t.Errorf("got %v\nwant %v", got, m)
+	}
+
+	for _, r := range []*big.Int{big.NewInt(0), pub.N, new(big.Int).Set(priv.P), big.NewInt(-1)} {
+		if _, err := pub.EncryptWithNonce(m, r); err == nil {
+			t.Errorf("nonce %v accepted", r)
+		}
+	}
+	if _, err := pub.EncryptWithNonce(pub.N, big.NewInt(1)); err == nil {
+		t.Error("plaintext n accepted")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	pub, priv, err := GenerateKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 準同型演算で得た暗号文も乱数ごと開示できる
+	ct, err := pub.Encrypt(big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct, err = ct.MulScalar(pub, big.NewInt(6)); err != nil {
+		t.Fatal(err)
+	}
+	m, r, err := priv.Open(ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Cmp(big.NewInt(42)) != 0 {
+		t.Errorf("got %v\nwant %v", m, 42)
+	}
+	if !pub.VerifyOpening(ct, m, r) {
+		t.Error("opening does not verify")
+	}
+	if pub.VerifyOpening(ct, big.NewInt(43), r) {
+		t.Error("opening with another plaintext was accepted")
+	}
+	if pub.VerifyOpening(ct, m, new(big.Int).Add(r, big.NewInt(1))) {
+		t.Error("opening with another nonce was accepted")
+	}
+}
+
+func TestSignedHomomorphicOps(t *testing.T) {
+	pub, priv, err := GenerateKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := big.NewInt(1000), big.NewInt(3000)
+	ctA, err := pub.Encrypt(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctB, err := pub.Encrypt(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	neg, err := ctA.Neg(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := ctA.Sub(pub, ctB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subScalar, err := ctA.SubScalar(pub, big.NewInt(1500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addNeg, err := ctA.AddScalar(pub, big.NewInt(-1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mulNeg, err := ctA.MulScalar(pub, big.NewInt(-7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// k·x − β
+	kx, err := ctA.MulScalar(pub, big.NewInt(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kxMinusBeta, err := kx.SubScalar(pub, big.NewInt(5000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		ct   *Ciphertext
+		want int64
+	}{
+		{"neg", neg, -1000},
+		{"sub", sub, -2000},
+		{"subScalar", subScalar, -500},
+		{"addScalar negative", addNeg, 999},
+		{"mulScalar negative", mulNeg, -7000},
+		{"k*x - beta", kxMinusBeta, -2000},
+	}
+	for _, tc := range cases {
+		got, err := priv.DecryptSigned(tc.ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Int64() != tc.want || !got.IsInt64() {
+			t.Errorf("%s: got %v\nwant %v", tc.name, got, tc.want)
+		}
+	}
+
+	// Decrypt は負の値を n - |m| として返す
+	got, err := priv.Decrypt(neg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := new(big.Int).Sub(pub.N, a); got.Cmp(want) != 0 {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+}
+
+func TestRerandomize(t *testing.T) {
+	pub, priv, err := GenerateKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := big.NewInt(424242)
+	ct, err := pub.Encrypt(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := ct.Rerandomize(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.c.Cmp(ct.c) == 0 {
+		t.Fatal("rerandomized ciphertext is unchanged")
+	}
+	if !pub.IsValidCiphertext(fresh) {
+		t.Fatal("rerandomized ciphertext is invalid")
+	}
+	got, err := priv.Decrypt(fresh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Cmp(m) != 0 {
+		t.Errorf("got %v\nwant %v", got, m)
+	}
+}
+
+func TestCiphertextFromProtoValidation(t *testing.T) {
+	pub, _, err := GenerateKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, err := pub.Encrypt(big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CiphertextFromProto(pub, ct.ToProto()); err != nil {
+		t.Fatalf("valid ciphertext rejected: %v", err)
+	}
+
+	invalid := map[string]*pb.Ciphertext{
+		"nil":           nil,
+		"zero":          {C: nil},
+		"n^2":           {C: pub.NSquare.Bytes()},
+		"too large":     {C: new(big.Int).Add(pub.NSquare, big.NewInt(5)).Bytes()},
+		"multiple of n": {C: new(big.Int).Mul(pub.N, big.NewInt(3)).Bytes()},
+	}
+	for name, msg := range invalid {
+		if _, err := CiphertextFromProto(pub, msg); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
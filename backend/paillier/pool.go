@@ -0,0 +1,120 @@
+package paillier
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/cronokirby/saferith"
+)
+
+// RandomnessPool keeps precomputed values of r^n mod n^2 for one public key,
+// so that Encrypt only pays for the message-dependent part. Each value is
+// handed out exactly once through the channel.
+//
+// The pool is a separate object rather than a field of PublicKey, which is
+// copied by value (embedded in PrivateKey, returned from decoders); it is
+// safe for concurrent use, including Stop concurrently with Encrypt.
+type RandomnessPool struct {
+	pub    *PublicKey
+	k      *natPublicKey
+	values chan *saferith.Nat
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewRandomnessPool starts workers goroutines that keep up to size values of
+// r^n mod n^2 ready for Encrypt. The workers stop when ctx is cancelled or
+// Stop is called; values already computed remain usable.
+func NewRandomnessPool(ctx context.Context, pub *PublicKey, size, workers int) (*RandomnessPool, error) {
+	if size <= 0 || workers <= 0 {
+		return nil, errors.New("pool size and worker count must be positive")
+	}
+	if pub == nil || pub.N == nil {
+		return nil, errors.New("public key is incomplete")
+	}
+	// 呼び出し側が鍵を書き換えても影響しないよう複製を持つ
+	pub = newPublicKey(pub.N)
+	k, err := pub.nat()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	pool := &RandomnessPool{
+		pub:    pub,
+		k:      k,
+		values: make(chan *saferith.Nat, size),
+		cancel: cancel,
+	}
+	for i := 0; i < workers; i++ {
+		pool.wg.Add(1)
+		go func() {
+			defer pool.wg.Done()
+			pool.fill(ctx)
+		}()
+	}
+	return pool, nil
+}
+
+// Stop stops the workers and waits for them to exit. It may be called more
+// than once. Encrypt keeps working afterwards and computes r^n on-line once
+// the remaining values are used up.
+func (p *RandomnessPool) Stop() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+// Len reports how many precomputed values are currently available.
+func (p *RandomnessPool) Len() int {
+	return len(p.values)
+}
+
+// PublicKey returns the key the pool encrypts under.
+func (p *RandomnessPool) PublicKey() *PublicKey {
+	return newPublicKey(p.pub.N)
+}
+
+// fill computes r^n mod n^2 until ctx is done, blocking while the pool is full.
+func (p *RandomnessPool) fill(ctx context.Context) {
+	for {
+		r, err := SampleUnit(p.pub.N)
+		if err != nil {
+			return
+		}
+		rn := p.k.mask(p.k.plaintext(r))
+		select {
+		case p.values <- rn:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// take returns a precomputed value, or nil if none is ready.
+func (p *RandomnessPool) take() *saferith.Nat {
+	select {
+	case rn := <-p.values:
+		return rn
+	default:
+		return nil
+	}
+}
+
+// Encrypt encrypts plaintext m ∈ [0, n) like PublicKey.Encrypt, but uses a
+// value from the pool when one is ready and otherwise computes r^n on-line.
+func (p *RandomnessPool) Encrypt(m *big.Int) (*Ciphertext, error) {
+	if m.Sign() < 0 || m.Cmp(p.pub.N) >= 0 {
+		return nil, errors.New("plaintext out of range")
+	}
+	rn := p.take()
+	if rn == nil {
+		r, err := SampleUnit(p.pub.N)
+		if err != nil {
+			return nil, err
+		}
+		rn = p.k.mask(p.k.plaintext(r))
+	}
+	return &Ciphertext{c: p.k.encryptMasked(p.k.plaintext(m), rn).Big()}, nil
+}
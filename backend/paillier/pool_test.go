@@ -0,0 +1,191 @@
+package paillier
+
+import (
+	"context"
+	"math/big"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForPool は事前計算プールが want 個まで埋まるのを待ちます。
+func waitForPool(tb testing.TB, pool *RandomnessPool, want int) {
+	tb.Helper()
+	deadline := time.Now().Add(time.Minute)
+	for pool.Len() < want {
+		if time.Now().After(deadline) {
+			tb.Fatalf("pool has %d values, want %d", pool.Len(), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRandomnessPoolEncrypt(t *testing.T) {
+	pub, priv, err := GenerateKey(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, err := NewRandomnessPool(context.Background(), pub, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(m *big.Int) *Ciphertext {
+		t.Helper()
+		ct, err := pool.Encrypt(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := priv.Decrypt(ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Cmp(m) != 0 {
+			t.Errorf("got %v\nwant %v", got, m)
+		}
+		return ct
+	}
+
+	waitForPool(t, pool, 4)
+
+	// 同じ平文でもプールの値は使い回されないこと
+	m := big.NewInt(123456789)
+	seen := make(map[string]*Ciphertext)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ct, err := pool.Encrypt(m)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if seen[ct.c.String()] != nil {
+				t.Error("ciphertext repeated")
+			}
+			seen[ct.c.String()] = ct
+		}()
+	}
+	wg.Wait()
+	for _, ct := range seen {
+		got, err := priv.Decrypt(ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Cmp(m) != 0 {
+			t.Errorf("got %v\nwant %v", got, m)
+		}
+	}
+
+	// 停止後も残りの値とオンライン計算で暗号化でき、Stop は何度呼んでもよいこと
+	pool.Stop()
+	pool.Stop()
+	for i := 0; i < 6; i++ {
+		check(new(big.Int).Sub(pub.N, big.NewInt(1)))
+	}
+	if pool.Len() != 0 {
+		t.Errorf("got %d pooled values after stop\nwant 0", pool.Len())
+	}
+
+	if _, err := pool.Encrypt(pub.N); err == nil {
+		t.Fatal("plaintext n should be rejected")
+	}
+}
+
+func TestRandomnessPoolStopDuringEncrypt(t *testing.T) {
+	pub, priv, err := GenerateKey(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, err := NewRandomnessPool(context.Background(), pub, 8, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 暗号化と並行して停止しても競合しないこと（go test -race で確認）
+	m := big.NewInt(42)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 8; j++ {
+				ct, err := pool.Encrypt(m)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if got, err := priv.Decrypt(ct); err != nil || got.Cmp(m) != 0 {
+					t.Errorf("got %v, %v\nwant %v", got, err, m)
+				}
+			}
+		}()
+	}
+	pool.Stop()
+	wg.Wait()
+}
+
+func TestRandomnessPoolContextCancel(t *testing.T) {
+	pub, _, err := GenerateKey(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	pool, err := NewRandomnessPool(ctx, pub, 2, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForPool(t, pool, 2)
+	cancel()
+
+	// キャンセル後はワーカーが終了し、Stop がすぐに戻ること
+	done := make(chan struct{})
+	go func() {
+		pool.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("workers did not stop after cancellation")
+	}
+
+	if _, err := NewRandomnessPool(context.Background(), pub, 0, 1); err == nil {
+		t.Fatal("zero-sized pool should be rejected")
+	}
+}
+
+// benchmarkConcurrentEncrypt は複数の署名セッションが同時に暗号化する状況を再現します。
+func benchmarkConcurrentEncrypt(b *testing.B, bitLen int, pooled bool) {
+	pub, _, err := GenerateKey(bitLen)
+	if err != nil {
+		b.Fatal(err)
+	}
+	encrypt := pub.Encrypt
+	if pooled {
+		workers := runtime.GOMAXPROCS(0)
+		pool, err := NewRandomnessPool(context.Background(), pub, 256, workers)
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer pool.Stop()
+		waitForPool(b, pool, 256)
+		encrypt = pool.Encrypt
+	}
+	m := big.NewInt(123456789)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := encrypt(m); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkEncryptConcurrent2048(b *testing.B)     { benchmarkConcurrentEncrypt(b, 2048, false) }
+func BenchmarkEncryptFastConcurrent2048(b *testing.B) { benchmarkConcurrentEncrypt(b, 2048, true) }
@@ -0,0 +1,77 @@
+package paillier
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// smallPrimes are the odd primes used to sieve safe-prime candidates before
+// running the expensive primality tests.
+var smallPrimes = func() []uint64 {
+	var primes []uint64
+	for i := uint64(3); i < 1<<12; i += 2 {
+		if big.NewInt(int64(i)).ProbablyPrime(0) {
+			primes = append(primes, i)
+		}
+	}
+	return primes
+}()
+
+// safePrime returns a random safe prime p = 2p'+1 of exactly bits bits,
+// where p' is also prime. Such a p always satisfies p ≡ 3 mod 4.
+func safePrime(random io.Reader, bits int) (*big.Int, error) {
+	// 5ビット以下には上位2ビットが立った安全素数がない
+	if bits < 6 {
+		return nil, errors.New("safe prime size must be at least 6 bits")
+	}
+
+	// ふるいには p' の最小値 3·2^(bits-3) 未満の素数だけを使う。
+	// それ以上の素数 sp では p' = sp や 2p'+1 = sp となる素数自体を除外してしまう
+	sieve := smallPrimes
+	for i, sp := range smallPrimes {
+		if bits < 64 && sp >= 3<<uint(bits-3) {
+			sieve = smallPrimes[:i]
+			break
+		}
+	}
+
+	one := big.NewInt(1)
+	for {
+		// p' は bits-1 ビットの奇数で、上位2ビットを立てて p のビット長を固定する
+		pPrime, err := rand.Int(random, new(big.Int).Lsh(one, uint(bits-1)))
+		if err != nil {
+			return nil, err
+		}
+		pPrime.SetBit(pPrime, bits-2, 1)
+		pPrime.SetBit(pPrime, bits-3, 1)
+		pPrime.SetBit(pPrime, 0, 1)
+
+		residues := make([]uint64, len(sieve))
+		for i, sp := range sieve {
+			residues[i] = new(big.Int).Mod(pPrime, new(big.Int).SetUint64(sp)).Uint64()
+		}
+
+		// p' を2ずつ増やしながら p' と 2p'+1 の両方を小さな素数でふるいにかける
+	next:
+		for delta := uint64(0); delta < 1<<20; delta += 2 {
+			for i, sp := range sieve {
+				r := (residues[i] + delta) % sp
+				if r == 0 || r == (sp-1)/2 {
+					continue next
+				}
+			}
+
+			candidate := new(big.Int).Add(pPrime, new(big.Int).SetUint64(delta))
+			if candidate.BitLen() != bits-1 {
+				break
+			}
+			p := new(big.Int).Lsh(candidate, 1)
+			p.Add(p, one)
+			if candidate.ProbablyPrime(1) && p.ProbablyPrime(20) && candidate.ProbablyPrime(20) {
+				return p, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package paillier
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	pb "multisigservice/proto/paillierpb"
+)
+
+// ChallengeBits is the bit length of Fiat-Shamir challenges used by the proofs in this package.
+const ChallengeBits = 256
+
+// PedersenParams are the verifier's ring-Pedersen parameters (Ñ, s, t) with
+// s = t^λ mod Ñ. The prover must not know the factorization of Ñ or λ.
+type PedersenParams struct {
+	N *big.Int
+	S *big.Int
+	T *big.Int
+}
+
+// NewPedersenParams derives ring-Pedersen parameters from the verifier's own
+// Paillier key: t = τ^2 mod N for a random τ and s = t^λ mod N.
+func NewPedersenParams(sk *PrivateKey) (*PedersenParams, error) {
+	pp, _, err := newPedersenParams(sk)
+	return pp, err
+}
+
+// newPedersenParams returns the parameters together with λ.
+func newPedersenParams(sk *PrivateKey) (*PedersenParams, *big.Int, error) {
+	tau, err := SampleUnit(sk.N)
+	if err != nil {
+		return nil, nil, err
+	}
+	t := new(big.Int).Exp(tau, big.NewInt(2), sk.N)
+
+	// λ ← Z_φ(N)（φ(N) が不明な旧形式の鍵では λ の倍数で代用する）
+	order := sk.Lambda
+	if sk.P != nil && sk.Q != nil {
+		order = new(big.Int).Mul(new(big.Int).Sub(sk.P, big.NewInt(1)), new(big.Int).Sub(sk.Q, big.NewInt(1)))
+	}
+	lambda, err := rand.Int(rand.Reader, order)
+	if err != nil {
+		return nil, nil, err
+	}
+	s := new(big.Int).Exp(t, lambda, sk.N)
+	return &PedersenParams{N: new(big.Int).Set(sk.N), S: s, T: t}, lambda, nil
+}
+
+// Validate reports whether the parameters are usable elements of Z*_Ñ.
+func (pp *PedersenParams) Validate() error {
+	if pp.N == nil || pp.S == nil || pp.T == nil {
+		return errors.New("pedersen parameters are incomplete")
+	}
+	if pp.N.BitLen() < MinBitLen || pp.N.Bit(0) == 0 {
+		return errors.New("pedersen modulus is invalid")
+	}
+	one := big.NewInt(1)
+	for _, x := range []*big.Int{pp.S, pp.T} {
+		if x.Cmp(one) <= 0 || x.Cmp(pp.N) >= 0 || new(big.Int).GCD(nil, nil, x, pp.N).Cmp(one) != 0 {
+			return errors.New("pedersen parameter is not in Z*_N")
+		}
+	}
+	if pp.S.Cmp(pp.T) == 0 {
+		return errors.New("pedersen parameters s and t must differ")
+	}
+	return nil
+}
+
+// commit computes s^x t^y mod Ñ for signed x, y.
+func (pp *PedersenParams) commit(x, y *big.Int) *big.Int {
+	sx := expSigned(pp.S, x, pp.N)
+	ty := expSigned(pp.T, y, pp.N)
+	return sx.Mod(sx.Mul(sx, ty), pp.N)
+}
+
+// PedersenParams → Protobuf
+func (pp *PedersenParams) ToProto() *pb.PedersenParams {
+	return &pb.PedersenParams{
+		N: pp.N.Bytes(),
+		S: pp.S.Bytes(),
+		T: pp.T.Bytes(),
+	}
+}
+
+// Protobuf → PedersenParams
+func PedersenParamsFromProto(msg *pb.PedersenParams) (*PedersenParams, error) {
+	if msg == nil {
+		return nil, errors.New("pedersen parameters are missing")
+	}
+	pp := &PedersenParams{
+		N: new(big.Int).SetBytes(msg.N),
+		S: new(big.Int).SetBytes(msg.S),
+		T: new(big.Int).SetBytes(msg.T),
+	}
+	if err := pp.Validate(); err != nil {
+		return nil, err
+	}
+	return pp, nil
+}
+
+// contextDomain appends the caller's context (for example the prover's
+// identity and a fresh challenge) to a Fiat-Shamir domain tag, so that a
+// proof made in one context does not verify in another.
+func contextDomain(domain string, ctx []byte) string {
+	return domain + "\x00" + string(ctx)
+}
+
+// expSigned computes x^y mod m, inverting x when y is negative.
+// x must be invertible modulo m.
+func expSigned(x, y, m *big.Int) *big.Int {
+	z := new(big.Int).Exp(x, new(big.Int).Abs(y), m)
+	if y.Sign() < 0 {
+		z.ModInverse(z, m)
+	}
+	return z
+}
+
+// sampleSigned draws x uniformly from [-bound, bound].
+func sampleSigned(bound *big.Int) (*big.Int, error) {
+	width := new(big.Int).Lsh(bound, 1)
+	x, err := rand.Int(rand.Reader, width.Add(width, big.NewInt(1)))
+	if err != nil {
+		return nil, err
+	}
+	return x.Sub(x, bound), nil
+}
+
+// inSignedRange reports whether |x| <= 2^bits.
+func inSignedRange(x *big.Int, bits int) bool {
+	bound := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	return new(big.Int).Abs(x).Cmp(bound) <= 0
+}
+
+// hashToInt derives a Fiat-Shamir value in [0, 2^bits) from the domain tag
+// and the given integers. Each integer is length-prefixed so that the
+// encoding is unambiguous.
+func hashToInt(bits int, domain string, values ...*big.Int) *big.Int {
+	h := sha256.New()
+	writeBytes := func(b []byte) {
+		var length [8]byte
+		binary.BigEndian.PutUint64(length[:], uint64(len(b)))
+		h.Write(length[:])
+		h.Write(b)
+	}
+	writeBytes([]byte(domain))
+	for _, v := range values {
+		// 符号も区別してハッシュする
+		writeBytes([]byte{byte(v.Sign() + 1)})
+		writeBytes(v.Bytes())
+	}
+	seed := h.Sum(nil)
+
+	// 必要なビット数までカウンタモードで伸長する
+	var out []byte
+	for counter := uint32(0); len(out)*8 < bits; counter++ {
+		block := sha256.New()
+		block.Write(seed)
+		var c [4]byte
+		binary.BigEndian.PutUint32(c[:], counter)
+		block.Write(c[:])
+		out = block.Sum(out)
+	}
+	e := new(big.Int).SetBytes(out)
+	return e.Rsh(e, uint(len(out)*8-bits))
+}
+
+// signedToBytes encodes x as a sign byte (0 for x >= 0, 1 for x < 0) followed by |x|.
+func signedToBytes(x *big.Int) []byte {
+	sign := byte(0)
+	if x.Sign() < 0 {
+		sign = 1
+	}
+	return append([]byte{sign}, new(big.Int).Abs(x).Bytes()...)
+}
+
+// signedFromBytes decodes the output of signedToBytes.
+func signedFromBytes(b []byte) (*big.Int, error) {
+	if len(b) == 0 || b[0] > 1 {
+		return nil, errors.New("invalid signed integer encoding")
+	}
+	x := new(big.Int).SetBytes(b[1:])
+	if b[0] == 1 {
+		x.Neg(x)
+	}
+	return x, nil
+}
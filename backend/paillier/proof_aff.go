@@ -0,0 +1,192 @@
+package paillier
+
+import (
+	"errors"
+	"math/big"
+
+	pb "multisigservice/proto/paillierpb"
+)
+
+// AffProof is a non-interactive proof (Π-aff without the group element) that
+// a ciphertext D = C^x·(1+N)^y·ρ^N under the verifier's Paillier key was
+// formed from C with |x| <= 2^ℓx and |y| <= 2^ℓy.
+type AffProof struct {
+	A  *big.Int
+	E  *big.Int
+	S  *big.Int
+	F  *big.Int
+	T  *big.Int
+	Z1 *big.Int
+	Z2 *big.Int
+	Z3 *big.Int
+	Z4 *big.Int
+	W  *big.Int
+}
+
+// ProveAff proves that D = C^x·Enc(y; rho) under pub with |x| <= 2^ellX and
+// |y| <= 2^ellY. pub and ped both belong to the verifier.
+func ProveAff(pub *PublicKey, ped *PedersenParams, C, D *Ciphertext, x, y, rho *big.Int, ellX, ellY int) (*AffProof, error) {
+	if ellX <= 0 || ellY <= 0 || ellX+EncSlackBits+2 > pub.N.BitLen() || ellY+EncSlackBits+2 > pub.N.BitLen() {
+		return nil, errors.New("range is too large for the paillier modulus")
+	}
+	if !inSignedRange(x, ellX) || !inSignedRange(y, ellY) {
+		return nil, errors.New("witness out of range")
+	}
+	if !pub.IsValidCiphertext(C) {
+		return nil, errors.New("invalid ciphertext")
+	}
+
+	boundX := new(big.Int).Lsh(big.NewInt(1), uint(ellX+EncSlackBits))
+	boundY := new(big.Int).Lsh(big.NewInt(1), uint(ellY+EncSlackBits))
+	// 1. α ← ±2^(ℓx+ε), β ← ±2^(ℓy+ε), r ← Z*_N
+	alpha, err := sampleSigned(boundX)
+	if err != nil {
+		return nil, err
+	}
+	beta, err := sampleSigned(boundY)
+	if err != nil {
+		return nil, err
+	}
+	r, err := SampleUnit(pub.N)
+	if err != nil {
+		return nil, err
+	}
+	// γ ← ±2^(ℓx+ε)·Ñ, m ← ±2^ℓx·Ñ, δ ← ±2^(ℓy+ε)·Ñ, μ ← ±2^ℓy·Ñ
+	gamma, err := sampleSigned(new(big.Int).Mul(boundX, ped.N))
+	if err != nil {
+		return nil, err
+	}
+	m, err := sampleSigned(new(big.Int).Lsh(ped.N, uint(ellX)))
+	if err != nil {
+		return nil, err
+	}
+	delta, err := sampleSigned(new(big.Int).Mul(boundY, ped.N))
+	if err != nil {
+		return nil, err
+	}
+	mu, err := sampleSigned(new(big.Int).Lsh(ped.N, uint(ellY)))
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. A = C^α (1+N)^β r^N, E = s^α t^γ, S = s^x t^m, F = s^β t^δ, T = s^y t^μ
+	A := expSigned(C.c, alpha, pub.NSquare)
+	A.Mod(A.Mul(A, pub.encryptSigned(beta, r)), pub.NSquare)
+	E := ped.commit(alpha, gamma)
+	S := ped.commit(x, m)
+	F := ped.commit(beta, delta)
+	T := ped.commit(y, mu)
+
+	// 3. e = H(...)
+	e := affChallenge(pub, ped, C, D, A, E, S, F, T, ellX, ellY)
+
+	// 4. z1 = α + e·x, z2 = β + e·y, z3 = γ + e·m, z4 = δ + e·μ, w = r·ρ^e mod N
+	z1 := new(big.Int).Add(alpha, new(big.Int).Mul(e, x))
+	z2 := new(big.Int).Add(beta, new(big.Int).Mul(e, y))
+	z3 := new(big.Int).Add(gamma, new(big.Int).Mul(e, m))
+	z4 := new(big.Int).Add(delta, new(big.Int).Mul(e, mu))
+	w := new(big.Int).Exp(rho, e, pub.N)
+	w.Mod(w.Mul(w, r), pub.N)
+
+	return &AffProof{A: A, E: E, S: S, F: F, T: T, Z1: z1, Z2: z2, Z3: z3, Z4: z4, W: w}, nil
+}
+
+// Verify checks the proof that D = C^x·Enc(y) under pub with
+// |x| <= 2^(ℓx+ε) and |y| <= 2^(ℓy+ε), relative to the verifier's
+// ring-Pedersen parameters ped.
+func (p *AffProof) Verify(pub *PublicKey, ped *PedersenParams, C, D *Ciphertext, ellX, ellY int) bool {
+	if p == nil || p.A == nil || p.E == nil || p.S == nil || p.F == nil || p.T == nil ||
+		p.Z1 == nil || p.Z2 == nil || p.Z3 == nil || p.Z4 == nil || p.W == nil {
+		return false
+	}
+	if ellX <= 0 || ellY <= 0 || !pub.IsValidCiphertext(C) || !pub.IsValidCiphertext(D) || !pub.IsValidCiphertext(&Ciphertext{c: p.A}) {
+		return false
+	}
+	one := big.NewInt(1)
+	for _, x := range []*big.Int{p.E, p.S, p.F, p.T} {
+		if x.Sign() <= 0 || x.Cmp(ped.N) >= 0 || new(big.Int).GCD(nil, nil, x, ped.N).Cmp(one) != 0 {
+			return false
+		}
+	}
+	if p.W.Sign() <= 0 || p.W.Cmp(pub.N) >= 0 || new(big.Int).GCD(nil, nil, p.W, pub.N).Cmp(one) != 0 {
+		return false
+	}
+	// z1 ∈ ±2^(ℓx+ε), z2 ∈ ±2^(ℓy+ε)
+	if !inSignedRange(p.Z1, ellX+EncSlackBits) || !inSignedRange(p.Z2, ellY+EncSlackBits) {
+		return false
+	}
+
+	e := affChallenge(pub, ped, C, D, p.A, p.E, p.S, p.F, p.T, ellX, ellY)
+
+	// C^z1 (1+N)^z2 w^N = A·D^e mod N^2
+	lhs := expSigned(C.c, p.Z1, pub.NSquare)
+	lhs.Mod(lhs.Mul(lhs, pub.encryptSigned(p.Z2, p.W)), pub.NSquare)
+	rhs := new(big.Int).Exp(D.c, e, pub.NSquare)
+	rhs.Mod(rhs.Mul(rhs, p.A), pub.NSquare)
+	if lhs.Cmp(rhs) != 0 {
+		return false
+	}
+
+	// s^z1 t^z3 = E·S^e mod Ñ
+	lhs = ped.commit(p.Z1, p.Z3)
+	rhs = new(big.Int).Exp(p.S, e, ped.N)
+	rhs.Mod(rhs.Mul(rhs, p.E), ped.N)
+	if lhs.Cmp(rhs) != 0 {
+		return false
+	}
+
+	// s^z2 t^z4 = F·T^e mod Ñ
+	lhs = ped.commit(p.Z2, p.Z4)
+	rhs = new(big.Int).Exp(p.T, e, ped.N)
+	rhs.Mod(rhs.Mul(rhs, p.F), ped.N)
+	return lhs.Cmp(rhs) == 0
+}
+
+// affChallenge derives the Fiat-Shamir challenge of Π-aff.
+func affChallenge(pub *PublicKey, ped *PedersenParams, C, D *Ciphertext, A, E, S, F, T *big.Int, ellX, ellY int) *big.Int {
+	return hashToInt(ChallengeBits, "paillier/aff",
+		pub.N, ped.N, ped.S, ped.T, C.c, D.c, A, E, S, F, T, big.NewInt(int64(ellX)), big.NewInt(int64(ellY)))
+}
+
+// AffProof → Protobuf
+func (p *AffProof) ToProto() *pb.AffProof {
+	return &pb.AffProof{
+		A:  p.A.Bytes(),
+		E:  p.E.Bytes(),
+		S:  p.S.Bytes(),
+		F:  p.F.Bytes(),
+		T:  p.T.Bytes(),
+		Z1: signedToBytes(p.Z1),
+		Z2: signedToBytes(p.Z2),
+		Z3: signedToBytes(p.Z3),
+		Z4: signedToBytes(p.Z4),
+		W:  p.W.Bytes(),
+	}
+}
+
+// Protobuf → AffProof
+func AffProofFromProto(msg *pb.AffProof) (*AffProof, error) {
+	if msg == nil {
+		return nil, errors.New("affine proof is missing")
+	}
+	z := make([]*big.Int, 4)
+	for i, b := range [][]byte{msg.Z1, msg.Z2, msg.Z3, msg.Z4} {
+		v, err := signedFromBytes(b)
+		if err != nil {
+			return nil, err
+		}
+		z[i] = v
+	}
+	return &AffProof{
+		A:  new(big.Int).SetBytes(msg.A),
+		E:  new(big.Int).SetBytes(msg.E),
+		S:  new(big.Int).SetBytes(msg.S),
+		F:  new(big.Int).SetBytes(msg.F),
+		T:  new(big.Int).SetBytes(msg.T),
+		Z1: z[0],
+		Z2: z[1],
+		Z3: z[2],
+		Z4: z[3],
+		W:  new(big.Int).SetBytes(msg.W),
+	}, nil
+}
@@ -0,0 +1,111 @@
+package paillier
+
+import (
+	"math/big"
+	"testing"
+)
+
+// affStatement は検証者の鍵で D = C^x·Enc(y; ρ) を作ります。
+func affStatement(t *testing.T, pub *PublicKey, x, y *big.Int) (*Ciphertext, *Ciphertext, *big.Int) {
+	t.Helper()
+	C, err := pub.Encrypt(big.NewInt(987654321))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rho, err := SampleUnit(pub.N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cx, err := C.MulScalar(pub, x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ey, err := pub.EncryptWithNonce(new(big.Int).Mod(y, pub.N), rho)
+	if err != nil {
+		t.Fatal(err)
+	}
+	D, err := cx.Add(pub, ey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return C, D, rho
+}
+
+func TestAffProof(t *testing.T) {
+	// 証明は検証者のPaillier鍵とリングPedersenパラメータに対して作る
+	verifier, pp := setupEncProof(t)
+	pub := &verifier.PublicKey
+	const ellX, ellY = 256, 1280
+
+	x := new(big.Int).Lsh(big.NewInt(1), ellX-1)
+	y := new(big.Int).Lsh(big.NewInt(3), ellY-2)
+	for _, w := range [][2]*big.Int{{x, y}, {new(big.Int).Neg(x), y}, {big.NewInt(0), big.NewInt(0)}} {
+		C, D, rho := affStatement(t, pub, w[0], w[1])
+		proof, err := ProveAff(pub, pp, C, D, w[0], w[1], rho, ellX, ellY)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := AffProofFromProto(proof.ToProto())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Verify(pub, pp, C, D, ellX, ellY) {
+			t.Fatalf("valid proof for x=%v, y=%v rejected", w[0], w[1])
+		}
+	}
+}
+
+func TestAffProofRejectsTampering(t *testing.T) {
+	verifier, pp := setupEncProof(t)
+	pub := &verifier.PublicKey
+	const ellX, ellY = 256, 1280
+
+	x, y := big.NewInt(42), big.NewInt(1000)
+	C, D, rho := affStatement(t, pub, x, y)
+	proof, err := ProveAff(pub, pp, C, D, x, y, rho, ellX, ellY)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	one := big.NewInt(1)
+	tampered := map[string]func(p *AffProof){
+		"A":  func(p *AffProof) { p.A = new(big.Int).Add(p.A, one) },
+		"E":  func(p *AffProof) { p.E = new(big.Int).Add(p.E, one) },
+		"S":  func(p *AffProof) { p.S = new(big.Int).Add(p.S, one) },
+		"F":  func(p *AffProof) { p.F = new(big.Int).Add(p.F, one) },
+		"T":  func(p *AffProof) { p.T = new(big.Int).Add(p.T, one) },
+		"z1": func(p *AffProof) { p.Z1 = new(big.Int).Add(p.Z1, one) },
+		"z2": func(p *AffProof) { p.Z2 = new(big.Int).Add(p.Z2, one) },
+		"z3": func(p *AffProof) { p.Z3 = new(big.Int).Add(p.Z3, one) },
+		"z4": func(p *AffProof) { p.Z4 = new(big.Int).Add(p.Z4, one) },
+		"w":  func(p *AffProof) { p.W = new(big.Int).Add(p.W, one) },
+		"z2 out of range": func(p *AffProof) {
+			p.Z2 = new(big.Int).Lsh(one, ellY+EncSlackBits+1)
+		},
+	}
+	for name, tamper := range tampered {
+		p := *proof
+		tamper(&p)
+		if p.Verify(pub, pp, C, D, ellX, ellY) {
+			t.Errorf("%s: tampered proof accepted", name)
+		}
+	}
+
+	// 別の暗号文、別の範囲では検証に失敗する
+	other, err := D.Add(pub, C)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proof.Verify(pub, pp, C, other, ellX, ellY) {
+		t.Error("proof accepted for another ciphertext")
+	}
+	if proof.Verify(pub, pp, C, D, ellX, ellY-1) {
+		t.Error("proof accepted for another range")
+	}
+
+	// 範囲外の値では証明を作れない
+	tooLarge := new(big.Int).Lsh(one, ellY+1)
+	if _, err := ProveAff(pub, pp, C, D, x, tooLarge, rho, ellX, ellY); err == nil {
+		t.Error("out-of-range witness accepted by prover")
+	}
+}
@@ -0,0 +1,158 @@
+package paillier
+
+import (
+	"errors"
+	"math/big"
+
+	pb "multisigservice/proto/paillierpb"
+)
+
+// EncSlackBits is the slack ε of the range proof: a valid proof shows that
+// the plaintext lies in [−2^(ℓ+ε), 2^(ℓ+ε)], and honest provers use values
+// in [−2^ℓ, 2^ℓ].
+const EncSlackBits = 2 * ChallengeBits
+
+// EncProof is a non-interactive proof (Π-enc) that a ciphertext K encrypts
+// a value k in [−2^ℓ, 2^ℓ] under the prover's Paillier key.
+type EncProof struct {
+	S  *big.Int
+	A  *big.Int
+	C  *big.Int
+	Z1 *big.Int
+	Z2 *big.Int
+	Z3 *big.Int
+}
+
+// ProveEnc proves that ct = Enc(k; rho) under pub with |k| <= 2^ell, using the
+// verifier's ring-Pedersen parameters ped.
+func ProveEnc(pub *PublicKey, ped *PedersenParams, ct *Ciphertext, k, rho *big.Int, ell int) (*EncProof, error) {
+	if ell <= 0 || ell+EncSlackBits+2 > pub.N.BitLen() {
+		return nil, errors.New("range is too large for the paillier modulus")
+	}
+	if !inSignedRange(k, ell) {
+		return nil, errors.New("plaintext out of range")
+	}
+
+	bound := new(big.Int).Lsh(big.NewInt(1), uint(ell+EncSlackBits))
+	// 1. α ← ±2^(ℓ+ε), μ ← ±2^ℓ·Ñ, r ← Z*_N, γ ← ±2^(ℓ+ε)·Ñ
+	alpha, err := sampleSigned(bound)
+	if err != nil {
+		return nil, err
+	}
+	mu, err := sampleSigned(new(big.Int).Lsh(ped.N, uint(ell)))
+	if err != nil {
+		return nil, err
+	}
+	r, err := SampleUnit(pub.N)
+	if err != nil {
+		return nil, err
+	}
+	gamma, err := sampleSigned(new(big.Int).Mul(bound, ped.N))
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. S = s^k t^μ, A = (1+N)^α r^N, C = s^α t^γ
+	S := ped.commit(k, mu)
+	A := pub.encryptSigned(alpha, r)
+	C := ped.commit(alpha, gamma)
+
+	// 3. e = H(...)
+	e := encChallenge(pub, ped, ct, S, A, C, ell)
+
+	// 4. z1 = α + e·k, z2 = r·ρ^e mod N, z3 = γ + e·μ
+	z1 := new(big.Int).Add(alpha, new(big.Int).Mul(e, k))
+	z2 := new(big.Int).Exp(rho, e, pub.N)
+	z2.Mod(z2.Mul(z2, r), pub.N)
+	z3 := new(big.Int).Add(gamma, new(big.Int).Mul(e, mu))
+
+	return &EncProof{S: S, A: A, C: C, Z1: z1, Z2: z2, Z3: z3}, nil
+}
+
+// Verify checks the proof that ct encrypts a value in [−2^(ℓ+ε), 2^(ℓ+ε)]
+// under pub, relative to the verifier's ring-Pedersen parameters ped.
+func (p *EncProof) Verify(pub *PublicKey, ped *PedersenParams, ct *Ciphertext, ell int) bool {
+	if p == nil || p.S == nil || p.A == nil || p.C == nil || p.Z1 == nil || p.Z2 == nil || p.Z3 == nil {
+		return false
+	}
+	if ell <= 0 || !pub.IsValidCiphertext(ct) || !pub.IsValidCiphertext(&Ciphertext{c: p.A}) {
+		return false
+	}
+	one := big.NewInt(1)
+	for _, x := range []*big.Int{p.S, p.C} {
+		if x.Sign() <= 0 || x.Cmp(ped.N) >= 0 || new(big.Int).GCD(nil, nil, x, ped.N).Cmp(one) != 0 {
+			return false
+		}
+	}
+	if p.Z2.Sign() <= 0 || p.Z2.Cmp(pub.N) >= 0 || new(big.Int).GCD(nil, nil, p.Z2, pub.N).Cmp(one) != 0 {
+		return false
+	}
+	// z1 ∈ ±2^(ℓ+ε)
+	if !inSignedRange(p.Z1, ell+EncSlackBits) {
+		return false
+	}
+
+	e := encChallenge(pub, ped, ct, p.S, p.A, p.C, ell)
+
+	// (1+N)^z1 z2^N = A·K^e mod N^2
+	lhs := pub.encryptSigned(p.Z1, p.Z2)
+	rhs := new(big.Int).Exp(ct.c, e, pub.NSquare)
+	rhs.Mod(rhs.Mul(rhs, p.A), pub.NSquare)
+	if lhs.Cmp(rhs) != 0 {
+		return false
+	}
+
+	// s^z1 t^z3 = C·S^e mod Ñ
+	lhs = ped.commit(p.Z1, p.Z3)
+	rhs = new(big.Int).Exp(p.S, e, ped.N)
+	rhs.Mod(rhs.Mul(rhs, p.C), ped.N)
+	return lhs.Cmp(rhs) == 0
+}
+
+// encryptSigned computes (1+N)^m r^N mod N^2 for a signed m.
+func (pub *PublicKey) encryptSigned(m, r *big.Int) *big.Int {
+	gm := expSigned(pub.G, m, pub.NSquare)
+	rn := new(big.Int).Exp(r, pub.N, pub.NSquare)
+	return gm.Mod(gm.Mul(gm, rn), pub.NSquare)
+}
+
+// encChallenge derives the Fiat-Shamir challenge of Π-enc.
+func encChallenge(pub *PublicKey, ped *PedersenParams, ct *Ciphertext, S, A, C *big.Int, ell int) *big.Int {
+	return hashToInt(ChallengeBits, "paillier/enc",
+		pub.N, ped.N, ped.S, ped.T, ct.c, S, A, C, big.NewInt(int64(ell)))
+}
+
+// EncProof → Protobuf
+func (p *EncProof) ToProto() *pb.EncProof {
+	return &pb.EncProof{
+		S:  p.S.Bytes(),
+		A:  p.A.Bytes(),
+		C:  p.C.Bytes(),
+		Z1: signedToBytes(p.Z1),
+		Z2: p.Z2.Bytes(),
+		Z3: signedToBytes(p.Z3),
+	}
+}
+
+// Protobuf → EncProof
+func EncProofFromProto(msg *pb.EncProof) (*EncProof, error) {
+	if msg == nil {
+		return nil, errors.New("range proof is missing")
+	}
+	z1, err := signedFromBytes(msg.Z1)
+	if err != nil {
+		return nil, err
+	}
+	z3, err := signedFromBytes(msg.Z3)
+	if err != nil {
+		return nil, err
+	}
+	return &EncProof{
+		S:  new(big.Int).SetBytes(msg.S),
+		A:  new(big.Int).SetBytes(msg.A),
+		C:  new(big.Int).SetBytes(msg.C),
+		Z1: z1,
+		Z2: new(big.Int).SetBytes(msg.Z2),
+		Z3: z3,
+	}, nil
+}
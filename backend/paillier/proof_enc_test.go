@@ -0,0 +1,122 @@
+package paillier
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func setupEncProof(t *testing.T) (*PrivateKey, *PedersenParams) {
+	t.Helper()
+	_, prover, err := GenerateKey(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, verifier, err := GenerateKey(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := NewPedersenParams(verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prover, pp
+}
+
+func TestEncProof(t *testing.T) {
+	prover, pp := setupEncProof(t)
+	pub := &prover.PublicKey
+	const ell = 256
+
+	k, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), ell))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []*big.Int{k, new(big.Int).Neg(k), big.NewInt(0)} {
+		rho, err := SampleUnit(pub.N)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct, err := pub.EncryptWithNonce(new(big.Int).Mod(m, pub.N), rho)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proof, err := ProveEnc(pub, pp, ct, m, rho, ell)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := EncProofFromProto(proof.ToProto())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Verify(pub, pp, ct, ell) {
+			t.Fatalf("valid proof for %v rejected", m)
+		}
+	}
+}
+
+func TestEncProofRejectsTampering(t *testing.T) {
+	prover, pp := setupEncProof(t)
+	pub := &prover.PublicKey
+	const ell = 256
+
+	k := big.NewInt(123456789)
+	rho, err := SampleUnit(pub.N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, err := pub.EncryptWithNonce(k, rho)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := ProveEnc(pub, pp, ct, k, rho, ell)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	one := big.NewInt(1)
+	tampered := map[string]func(p *EncProof){
+		"S":  func(p *EncProof) { p.S = new(big.Int).Add(p.S, one) },
+		"A":  func(p *EncProof) { p.A = new(big.Int).Add(p.A, one) },
+		"C":  func(p *EncProof) { p.C = new(big.Int).Add(p.C, one) },
+		"z1": func(p *EncProof) { p.Z1 = new(big.Int).Add(p.Z1, one) },
+		"z2": func(p *EncProof) { p.Z2 = new(big.Int).Add(p.Z2, one) },
+		"z3": func(p *EncProof) { p.Z3 = new(big.Int).Add(p.Z3, one) },
+		"z1 out of range": func(p *EncProof) {
+			p.Z1 = new(big.Int).Lsh(one, ell+EncSlackBits+1)
+		},
+	}
+	for name, tamper := range tampered {
+		p := *proof
+		tamper(&p)
+		if p.Verify(pub, pp, ct, ell) {
+			t.Errorf("%s: tampered proof accepted", name)
+		}
+	}
+
+	// 別の暗号文、別の範囲、別の検証者パラメータでは検証に失敗する
+	other, err := pub.Encrypt(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proof.Verify(pub, pp, other, ell) {
+		t.Error("proof accepted for another ciphertext")
+	}
+	if proof.Verify(pub, pp, ct, ell-1) {
+		t.Error("proof accepted for another range")
+	}
+	otherPP, err := NewPedersenParams(prover)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proof.Verify(pub, otherPP, ct, ell) {
+		t.Error("proof accepted for other pedersen parameters")
+	}
+
+	// 範囲外の平文では証明を作れない
+	tooLarge := new(big.Int).Lsh(one, ell+1)
+	if _, err := ProveEnc(pub, pp, ct, tooLarge, rho, ell); err == nil {
+		t.Error("out-of-range plaintext accepted by prover")
+	}
+}
@@ -0,0 +1,191 @@
+package paillier
+
+import (
+	"errors"
+	"math/big"
+
+	pb "multisigservice/proto/paillierpb"
+)
+
+// FacRangeBits and FacSlackBits are ℓ and ε of Π-fac: a valid proof shows
+// that both factors of N are larger than roughly 2^ℓ.
+const (
+	FacRangeBits = 256
+	FacSlackBits = 2 * ChallengeBits
+)
+
+// FacProof is a non-interactive proof (Π-fac) that N = pq has no small
+// factors, relative to the verifier's ring-Pedersen parameters.
+type FacProof struct {
+	P     *big.Int
+	Q     *big.Int
+	A     *big.Int
+	B     *big.Int
+	T     *big.Int
+	Sigma *big.Int
+	Z1    *big.Int
+	Z2    *big.Int
+	W1    *big.Int
+	W2    *big.Int
+	V     *big.Int
+}
+
+// ProveFac proves that the modulus of sk has no small factors, using the
+// verifier's ring-Pedersen parameters ped, bound to ctx.
+func ProveFac(sk *PrivateKey, ped *PedersenParams, ctx []byte) (*FacProof, error) {
+	if sk.P == nil || sk.Q == nil {
+		return nil, errors.New("prime factors are not available")
+	}
+	n0, nHat := sk.N, ped.N
+	sqrtN0 := new(big.Int).Sqrt(n0)
+	sqrtN0.Add(sqrtN0, big.NewInt(1))
+
+	// 1. α, β ← ±2^(ℓ+ε)·√N0, μ, ν ← ±2^ℓ·Ñ, σ ← ±2^ℓ·N0·Ñ, r ← ±2^(ℓ+ε)·N0·Ñ, x, y ← ±2^(ℓ+ε)·Ñ
+	alphaBound := new(big.Int).Lsh(sqrtN0, FacRangeBits+FacSlackBits)
+	muBound := new(big.Int).Lsh(nHat, FacRangeBits)
+	sigmaBound := new(big.Int).Mul(muBound, n0)
+	rBound := new(big.Int).Lsh(new(big.Int).Mul(n0, nHat), FacRangeBits+FacSlackBits)
+	xBound := new(big.Int).Lsh(nHat, FacRangeBits+FacSlackBits)
+
+	var alpha, beta, mu, nu, sigma, r, x, y *big.Int
+	for _, s := range []struct {
+		dst   **big.Int
+		bound *big.Int
+	}{
+		{&alpha, alphaBound}, {&beta, alphaBound},
+		{&mu, muBound}, {&nu, muBound},
+		{&sigma, sigmaBound}, {&r, rBound},
+		{&x, xBound}, {&y, xBound},
+	} {
+		v, err := sampleSigned(s.bound)
+		if err != nil {
+			return nil, err
+		}
+		*s.dst = v
+	}
+
+	// 2. P = s^p t^μ, Q = s^q t^ν, A = s^α t^x, B = s^β t^y, T = Q^α t^r
+	P := ped.commit(sk.P, mu)
+	Q := ped.commit(sk.Q, nu)
+	A := ped.commit(alpha, x)
+	B := ped.commit(beta, y)
+	T := expSigned(Q, alpha, nHat)
+	T.Mod(T.Mul(T, expSigned(ped.T, r, nHat)), nHat)
+
+	// 3. e = H(...)
+	e := facChallenge(n0, ped, P, Q, A, B, T, sigma, ctx)
+
+	// 4. σ̂ = σ − ν·p, z1 = α + e·p, z2 = β + e·q, w1 = x + e·μ, w2 = y + e·ν, v = r + e·σ̂
+	sigmaHat := new(big.Int).Sub(sigma, new(big.Int).Mul(nu, sk.P))
+	mulAdd := func(a, b *big.Int) *big.Int {
+		return new(big.Int).Add(a, new(big.Int).Mul(e, b))
+	}
+	return &FacProof{
+		P: P, Q: Q, A: A, B: B, T: T, Sigma: sigma,
+		Z1: mulAdd(alpha, sk.P),
+		Z2: mulAdd(beta, sk.Q),
+		W1: mulAdd(x, mu),
+		W2: mulAdd(y, nu),
+		V:  mulAdd(r, sigmaHat),
+	}, nil
+}
+
+// Verify checks that n0 has no factors smaller than about 2^ℓ, relative
+// to the verifier's ring-Pedersen parameters ped, for a proof made with ctx.
+func (p *FacProof) Verify(n0 *big.Int, ped *PedersenParams, ctx []byte) bool {
+	if p == nil || n0 == nil {
+		return false
+	}
+	for _, v := range []*big.Int{p.P, p.Q, p.A, p.B, p.T, p.Sigma, p.Z1, p.Z2, p.W1, p.W2, p.V} {
+		if v == nil {
+			return false
+		}
+	}
+	nHat := ped.N
+	one := big.NewInt(1)
+	for _, v := range []*big.Int{p.P, p.Q, p.A, p.B, p.T} {
+		if v.Sign() <= 0 || v.Cmp(nHat) >= 0 || new(big.Int).GCD(nil, nil, v, nHat).Cmp(one) != 0 {
+			return false
+		}
+	}
+
+	// z1, z2 ∈ ±√N0·2^(ℓ+ε)
+	sqrtN0 := new(big.Int).Sqrt(n0)
+	sqrtN0.Add(sqrtN0, one)
+	bound := new(big.Int).Lsh(sqrtN0, FacRangeBits+FacSlackBits)
+	if new(big.Int).Abs(p.Z1).Cmp(bound) > 0 || new(big.Int).Abs(p.Z2).Cmp(bound) > 0 {
+		return false
+	}
+
+	e := facChallenge(n0, ped, p.P, p.Q, p.A, p.B, p.T, p.Sigma, ctx)
+	// mulExp computes base·x^e mod Ñ
+	mulExp := func(base, x *big.Int) *big.Int {
+		r := new(big.Int).Exp(x, e, nHat)
+		return r.Mod(r.Mul(r, base), nHat)
+	}
+
+	// R = s^N0 t^σ
+	R := ped.commit(n0, p.Sigma)
+	// s^z1 t^w1 = A·P^e
+	if ped.commit(p.Z1, p.W1).Cmp(mulExp(p.A, p.P)) != 0 {
+		return false
+	}
+	// s^z2 t^w2 = B·Q^e
+	if ped.commit(p.Z2, p.W2).Cmp(mulExp(p.B, p.Q)) != 0 {
+		return false
+	}
+	// Q^z1 t^v = T·R^e
+	lhs := expSigned(p.Q, p.Z1, nHat)
+	lhs.Mod(lhs.Mul(lhs, expSigned(ped.T, p.V, nHat)), nHat)
+	return lhs.Cmp(mulExp(p.T, R)) == 0
+}
+
+// facChallenge derives the Fiat-Shamir challenge of Π-fac.
+func facChallenge(n0 *big.Int, ped *PedersenParams, P, Q, A, B, T, sigma *big.Int, ctx []byte) *big.Int {
+	return hashToInt(ChallengeBits, contextDomain("paillier/fac", ctx), n0, ped.N, ped.S, ped.T, P, Q, A, B, T, sigma)
+}
+
+// FacProof → Protobuf
+func (p *FacProof) ToProto() *pb.FacProof {
+	return &pb.FacProof{
+		P:     p.P.Bytes(),
+		Q:     p.Q.Bytes(),
+		A:     p.A.Bytes(),
+		B:     p.B.Bytes(),
+		T:     p.T.Bytes(),
+		Sigma: signedToBytes(p.Sigma),
+		Z1:    signedToBytes(p.Z1),
+		Z2:    signedToBytes(p.Z2),
+		W1:    signedToBytes(p.W1),
+		W2:    signedToBytes(p.W2),
+		V:     signedToBytes(p.V),
+	}
+}
+
+// Protobuf → FacProof
+func FacProofFromProto(msg *pb.FacProof) (*FacProof, error) {
+	if msg == nil {
+		return nil, errors.New("factor proof is missing")
+	}
+	p := &FacProof{
+		P: new(big.Int).SetBytes(msg.P),
+		Q: new(big.Int).SetBytes(msg.Q),
+		A: new(big.Int).SetBytes(msg.A),
+		B: new(big.Int).SetBytes(msg.B),
+		T: new(big.Int).SetBytes(msg.T),
+	}
+	for _, f := range []struct {
+		dst **big.Int
+		src []byte
+	}{
+		{&p.Sigma, msg.Sigma}, {&p.Z1, msg.Z1}, {&p.Z2, msg.Z2},
+		{&p.W1, msg.W1}, {&p.W2, msg.W2}, {&p.V, msg.V},
+	} {
+		v, err := signedFromBytes(f.src)
+		if err != nil {
+			return nil, err
+		}
+		*f.dst = v
+	}
+	return p, nil
+}
@@ -0,0 +1,193 @@
+package paillier
+
+import (
+	"errors"
+	"math/big"
+
+	pb "multisigservice/proto/paillierpb"
+)
+
+// ModProofIterations is the number of parallel repetitions m of Π-mod; a
+// cheating prover succeeds with probability at most 2^-m.
+const ModProofIterations = 80
+
+// ModProof is a non-interactive proof (Π-mod) that N is a Paillier-Blum
+// integer: N = pq with p ≡ q ≡ 3 mod 4 and gcd(N, φ(N)) = 1.
+type ModProof struct {
+	W *big.Int
+	X []*big.Int
+	A []bool
+	B []bool
+	Z []*big.Int
+}
+
+// ProveMod proves that the modulus of sk is a Paillier-Blum integer, bound
+// to ctx. It requires the prime factors, which must both be 3 mod 4.
+func ProveMod(sk *PrivateKey, ctx []byte) (*ModProof, error) {
+	if sk.P == nil || sk.Q == nil {
+		return nil, errors.New("prime factors are not available")
+	}
+	p, q, n := sk.P, sk.Q, sk.N
+	if p.Bit(0) != 1 || p.Bit(1) != 1 || q.Bit(0) != 1 || q.Bit(1) != 1 {
+		return nil, errors.New("modulus is not a Blum integer")
+	}
+	one := big.NewInt(1)
+	phi := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+	nInv := new(big.Int).ModInverse(n, phi)
+	if nInv == nil {
+		return nil, errors.New("gcd(N, φ(N)) != 1")
+	}
+
+	// 1. ヤコビ記号が −1 となる w を選ぶ
+	var w *big.Int
+	for {
+		var err error
+		if w, err = SampleUnit(n); err != nil {
+			return nil, err
+		}
+		if big.Jacobi(w, n) == -1 {
+			break
+		}
+	}
+
+	// 4乗根の指数 ((p+1)/4)^2 mod (p-1)
+	rootExp := func(prime *big.Int) *big.Int {
+		e := new(big.Int).Add(prime, one)
+		e.Rsh(e, 2)
+		e.Mul(e, e)
+		return e.Mod(e, new(big.Int).Sub(prime, one))
+	}
+	expP, expQ := rootExp(p), rootExp(q)
+	qInv := new(big.Int).ModInverse(q, p)
+	minusOne := new(big.Int).Sub(n, one)
+
+	proof := &ModProof{W: w}
+	for i := 0; i < ModProofIterations; i++ {
+		y := modChallenge(n, w, i, ctx)
+
+		// 2. z = y^(N^-1 mod φ(N)) mod N
+		z := new(big.Int).Exp(y, nInv, n)
+
+		// 3. y' = (−1)^a w^b y が p, q の両方で平方剰余となる a, b を選び、その4乗根を求める
+		found := false
+		for _, a := range []bool{false, true} {
+			for _, b := range []bool{false, true} {
+				yy := new(big.Int).Set(y)
+				if a {
+					yy.Mod(yy.Mul(yy, minusOne), n)
+				}
+				if b {
+					yy.Mod(yy.Mul(yy, w), n)
+				}
+				if big.Jacobi(yy, p) != 1 || big.Jacobi(yy, q) != 1 {
+					continue
+				}
+				xp := new(big.Int).Exp(yy, expP, p)
+				xq := new(big.Int).Exp(yy, expQ, q)
+				// x = xq + q * ((xp - xq) * q^-1 mod p)
+				x := new(big.Int).Sub(xp, xq)
+				x.Mod(x.Mul(x, qInv), p)
+				x.Add(xq, x.Mul(x, q))
+
+				proof.X = append(proof.X, x)
+				proof.A = append(proof.A, a)
+				proof.B = append(proof.B, b)
+				proof.Z = append(proof.Z, z)
+				found = true
+				break
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			return nil, errors.New("failed to compute fourth root")
+		}
+	}
+	return proof, nil
+}
+
+// Verify checks that n is a Paillier-Blum integer, for a proof made with ctx.
+func (p *ModProof) Verify(n *big.Int, ctx []byte) bool {
+	if p == nil || p.W == nil || n == nil {
+		return false
+	}
+	if len(p.X) != ModProofIterations || len(p.A) != ModProofIterations ||
+		len(p.B) != ModProofIterations || len(p.Z) != ModProofIterations {
+		return false
+	}
+	// N は奇数の合成数でなければならない
+	if n.Bit(0) == 0 || n.ProbablyPrime(20) {
+		return false
+	}
+	if p.W.Sign() <= 0 || p.W.Cmp(n) >= 0 || big.Jacobi(p.W, n) != -1 {
+		return false
+	}
+
+	four := big.NewInt(4)
+	minusOne := new(big.Int).Sub(n, big.NewInt(1))
+	for i := 0; i < ModProofIterations; i++ {
+		x, z := p.X[i], p.Z[i]
+		if x == nil || z == nil || x.Sign() <= 0 || x.Cmp(n) >= 0 || z.Sign() <= 0 || z.Cmp(n) >= 0 {
+			return false
+		}
+		y := modChallenge(n, p.W, i, ctx)
+
+		// z^N = y mod N
+		if new(big.Int).Exp(z, n, n).Cmp(y) != 0 {
+			return false
+		}
+		// x^4 = (−1)^a w^b y mod N
+		yy := new(big.Int).Set(y)
+		if p.A[i] {
+			yy.Mod(yy.Mul(yy, minusOne), n)
+		}
+		if p.B[i] {
+			yy.Mod(yy.Mul(yy, p.W), n)
+		}
+		if new(big.Int).Exp(x, four, n).Cmp(yy) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// modChallenge derives the i-th Fiat-Shamir challenge y_i ∈ Z_N of Π-mod.
+func modChallenge(n, w *big.Int, i int, ctx []byte) *big.Int {
+	y := hashToInt(n.BitLen()+ChallengeBits, contextDomain("paillier/mod", ctx), n, w, big.NewInt(int64(i)))
+	return y.Mod(y, n)
+}
+
+// ModProof → Protobuf
+func (p *ModProof) ToProto() *pb.ModProof {
+	msg := &pb.ModProof{
+		W: p.W.Bytes(),
+		A: p.A,
+		B: p.B,
+	}
+	for i := range p.X {
+		msg.X = append(msg.X, p.X[i].Bytes())
+		msg.Z = append(msg.Z, p.Z[i].Bytes())
+	}
+	return msg
+}
+
+// Protobuf → ModProof
+func ModProofFromProto(msg *pb.ModProof) (*ModProof, error) {
+	if msg == nil {
+		return nil, errors.New("modulus proof is missing")
+	}
+	if len(msg.X) != len(msg.Z) || len(msg.X) != len(msg.A) || len(msg.X) != len(msg.B) {
+		return nil, errors.New("modulus proof is malformed")
+	}
+	p := &ModProof{
+		W: new(big.Int).SetBytes(msg.W),
+		A: msg.A,
+		B: msg.B,
+	}
+	for i := range msg.X {
+		p.X = append(p.X, new(big.Int).SetBytes(msg.X[i]))
+		p.Z = append(p.Z, new(big.Int).SetBytes(msg.Z[i]))
+	}
+	return p, nil
+}
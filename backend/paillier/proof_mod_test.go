@@ -0,0 +1,165 @@
+package paillier
+
+import (
+	"math/big"
+	"testing"
+)
+
+// modCtx は証明を結び付ける文脈（登録するアドレスとチャレンジ）の例です。
+var modCtx = []byte("register/0x1111111111111111111111111111111111111111/challenge")
+
+func TestModProof(t *testing.T) {
+	_, priv, err := GenerateKeyWithOptions(KeyOptions{BitLen: 1024, SafePrimes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := ProveMod(priv, modCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := ModProofFromProto(proof.ToProto())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Verify(priv.N, modCtx) {
+		t.Fatal("valid proof rejected")
+	}
+
+	// 別のNや改ざんされた証明は拒否される
+	_, other, err := GenerateKeyWithOptions(KeyOptions{BitLen: 1024, SafePrimes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proof.Verify(other.N, modCtx) {
+		t.Error("proof accepted for another modulus")
+	}
+	// 別の文脈（別のアドレスやチャレンジ）での再利用は拒否される
+	if proof.Verify(priv.N, []byte("register/0x2222222222222222222222222222222222222222/challenge")) {
+		t.Error("proof replayed in another context")
+	}
+	tampered := *proof
+	tampered.X = append([]*big.Int{new(big.Int).Add(proof.X[0], big.NewInt(1))}, proof.X[1:]...)
+	if tampered.Verify(priv.N, modCtx) {
+		t.Error("tampered x accepted")
+	}
+	tampered = *proof
+	tampered.A = append([]bool{!proof.A[0]}, proof.A[1:]...)
+	if tampered.Verify(priv.N, modCtx) {
+		t.Error("tampered a accepted")
+	}
+	tampered = *proof
+	tampered.X, tampered.A, tampered.B, tampered.Z = proof.X[:1], proof.A[:1], proof.B[:1], proof.Z[:1]
+	if tampered.Verify(priv.N, modCtx) {
+		t.Error("truncated proof accepted")
+	}
+}
+
+func TestModProofRejectsNonBlum(t *testing.T) {
+	// p ≡ 1 mod 4 となる素因数を持つ鍵では証明できない
+	for {
+		_, priv, err := GenerateKey(512)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if priv.P.Bit(1) == 1 && priv.Q.Bit(1) == 1 {
+			continue
+		}
+		if _, err := ProveMod(priv, modCtx); err == nil {
+			t.Fatal("non-Blum modulus accepted by prover")
+		}
+		return
+	}
+}
+
+func TestFacProof(t *testing.T) {
+	_, priv, err := GenerateKeyWithOptions(KeyOptions{BitLen: 2048, SafePrimes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, verifier, err := GenerateKey(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, err := NewPedersenParams(verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof, err := ProveFac(priv, pp, modCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := FacProofFromProto(proof.ToProto())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Verify(priv.N, pp, modCtx) {
+		t.Fatal("valid proof rejected")
+	}
+
+	if proof.Verify(verifier.N, pp, modCtx) {
+		t.Error("proof accepted for another modulus")
+	}
+	if proof.Verify(priv.N, pp, []byte("register/0x1111111111111111111111111111111111111111/other")) {
+		t.Error("proof replayed in another context")
+	}
+	one := big.NewInt(1)
+	for name, tamper := range map[string]func(p *FacProof){
+		"P":     func(p *FacProof) { p.P = new(big.Int).Add(p.P, one) },
+		"T":     func(p *FacProof) { p.T = new(big.Int).Add(p.T, one) },
+		"sigma": func(p *FacProof) { p.Sigma = new(big.Int).Add(p.Sigma, one) },
+		"z1":    func(p *FacProof) { p.Z1 = new(big.Int).Add(p.Z1, one) },
+		"v":     func(p *FacProof) { p.V = new(big.Int).Add(p.V, one) },
+	} {
+		p := *proof
+		tamper(&p)
+		if p.Verify(priv.N, pp, modCtx) {
+			t.Errorf("%s: tampered proof accepted", name)
+		}
+	}
+}
+
+func TestPrmProof(t *testing.T) {
+	_, priv, err := GenerateKey(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pp, proof, err := NewPedersenParamsWithProof(priv, modCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pp.N.Cmp(priv.N) != 0 {
+		t.Error("parameters are not over the key's modulus")
+	}
+	decoded, err := PrmProofFromProto(proof.ToProto())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Verify(pp, modCtx) {
+		t.Fatal("valid proof rejected")
+	}
+
+	if proof.Verify(pp, []byte("register/0x2222222222222222222222222222222222222222/challenge")) {
+		t.Error("proof replayed in another context")
+	}
+	// 証明と別のパラメータ（s が ⟨t⟩ に含まれると示されていないもの）は拒否される
+	other, err := NewPedersenParams(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proof.Verify(&PedersenParams{N: pp.N, S: other.S, T: pp.T}, modCtx) {
+		t.Error("proof accepted for another s")
+	}
+	one := big.NewInt(1)
+	for name, tamper := range map[string]func(p *PrmProof){
+		"A":         func(p *PrmProof) { p.A = append([]*big.Int{new(big.Int).Add(p.A[0], one)}, p.A[1:]...) },
+		"z":         func(p *PrmProof) { p.Z = append([]*big.Int{new(big.Int).Add(p.Z[0], one)}, p.Z[1:]...) },
+		"truncated": func(p *PrmProof) { p.A, p.Z = p.A[:1], p.Z[:1] },
+	} {
+		p := *proof
+		tamper(&p)
+		if p.Verify(pp, modCtx) {
+			t.Errorf("%s: tampered proof accepted", name)
+		}
+	}
+}
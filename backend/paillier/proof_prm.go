@@ -0,0 +1,121 @@
+package paillier
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+
+	pb "multisigservice/proto/paillierpb"
+)
+
+// PrmProofIterations is the number of parallel repetitions m of Π-prm; a
+// cheating prover succeeds with probability at most 2^-m.
+const PrmProofIterations = 80
+
+// PrmProof is a non-interactive proof (Π-prm) that ring-Pedersen parameters
+// satisfy s = t^λ mod Ñ for some λ known to the prover, i.e. that s lies in
+// the subgroup generated by t. Without it the owner of the parameters could
+// choose s outside ⟨t⟩ and break the hiding of the commitments made to it.
+type PrmProof struct {
+	A []*big.Int
+	Z []*big.Int
+}
+
+// NewPedersenParamsWithProof derives ring-Pedersen parameters from sk like
+// NewPedersenParams and proves them with Π-prm bound to ctx, so that they can
+// be published for others to prove statements against. It requires the prime
+// factors of sk.
+func NewPedersenParamsWithProof(sk *PrivateKey, ctx []byte) (*PedersenParams, *PrmProof, error) {
+	if sk.P == nil || sk.Q == nil {
+		return nil, nil, errors.New("prime factors are not available")
+	}
+	pp, lambda, err := newPedersenParams(sk)
+	if err != nil {
+		return nil, nil, err
+	}
+	one := big.NewInt(1)
+	phi := new(big.Int).Mul(new(big.Int).Sub(sk.P, one), new(big.Int).Sub(sk.Q, one))
+
+	// 1. a_i ← Z_φ(Ñ), A_i = t^a_i mod Ñ
+	a := make([]*big.Int, PrmProofIterations)
+	proof := &PrmProof{}
+	for i := range a {
+		if a[i], err = rand.Int(rand.Reader, phi); err != nil {
+			return nil, nil, err
+		}
+		proof.A = append(proof.A, new(big.Int).Exp(pp.T, a[i], pp.N))
+	}
+
+	// 2. e_i ∈ {0, 1}, z_i = a_i + e_i·λ mod φ(Ñ)
+	e := prmChallenge(pp, proof.A, ctx)
+	for i := range a {
+		z := new(big.Int).Set(a[i])
+		if e.Bit(i) == 1 {
+			z.Add(z, lambda)
+		}
+		proof.Z = append(proof.Z, z.Mod(z, phi))
+	}
+	return pp, proof, nil
+}
+
+// Verify checks that pp satisfies s = t^λ mod Ñ, for a proof made with ctx.
+func (p *PrmProof) Verify(pp *PedersenParams, ctx []byte) bool {
+	if p == nil || pp.Validate() != nil {
+		return false
+	}
+	if len(p.A) != PrmProofIterations || len(p.Z) != PrmProofIterations {
+		return false
+	}
+	one := big.NewInt(1)
+	e := prmChallenge(pp, p.A, ctx)
+	for i := 0; i < PrmProofIterations; i++ {
+		A, z := p.A[i], p.Z[i]
+		if A == nil || z == nil || A.Sign() <= 0 || A.Cmp(pp.N) >= 0 || z.Sign() < 0 || z.Cmp(pp.N) >= 0 {
+			return false
+		}
+		if new(big.Int).GCD(nil, nil, A, pp.N).Cmp(one) != 0 {
+			return false
+		}
+		// t^z_i = A_i · s^e_i mod Ñ
+		want := new(big.Int).Set(A)
+		if e.Bit(i) == 1 {
+			want.Mod(want.Mul(want, pp.S), pp.N)
+		}
+		if new(big.Int).Exp(pp.T, z, pp.N).Cmp(want) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// prmChallenge derives the Fiat-Shamir challenge bits e_1..e_m of Π-prm.
+func prmChallenge(pp *PedersenParams, A []*big.Int, ctx []byte) *big.Int {
+	values := append([]*big.Int{pp.N, pp.S, pp.T}, A...)
+	return hashToInt(PrmProofIterations, contextDomain("paillier/prm", ctx), values...)
+}
+
+// PrmProof → Protobuf
+func (p *PrmProof) ToProto() *pb.PrmProof {
+	msg := &pb.PrmProof{}
+	for i := range p.A {
+		msg.A = append(msg.A, p.A[i].Bytes())
+		msg.Z = append(msg.Z, p.Z[i].Bytes())
+	}
+	return msg
+}
+
+// Protobuf → PrmProof
+func PrmProofFromProto(msg *pb.PrmProof) (*PrmProof, error) {
+	if msg == nil {
+		return nil, errors.New("pedersen parameter proof is missing")
+	}
+	if len(msg.A) != len(msg.Z) {
+		return nil, errors.New("pedersen parameter proof is malformed")
+	}
+	p := &PrmProof{}
+	for i := range msg.A {
+		p.A = append(p.A, new(big.Int).SetBytes(msg.A[i]))
+		p.Z = append(p.Z, new(big.Int).SetBytes(msg.Z[i]))
+	}
+	return p, nil
+}
@@ -0,0 +1,251 @@
+package paillier
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ThresholdPublicKey is the public part of a t-of-n threshold Paillier key
+// (Shoup / Damgård–Jurik style). Ciphertexts are ordinary Paillier
+// ciphertexts under the embedded PublicKey; decrypting one requires
+// decryption shares from any Threshold of the Parties share holders.
+type ThresholdPublicKey struct {
+	PublicKey
+	Threshold int
+	Parties   int
+	// V generates the squares of Z*_{n^2}; VerificationKeys[i-1] = V^(Δ·s_i)
+	// lets anyone check the decryption share of party i.
+	V                *big.Int
+	VerificationKeys []*big.Int
+}
+
+// KeyShare is the secret decryption key s_i of party Index (1-based).
+type KeyShare struct {
+	Index int
+	Share *big.Int
+}
+
+// DecryptionShare is party Index's partial decryption c_i = c^(2Δ·s_i) of a
+// ciphertext, with a proof that it was computed with the committed share.
+type DecryptionShare struct {
+	Index int
+	C     *big.Int
+	Proof *DecryptionShareProof
+}
+
+// DecryptionShareProof proves log_{c^4}(c_i^2) = log_V(v_i).
+type DecryptionShareProof struct {
+	A *big.Int
+	B *big.Int
+	Z *big.Int
+}
+
+// InvalidShareError identifies the party whose decryption share failed verification.
+type InvalidShareError struct {
+	Index int
+}
+
+func (e *InvalidShareError) Error() string {
+	return fmt.Sprintf("invalid decryption share from party %d", e.Index)
+}
+
+// GenerateThresholdKey acts as a trusted dealer: it generates a safe-prime
+// Paillier modulus of bitLen bits and splits the decryption key into
+// parties shares, any threshold of which can decrypt.
+func GenerateThresholdKey(bitLen, threshold, parties int) (*ThresholdPublicKey, []*KeyShare, error) {
+	if threshold < 1 || threshold > parties {
+		return nil, nil, errors.New("threshold must satisfy 1 <= t <= n")
+	}
+	_, sk, err := GenerateKeyWithOptions(KeyOptions{BitLen: bitLen, SafePrimes: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	n := sk.N
+
+	// 1. m = p'q'、d ≡ 0 mod m かつ d ≡ 1 mod n
+	m := new(big.Int).Mul(new(big.Int).Rsh(sk.P, 1), new(big.Int).Rsh(sk.Q, 1))
+	mInv := new(big.Int).ModInverse(m, n)
+	if mInv == nil {
+		return nil, nil, errors.New("failed to compute m^-1 mod n")
+	}
+	d := new(big.Int).Mul(m, mInv)
+
+	// 2. f(X) = d + a_1 X + ... + a_{t-1} X^{t-1} mod n·m
+	nm := new(big.Int).Mul(n, m)
+	coeffs := []*big.Int{d}
+	for i := 1; i < threshold; i++ {
+		a, err := rand.Int(rand.Reader, nm)
+		if err != nil {
+			return nil, nil, err
+		}
+		coeffs = append(coeffs, a)
+	}
+
+	// 3. v = r^2 mod n^2（平方剰余群の生成元）
+	r, err := SampleUnit(sk.NSquare)
+	if err != nil {
+		return nil, nil, err
+	}
+	v := new(big.Int).Exp(r, big.NewInt(2), sk.NSquare)
+
+	delta := factorial(parties)
+	tpk := &ThresholdPublicKey{
+		PublicKey: sk.PublicKey,
+		Threshold: threshold,
+		Parties:   parties,
+		V:         v,
+	}
+	shares := make([]*KeyShare, parties)
+	for i := 1; i <= parties; i++ {
+		// s_i = f(i) mod n·m（ホーナー法）
+		x := big.NewInt(int64(i))
+		s := new(big.Int)
+		for j := len(coeffs) - 1; j >= 0; j-- {
+			s.Mul(s, x)
+			s.Add(s, coeffs[j])
+			s.Mod(s, nm)
+		}
+		shares[i-1] = &KeyShare{Index: i, Share: s}
+
+		// v_i = v^(Δ·s_i) mod n^2
+		exp := new(big.Int).Mul(delta, s)
+		tpk.VerificationKeys = append(tpk.VerificationKeys, new(big.Int).Exp(v, exp, sk.NSquare))
+	}
+	return tpk, shares, nil
+}
+
+// PartialDecrypt computes this party's decryption share of ct together with
+// a proof of correctness.
+func (ks *KeyShare) PartialDecrypt(tpk *ThresholdPublicKey, ct *Ciphertext) (*DecryptionShare, error) {
+	if ks.Index < 1 || ks.Index > tpk.Parties {
+		return nil, errors.New("share index out of range")
+	}
+	if !tpk.IsValidCiphertext(ct) {
+		return nil, errors.New("invalid ciphertext")
+	}
+	nSquare := tpk.NSquare
+	delta := factorial(tpk.Parties)
+
+	// c_i = c^(2Δ·s_i) mod n^2
+	x := new(big.Int).Mul(delta, ks.Share) // Δ·s_i
+	ci := new(big.Int).Exp(ct.c, new(big.Int).Lsh(x, 1), nSquare)
+
+	// log_{c^4}(c_i^2) = log_v(v_i) = Δ·s_i の証明
+	c4 := new(big.Int).Exp(ct.c, big.NewInt(4), nSquare)
+	ci2 := new(big.Int).Exp(ci, big.NewInt(2), nSquare)
+	rBits := nSquare.BitLen() + x.BitLen() + 2*ChallengeBits
+	r, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), uint(rBits)))
+	if err != nil {
+		return nil, err
+	}
+	a := new(big.Int).Exp(c4, r, nSquare)
+	b := new(big.Int).Exp(tpk.V, r, nSquare)
+	e := shareChallenge(tpk, ks.Index, c4, ci2, a, b)
+	z := new(big.Int).Add(r, new(big.Int).Mul(e, x))
+
+	return &DecryptionShare{
+		Index: ks.Index,
+		C:     ci,
+		Proof: &DecryptionShareProof{A: a, B: b, Z: z},
+	}, nil
+}
+
+// VerifyDecryptionShare checks the proof attached to a decryption share of ct.
+func (tpk *ThresholdPublicKey) VerifyDecryptionShare(ct *Ciphertext, ds *DecryptionShare) bool {
+	if ds == nil || ds.C == nil || ds.Proof == nil || ds.Proof.A == nil || ds.Proof.B == nil || ds.Proof.Z == nil {
+		return false
+	}
+	if ds.Index < 1 || ds.Index > tpk.Parties || len(tpk.VerificationKeys) != tpk.Parties {
+		return false
+	}
+	if !tpk.IsValidCiphertext(ct) || !tpk.IsValidCiphertext(&Ciphertext{c: ds.C}) || ds.Proof.Z.Sign() < 0 {
+		return false
+	}
+	nSquare := tpk.NSquare
+	vi := tpk.VerificationKeys[ds.Index-1]
+	c4 := new(big.Int).Exp(ct.c, big.NewInt(4), nSquare)
+	ci2 := new(big.Int).Exp(ds.C, big.NewInt(2), nSquare)
+	e := shareChallenge(tpk, ds.Index, c4, ci2, ds.Proof.A, ds.Proof.B)
+
+	// (c^4)^z = a·(c_i^2)^e
+	lhs := new(big.Int).Exp(c4, ds.Proof.Z, nSquare)
+	rhs := new(big.Int).Exp(ci2, e, nSquare)
+	rhs.Mod(rhs.Mul(rhs, ds.Proof.A), nSquare)
+	if lhs.Cmp(rhs) != 0 {
+		return false
+	}
+	// v^z = b·v_i^e
+	lhs = new(big.Int).Exp(tpk.V, ds.Proof.Z, nSquare)
+	rhs = new(big.Int).Exp(vi, e, nSquare)
+	rhs.Mod(rhs.Mul(rhs, ds.Proof.B), nSquare)
+	return lhs.Cmp(rhs) == 0
+}
+
+// CombineShares verifies the decryption shares of ct and combines any
+// Threshold of them with Lagrange interpolation to recover the plaintext.
+// If a share fails verification the returned error is an *InvalidShareError
+// naming the misbehaving party.
+func (tpk *ThresholdPublicKey) CombineShares(ct *Ciphertext, shares []*DecryptionShare) (*big.Int, error) {
+	seen := make(map[int]bool)
+	var valid []*DecryptionShare
+	for _, ds := range shares {
+		if !tpk.VerifyDecryptionShare(ct, ds) {
+			index := 0
+			if ds != nil {
+				index = ds.Index
+			}
+			return nil, &InvalidShareError{Index: index}
+		}
+		if seen[ds.Index] {
+			continue
+		}
+		seen[ds.Index] = true
+		valid = append(valid, ds)
+	}
+	if len(valid) < tpk.Threshold {
+		return nil, fmt.Errorf("need %d decryption shares, got %d", tpk.Threshold, len(valid))
+	}
+	valid = valid[:tpk.Threshold]
+
+	// c' = Π c_i^(2λ_i) mod n^2、λ_i = Δ·Π_{j≠i} j/(j−i)
+	nSquare := tpk.NSquare
+	delta := factorial(tpk.Parties)
+	combined := big.NewInt(1)
+	for _, ds := range valid {
+		num := new(big.Int).Set(delta)
+		den := big.NewInt(1)
+		for _, other := range valid {
+			if other.Index == ds.Index {
+				continue
+			}
+			num.Mul(num, big.NewInt(int64(other.Index)))
+			den.Mul(den, big.NewInt(int64(other.Index-ds.Index)))
+		}
+		lambda := num.Quo(num, den)
+		term := expSigned(ds.C, lambda.Lsh(lambda, 1), nSquare)
+		combined.Mod(combined.Mul(combined, term), nSquare)
+	}
+
+	// m = L(c') · (4Δ^2)^-1 mod n
+	fourDelta2 := new(big.Int).Mul(delta, delta)
+	fourDelta2.Lsh(fourDelta2, 2)
+	inv := new(big.Int).ModInverse(fourDelta2, tpk.N)
+	if inv == nil {
+		return nil, errors.New("4Δ^2 is not invertible mod n")
+	}
+	m := L(combined, tpk.N)
+	return m.Mod(m.Mul(m, inv), tpk.N), nil
+}
+
+// shareChallenge derives the Fiat-Shamir challenge of a decryption share proof.
+func shareChallenge(tpk *ThresholdPublicKey, index int, c4, ci2, a, b *big.Int) *big.Int {
+	return hashToInt(ChallengeBits, "paillier/threshold-decrypt",
+		tpk.N, tpk.V, tpk.VerificationKeys[index-1], big.NewInt(int64(index)), c4, ci2, a, b)
+}
+
+// factorial returns n!.
+func factorial(n int) *big.Int {
+	return new(big.Int).MulRange(1, int64(n))
+}
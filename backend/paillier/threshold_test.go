@@ -0,0 +1,86 @@
+package paillier
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestThresholdDecrypt(t *testing.T) {
+	for _, tc := range []struct{ threshold, parties int }{{2, 3}, {3, 5}, {1, 1}} {
+		tpk, shares, err := GenerateThresholdKey(1024, tc.threshold, tc.parties)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := big.NewInt(8675309)
+		ct, err := tpk.Encrypt(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var partials []*DecryptionShare
+		for _, ks := range shares {
+			ds, err := ks.PartialDecrypt(tpk, ct)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tpk.VerifyDecryptionShare(ct, ds) {
+				t.Fatalf("valid share from party %d rejected", ds.Index)
+			}
+			partials = append(partials, ds)
+		}
+
+		// 任意の t 個の部分集合（末尾から選ぶ）で復号できる
+		subset := partials[len(partials)-tc.threshold:]
+		got, err := tpk.CombineShares(ct, subset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Cmp(m) != 0 {
+			t.Errorf("%d-of-%d: got %v\nwant %v", tc.threshold, tc.parties, got, m)
+		}
+
+		if tc.threshold > 1 {
+			if _, err := tpk.CombineShares(ct, subset[1:]); err == nil {
+				t.Errorf("%d-of-%d: decrypted with too few shares", tc.threshold, tc.parties)
+			}
+		}
+	}
+}
+
+func TestThresholdIdentifiesBadShare(t *testing.T) {
+	tpk, shares, err := GenerateThresholdKey(1024, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, err := tpk.Encrypt(big.NewInt(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	good, err := shares[0].PartialDecrypt(tpk, ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 他人の鍵シェアで計算した部分復号は検出される
+	cheater := &KeyShare{Index: 2, Share: shares[2].Share}
+	bad, err := cheater.PartialDecrypt(tpk, ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tpk.CombineShares(ct, []*DecryptionShare{good, bad})
+	var invalid *InvalidShareError
+	if !errors.As(err, &invalid) || invalid.Index != 2 {
+		t.Fatalf("got %v, want invalid share from party 2", err)
+	}
+
+	// 部分復号値の改ざんも検出される
+	honest, err := shares[1].PartialDecrypt(tpk, ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	honest.C = new(big.Int).Mod(new(big.Int).Mul(honest.C, tpk.G), tpk.NSquare)
+	if tpk.VerifyDecryptionShare(ct, honest) {
+		t.Error("tampered share accepted")
+	}
+}
@@ -0,0 +1,103 @@
+package paillierrpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "multisigservice/proto/paillierpb"
+)
+
+// Config configures the gRPC server returned by NewGRPCServer.
+type Config struct {
+	// Addr is the listen address, e.g. "127.0.0.1:9090".
+	Addr string
+	// Token is the bearer token every call must present in its
+	// "authorization" metadata. It is required.
+	Token string
+	// CertFile and KeyFile enable TLS. They are required unless Addr is a
+	// loopback address.
+	CertFile string
+	KeyFile  string
+	// MaxKeys bounds the number of generated keys (DefaultMaxKeys if zero).
+	MaxKeys int
+}
+
+// NewGRPCServer returns a gRPC server with the PaillierService registered,
+// requiring the bearer token on every call and TLS for non-loopback addresses.
+func NewGRPCServer(cfg Config) (*grpc.Server, error) {
+	if cfg.Token == "" {
+		return nil, errors.New("paillier service requires an auth token")
+	}
+	opts := TokenAuth(cfg.Token)
+	switch {
+	case cfg.CertFile != "" || cfg.KeyFile != "":
+		creds, err := credentials.NewServerTLSFromFile(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	case !isLoopback(cfg.Addr):
+		// 平文のトークンをネットワークに流さないよう、外部公開にはTLSを必須とする
+		return nil, errors.New("paillier service requires TLS unless it listens on a loopback address")
+	}
+
+	maxKeys := cfg.MaxKeys
+	if maxKeys == 0 {
+		maxKeys = DefaultMaxKeys
+	}
+	gs := grpc.NewServer(opts...)
+	pb.RegisterPaillierServiceServer(gs, NewServerWithMaxKeys(maxKeys))
+	return gs, nil
+}
+
+// TokenAuth returns server options that reject every call whose
+// "authorization" metadata is not "Bearer <token>".
+func TokenAuth(token string) []grpc.ServerOption {
+	check := func(ctx context.Context) error {
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, v := range md.Get("authorization") {
+			got, ok := strings.CutPrefix(v, "Bearer ")
+			if ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+				return nil
+			}
+		}
+		return status.Error(codes.Unauthenticated, "invalid or missing auth token")
+	}
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := check(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := check(ss.Context()); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
+
+// isLoopback reports whether addr names a loopback host. An empty host
+// (":9090") listens on every interface and is not loopback.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
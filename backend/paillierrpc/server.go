@@ -0,0 +1,246 @@
+// Package paillierrpc implements the PaillierService gRPC service defined in
+// proto/paillier.proto on top of package paillier.
+package paillierrpc
+
+import (
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"multisigservice/paillier"
+	pb "multisigservice/proto/paillierpb"
+)
+
+// DefaultBitLen is used when GenerateKeyRequest.bit_len is zero.
+const DefaultBitLen = 2048
+
+// MaxBitLen bounds the modulus size a client may request.
+const MaxBitLen = 4096
+
+// DefaultMaxKeys is the number of keys from GenerateKey that NewServer keeps.
+const DefaultMaxKeys = 64
+
+// Server implements pb.PaillierServiceServer. Private keys never leave the
+// server; clients refer to them by the id returned from GenerateKey.
+//
+// At most maxKeys generated keys are kept; generating another evicts the
+// oldest one. Keys registered with AddKey are never evicted. Only one key is
+// generated at a time, since safe-prime generation can take minutes of CPU.
+type Server struct {
+	pb.UnimplementedPaillierServiceServer
+
+	mu        sync.RWMutex
+	keys      map[string]*paillier.PrivateKey
+	generated []string // GenerateKey で作った鍵のid（古い順）
+	maxKeys   int
+	generate  chan struct{}
+}
+
+// NewServer returns a Server with no keys that keeps DefaultMaxKeys generated keys.
+func NewServer() *Server {
+	return NewServerWithMaxKeys(DefaultMaxKeys)
+}
+
+// NewServerWithMaxKeys returns a Server with no keys that keeps at most
+// maxKeys (at least one) generated keys.
+func NewServerWithMaxKeys(maxKeys int) *Server {
+	if maxKeys < 1 {
+		maxKeys = 1
+	}
+	return &Server{
+		keys:     make(map[string]*paillier.PrivateKey),
+		maxKeys:  maxKeys,
+		generate: make(chan struct{}, 1),
+	}
+}
+
+// AddKey registers a locally held private key (e.g. one loaded with
+// keystore.LoadKey) and returns the id under which Decrypt can use it.
+func (s *Server) AddKey(priv *paillier.PrivateKey) string {
+	id := uuid.NewString()
+	s.mu.Lock()
+	s.keys[id] = priv
+	s.mu.Unlock()
+	return id
+}
+
+// addGenerated stores a key from GenerateKey, evicting the oldest generated
+// keys beyond maxKeys.
+func (s *Server) addGenerated(priv *paillier.PrivateKey) string {
+	id := uuid.NewString()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.generated) >= s.maxKeys {
+		delete(s.keys, s.generated[0])
+		s.generated = s.generated[1:]
+	}
+	s.keys[id] = priv
+	s.generated = append(s.generated, id)
+	return id
+}
+
+// key looks up a private key by id.
+func (s *Server) key(id string) (*paillier.PrivateKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	priv, ok := s.keys[id]
+	return priv, ok
+}
+
+// GenerateKey creates a key pair, keeps the private key and returns its id
+// together with the public key.
+func (s *Server) GenerateKey(ctx context.Context, req *pb.GenerateKeyRequest) (*pb.GenerateKeyResponse, error) {
+	bitLen := int(req.GetBitLen())
+	if bitLen == 0 {
+		bitLen = DefaultBitLen
+	}
+	if bitLen < paillier.MinBitLen || bitLen > MaxBitLen {
+		return nil, status.Errorf(codes.InvalidArgument, "bit length must be between %d and %d", paillier.MinBitLen, MaxBitLen)
+	}
+	// 鍵生成は同時に1つだけ行い、待っている間にクライアントが切断すれば諦める
+	select {
+	case s.generate <- struct{}{}:
+		defer func() { <-s.generate }()
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	pub, priv, err := paillier.GenerateKeyWithOptions(paillier.KeyOptions{
+		BitLen:     bitLen,
+		SafePrimes: req.GetSafePrimes(),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "key generation failed: %v", err)
+	}
+	return &pb.GenerateKeyResponse{
+		KeyId:     s.addGenerated(priv),
+		PublicKey: pub.ToProto(),
+	}, nil
+}
+
+// Encrypt encrypts a plaintext in [0, n) under the given public key.
+func (s *Server) Encrypt(ctx context.Context, req *pb.EncryptRequest) (*pb.CiphertextResponse, error) {
+	pub, err := publicKey(req.GetPublicKey())
+	if err != nil {
+		return nil, err
+	}
+	ct, err := pub.Encrypt(new(big.Int).SetBytes(req.GetPlaintext()))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return ciphertextResponse(ct), nil
+}
+
+// Add returns Enc(a + b).
+func (s *Server) Add(ctx context.Context, req *pb.AddRequest) (*pb.CiphertextResponse, error) {
+	pub, err := publicKey(req.GetPublicKey())
+	if err != nil {
+		return nil, err
+	}
+	a, err := ciphertext(pub, req.GetA())
+	if err != nil {
+		return nil, err
+	}
+	b, err := ciphertext(pub, req.GetB())
+	if err != nil {
+		return nil, err
+	}
+	sum, err := a.Add(pub, b)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return ciphertextResponse(sum), nil
+}
+
+// AddScalar returns Enc(m + k) for a signed scalar k.
+func (s *Server) AddScalar(ctx context.Context, req *pb.ScalarRequest) (*pb.CiphertextResponse, error) {
+	return scalarOp(req, (*paillier.Ciphertext).AddScalar)
+}
+
+// MulScalar returns Enc(m * k) for a signed scalar k.
+func (s *Server) MulScalar(ctx context.Context, req *pb.ScalarRequest) (*pb.CiphertextResponse, error) {
+	return scalarOp(req, (*paillier.Ciphertext).MulScalar)
+}
+
+// Rerandomize returns a fresh ciphertext of the same plaintext.
+func (s *Server) Rerandomize(ctx context.Context, req *pb.RerandomizeRequest) (*pb.CiphertextResponse, error) {
+	pub, err := publicKey(req.GetPublicKey())
+	if err != nil {
+		return nil, err
+	}
+	ct, err := ciphertext(pub, req.GetCiphertext())
+	if err != nil {
+		return nil, err
+	}
+	fresh, err := ct.Rerandomize(pub)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "rerandomization failed: %v", err)
+	}
+	return ciphertextResponse(fresh), nil
+}
+
+// Decrypt decrypts a ciphertext with a private key held by this server.
+func (s *Server) Decrypt(ctx context.Context, req *pb.DecryptRequest) (*pb.DecryptResponse, error) {
+	priv, ok := s.key(req.GetKeyId())
+	if !ok {
+		return nil, status.Error(codes.NotFound, "unknown key id")
+	}
+	ct, err := ciphertext(&priv.PublicKey, req.GetCiphertext())
+	if err != nil {
+		return nil, err
+	}
+	m, err := priv.Decrypt(ct)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &pb.DecryptResponse{Plaintext: m.Bytes()}, nil
+}
+
+// scalarOp applies a homomorphic scalar operation to the request's ciphertext.
+func scalarOp(req *pb.ScalarRequest, op func(*paillier.Ciphertext, *paillier.PublicKey, *big.Int) (*paillier.Ciphertext, error)) (*pb.CiphertextResponse, error) {
+	pub, err := publicKey(req.GetPublicKey())
+	if err != nil {
+		return nil, err
+	}
+	ct, err := ciphertext(pub, req.GetCiphertext())
+	if err != nil {
+		return nil, err
+	}
+	k, err := paillier.UnmarshalSigned(req.GetScalar())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	res, err := op(ct, pub, k)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return ciphertextResponse(res), nil
+}
+
+// publicKey decodes and validates a public key supplied by the client.
+func publicKey(msg *pb.PublicKey) (*paillier.PublicKey, error) {
+	if msg == nil {
+		return nil, status.Error(codes.InvalidArgument, "public key is missing")
+	}
+	pub := paillier.PublicKeyFromProto(msg)
+	if err := pub.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid public key: %v", err)
+	}
+	return pub, nil
+}
+
+// ciphertext decodes a ciphertext and checks it against pub.
+func ciphertext(pub *paillier.PublicKey, msg *pb.Ciphertext) (*paillier.Ciphertext, error) {
+	ct, err := paillier.CiphertextFromProto(pub, msg)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return ct, nil
+}
+
+func ciphertextResponse(ct *paillier.Ciphertext) *pb.CiphertextResponse {
+	return &pb.CiphertextResponse{Ciphertext: ct.ToProto()}
+}
@@ -88,6 +88,8 @@ type PrivateKey struct {
 	PublicKey     *PublicKey             `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
 	Lambda        []byte                 `protobuf:"bytes,2,opt,name=lambda,proto3" json:"lambda,omitempty"`
 	Mu            []byte                 `protobuf:"bytes,3,opt,name=mu,proto3" json:"mu,omitempty"`
+	P             []byte                 `protobuf:"bytes,4,opt,name=p,proto3" json:"p,omitempty"` // 素因数p（CRT復号用、旧形式では空）
+	Q             []byte                 `protobuf:"bytes,5,opt,name=q,proto3" json:"q,omitempty"` // 素因数q（CRT復号用、旧形式では空）
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -143,6 +145,20 @@ func (x *PrivateKey) GetMu() []byte {
 	return nil
 }
 
+func (x *PrivateKey) GetP() []byte {
+	if x != nil {
+		return x.P
+	}
+	return nil
+}
+
+func (x *PrivateKey) GetQ() []byte {
+	if x != nil {
+		return x.Q
+	}
+	return nil
+}
+
 // 暗号文
 type Ciphertext struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -188,24 +204,1384 @@ func (x *Ciphertext) GetC() []byte {
 	return nil
 }
 
-var File_paillier_proto protoreflect.FileDescriptor
+// Damgård–Jurik公開鍵（平文空間 Z_{n^s}）
+type DJPublicKey struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	N             []byte                 `protobuf:"bytes,1,opt,name=n,proto3" json:"n,omitempty"`
+	S             uint32                 `protobuf:"varint,2,opt,name=s,proto3" json:"s,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_paillier_proto_rawDesc = "" +
-	"\n" +
-	"\x0epaillier.proto\x12\bpaillier\"B\n" +
-	"\tPublicKey\x12\f\n" +
-	"\x01n\x18\x01 \x01(\fR\x01n\x12\x19\n" +
-	"\bn_square\x18\x02 \x01(\fR\anSquare\x12\f\n" +
-	"\x01g\x18\x03 \x01(\fR\x01g\"h\n" +
-	"\n" +
-	"PrivateKey\x122\n" +
-	"\n" +
-	"public_key\x18\x01 \x01(\v2\x13.paillier.PublicKeyR\tpublicKey\x12\x16\n" +
-	"\x06lambda\x18\x02 \x01(\fR\x06lambda\x12\x0e\n" +
-	"\x02mu\x18\x03 \x01(\fR\x02mu\"\x1a\n" +
-	"\n" +
-	"Ciphertext\x12\f\n" +
-	"\x01c\x18\x01 \x01(\fR\x01cB\rZ\v/paillierpbb\x06proto3"
+func (x *DJPublicKey) Reset() {
+	*x = DJPublicKey{}
+	mi := &file_paillier_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DJPublicKey) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DJPublicKey) ProtoMessage() {}
+
+func (x *DJPublicKey) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DJPublicKey.ProtoReflect.Descriptor instead.
+func (*DJPublicKey) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *DJPublicKey) GetN() []byte {
+	if x != nil {
+		return x.N
+	}
+	return nil
+}
+
+func (x *DJPublicKey) GetS() uint32 {
+	if x != nil {
+		return x.S
+	}
+	return 0
+}
+
+// Damgård–Jurik秘密鍵
+type DJPrivateKey struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PublicKey     *DJPublicKey           `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Lambda        []byte                 `protobuf:"bytes,2,opt,name=lambda,proto3" json:"lambda,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DJPrivateKey) Reset() {
+	*x = DJPrivateKey{}
+	mi := &file_paillier_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DJPrivateKey) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DJPrivateKey) ProtoMessage() {}
+
+func (x *DJPrivateKey) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DJPrivateKey.ProtoReflect.Descriptor instead.
+func (*DJPrivateKey) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DJPrivateKey) GetPublicKey() *DJPublicKey {
+	if x != nil {
+		return x.PublicKey
+	}
+	return nil
+}
+
+func (x *DJPrivateKey) GetLambda() []byte {
+	if x != nil {
+		return x.Lambda
+	}
+	return nil
+}
+
+// Damgård–Jurik暗号文（mod n^(s+1)）
+type DJCiphertext struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	C             []byte                 `protobuf:"bytes,1,opt,name=c,proto3" json:"c,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DJCiphertext) Reset() {
+	*x = DJCiphertext{}
+	mi := &file_paillier_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DJCiphertext) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DJCiphertext) ProtoMessage() {}
+
+func (x *DJCiphertext) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DJCiphertext.ProtoReflect.Descriptor instead.
+func (*DJCiphertext) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DJCiphertext) GetC() []byte {
+	if x != nil {
+		return x.C
+	}
+	return nil
+}
+
+// リングPedersenパラメータ（検証者の Ñ, s, t）
+type PedersenParams struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	N             []byte                 `protobuf:"bytes,1,opt,name=n,proto3" json:"n,omitempty"`
+	S             []byte                 `protobuf:"bytes,2,opt,name=s,proto3" json:"s,omitempty"`
+	T             []byte                 `protobuf:"bytes,3,opt,name=t,proto3" json:"t,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PedersenParams) Reset() {
+	*x = PedersenParams{}
+	mi := &file_paillier_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PedersenParams) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PedersenParams) ProtoMessage() {}
+
+func (x *PedersenParams) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PedersenParams.ProtoReflect.Descriptor instead.
+func (*PedersenParams) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PedersenParams) GetN() []byte {
+	if x != nil {
+		return x.N
+	}
+	return nil
+}
+
+func (x *PedersenParams) GetS() []byte {
+	if x != nil {
+		return x.S
+	}
+	return nil
+}
+
+func (x *PedersenParams) GetT() []byte {
+	if x != nil {
+		return x.T
+	}
+	return nil
+}
+
+// 暗号文の平文が [−2^ℓ, 2^ℓ] に含まれることの非対話ゼロ知識証明（Π-enc）
+// z1, z3 は符号付き整数で、先頭1バイトが符号（0: 非負, 1: 負）、残りが絶対値です。
+type EncProof struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	S             []byte                 `protobuf:"bytes,1,opt,name=s,proto3" json:"s,omitempty"`
+	A             []byte                 `protobuf:"bytes,2,opt,name=a,proto3" json:"a,omitempty"`
+	C             []byte                 `protobuf:"bytes,3,opt,name=c,proto3" json:"c,omitempty"`
+	Z1            []byte                 `protobuf:"bytes,4,opt,name=z1,proto3" json:"z1,omitempty"`
+	Z2            []byte                 `protobuf:"bytes,5,opt,name=z2,proto3" json:"z2,omitempty"`
+	Z3            []byte                 `protobuf:"bytes,6,opt,name=z3,proto3" json:"z3,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EncProof) Reset() {
+	*x = EncProof{}
+	mi := &file_paillier_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EncProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncProof) ProtoMessage() {}
+
+func (x *EncProof) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncProof.ProtoReflect.Descriptor instead.
+func (*EncProof) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *EncProof) GetS() []byte {
+	if x != nil {
+		return x.S
+	}
+	return nil
+}
+
+func (x *EncProof) GetA() []byte {
+	if x != nil {
+		return x.A
+	}
+	return nil
+}
+
+func (x *EncProof) GetC() []byte {
+	if x != nil {
+		return x.C
+	}
+	return nil
+}
+
+func (x *EncProof) GetZ1() []byte {
+	if x != nil {
+		return x.Z1
+	}
+	return nil
+}
+
+func (x *EncProof) GetZ2() []byte {
+	if x != nil {
+		return x.Z2
+	}
+	return nil
+}
+
+func (x *EncProof) GetZ3() []byte {
+	if x != nil {
+		return x.Z3
+	}
+	return nil
+}
+
+// D = C^x·(1+N)^y·ρ^N の x, y がそれぞれ [−2^ℓx, 2^ℓx], [−2^ℓy, 2^ℓy] に含まれることの非対話ゼロ知識証明（Π-aff）
+// z1〜z4 は EncProof と同じ符号付き整数の形式です。
+type AffProof struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	A             []byte                 `protobuf:"bytes,1,opt,name=a,proto3" json:"a,omitempty"`
+	E             []byte                 `protobuf:"bytes,2,opt,name=e,proto3" json:"e,omitempty"`
+	S             []byte                 `protobuf:"bytes,3,opt,name=s,proto3" json:"s,omitempty"`
+	F             []byte                 `protobuf:"bytes,4,opt,name=f,proto3" json:"f,omitempty"`
+	T             []byte                 `protobuf:"bytes,5,opt,name=t,proto3" json:"t,omitempty"`
+	Z1            []byte                 `protobuf:"bytes,6,opt,name=z1,proto3" json:"z1,omitempty"`
+	Z2            []byte                 `protobuf:"bytes,7,opt,name=z2,proto3" json:"z2,omitempty"`
+	Z3            []byte                 `protobuf:"bytes,8,opt,name=z3,proto3" json:"z3,omitempty"`
+	Z4            []byte                 `protobuf:"bytes,9,opt,name=z4,proto3" json:"z4,omitempty"`
+	W             []byte                 `protobuf:"bytes,10,opt,name=w,proto3" json:"w,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AffProof) Reset() {
+	*x = AffProof{}
+	mi := &file_paillier_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AffProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AffProof) ProtoMessage() {}
+
+func (x *AffProof) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AffProof.ProtoReflect.Descriptor instead.
+func (*AffProof) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *AffProof) GetA() []byte {
+	if x != nil {
+		return x.A
+	}
+	return nil
+}
+
+func (x *AffProof) GetE() []byte {
+	if x != nil {
+		return x.E
+	}
+	return nil
+}
+
+func (x *AffProof) GetS() []byte {
+	if x != nil {
+		return x.S
+	}
+	return nil
+}
+
+func (x *AffProof) GetF() []byte {
+	if x != nil {
+		return x.F
+	}
+	return nil
+}
+
+func (x *AffProof) GetT() []byte {
+	if x != nil {
+		return x.T
+	}
+	return nil
+}
+
+func (x *AffProof) GetZ1() []byte {
+	if x != nil {
+		return x.Z1
+	}
+	return nil
+}
+
+func (x *AffProof) GetZ2() []byte {
+	if x != nil {
+		return x.Z2
+	}
+	return nil
+}
+
+func (x *AffProof) GetZ3() []byte {
+	if x != nil {
+		return x.Z3
+	}
+	return nil
+}
+
+func (x *AffProof) GetZ4() []byte {
+	if x != nil {
+		return x.Z4
+	}
+	return nil
+}
+
+func (x *AffProof) GetW() []byte {
+	if x != nil {
+		return x.W
+	}
+	return nil
+}
+
+// Nがパイエ・ブラム整数であることの非対話ゼロ知識証明（Π-mod）
+type ModProof struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	W             []byte                 `protobuf:"bytes,1,opt,name=w,proto3" json:"w,omitempty"`
+	X             [][]byte               `protobuf:"bytes,2,rep,name=x,proto3" json:"x,omitempty"`
+	A             []bool                 `protobuf:"varint,3,rep,packed,name=a,proto3" json:"a,omitempty"`
+	B             []bool                 `protobuf:"varint,4,rep,packed,name=b,proto3" json:"b,omitempty"`
+	Z             [][]byte               `protobuf:"bytes,5,rep,name=z,proto3" json:"z,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ModProof) Reset() {
+	*x = ModProof{}
+	mi := &file_paillier_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ModProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModProof) ProtoMessage() {}
+
+func (x *ModProof) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ModProof.ProtoReflect.Descriptor instead.
+func (*ModProof) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ModProof) GetW() []byte {
+	if x != nil {
+		return x.W
+	}
+	return nil
+}
+
+func (x *ModProof) GetX() [][]byte {
+	if x != nil {
+		return x.X
+	}
+	return nil
+}
+
+func (x *ModProof) GetA() []bool {
+	if x != nil {
+		return x.A
+	}
+	return nil
+}
+
+func (x *ModProof) GetB() []bool {
+	if x != nil {
+		return x.B
+	}
+	return nil
+}
+
+func (x *ModProof) GetZ() [][]byte {
+	if x != nil {
+		return x.Z
+	}
+	return nil
+}
+
+// Nが小さな素因数を持たないことの非対話ゼロ知識証明（Π-fac）
+// sigma, z1, z2, w1, w2, v は EncProof と同じ符号付き整数の形式です。
+type FacProof struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	P             []byte                 `protobuf:"bytes,1,opt,name=p,proto3" json:"p,omitempty"`
+	Q             []byte                 `protobuf:"bytes,2,opt,name=q,proto3" json:"q,omitempty"`
+	A             []byte                 `protobuf:"bytes,3,opt,name=a,proto3" json:"a,omitempty"`
+	B             []byte                 `protobuf:"bytes,4,opt,name=b,proto3" json:"b,omitempty"`
+	T             []byte                 `protobuf:"bytes,5,opt,name=t,proto3" json:"t,omitempty"`
+	Sigma         []byte                 `protobuf:"bytes,6,opt,name=sigma,proto3" json:"sigma,omitempty"`
+	Z1            []byte                 `protobuf:"bytes,7,opt,name=z1,proto3" json:"z1,omitempty"`
+	Z2            []byte                 `protobuf:"bytes,8,opt,name=z2,proto3" json:"z2,omitempty"`
+	W1            []byte                 `protobuf:"bytes,9,opt,name=w1,proto3" json:"w1,omitempty"`
+	W2            []byte                 `protobuf:"bytes,10,opt,name=w2,proto3" json:"w2,omitempty"`
+	V             []byte                 `protobuf:"bytes,11,opt,name=v,proto3" json:"v,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FacProof) Reset() {
+	*x = FacProof{}
+	mi := &file_paillier_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FacProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FacProof) ProtoMessage() {}
+
+func (x *FacProof) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FacProof.ProtoReflect.Descriptor instead.
+func (*FacProof) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *FacProof) GetP() []byte {
+	if x != nil {
+		return x.P
+	}
+	return nil
+}
+
+func (x *FacProof) GetQ() []byte {
+	if x != nil {
+		return x.Q
+	}
+	return nil
+}
+
+func (x *FacProof) GetA() []byte {
+	if x != nil {
+		return x.A
+	}
+	return nil
+}
+
+func (x *FacProof) GetB() []byte {
+	if x != nil {
+		return x.B
+	}
+	return nil
+}
+
+func (x *FacProof) GetT() []byte {
+	if x != nil {
+		return x.T
+	}
+	return nil
+}
+
+func (x *FacProof) GetSigma() []byte {
+	if x != nil {
+		return x.Sigma
+	}
+	return nil
+}
+
+func (x *FacProof) GetZ1() []byte {
+	if x != nil {
+		return x.Z1
+	}
+	return nil
+}
+
+func (x *FacProof) GetZ2() []byte {
+	if x != nil {
+		return x.Z2
+	}
+	return nil
+}
+
+func (x *FacProof) GetW1() []byte {
+	if x != nil {
+		return x.W1
+	}
+	return nil
+}
+
+func (x *FacProof) GetW2() []byte {
+	if x != nil {
+		return x.W2
+	}
+	return nil
+}
+
+func (x *FacProof) GetV() []byte {
+	if x != nil {
+		return x.V
+	}
+	return nil
+}
+
+// リングPedersenパラメータが s = t^λ mod Ñ を満たすことの非対話ゼロ知識証明（Π-prm）
+type PrmProof struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	A             [][]byte               `protobuf:"bytes,1,rep,name=a,proto3" json:"a,omitempty"`
+	Z             [][]byte               `protobuf:"bytes,2,rep,name=z,proto3" json:"z,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PrmProof) Reset() {
+	*x = PrmProof{}
+	mi := &file_paillier_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrmProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrmProof) ProtoMessage() {}
+
+func (x *PrmProof) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrmProof.ProtoReflect.Descriptor instead.
+func (*PrmProof) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *PrmProof) GetA() [][]byte {
+	if x != nil {
+		return x.A
+	}
+	return nil
+}
+
+func (x *PrmProof) GetZ() [][]byte {
+	if x != nil {
+		return x.Z
+	}
+	return nil
+}
+
+// MtA: Alice → Bob（Enc_A(a) と範囲証明）
+type MtARound1 struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CA            *Ciphertext            `protobuf:"bytes,1,opt,name=c_a,json=cA,proto3" json:"c_a,omitempty"`
+	RangeProof    *EncProof              `protobuf:"bytes,2,opt,name=range_proof,json=rangeProof,proto3" json:"range_proof,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MtARound1) Reset() {
+	*x = MtARound1{}
+	mi := &file_paillier_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MtARound1) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MtARound1) ProtoMessage() {}
+
+func (x *MtARound1) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MtARound1.ProtoReflect.Descriptor instead.
+func (*MtARound1) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *MtARound1) GetCA() *Ciphertext {
+	if x != nil {
+		return x.CA
+	}
+	return nil
+}
+
+func (x *MtARound1) GetRangeProof() *EncProof {
+	if x != nil {
+		return x.RangeProof
+	}
+	return nil
+}
+
+// MtA: Bob → Alice（Enc_A(a·b + β') とアフィン証明）
+type MtARound2 struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CB            *Ciphertext            `protobuf:"bytes,1,opt,name=c_b,json=cB,proto3" json:"c_b,omitempty"`
+	AffineProof   *AffProof              `protobuf:"bytes,2,opt,name=affine_proof,json=affineProof,proto3" json:"affine_proof,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MtARound2) Reset() {
+	*x = MtARound2{}
+	mi := &file_paillier_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MtARound2) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MtARound2) ProtoMessage() {}
+
+func (x *MtARound2) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MtARound2.ProtoReflect.Descriptor instead.
+func (*MtARound2) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *MtARound2) GetCB() *Ciphertext {
+	if x != nil {
+		return x.CB
+	}
+	return nil
+}
+
+func (x *MtARound2) GetAffineProof() *AffProof {
+	if x != nil {
+		return x.AffineProof
+	}
+	return nil
+}
+
+type GenerateKeyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BitLen        uint32                 `protobuf:"varint,1,opt,name=bit_len,json=bitLen,proto3" json:"bit_len,omitempty"` // 0 の場合は 2048
+	SafePrimes    bool                   `protobuf:"varint,2,opt,name=safe_primes,json=safePrimes,proto3" json:"safe_primes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateKeyRequest) Reset() {
+	*x = GenerateKeyRequest{}
+	mi := &file_paillier_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateKeyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateKeyRequest) ProtoMessage() {}
+
+func (x *GenerateKeyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateKeyRequest.ProtoReflect.Descriptor instead.
+func (*GenerateKeyRequest) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GenerateKeyRequest) GetBitLen() uint32 {
+	if x != nil {
+		return x.BitLen
+	}
+	return 0
+}
+
+func (x *GenerateKeyRequest) GetSafePrimes() bool {
+	if x != nil {
+		return x.SafePrimes
+	}
+	return false
+}
+
+type GenerateKeyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	KeyId         string                 `protobuf:"bytes,1,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	PublicKey     *PublicKey             `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateKeyResponse) Reset() {
+	*x = GenerateKeyResponse{}
+	mi := &file_paillier_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateKeyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateKeyResponse) ProtoMessage() {}
+
+func (x *GenerateKeyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateKeyResponse.ProtoReflect.Descriptor instead.
+func (*GenerateKeyResponse) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GenerateKeyResponse) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+func (x *GenerateKeyResponse) GetPublicKey() *PublicKey {
+	if x != nil {
+		return x.PublicKey
+	}
+	return nil
+}
+
+type EncryptRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PublicKey     *PublicKey             `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Plaintext     []byte                 `protobuf:"bytes,2,opt,name=plaintext,proto3" json:"plaintext,omitempty"` // [0, n) の非負整数
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EncryptRequest) Reset() {
+	*x = EncryptRequest{}
+	mi := &file_paillier_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EncryptRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EncryptRequest) ProtoMessage() {}
+
+func (x *EncryptRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EncryptRequest.ProtoReflect.Descriptor instead.
+func (*EncryptRequest) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *EncryptRequest) GetPublicKey() *PublicKey {
+	if x != nil {
+		return x.PublicKey
+	}
+	return nil
+}
+
+func (x *EncryptRequest) GetPlaintext() []byte {
+	if x != nil {
+		return x.Plaintext
+	}
+	return nil
+}
+
+type AddRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PublicKey     *PublicKey             `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	A             *Ciphertext            `protobuf:"bytes,2,opt,name=a,proto3" json:"a,omitempty"`
+	B             *Ciphertext            `protobuf:"bytes,3,opt,name=b,proto3" json:"b,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddRequest) Reset() {
+	*x = AddRequest{}
+	mi := &file_paillier_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddRequest) ProtoMessage() {}
+
+func (x *AddRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddRequest.ProtoReflect.Descriptor instead.
+func (*AddRequest) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *AddRequest) GetPublicKey() *PublicKey {
+	if x != nil {
+		return x.PublicKey
+	}
+	return nil
+}
+
+func (x *AddRequest) GetA() *Ciphertext {
+	if x != nil {
+		return x.A
+	}
+	return nil
+}
+
+func (x *AddRequest) GetB() *Ciphertext {
+	if x != nil {
+		return x.B
+	}
+	return nil
+}
+
+// scalar は EncProof と同じ符号付き整数の形式です。
+type ScalarRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PublicKey     *PublicKey             `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Ciphertext    *Ciphertext            `protobuf:"bytes,2,opt,name=ciphertext,proto3" json:"ciphertext,omitempty"`
+	Scalar        []byte                 `protobuf:"bytes,3,opt,name=scalar,proto3" json:"scalar,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScalarRequest) Reset() {
+	*x = ScalarRequest{}
+	mi := &file_paillier_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScalarRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScalarRequest) ProtoMessage() {}
+
+func (x *ScalarRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScalarRequest.ProtoReflect.Descriptor instead.
+func (*ScalarRequest) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ScalarRequest) GetPublicKey() *PublicKey {
+	if x != nil {
+		return x.PublicKey
+	}
+	return nil
+}
+
+func (x *ScalarRequest) GetCiphertext() *Ciphertext {
+	if x != nil {
+		return x.Ciphertext
+	}
+	return nil
+}
+
+func (x *ScalarRequest) GetScalar() []byte {
+	if x != nil {
+		return x.Scalar
+	}
+	return nil
+}
+
+type RerandomizeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PublicKey     *PublicKey             `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Ciphertext    *Ciphertext            `protobuf:"bytes,2,opt,name=ciphertext,proto3" json:"ciphertext,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RerandomizeRequest) Reset() {
+	*x = RerandomizeRequest{}
+	mi := &file_paillier_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RerandomizeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RerandomizeRequest) ProtoMessage() {}
+
+func (x *RerandomizeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RerandomizeRequest.ProtoReflect.Descriptor instead.
+func (*RerandomizeRequest) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *RerandomizeRequest) GetPublicKey() *PublicKey {
+	if x != nil {
+		return x.PublicKey
+	}
+	return nil
+}
+
+func (x *RerandomizeRequest) GetCiphertext() *Ciphertext {
+	if x != nil {
+		return x.Ciphertext
+	}
+	return nil
+}
+
+type CiphertextResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ciphertext    *Ciphertext            `protobuf:"bytes,1,opt,name=ciphertext,proto3" json:"ciphertext,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CiphertextResponse) Reset() {
+	*x = CiphertextResponse{}
+	mi := &file_paillier_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CiphertextResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CiphertextResponse) ProtoMessage() {}
+
+func (x *CiphertextResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CiphertextResponse.ProtoReflect.Descriptor instead.
+func (*CiphertextResponse) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *CiphertextResponse) GetCiphertext() *Ciphertext {
+	if x != nil {
+		return x.Ciphertext
+	}
+	return nil
+}
+
+type DecryptRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	KeyId         string                 `protobuf:"bytes,1,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	Ciphertext    *Ciphertext            `protobuf:"bytes,2,opt,name=ciphertext,proto3" json:"ciphertext,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DecryptRequest) Reset() {
+	*x = DecryptRequest{}
+	mi := &file_paillier_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DecryptRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DecryptRequest) ProtoMessage() {}
+
+func (x *DecryptRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DecryptRequest.ProtoReflect.Descriptor instead.
+func (*DecryptRequest) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *DecryptRequest) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+func (x *DecryptRequest) GetCiphertext() *Ciphertext {
+	if x != nil {
+		return x.Ciphertext
+	}
+	return nil
+}
+
+type DecryptResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Plaintext     []byte                 `protobuf:"bytes,1,opt,name=plaintext,proto3" json:"plaintext,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DecryptResponse) Reset() {
+	*x = DecryptResponse{}
+	mi := &file_paillier_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DecryptResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DecryptResponse) ProtoMessage() {}
+
+func (x *DecryptResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_paillier_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DecryptResponse.ProtoReflect.Descriptor instead.
+func (*DecryptResponse) Descriptor() ([]byte, []int) {
+	return file_paillier_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *DecryptResponse) GetPlaintext() []byte {
+	if x != nil {
+		return x.Plaintext
+	}
+	return nil
+}
+
+var File_paillier_proto protoreflect.FileDescriptor
+
+const file_paillier_proto_rawDesc = "" +
+	"\n" +
+	"\x0epaillier.proto\x12\bpaillier\"B\n" +
+	"\tPublicKey\x12\f\n" +
+	"\x01n\x18\x01 \x01(\fR\x01n\x12\x19\n" +
+	"\bn_square\x18\x02 \x01(\fR\anSquare\x12\f\n" +
+	"\x01g\x18\x03 \x01(\fR\x01g\"\x84\x01\n" +
+	"\n" +
+	"PrivateKey\x122\n" +
+	"\n" +
+	"public_key\x18\x01 \x01(\v2\x13.paillier.PublicKeyR\tpublicKey\x12\x16\n" +
+	"\x06lambda\x18\x02 \x01(\fR\x06lambda\x12\x0e\n" +
+	"\x02mu\x18\x03 \x01(\fR\x02mu\x12\f\n" +
+	"\x01p\x18\x04 \x01(\fR\x01p\x12\f\n" +
+	"\x01q\x18\x05 \x01(\fR\x01q\"\x1a\n" +
+	"\n" +
+	"Ciphertext\x12\f\n" +
+	"\x01c\x18\x01 \x01(\fR\x01c\")\n" +
+	"\vDJPublicKey\x12\f\n" +
+	"\x01n\x18\x01 \x01(\fR\x01n\x12\f\n" +
+	"\x01s\x18\x02 \x01(\rR\x01s\"\\\n" +
+	"\fDJPrivateKey\x124\n" +
+	"\n" +
+	"public_key\x18\x01 \x01(\v2\x15.paillier.DJPublicKeyR\tpublicKey\x12\x16\n" +
+	"\x06lambda\x18\x02 \x01(\fR\x06lambda\"\x1c\n" +
+	"\fDJCiphertext\x12\f\n" +
+	"\x01c\x18\x01 \x01(\fR\x01c\":\n" +
+	"\x0ePedersenParams\x12\f\n" +
+	"\x01n\x18\x01 \x01(\fR\x01n\x12\f\n" +
+	"\x01s\x18\x02 \x01(\fR\x01s\x12\f\n" +
+	"\x01t\x18\x03 \x01(\fR\x01t\"d\n" +
+	"\bEncProof\x12\f\n" +
+	"\x01s\x18\x01 \x01(\fR\x01s\x12\f\n" +
+	"\x01a\x18\x02 \x01(\fR\x01a\x12\f\n" +
+	"\x01c\x18\x03 \x01(\fR\x01c\x12\x0e\n" +
+	"\x02z1\x18\x04 \x01(\fR\x02z1\x12\x0e\n" +
+	"\x02z2\x18\x05 \x01(\fR\x02z2\x12\x0e\n" +
+	"\x02z3\x18\x06 \x01(\fR\x02z3\"\x9e\x01\n" +
+	"\bAffProof\x12\f\n" +
+	"\x01a\x18\x01 \x01(\fR\x01a\x12\f\n" +
+	"\x01e\x18\x02 \x01(\fR\x01e\x12\f\n" +
+	"\x01s\x18\x03 \x01(\fR\x01s\x12\f\n" +
+	"\x01f\x18\x04 \x01(\fR\x01f\x12\f\n" +
+	"\x01t\x18\x05 \x01(\fR\x01t\x12\x0e\n" +
+	"\x02z1\x18\x06 \x01(\fR\x02z1\x12\x0e\n" +
+	"\x02z2\x18\a \x01(\fR\x02z2\x12\x0e\n" +
+	"\x02z3\x18\b \x01(\fR\x02z3\x12\x0e\n" +
+	"\x02z4\x18\t \x01(\fR\x02z4\x12\f\n" +
+	"\x01w\x18\n" +
+	" \x01(\fR\x01w\"P\n" +
+	"\bModProof\x12\f\n" +
+	"\x01w\x18\x01 \x01(\fR\x01w\x12\f\n" +
+	"\x01x\x18\x02 \x03(\fR\x01x\x12\f\n" +
+	"\x01a\x18\x03 \x03(\bR\x01a\x12\f\n" +
+	"\x01b\x18\x04 \x03(\bR\x01b\x12\f\n" +
+	"\x01z\x18\x05 \x03(\fR\x01z\"\xb4\x01\n" +
+	"\bFacProof\x12\f\n" +
+	"\x01p\x18\x01 \x01(\fR\x01p\x12\f\n" +
+	"\x01q\x18\x02 \x01(\fR\x01q\x12\f\n" +
+	"\x01a\x18\x03 \x01(\fR\x01a\x12\f\n" +
+	"\x01b\x18\x04 \x01(\fR\x01b\x12\f\n" +
+	"\x01t\x18\x05 \x01(\fR\x01t\x12\x14\n" +
+	"\x05sigma\x18\x06 \x01(\fR\x05sigma\x12\x0e\n" +
+	"\x02z1\x18\a \x01(\fR\x02z1\x12\x0e\n" +
+	"\x02z2\x18\b \x01(\fR\x02z2\x12\x0e\n" +
+	"\x02w1\x18\t \x01(\fR\x02w1\x12\x0e\n" +
+	"\x02w2\x18\n" +
+	" \x01(\fR\x02w2\x12\f\n" +
+	"\x01v\x18\v \x01(\fR\x01v\"&\n" +
+	"\bPrmProof\x12\f\n" +
+	"\x01a\x18\x01 \x03(\fR\x01a\x12\f\n" +
+	"\x01z\x18\x02 \x03(\fR\x01z\"g\n" +
+	"\tMtARound1\x12%\n" +
+	"\x03c_a\x18\x01 \x01(\v2\x14.paillier.CiphertextR\x02cA\x123\n" +
+	"\vrange_proof\x18\x02 \x01(\v2\x12.paillier.EncProofR\n" +
+	"rangeProof\"i\n" +
+	"\tMtARound2\x12%\n" +
+	"\x03c_b\x18\x01 \x01(\v2\x14.paillier.CiphertextR\x02cB\x125\n" +
+	"\faffine_proof\x18\x02 \x01(\v2\x12.paillier.AffProofR\vaffineProof\"N\n" +
+	"\x12GenerateKeyRequest\x12\x17\n" +
+	"\abit_len\x18\x01 \x01(\rR\x06bitLen\x12\x1f\n" +
+	"\vsafe_primes\x18\x02 \x01(\bR\n" +
+	"safePrimes\"`\n" +
+	"\x13GenerateKeyResponse\x12\x15\n" +
+	"\x06key_id\x18\x01 \x01(\tR\x05keyId\x122\n" +
+	"\n" +
+	"public_key\x18\x02 \x01(\v2\x13.paillier.PublicKeyR\tpublicKey\"b\n" +
+	"\x0eEncryptRequest\x122\n" +
+	"\n" +
+	"public_key\x18\x01 \x01(\v2\x13.paillier.PublicKeyR\tpublicKey\x12\x1c\n" +
+	"\tplaintext\x18\x02 \x01(\fR\tplaintext\"\x88\x01\n" +
+	"\n" +
+	"AddRequest\x122\n" +
+	"\n" +
+	"public_key\x18\x01 \x01(\v2\x13.paillier.PublicKeyR\tpublicKey\x12\"\n" +
+	"\x01a\x18\x02 \x01(\v2\x14.paillier.CiphertextR\x01a\x12\"\n" +
+	"\x01b\x18\x03 \x01(\v2\x14.paillier.CiphertextR\x01b\"\x91\x01\n" +
+	"\rScalarRequest\x122\n" +
+	"\n" +
+	"public_key\x18\x01 \x01(\v2\x13.paillier.PublicKeyR\tpublicKey\x124\n" +
+	"\n" +
+	"ciphertext\x18\x02 \x01(\v2\x14.paillier.CiphertextR\n" +
+	"ciphertext\x12\x16\n" +
+	"\x06scalar\x18\x03 \x01(\fR\x06scalar\"~\n" +
+	"\x12RerandomizeRequest\x122\n" +
+	"\n" +
+	"public_key\x18\x01 \x01(\v2\x13.paillier.PublicKeyR\tpublicKey\x124\n" +
+	"\n" +
+	"ciphertext\x18\x02 \x01(\v2\x14.paillier.CiphertextR\n" +
+	"ciphertext\"J\n" +
+	"\x12CiphertextResponse\x124\n" +
+	"\n" +
+	"ciphertext\x18\x01 \x01(\v2\x14.paillier.CiphertextR\n" +
+	"ciphertext\"]\n" +
+	"\x0eDecryptRequest\x12\x15\n" +
+	"\x06key_id\x18\x01 \x01(\tR\x05keyId\x124\n" +
+	"\n" +
+	"ciphertext\x18\x02 \x01(\v2\x14.paillier.CiphertextR\n" +
+	"ciphertext\"/\n" +
+	"\x0fDecryptResponse\x12\x1c\n" +
+	"\tplaintext\x18\x01 \x01(\fR\tplaintext2\xee\x03\n" +
+	"\x0fPaillierService\x12J\n" +
+	"\vGenerateKey\x12\x1c.paillier.GenerateKeyRequest\x1a\x1d.paillier.GenerateKeyResponse\x12A\n" +
+	"\aEncrypt\x12\x18.paillier.EncryptRequest\x1a\x1c.paillier.CiphertextResponse\x129\n" +
+	"\x03Add\x12\x14.paillier.AddRequest\x1a\x1c.paillier.CiphertextResponse\x12B\n" +
+	"\tAddScalar\x12\x17.paillier.ScalarRequest\x1a\x1c.paillier.CiphertextResponse\x12B\n" +
+	"\tMulScalar\x12\x17.paillier.ScalarRequest\x1a\x1c.paillier.CiphertextResponse\x12I\n" +
+	"\vRerandomize\x12\x1c.paillier.RerandomizeRequest\x1a\x1c.paillier.CiphertextResponse\x12>\n" +
+	"\aDecrypt\x12\x18.paillier.DecryptRequest\x1a\x19.paillier.DecryptResponseB\rZ\v/paillierpbb\x06proto3"
 
 var (
 	file_paillier_proto_rawDescOnce sync.Once
@@ -219,19 +1595,69 @@ func file_paillier_proto_rawDescGZIP() []byte {
 	return file_paillier_proto_rawDescData
 }
 
-var file_paillier_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_paillier_proto_msgTypes = make([]protoimpl.MessageInfo, 23)
 var file_paillier_proto_goTypes = []any{
-	(*PublicKey)(nil),  // 0: paillier.PublicKey
-	(*PrivateKey)(nil), // 1: paillier.PrivateKey
-	(*Ciphertext)(nil), // 2: paillier.Ciphertext
+	(*PublicKey)(nil),           // 0: paillier.PublicKey
+	(*PrivateKey)(nil),          // 1: paillier.PrivateKey
+	(*Ciphertext)(nil),          // 2: paillier.Ciphertext
+	(*DJPublicKey)(nil),         // 3: paillier.DJPublicKey
+	(*DJPrivateKey)(nil),        // 4: paillier.DJPrivateKey
+	(*DJCiphertext)(nil),        // 5: paillier.DJCiphertext
+	(*PedersenParams)(nil),      // 6: paillier.PedersenParams
+	(*EncProof)(nil),            // 7: paillier.EncProof
+	(*AffProof)(nil),            // 8: paillier.AffProof
+	(*ModProof)(nil),            // 9: paillier.ModProof
+	(*FacProof)(nil),            // 10: paillier.FacProof
+	(*PrmProof)(nil),            // 11: paillier.PrmProof
+	(*MtARound1)(nil),           // 12: paillier.MtARound1
+	(*MtARound2)(nil),           // 13: paillier.MtARound2
+	(*GenerateKeyRequest)(nil),  // 14: paillier.GenerateKeyRequest
+	(*GenerateKeyResponse)(nil), // 15: paillier.GenerateKeyResponse
+	(*EncryptRequest)(nil),      // 16: paillier.EncryptRequest
+	(*AddRequest)(nil),          // 17: paillier.AddRequest
+	(*ScalarRequest)(nil),       // 18: paillier.ScalarRequest
+	(*RerandomizeRequest)(nil),  // 19: paillier.RerandomizeRequest
+	(*CiphertextResponse)(nil),  // 20: paillier.CiphertextResponse
+	(*DecryptRequest)(nil),      // 21: paillier.DecryptRequest
+	(*DecryptResponse)(nil),     // 22: paillier.DecryptResponse
 }
 var file_paillier_proto_depIdxs = []int32{
-	0, // 0: paillier.PrivateKey.public_key:type_name -> paillier.PublicKey
-	1, // [1:1] is the sub-list for method output_type
-	1, // [1:1] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	0,  // 0: paillier.PrivateKey.public_key:type_name -> paillier.PublicKey
+	3,  // 1: paillier.DJPrivateKey.public_key:type_name -> paillier.DJPublicKey
+	2,  // 2: paillier.MtARound1.c_a:type_name -> paillier.Ciphertext
+	7,  // 3: paillier.MtARound1.range_proof:type_name -> paillier.EncProof
+	2,  // 4: paillier.MtARound2.c_b:type_name -> paillier.Ciphertext
+	8,  // 5: paillier.MtARound2.affine_proof:type_name -> paillier.AffProof
+	0,  // 6: paillier.GenerateKeyResponse.public_key:type_name -> paillier.PublicKey
+	0,  // 7: paillier.EncryptRequest.public_key:type_name -> paillier.PublicKey
+	0,  // 8: paillier.AddRequest.public_key:type_name -> paillier.PublicKey
+	2,  // 9: paillier.AddRequest.a:type_name -> paillier.Ciphertext
+	2,  // 10: paillier.AddRequest.b:type_name -> paillier.Ciphertext
+	0,  // 11: paillier.ScalarRequest.public_key:type_name -> paillier.PublicKey
+	2,  // 12: paillier.ScalarRequest.ciphertext:type_name -> paillier.Ciphertext
+	0,  // 13: paillier.RerandomizeRequest.public_key:type_name -> paillier.PublicKey
+	2,  // 14: paillier.RerandomizeRequest.ciphertext:type_name -> paillier.Ciphertext
+	2,  // 15: paillier.CiphertextResponse.ciphertext:type_name -> paillier.Ciphertext
+	2,  // 16: paillier.DecryptRequest.ciphertext:type_name -> paillier.Ciphertext
+	14, // 17: paillier.PaillierService.GenerateKey:input_type -> paillier.GenerateKeyRequest
+	16, // 18: paillier.PaillierService.Encrypt:input_type -> paillier.EncryptRequest
+	17, // 19: paillier.PaillierService.Add:input_type -> paillier.AddRequest
+	18, // 20: paillier.PaillierService.AddScalar:input_type -> paillier.ScalarRequest
+	18, // 21: paillier.PaillierService.MulScalar:input_type -> paillier.ScalarRequest
+	19, // 22: paillier.PaillierService.Rerandomize:input_type -> paillier.RerandomizeRequest
+	21, // 23: paillier.PaillierService.Decrypt:input_type -> paillier.DecryptRequest
+	15, // 24: paillier.PaillierService.GenerateKey:output_type -> paillier.GenerateKeyResponse
+	20, // 25: paillier.PaillierService.Encrypt:output_type -> paillier.CiphertextResponse
+	20, // 26: paillier.PaillierService.Add:output_type -> paillier.CiphertextResponse
+	20, // 27: paillier.PaillierService.AddScalar:output_type -> paillier.CiphertextResponse
+	20, // 28: paillier.PaillierService.MulScalar:output_type -> paillier.CiphertextResponse
+	20, // 29: paillier.PaillierService.Rerandomize:output_type -> paillier.CiphertextResponse
+	22, // 30: paillier.PaillierService.Decrypt:output_type -> paillier.DecryptResponse
+	24, // [24:31] is the sub-list for method output_type
+	17, // [17:24] is the sub-list for method input_type
+	17, // [17:17] is the sub-list for extension type_name
+	17, // [17:17] is the sub-list for extension extendee
+	0,  // [0:17] is the sub-list for field type_name
 }
 
 func init() { file_paillier_proto_init() }
@@ -245,9 +1671,9 @@ func file_paillier_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_paillier_proto_rawDesc), len(file_paillier_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   3,
+			NumMessages:   23,
 			NumExtensions: 0,
-			NumServices:   0,
+			NumServices:   1,
 		},
 		GoTypes:           file_paillier_proto_goTypes,
 		DependencyIndexes: file_paillier_proto_depIdxs,
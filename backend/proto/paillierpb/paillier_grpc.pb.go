@@ -0,0 +1,331 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v6.31.0
+// source: paillier.proto
+
+package paillierpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	PaillierService_GenerateKey_FullMethodName = "/paillier.PaillierService/GenerateKey"
+	PaillierService_Encrypt_FullMethodName     = "/paillier.PaillierService/Encrypt"
+	PaillierService_Add_FullMethodName         = "/paillier.PaillierService/Add"
+	PaillierService_AddScalar_FullMethodName   = "/paillier.PaillierService/AddScalar"
+	PaillierService_MulScalar_FullMethodName   = "/paillier.PaillierService/MulScalar"
+	PaillierService_Rerandomize_FullMethodName = "/paillier.PaillierService/Rerandomize"
+	PaillierService_Decrypt_FullMethodName     = "/paillier.PaillierService/Decrypt"
+)
+
+// PaillierServiceClient is the client API for PaillierService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PaillierServiceClient interface {
+	GenerateKey(ctx context.Context, in *GenerateKeyRequest, opts ...grpc.CallOption) (*GenerateKeyResponse, error)
+	Encrypt(ctx context.Context, in *EncryptRequest, opts ...grpc.CallOption) (*CiphertextResponse, error)
+	Add(ctx context.Context, in *AddRequest, opts ...grpc.CallOption) (*CiphertextResponse, error)
+	AddScalar(ctx context.Context, in *ScalarRequest, opts ...grpc.CallOption) (*CiphertextResponse, error)
+	MulScalar(ctx context.Context, in *ScalarRequest, opts ...grpc.CallOption) (*CiphertextResponse, error)
+	Rerandomize(ctx context.Context, in *RerandomizeRequest, opts ...grpc.CallOption) (*CiphertextResponse, error)
+	Decrypt(ctx context.Context, in *DecryptRequest, opts ...grpc.CallOption) (*DecryptResponse, error)
+}
+
+type paillierServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPaillierServiceClient(cc grpc.ClientConnInterface) PaillierServiceClient {
+	return &paillierServiceClient{cc}
+}
+
+func (c *paillierServiceClient) GenerateKey(ctx context.Context, in *GenerateKeyRequest, opts ...grpc.CallOption) (*GenerateKeyResponse, error) {
+	out := new(GenerateKeyResponse)
+	err := c.cc.Invoke(ctx, PaillierService_GenerateKey_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paillierServiceClient) Encrypt(ctx context.Context, in *EncryptRequest, opts ...grpc.CallOption) (*CiphertextResponse, error) {
+	out := new(CiphertextResponse)
+	err := c.cc.Invoke(ctx, PaillierService_Encrypt_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paillierServiceClient) Add(ctx context.Context, in *AddRequest, opts ...grpc.CallOption) (*CiphertextResponse, error) {
+	out := new(CiphertextResponse)
+	err := c.cc.Invoke(ctx, PaillierService_Add_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paillierServiceClient) AddScalar(ctx context.Context, in *ScalarRequest, opts ...grpc.CallOption) (*CiphertextResponse, error) {
+	out := new(CiphertextResponse)
+	err := c.cc.Invoke(ctx, PaillierService_AddScalar_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paillierServiceClient) MulScalar(ctx context.Context, in *ScalarRequest, opts ...grpc.CallOption) (*CiphertextResponse, error) {
+	out := new(CiphertextResponse)
+	err := c.cc.Invoke(ctx, PaillierService_MulScalar_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paillierServiceClient) Rerandomize(ctx context.Context, in *RerandomizeRequest, opts ...grpc.CallOption) (*CiphertextResponse, error) {
+	out := new(CiphertextResponse)
+	err := c.cc.Invoke(ctx, PaillierService_Rerandomize_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paillierServiceClient) Decrypt(ctx context.Context, in *DecryptRequest, opts ...grpc.CallOption) (*DecryptResponse, error) {
+	out := new(DecryptResponse)
+	err := c.cc.Invoke(ctx, PaillierService_Decrypt_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PaillierServiceServer is the server API for PaillierService service.
+// All implementations must embed UnimplementedPaillierServiceServer
+// for forward compatibility
+type PaillierServiceServer interface {
+	GenerateKey(context.Context, *GenerateKeyRequest) (*GenerateKeyResponse, error)
+	Encrypt(context.Context, *EncryptRequest) (*CiphertextResponse, error)
+	Add(context.Context, *AddRequest) (*CiphertextResponse, error)
+	AddScalar(context.Context, *ScalarRequest) (*CiphertextResponse, error)
+	MulScalar(context.Context, *ScalarRequest) (*CiphertextResponse, error)
+	Rerandomize(context.Context, *RerandomizeRequest) (*CiphertextResponse, error)
+	Decrypt(context.Context, *DecryptRequest) (*DecryptResponse, error)
+	mustEmbedUnimplementedPaillierServiceServer()
+}
+
+// UnimplementedPaillierServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedPaillierServiceServer struct {
+}
+
+func (UnimplementedPaillierServiceServer) GenerateKey(context.Context, *GenerateKeyRequest) (*GenerateKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GenerateKey not implemented")
+}
+func (UnimplementedPaillierServiceServer) Encrypt(context.Context, *EncryptRequest) (*CiphertextResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Encrypt not implemented")
+}
+func (UnimplementedPaillierServiceServer) Add(context.Context, *AddRequest) (*CiphertextResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Add not implemented")
+}
+func (UnimplementedPaillierServiceServer) AddScalar(context.Context, *ScalarRequest) (*CiphertextResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddScalar not implemented")
+}
+func (UnimplementedPaillierServiceServer) MulScalar(context.Context, *ScalarRequest) (*CiphertextResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MulScalar not implemented")
+}
+func (UnimplementedPaillierServiceServer) Rerandomize(context.Context, *RerandomizeRequest) (*CiphertextResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Rerandomize not implemented")
+}
+func (UnimplementedPaillierServiceServer) Decrypt(context.Context, *DecryptRequest) (*DecryptResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Decrypt not implemented")
+}
+func (UnimplementedPaillierServiceServer) mustEmbedUnimplementedPaillierServiceServer() {}
+
+// UnsafePaillierServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PaillierServiceServer will
+// result in compilation errors.
+type UnsafePaillierServiceServer interface {
+	mustEmbedUnimplementedPaillierServiceServer()
+}
+
+func RegisterPaillierServiceServer(s grpc.ServiceRegistrar, srv PaillierServiceServer) {
+	s.RegisterService(&PaillierService_ServiceDesc, srv)
+}
+
+func _PaillierService_GenerateKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaillierServiceServer).GenerateKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaillierService_GenerateKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaillierServiceServer).GenerateKey(ctx, req.(*GenerateKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaillierService_Encrypt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EncryptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaillierServiceServer).Encrypt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaillierService_Encrypt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaillierServiceServer).Encrypt(ctx, req.(*EncryptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaillierService_Add_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaillierServiceServer).Add(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaillierService_Add_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaillierServiceServer).Add(ctx, req.(*AddRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaillierService_AddScalar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScalarRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaillierServiceServer).AddScalar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaillierService_AddScalar_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaillierServiceServer).AddScalar(ctx, req.(*ScalarRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaillierService_MulScalar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScalarRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaillierServiceServer).MulScalar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaillierService_MulScalar_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaillierServiceServer).MulScalar(ctx, req.(*ScalarRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaillierService_Rerandomize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RerandomizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaillierServiceServer).Rerandomize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaillierService_Rerandomize_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaillierServiceServer).Rerandomize(ctx, req.(*RerandomizeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaillierService_Decrypt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DecryptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaillierServiceServer).Decrypt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PaillierService_Decrypt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaillierServiceServer).Decrypt(ctx, req.(*DecryptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PaillierService_ServiceDesc is the grpc.ServiceDesc for PaillierService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PaillierService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "paillier.PaillierService",
+	HandlerType: (*PaillierServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GenerateKey",
+			Handler:    _PaillierService_GenerateKey_Handler,
+		},
+		{
+			MethodName: "Encrypt",
+			Handler:    _PaillierService_Encrypt_Handler,
+		},
+		{
+			MethodName: "Add",
+			Handler:    _PaillierService_Add_Handler,
+		},
+		{
+			MethodName: "AddScalar",
+			Handler:    _PaillierService_AddScalar_Handler,
+		},
+		{
+			MethodName: "MulScalar",
+			Handler:    _PaillierService_MulScalar_Handler,
+		},
+		{
+			MethodName: "Rerandomize",
+			Handler:    _PaillierService_Rerandomize_Handler,
+		},
+		{
+			MethodName: "Decrypt",
+			Handler:    _PaillierService_Decrypt_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "paillier.proto",
+}
@@ -0,0 +1,522 @@
+package additive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"google.golang.org/protobuf/proto"
+
+	"multisigservice/paillier"
+	pb "multisigservice/proto/paillierpb"
+)
+
+// 署名の不正者の特定（identifiable abort）
+// 署名で中継するメッセージはすべて送信者がEthereum鍵で personal_sign した SignedMessage とし、
+// 送信者は自分のメッセージを否認できません。検証に失敗したセッションは、署名済みメッセージの一式
+// （SignTranscript）から Blame で不正者を特定します。証拠を受け取った誰もが同じ結果を再現できます。
+//   - ラウンド1〜4のメッセージの形式と証明、ラウンド5の部分署名は、送信者のメッセージだけで検証できる
+//   - ラウンド3で R を導出できない、またはラウンド4の和の確認に失敗した場合は、各署名者が k_i と
+//     自分宛てのMtA応答の平文と乱数を開示する（SignOpening）。開示された値と応答側のマスク β'·G, ν'·G、
+//     Γ_j, W_j = w_j·G から、δ_i, k_i·R, σ_i·R を点として検算し、食い違う署名者を特定する
+// 開示は部分署名を送る前にのみ行います。部分署名の後に k_i を開示すると σ_i が求まり、秘密鍵が漏れます。
+// w 側のMtAは ν' を開示しないため、開示後も w_j は漏れません。
+
+// Signing rounds as numbered by the server. SignRoundOpen is the round in
+// which the signers post their SignOpening.
+const (
+	SignRoundCommit  = 1
+	SignRoundMtA     = 2
+	SignRoundReveal  = 3
+	SignRoundCheck   = 4
+	SignRoundPartial = 5
+	SignRoundOpen    = 6
+)
+
+// SignedMessage is a relayed signing message together with the sender's
+// personal_sign signature over Statement.
+type SignedMessage struct {
+	Session   string        `json:"session"`
+	Round     int           `json:"round"`
+	From      string        `json:"from"`
+	Payload   string        `json:"payload"` // 署名されたメッセージのJSON
+	Signature hexutil.Bytes `json:"signature"`
+}
+
+// Statement returns the text the sender signs, which binds the payload to
+// the session and round.
+func (m *SignedMessage) Statement() string {
+	return fmt.Sprintf("Multisig signing message\nSession: %s\nRound: %d\nPayload: %s",
+		m.Session, m.Round, hexutil.Encode(crypto.Keccak256([]byte(m.Payload))))
+}
+
+// Verify checks that the signature was made by From.
+func (m *SignedMessage) Verify() error {
+	if len(m.Signature) != 65 {
+		return errors.New("signature must be 65 bytes")
+	}
+	sig := append([]byte{}, m.Signature...)
+	// SigToPub は 0,1 のみを受け付けるため、27,28 を変換する
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+	statement := m.Statement()
+	hash := crypto.Keccak256([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(statement), statement)))
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(crypto.PubkeyToAddress(*pub).Hex(), m.From) {
+		return fmt.Errorf("message is not signed by %s", m.From)
+	}
+	return nil
+}
+
+// Opening reveals the plaintext and nonce of a Paillier ciphertext as
+// big-endian integers.
+type Opening struct {
+	M hexutil.Bytes `json:"m"`
+	R hexutil.Bytes `json:"r"`
+}
+
+// SignOpening is a participant's opening of Enc_i(k_i) and of the MtA
+// replies addressed to it, posted after a failed round 3 or round 4 check.
+type SignOpening struct {
+	K     *Opening            `json:"k"`
+	Gamma map[string]*Opening `json:"gamma"` // 送信者 j の k_i·γ_j + β' の応答
+	W     map[string]*Opening `json:"w"`     // 送信者 j の k_i·w_j + ν' の応答
+}
+
+// Open reveals k_i and the plaintexts of the MtA replies addressed to this
+// participant. It must only be called after a failed round 3 or round 4
+// check, and never once a partial signature has been sent.
+func (p *SignParty) Open() (*SignOpening, error) {
+	if p.encK == nil || p.inbox == nil {
+		return nil, errors.New("round 3 has not been run")
+	}
+	priv := p.cfg.Paillier
+	K, err := openCiphertext(priv, p.encK)
+	if err != nil {
+		return nil, err
+	}
+	o := &SignOpening{K: K, Gamma: make(map[string]*Opening), W: make(map[string]*Opening)}
+	for _, j := range p.others() {
+		msg := p.inbox[j]
+		if msg == nil {
+			return nil, fmt.Errorf("missing round 2 message from %s", j)
+		}
+		for _, reply := range []struct {
+			raw []byte
+			dst map[string]*Opening
+		}{{msg.Gamma, o.Gamma}, {msg.W, o.W}} {
+			ct, err := replyCiphertext(&priv.PublicKey, reply.raw)
+			if err != nil {
+				return nil, err
+			}
+			if reply.dst[j], err = openCiphertext(priv, ct); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return o, nil
+}
+
+func openCiphertext(priv *paillier.PrivateKey, ct *paillier.Ciphertext) (*Opening, error) {
+	m, r, err := priv.Open(ct)
+	if err != nil {
+		return nil, err
+	}
+	return &Opening{M: m.Bytes(), R: r.Bytes()}, nil
+}
+
+// verify checks that o opens ct under pub and returns the plaintext read as
+// a signed integer, as the homomorphic operations treat it.
+func (o *Opening) verify(pub *paillier.PublicKey, ct *paillier.Ciphertext) (*big.Int, error) {
+	if o == nil {
+		return nil, errors.New("opening is missing")
+	}
+	m := new(big.Int).SetBytes(o.M)
+	if !pub.VerifyOpening(ct, m, new(big.Int).SetBytes(o.R)) {
+		return nil, errors.New("opening does not match the ciphertext")
+	}
+	if m.Cmp(new(big.Int).Rsh(pub.N, 1)) > 0 {
+		m.Sub(m, pub.N)
+	}
+	return m.Mod(m, q), nil
+}
+
+func encKCiphertext(pub *paillier.PublicKey, msg *SignRound1) (*paillier.Ciphertext, error) {
+	var encK pb.Ciphertext
+	if err := proto.Unmarshal(msg.EncK, &encK); err != nil {
+		return nil, err
+	}
+	return paillier.CiphertextFromProto(pub, &encK)
+}
+
+func replyCiphertext(pub *paillier.PublicKey, raw []byte) (*paillier.Ciphertext, error) {
+	var reply pb.MtARound2
+	if err := proto.Unmarshal(raw, &reply); err != nil {
+		return nil, err
+	}
+	return paillier.CiphertextFromProto(pub, reply.CB)
+}
+
+// Fault names a participant who deviated from the protocol.
+type Fault struct {
+	Culprit string `json:"culprit"`
+	Reason  string `json:"reason"`
+}
+
+func (f *Fault) Error() string {
+	return fmt.Sprintf("%s: %s", f.Culprit, f.Reason)
+}
+
+// SignTranscript is the public record of a signing session, from which Blame
+// identifies a participant who deviated from the protocol.
+type SignTranscript struct {
+	SessionID    string                              // ナンスを生成したセッション（事前署名を用いた署名ではその事前署名セッション）
+	SignSession  string                              // 部分署名を送ったセッション（省略時は SessionID）
+	Parties      []string                            // 署名者
+	KeyParties   []string                            // 鍵の参加者（公開シェアの評価点の順序）
+	PublicKey    *Point                              // マルチシグの公開鍵 Q
+	PublicShares map[string]*Point                   // 参加者の公開シェア S_i
+	PaillierKeys map[string]*paillier.PublicKey      // 署名者のPaillier公開鍵
+	Pedersen     map[string]*paillier.PedersenParams // 署名者のリングPedersenパラメータ
+	Hash         []byte                              // 署名対象のハッシュ（部分署名がある場合）
+	Presign      bool                                // 事前署名セッションか（ラウンド3で PresignShares を要求する）
+
+	Round1   map[string]*SignRound1
+	Round2   map[string]map[string]*SignRound2 // 送信者 → 宛先
+	Round3   map[string]*PresignRound3
+	Round4   map[string]*SignRound4
+	Round5   map[string]*SignRound5
+	Openings map[string]*SignOpening
+}
+
+// SignEvidence is what the server stores when it aborts a signing session:
+// the public inputs of the session and every signed message, so that anyone
+// can rebuild the transcript and rerun Blame.
+type SignEvidence struct {
+	SessionID    string                              `json:"sessionId"`
+	SignSession  string                              `json:"signSession,omitempty"`
+	Parties      []string                            `json:"parties"`
+	KeyParties   []string                            `json:"keyParties"`
+	PublicKey    *Point                              `json:"publicKey"`
+	PublicShares map[string]*Point                   `json:"publicShares"`
+	PaillierKeys map[string]*paillier.PublicKey      `json:"paillierKeys"`
+	Pedersen     map[string]*paillier.PedersenParams `json:"pedersen"`
+	Hash         hexutil.Bytes                       `json:"hash,omitempty"`
+	Presign      bool                                `json:"presign,omitempty"`
+	Messages     []*SignedMessage                    `json:"messages"`
+	Fault        *Fault                              `json:"fault,omitempty"` // Blame の結果（特定できなかった場合は nil）
+	Reason       string                              `json:"reason"`          // 中断の理由
+}
+
+// Transcript verifies every message in e and returns the transcript on
+// which Blame reproduces e.Fault.
+func (e *SignEvidence) Transcript() (*SignTranscript, error) {
+	t := &SignTranscript{
+		SessionID:    e.SessionID,
+		SignSession:  e.SignSession,
+		Parties:      e.Parties,
+		KeyParties:   e.KeyParties,
+		PublicKey:    e.PublicKey,
+		PublicShares: e.PublicShares,
+		PaillierKeys: e.PaillierKeys,
+		Pedersen:     e.Pedersen,
+		Hash:         e.Hash,
+		Presign:      e.Presign,
+	}
+	for _, m := range e.Messages {
+		if err := t.Add(m); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Add verifies the signature of m and records its payload in the
+// transcript.
+func (t *SignTranscript) Add(m *SignedMessage) error {
+	session := t.SessionID
+	if m.Round == SignRoundPartial && t.SignSession != "" {
+		session = t.SignSession
+	}
+	if m.Session != session {
+		return fmt.Errorf("message of %s belongs to another session", m.From)
+	}
+	if partyIndex(t.Parties, m.From) == 0 {
+		return fmt.Errorf("%s is not a signer", m.From)
+	}
+	if err := m.Verify(); err != nil {
+		return err
+	}
+	raw := []byte(m.Payload)
+	switch m.Round {
+	case SignRoundCommit:
+		return record(&t.Round1, m.From, raw)
+	case SignRoundMtA:
+		// ラウンド2は宛先をキーとする1つのメッセージとして署名される
+		if err := record(&t.Round2, m.From, raw); err != nil {
+			return err
+		}
+		// 宛先は大文字小文字を区別せずに署名者のアドレスに揃える
+		out := make(map[string]*SignRound2, len(t.Round2[m.From]))
+		for to, msg := range t.Round2[m.From] {
+			for _, p := range t.Parties {
+				if strings.EqualFold(p, to) {
+					to = p
+				}
+			}
+			out[to] = msg
+		}
+		t.Round2[m.From] = out
+		return nil
+	case SignRoundReveal:
+		return record(&t.Round3, m.From, raw)
+	case SignRoundCheck:
+		return record(&t.Round4, m.From, raw)
+	case SignRoundPartial:
+		return record(&t.Round5, m.From, raw)
+	case SignRoundOpen:
+		return record(&t.Openings, m.From, raw)
+	}
+	return fmt.Errorf("unknown round %d", m.Round)
+}
+
+// record decodes a message and stores it under its sender.
+func record[V any](dst *map[string]V, from string, raw []byte) error {
+	if *dst == nil {
+		*dst = make(map[string]V)
+	}
+	if _, ok := (*dst)[from]; ok {
+		return fmt.Errorf("duplicate message from %s", from)
+	}
+	var v V
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return fmt.Errorf("message from %s: %v", from, err)
+	}
+	(*dst)[from] = v
+	return nil
+}
+
+// Blame returns the first deviation found in the transcript, checking the
+// rounds in order and the signers in order within a round, or nil if there
+// is none. A missing message is not a deviation.
+func (t *SignTranscript) Blame() *Fault {
+	blame := func(culprit string, err error) *Fault {
+		return &Fault{Culprit: culprit, Reason: err.Error()}
+	}
+	for _, i := range t.Parties {
+		if m, ok := t.Round1[i]; ok {
+			if err := VerifySignRound1(t.PaillierKeys[i], t.Pedersen, i, m); err != nil {
+				return blame(i, err)
+			}
+		}
+	}
+	for _, j := range t.Parties {
+		out, ok := t.Round2[j]
+		if !ok {
+			continue
+		}
+		if len(out) != len(t.Parties)-1 {
+			return blame(j, errors.New("round 2 messages are missing"))
+		}
+		for _, i := range t.Parties {
+			if i == j {
+				continue
+			}
+			// 応答は宛先のラウンド1の暗号文に対して検証する（ラウンド1が揃う前の応答はない）
+			r1, ok := t.Round1[i]
+			if !ok {
+				continue
+			}
+			if err := VerifySignRound2(t.PaillierKeys[i], t.Pedersen[i], r1, out[i]); err != nil {
+				return blame(j, fmt.Errorf("to %s: %v", i, err))
+			}
+		}
+	}
+	for _, i := range t.Parties {
+		m, ok := t.Round3[i]
+		if !ok {
+			continue
+		}
+		if m == nil {
+			return blame(i, errors.New("round 3 message is empty"))
+		}
+		if err := VerifySignRound3(t.SessionID, i, t.Round1[i], &m.SignRound3); err != nil {
+			return blame(i, err)
+		}
+		if t.Presign {
+			if err := VerifyPresignShares(t.PaillierKeys[i], m.Shares); err != nil {
+				return blame(i, err)
+			}
+		}
+	}
+	for _, i := range t.Parties {
+		if m, ok := t.Round4[i]; ok {
+			if err := VerifySignRound4(m); err != nil {
+				return blame(i, err)
+			}
+		}
+	}
+	// ラウンド4の和の確認が通った後は、式を満たさない部分署名の送信者が不正者
+	if len(t.Round5) > 0 {
+		R, _, err := ComputeR(t.reveals())
+		if err == nil && CheckSignRound4(t.PublicKey, t.Round4) == nil {
+			for _, i := range t.Parties {
+				if m := t.Round5[i]; m != nil {
+					if err := VerifySignRound5(t.Hash, R, t.Round4[i], m); err != nil {
+						return blame(i, err)
+					}
+				}
+			}
+		}
+	}
+	return t.blameOpenings()
+}
+
+// reveals returns the round 3 messages as SignRound3.
+func (t *SignTranscript) reveals() map[string]*SignRound3 {
+	out := make(map[string]*SignRound3, len(t.Round3))
+	for from, m := range t.Round3 {
+		out[from] = &m.SignRound3
+	}
+	return out
+}
+
+// opened holds the values recomputed from a signer's opening.
+type opened struct {
+	k     *big.Int
+	alpha map[string]*big.Int // 送信者 j の応答の平文 k_i·γ_j + β'_ji mod q
+	mu    map[string]*big.Int // 送信者 j の応答の平文 k_i·w_j + ν'_ji mod q
+}
+
+// blameOpenings checks every posted opening against the ciphertexts it
+// opens and, once all signers have opened, recomputes δ_i, k_i·R and σ_i·R.
+func (t *SignTranscript) blameOpenings() *Fault {
+	if len(t.Openings) == 0 || len(t.Round1) != len(t.Parties) || len(t.Round2) != len(t.Parties) || len(t.Round3) != len(t.Parties) {
+		return nil
+	}
+	values := make(map[string]*opened, len(t.Parties))
+	for _, i := range t.Parties {
+		o, ok := t.Openings[i]
+		if !ok {
+			continue
+		}
+		if o == nil || o.K == nil {
+			return &Fault{Culprit: i, Reason: "opening is empty"}
+		}
+		v, err := t.checkOpening(i, o)
+		if err != nil {
+			return &Fault{Culprit: i, Reason: err.Error()}
+		}
+		values[i] = v
+	}
+	if len(values) != len(t.Parties) {
+		return nil
+	}
+
+	// MtAの応答：α'_ij·G = k_i·Γ_j + β'_ji·G, μ'_ij·G = k_i·W_j + ν'_ji·G
+	W := make(map[string]*Point, len(t.Parties))
+	for _, j := range t.Parties {
+		lambda, err := lagrange(t.KeyParties, t.Parties, j)
+		if err != nil || t.PublicShares[j] == nil {
+			return nil
+		}
+		W[j] = t.PublicShares[j].ScalarMult(lambda)
+	}
+	for _, i := range t.Parties {
+		for _, j := range t.Parties {
+			if i == j {
+				continue
+			}
+			reply := t.Round2[j][i]
+			if !ScalarBaseMult(values[i].alpha[j]).Equal(t.Round3[j].Gamma.ScalarMult(values[i].k).Add(reply.GammaMask)) {
+				return &Fault{Culprit: j, Reason: fmt.Sprintf("MtA reply to %s does not match Γ and the mask", i)}
+			}
+			if !ScalarBaseMult(values[i].mu[j]).Equal(W[j].ScalarMult(values[i].k).Add(reply.WMask)) {
+				return &Fault{Culprit: j, Reason: fmt.Sprintf("MtA reply to %s does not match the key share and the mask", i)}
+			}
+		}
+	}
+
+	// δ_i·G = k_i·Γ_i + Σ_j α'_ij·G − Σ_j β'_ij·G
+	k := new(big.Int)
+	sigmaG := make(map[string]*Point, len(t.Parties))
+	for _, i := range t.Parties {
+		v := values[i]
+		k.Add(k, v.k)
+		delta := t.Round3[i].Gamma.ScalarMult(v.k)
+		sigma := W[i].ScalarMult(v.k)
+		for _, j := range t.Parties {
+			if i == j {
+				continue
+			}
+			delta = delta.Add(ScalarBaseMult(v.alpha[j])).Add(t.Round2[i][j].GammaMask.Neg())
+			sigma = sigma.Add(ScalarBaseMult(v.mu[j])).Add(t.Round2[i][j].WMask.Neg())
+		}
+		if !ScalarBaseMult(t.Round3[i].Delta.ToInt()).Equal(delta) {
+			return &Fault{Culprit: i, Reason: "δ does not match the opened MtA values"}
+		}
+		sigmaG[i] = sigma
+	}
+
+	// δ が正しければ R = k⁻¹·G なので、k_i·R と k·(σ_i·R) = σ_i·G を確認できる
+	if len(t.Round4) != len(t.Parties) {
+		return nil
+	}
+	R, _, err := ComputeR(t.reveals())
+	if err != nil {
+		return nil
+	}
+	k.Mod(k, q)
+	for _, i := range t.Parties {
+		if !t.Round4[i].KR.Equal(R.ScalarMult(values[i].k)) {
+			return &Fault{Culprit: i, Reason: "k_i·R does not match the opened k_i"}
+		}
+		if !t.Round4[i].SigmaR.ScalarMult(k).Equal(sigmaG[i]) {
+			return &Fault{Culprit: i, Reason: "σ_i·R does not match the opened MtA values"}
+		}
+	}
+	return nil
+}
+
+// checkOpening checks that o opens signer i's Enc_i(k_i) and every MtA
+// reply addressed to i, and returns the opened values.
+func (t *SignTranscript) checkOpening(i string, o *SignOpening) (*opened, error) {
+	pub := t.PaillierKeys[i]
+	ct, err := encKCiphertext(pub, t.Round1[i])
+	if err != nil {
+		return nil, err
+	}
+	v := &opened{alpha: make(map[string]*big.Int), mu: make(map[string]*big.Int)}
+	if v.k, err = o.K.verify(pub, ct); err != nil {
+		return nil, fmt.Errorf("k: %v", err)
+	}
+	for _, j := range t.Parties {
+		if j == i {
+			continue
+		}
+		reply := t.Round2[j][i]
+		for _, part := range []struct {
+			name    string
+			raw     []byte
+			opening *Opening
+			dst     map[string]*big.Int
+		}{{"γ", reply.Gamma, o.Gamma[j], v.alpha}, {"w", reply.W, o.W[j], v.mu}} {
+			ct, err := replyCiphertext(pub, part.raw)
+			if err != nil {
+				return nil, err
+			}
+			if part.dst[j], err = part.opening.verify(pub, ct); err != nil {
+				return nil, fmt.Errorf("%s reply from %s: %v", part.name, j, err)
+			}
+		}
+	}
+	return v, nil
+}
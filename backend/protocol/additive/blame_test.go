@@ -0,0 +1,221 @@
+package additive
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"google.golang.org/protobuf/proto"
+
+	"multisigservice/paillier"
+	pb "multisigservice/proto/paillierpb"
+)
+
+// newTranscript は run の公開メッセージから不正者の特定に用いる記録を作ります。
+func newTranscript(t *testing.T, sid string, shares map[string]*KeyShare, subset []string, run *signRun) *SignTranscript {
+	t.Helper()
+	keys, _ := paillierFixture(t)
+	share := shares[subset[0]]
+	tr := &SignTranscript{
+		SessionID:    sid,
+		Parties:      subset,
+		KeyParties:   share.Parties,
+		PublicKey:    share.PublicKey,
+		PublicShares: share.PublicShares,
+		PaillierKeys: make(map[string]*paillier.PublicKey),
+		Pedersen:     pedersenOf(t, subset),
+		Round1:       run.round1,
+		Round2:       make(map[string]map[string]*SignRound2),
+		Round3:       make(map[string]*PresignRound3),
+		Round4:       run.round4,
+	}
+	// テストで書き換えても run に影響しないよう複製する
+	for from, out := range run.round2 {
+		tr.Round2[from] = make(map[string]*SignRound2)
+		for to, msg := range out {
+			tr.Round2[from][to] = msg
+		}
+	}
+	for _, addr := range subset {
+		tr.PaillierKeys[addr] = &keys[addr].PublicKey
+		tr.Round3[addr] = &PresignRound3{SignRound3: *run.round3[addr]}
+	}
+	return tr
+}
+
+// openAll は全員の開示を記録します。
+func openAll(t *testing.T, signers map[string]*SignParty, tr *SignTranscript) {
+	t.Helper()
+	tr.Openings = make(map[string]*SignOpening)
+	for addr, p := range signers {
+		o, err := p.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr.Openings[addr] = relay(t, o)
+	}
+}
+
+func TestSignedMessage(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	m := &SignedMessage{Session: "sign-1", Round: SignRoundPartial, From: from, Payload: `{"s":"0x1"}`}
+	statement := m.Statement()
+	hash := crypto.Keccak256([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(statement), statement)))
+	if m.Signature, err = crypto.Sign(hash, key); err != nil {
+		t.Fatal(err)
+	}
+	// ウォレットは v を 27, 28 で返す
+	m.Signature[64] += 27
+	if err := m.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := &SignTranscript{SessionID: "sign-1", Parties: []string{from}}
+	if err := tr.Add(m); err != nil {
+		t.Fatal(err)
+	}
+	if got := tr.Round5[from].S.ToInt(); got.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("got %v\nwant %v", got, 1)
+	}
+	if err := tr.Add(m); err == nil {
+		t.Error("duplicate message was accepted")
+	}
+
+	// 署名後の書き換えは検出されること
+	forged := *m
+	forged.Payload = `{"s":"0x2"}`
+	if err := forged.Verify(); err == nil {
+		t.Error("modified payload was accepted")
+	}
+	forged = *m
+	forged.Round = SignRoundCheck
+	if err := forged.Verify(); err == nil {
+		t.Error("message replayed in another round was accepted")
+	}
+	forged = *m
+	forged.From = signParties[0]
+	if err := forged.Verify(); err == nil {
+		t.Error("message of another sender was accepted")
+	}
+	forged = *m
+	forged.Session = "sign-2"
+	if err := tr.Add(&forged); err == nil {
+		t.Error("message of another session was accepted")
+	}
+}
+
+func TestBlamePartialSignature(t *testing.T) {
+	shares := runKeygen(t, "keygen", signParties, 2)
+	hash := sha256.Sum256([]byte("transfer 3 ETH"))
+	const sid = "sign-blame-1"
+	subset := []string{signParties[0], signParties[1]}
+	signers := newSigners(t, sid, shares, subset, hash[:])
+	run := runSignRounds(t, sid, signers, subset, nil)
+	runCheckRound(t, signers, run)
+
+	tr := newTranscript(t, sid, shares, subset, run)
+	tr.Hash = hash[:]
+	tr.Round5 = make(map[string]*SignRound5)
+	for addr, p := range signers {
+		msg, err := p.Round5(run.round4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr.Round5[addr] = relay(t, msg)
+	}
+	if f := tr.Blame(); f != nil {
+		t.Fatalf("honest signers were blamed: %v", f)
+	}
+
+	cheater := subset[1]
+	bad := *tr.Round5[cheater]
+	bad.S = (*hexutil.Big)(new(big.Int).Add(bad.S.ToInt(), big.NewInt(1)))
+	tr.Round5[cheater] = &bad
+	f := tr.Blame()
+	if f == nil || f.Culprit != cheater {
+		t.Errorf("got %v\nwant culprit %s", f, cheater)
+	}
+}
+
+func TestBlameOpenings(t *testing.T) {
+	shares := runKeygen(t, "keygen", signParties, 2)
+	Q := shares[signParties[0]].PublicKey
+	subset := []string{signParties[0], signParties[2]}
+
+	// 正しい開示では誰も特定されないこと
+	const sid = "sign-blame-2"
+	signers := newSigners(t, sid, shares, subset, nil)
+	run := runSignRounds(t, sid, signers, subset, nil)
+	runCheckRound(t, signers, run)
+	tr := newTranscript(t, sid, shares, subset, run)
+	openAll(t, signers, tr)
+	if f := tr.Blame(); f != nil {
+		t.Fatalf("honest signers were blamed: %v", f)
+	}
+
+	// マスクの公開値と食い違うMtA応答は送信者が特定されること
+	sender, recipient := subset[1], subset[0]
+	reply := *tr.Round2[sender][recipient]
+	reply.GammaMask = ScalarBaseMult(big.NewInt(1))
+	tr.Round2[sender] = map[string]*SignRound2{recipient: &reply}
+	if f := tr.Blame(); f == nil || f.Culprit != sender {
+		t.Errorf("got %v\nwant culprit %s", f, sender)
+	}
+
+	// アフィン証明のないMtA応答は送信者が特定されること
+	tr = newTranscript(t, sid, shares, subset, run)
+	reply = *tr.Round2[sender][recipient]
+	var stripped pb.MtARound2
+	if err := proto.Unmarshal(reply.Gamma, &stripped); err != nil {
+		t.Fatal(err)
+	}
+	stripped.AffineProof = nil
+	raw, err := proto.Marshal(&stripped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply.Gamma = raw
+	tr.Round2[sender] = map[string]*SignRound2{recipient: &reply}
+	if f := tr.Blame(); f == nil || f.Culprit != sender {
+		t.Errorf("got %v\nwant culprit %s", f, sender)
+	}
+
+	// 開示が暗号文と一致しなければ開示した署名者が特定されること
+	tr = newTranscript(t, sid, shares, subset, run)
+	openAll(t, signers, tr)
+	opening := *tr.Openings[recipient]
+	opening.K = &Opening{M: []byte{1}, R: opening.K.R}
+	tr.Openings[recipient] = &opening
+	if f := tr.Blame(); f == nil || f.Culprit != recipient {
+		t.Errorf("got %v\nwant culprit %s", f, recipient)
+	}
+
+	// 誤った δ_i を公開すると R がずれてラウンド4の確認に失敗し、開示により特定されること
+	const sid2 = "sign-blame-3"
+	cheater := subset[1]
+	signers = newSigners(t, sid2, shares, subset, nil)
+	run = runSignRounds(t, sid2, signers, subset, func(from string, msg *SignRound3) {
+		if from == cheater {
+			msg.Delta = (*hexutil.Big)(new(big.Int).Mod(new(big.Int).Add(msg.Delta.ToInt(), big.NewInt(1)), q))
+		}
+	})
+	runCheckRound(t, signers, run)
+	if err := CheckSignRound4(Q, run.round4); err == nil {
+		t.Fatal("wrong δ passed the round 4 check")
+	}
+	tr = newTranscript(t, sid2, shares, subset, run)
+	if f := tr.Blame(); f != nil {
+		t.Errorf("signer was blamed before opening: %v", f)
+	}
+	openAll(t, signers, tr)
+	if f := tr.Blame(); f == nil || f.Culprit != cheater {
+		t.Errorf("got %v\nwant culprit %s", f, cheater)
+	}
+}
@@ -0,0 +1,190 @@
+// Package additive implements the built-in additive ECDSA scheme over
+// secp256k1: distributed key generation, signing and the share maintenance
+// protocols. Participants run the party side of each protocol; the server
+// relays their messages and uses the Verify functions to check every message
+// before it is stored, so that it can derive the public key and assemble the
+// final signature itself.
+package additive
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"math/big"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"multisigservice/mta"
+)
+
+// q は secp256k1 の位数です。
+var q = mta.Order()
+
+// Point is a point on secp256k1. The zero value is the point at infinity.
+type Point struct {
+	p secp256k1.JacobianPoint
+}
+
+// scalar reduces k mod q into a ModNScalar.
+func scalar(k *big.Int) *secp256k1.ModNScalar {
+	var s secp256k1.ModNScalar
+	s.SetByteSlice(new(big.Int).Mod(k, q).Bytes())
+	return &s
+}
+
+// ScalarBaseMult returns k·G.
+func ScalarBaseMult(k *big.Int) *Point {
+	var r Point
+	secp256k1.ScalarBaseMultNonConst(scalar(k), &r.p)
+	r.p.ToAffine()
+	return &r
+}
+
+// ScalarMult returns k·p.
+func (p *Point) ScalarMult(k *big.Int) *Point {
+	var r Point
+	secp256k1.ScalarMultNonConst(scalar(k), &p.p, &r.p)
+	r.p.ToAffine()
+	return &r
+}
+
+// Add returns p + o.
+func (p *Point) Add(o *Point) *Point {
+	var r Point
+	secp256k1.AddNonConst(&p.p, &o.p, &r.p)
+	r.p.ToAffine()
+	return &r
+}
+
+// Neg returns -p.
+func (p *Point) Neg() *Point {
+	var r Point
+	r.p.Set(&p.p)
+	r.p.Y.Negate(1).Normalize()
+	return &r
+}
+
+// IsIdentity reports whether p is the point at infinity.
+func (p *Point) IsIdentity() bool {
+	return p.p.Z.IsZero() || (p.p.X.IsZero() && p.p.Y.IsZero())
+}
+
+// Equal reports whether p and o are the same point.
+func (p *Point) Equal(o *Point) bool {
+	if p.IsIdentity() || o.IsIdentity() {
+		return p.IsIdentity() && o.IsIdentity()
+	}
+	return p.p.X.Equals(&o.p.X) && p.p.Y.Equals(&o.p.Y)
+}
+
+// X returns the affine x coordinate of p.
+func (p *Point) X() *big.Int {
+	b := p.p.X.Bytes()
+	return new(big.Int).SetBytes(b[:])
+}
+
+// Bytes returns the 33-byte compressed encoding of p.
+// The point at infinity has no encoding and yields nil.
+func (p *Point) Bytes() []byte {
+	if p.IsIdentity() {
+		return nil
+	}
+	return secp256k1.NewPublicKey(&p.p.X, &p.p.Y).SerializeCompressed()
+}
+
+// PointFromBytes decodes a compressed or uncompressed point and checks that it
+// lies on the curve.
+func PointFromBytes(b []byte) (*Point, error) {
+	pk, err := secp256k1.ParsePubKey(b)
+	if err != nil {
+		return nil, err
+	}
+	var r Point
+	pk.AsJacobian(&r.p)
+	return &r, nil
+}
+
+// MarshalText encodes p as 0x-prefixed hex of its compressed form.
+func (p *Point) MarshalText() ([]byte, error) {
+	b := p.Bytes()
+	if b == nil {
+		return nil, errors.New("cannot encode the point at infinity")
+	}
+	return []byte("0x" + hex.EncodeToString(b)), nil
+}
+
+// UnmarshalText decodes the output of MarshalText.
+func (p *Point) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(strings.TrimPrefix(string(text), "0x"))
+	if err != nil {
+		return err
+	}
+	decoded, err := PointFromBytes(b)
+	if err != nil {
+		return err
+	}
+	*p = *decoded
+	return nil
+}
+
+// ToECDSA converts p to a go-ethereum public key.
+func (p *Point) ToECDSA() *ecdsa.PublicKey {
+	y := p.p.Y.Bytes()
+	return &ecdsa.PublicKey{Curve: crypto.S256(), X: p.X(), Y: new(big.Int).SetBytes(y[:])}
+}
+
+// EthereumAddress returns the checksummed Ethereum address of the public key p.
+func EthereumAddress(p *Point) string {
+	return crypto.PubkeyToAddress(*p.ToECDSA()).Hex()
+}
+
+// SumPoints returns the sum of points.
+func SumPoints(points ...*Point) *Point {
+	sum := new(Point)
+	for _, p := range points {
+		sum = sum.Add(p)
+	}
+	return sum
+}
+
+// randomScalar draws a uniformly random non-zero scalar mod q.
+func randomScalar() (*big.Int, error) {
+	for {
+		k, err := rand.Int(rand.Reader, q)
+		if err != nil {
+			return nil, err
+		}
+		if k.Sign() != 0 {
+			return k, nil
+		}
+	}
+}
+
+// hashToScalar derives a Fiat–Shamir challenge mod q. Each part is length
+// prefixed so that different splits of the same bytes hash differently.
+func hashToScalar(domain string, parts ...[]byte) *big.Int {
+	return new(big.Int).Mod(new(big.Int).SetBytes(hashParts(domain, parts...)), q)
+}
+
+// hashParts returns SHA-256 over the length-prefixed domain and parts.
+func hashParts(domain string, parts ...[]byte) []byte {
+	h := sha256.New()
+	writePart(h, []byte(domain))
+	for _, part := range parts {
+		writePart(h, part)
+	}
+	return h.Sum(nil)
+}
+
+func writePart(h hash.Hash, part []byte) {
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], uint64(len(part)))
+	h.Write(n[:])
+	h.Write(part)
+}
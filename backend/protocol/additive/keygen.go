@@ -0,0 +1,574 @@
+package additive
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"multisigservice/paillier"
+)
+
+// 鍵生成は t-of-n のFeldman VSSを3ラウンドで行います。
+//   1. 各参加者 i は次数 t-1 の多項式 f_i（f_i(0) = x_i）を選び、係数のコミットメント
+//      A_ik = a_ik·G の組へのハッシュコミットメントを送る
+//   2. 全員のコミットメントが揃った後、A_ik・nonce・x_i の知識のSchnorr証明と、
+//      各参加者 j 宛てのシェア f_i(j) を j のPaillier公開鍵で暗号化したものを公開する
+//   3. 各参加者 j は受け取ったシェアを復号してコミットメントと照合し、すべて一致すれば s_j の知識の
+//      Schnorr証明（KeygenConfirm）を送る。一致しないシェアがあれば、その暗号文の平文と乱数を開示する
+//      苦情（KeygenComplaint）を送る。苦情は誰でも検証でき、正当であればディーラーを不正者として中断する
+// 参加者 j のシェアは s_j = Σ_i f_i(j)、公開鍵は Q = Σ_i A_i0 です。
+// サーバーは各公開をコミットメントと証明に照らして検証し、Q と各参加者の公開シェア
+// S_j = s_j·G = Σ_i Σ_k A_ik·j^k を導出します。
+// 先にコミットさせることで、他者の A_j0 を見てから自分の値を選ぶ rogue-key 攻撃を防ぎます。
+
+// Key generation rounds as numbered by the server. A complaint is signed as
+// KeygenRoundComplaint while the server accepts confirmations.
+const (
+	KeygenRoundCommit    = 1
+	KeygenRoundReveal    = 2
+	KeygenRoundConfirm   = 3
+	KeygenRoundComplaint = 4
+)
+
+// SchnorrProof is a non-interactive proof of knowledge of x such that X = x·G,
+// bound to a context so that it cannot be replayed in another session.
+type SchnorrProof struct {
+	A *Point       `json:"a"`
+	Z *hexutil.Big `json:"z"`
+}
+
+// ProveSchnorr proves knowledge of x for X = x·G under context ctx.
+func ProveSchnorr(x *big.Int, ctx []byte) (*SchnorrProof, error) {
+	a, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	A := ScalarBaseMult(a)
+	e := schnorrChallenge(ctx, ScalarBaseMult(x), A)
+	// z = a + e·x mod q
+	z := new(big.Int).Mul(e, x)
+	z.Add(z, a).Mod(z, q)
+	return &SchnorrProof{A: A, Z: (*hexutil.Big)(z)}, nil
+}
+
+// Verify checks the proof against X and ctx.
+func (p *SchnorrProof) Verify(X *Point, ctx []byte) bool {
+	if p == nil || p.A == nil || p.Z == nil || X == nil || X.IsIdentity() || p.A.IsIdentity() {
+		return false
+	}
+	z := p.Z.ToInt()
+	if z.Sign() < 0 || z.Cmp(q) >= 0 {
+		return false
+	}
+	// z·G = A + e·X
+	e := schnorrChallenge(ctx, X, p.A)
+	return ScalarBaseMult(z).Equal(p.A.Add(X.ScalarMult(e)))
+}
+
+func schnorrChallenge(ctx []byte, X, A *Point) *big.Int {
+	return hashToScalar("additive/schnorr", ctx, X.Bytes(), A.Bytes())
+}
+
+// keygenContext binds commitments and proofs to a session and participant.
+func keygenContext(sessionID, from string) []byte {
+	return []byte(sessionID + "/" + from)
+}
+
+// KeygenCommit is a participant's first key generation message.
+type KeygenCommit struct {
+	Commitment hexutil.Bytes `json:"commitment"`
+}
+
+// KeygenReveal is a participant's second key generation message, opening the
+// commitment to its Feldman commitments and dealing the shares.
+type KeygenReveal struct {
+	Commitments []*Point                        `json:"commitments"` // A_i0..A_i(t-1)（A_i0 = x_i·G）
+	Nonce       hexutil.Bytes                   `json:"nonce"`
+	Proof       *SchnorrProof                   `json:"proof"`  // x_i の知識の証明
+	Shares      map[string]*paillier.Ciphertext `json:"shares"` // 参加者 j 宛ての Enc_j(f_i(j))
+}
+
+// KeygenConfirm is a participant's third key generation message, proving
+// knowledge of its share s_j for the public share S_j. It is sent only once
+// every share dealt to the participant matched its dealer's commitments.
+type KeygenConfirm struct {
+	Proof *SchnorrProof `json:"proof"`
+}
+
+// KeygenComplaint accuses Dealer of dealing the sender a share inconsistent
+// with its Feldman commitments. It opens the ciphertext so that anyone can
+// check the accusation without the sender's Paillier secret key.
+type KeygenComplaint struct {
+	Dealer string   `json:"dealer"`
+	Share  *Opening `json:"share"` // Dealer の公開に含まれる送信者宛ての暗号文の開示
+}
+
+// keygenConfirmContext binds a confirmation to the session and participant,
+// separately from the proof of x_i.
+func keygenConfirmContext(sessionID, from string) []byte {
+	return []byte("keygen-confirm/" + sessionID + "/" + from)
+}
+
+// keygenCommitment computes H(session, from, A_i0, ..., A_i(t-1), nonce).
+func keygenCommitment(sessionID, from string, commitments []*Point, nonce []byte) []byte {
+	parts := [][]byte{keygenContext(sessionID, from)}
+	for _, A := range commitments {
+		parts = append(parts, A.Bytes())
+	}
+	return hashParts("additive/keygen-commit", append(parts, nonce)...)
+}
+
+// Session describes a key generation or refresh session. Participants and
+// the server use the same description to check messages.
+type Session struct {
+	ID           string
+	Parties      []string                       // 参加者（順序がShamirの評価点 1..n を定める）
+	Threshold    int                            // 署名に必要な人数 t
+	PaillierKeys map[string]*paillier.PublicKey // シェアの暗号化に用いる各参加者のPaillier公開鍵
+}
+
+// Validate checks that 1 <= t <= n, that the participants are unique and that
+// every participant has a Paillier key.
+func (s *Session) Validate() error {
+	if s.Threshold < 1 || s.Threshold > len(s.Parties) {
+		return fmt.Errorf("threshold must be between 1 and %d", len(s.Parties))
+	}
+	seen := make(map[string]bool, len(s.Parties))
+	for _, p := range s.Parties {
+		if seen[p] {
+			return fmt.Errorf("duplicate participant %s", p)
+		}
+		seen[p] = true
+		if s.PaillierKeys[p] == nil {
+			return fmt.Errorf("missing paillier key of %s", p)
+		}
+	}
+	return nil
+}
+
+// VerifyKeygenReveal checks that r opens from's commitment c, proves knowledge of
+// x_i and carries a valid encrypted share for every other participant. The
+// server calls it before storing a reveal. Whether each share matches the
+// Feldman commitments can only be checked by its recipient.
+func (s *Session) VerifyKeygenReveal(from string, c *KeygenCommit, r *KeygenReveal) error {
+	if c == nil {
+		return fmt.Errorf("%s has not committed", from)
+	}
+	if r == nil || len(r.Commitments) != s.Threshold {
+		return fmt.Errorf("%s revealed %d commitments, expected %d", from, commitmentCount(r), s.Threshold)
+	}
+	for _, A := range r.Commitments {
+		if A == nil || A.IsIdentity() {
+			return fmt.Errorf("%s revealed an invalid commitment", from)
+		}
+	}
+	want := keygenCommitment(s.ID, from, r.Commitments, r.Nonce)
+	if subtle.ConstantTimeCompare(want, c.Commitment) != 1 {
+		return fmt.Errorf("%s revealed commitments that do not match its commitment", from)
+	}
+	if !r.Proof.Verify(r.Commitments[0], keygenContext(s.ID, from)) {
+		return fmt.Errorf("%s sent an invalid proof of knowledge", from)
+	}
+	return s.checkShares(from, from, r.Shares)
+}
+
+// checkShares checks that from dealt a valid ciphertext to every participant
+// other than skip. from need not be a participant; skip is either from or
+// empty when from also deals a share to itself.
+func (s *Session) checkShares(from, skip string, shares map[string]*paillier.Ciphertext) error {
+	want := len(s.Parties)
+	if partyIndex(s.Parties, skip) != 0 {
+		want--
+	}
+	if len(shares) != want {
+		return fmt.Errorf("%s must deal a share to every participant", from)
+	}
+	for _, j := range s.Parties {
+		if j == skip {
+			continue
+		}
+		if ct := shares[j]; ct == nil || !s.PaillierKeys[j].IsValidCiphertext(ct) {
+			return fmt.Errorf("%s dealt an invalid share to %s", from, j)
+		}
+	}
+	return nil
+}
+
+// dealShares encrypts f(j) under the Paillier key of every participant j
+// other than skip. An empty skip deals to every participant.
+func (s *Session) dealShares(skip string, coeffs []*big.Int) (map[string]*paillier.Ciphertext, error) {
+	shares := make(map[string]*paillier.Ciphertext, len(s.Parties))
+	for idx, j := range s.Parties {
+		if j == skip {
+			continue
+		}
+		ct, err := s.PaillierKeys[j].Encrypt(evalPolynomial(coeffs, idx+1))
+		if err != nil {
+			return nil, err
+		}
+		shares[j] = ct
+	}
+	return shares, nil
+}
+
+// receiveShare decrypts the share dealt by from to the participant at
+// evaluation point idx and checks it against from's Feldman commitments.
+func receiveShare(priv *paillier.PrivateKey, from string, ct *paillier.Ciphertext, commitments []*Point, idx int) (*big.Int, error) {
+	share, err := priv.Decrypt(ct)
+	if err != nil {
+		return nil, fmt.Errorf("share from %s: %v", from, err)
+	}
+	// f_i(j)·G = F_i(j) でなければ i が不正なシェアを配った
+	if share.Cmp(q) >= 0 || !ScalarBaseMult(share).Equal(evalCommitments(commitments, idx)) {
+		return nil, &Fault{Culprit: from, Reason: "dealt a share inconsistent with its commitments"}
+	}
+	return share, nil
+}
+
+// VerifyKeygenConfirm checks that from proved knowledge of the share behind
+// its public share S, as returned by CombineKeygen. The server completes key
+// generation only once every participant has confirmed.
+func (s *Session) VerifyKeygenConfirm(from string, S *Point, c *KeygenConfirm) error {
+	if c == nil || !c.Proof.Verify(S, keygenConfirmContext(s.ID, from)) {
+		return fmt.Errorf("%s sent an invalid proof of its share", from)
+	}
+	return nil
+}
+
+// VerifyKeygenComplaint checks that c opens the share that c.Dealer dealt to
+// from in its reveal r, and that the opened share does not match the dealer's
+// commitments. A nil error means the dealer is at fault; an error means the
+// complaint is unfounded and must be rejected.
+func (s *Session) VerifyKeygenComplaint(from string, r *KeygenReveal, c *KeygenComplaint) error {
+	idx := partyIndex(s.Parties, from)
+	if idx == 0 || c == nil || c.Share == nil || c.Dealer == from || partyIndex(s.Parties, c.Dealer) == 0 {
+		return fmt.Errorf("invalid complaint from %s", from)
+	}
+	if r == nil || len(r.Commitments) != s.Threshold || r.Shares[from] == nil || s.PaillierKeys[from] == nil {
+		return fmt.Errorf("reveal of %s has no share for %s", c.Dealer, from)
+	}
+	m := new(big.Int).SetBytes(c.Share.M)
+	if !s.PaillierKeys[from].VerifyOpening(r.Shares[from], m, new(big.Int).SetBytes(c.Share.R)) {
+		return fmt.Errorf("%s opened a share that %s did not deal", from, c.Dealer)
+	}
+	if m.Cmp(q) < 0 && ScalarBaseMult(m).Equal(evalCommitments(r.Commitments, idx)) {
+		return fmt.Errorf("share dealt by %s to %s matches its commitments", c.Dealer, from)
+	}
+	return nil
+}
+
+// KeygenEvidence is what the server stores when a complaint aborts key
+// generation: the dealer's reveal and the complaint as signed by their
+// senders, and the complainant's Paillier key, so that anyone can recheck the
+// complaint.
+type KeygenEvidence struct {
+	SessionID   string              `json:"sessionId"`
+	Parties     []string            `json:"parties"`
+	Threshold   int                 `json:"threshold"`
+	PaillierKey *paillier.PublicKey `json:"paillierKey"` // 苦情を送った参加者のPaillier公開鍵
+	Reveal      *SignedMessage      `json:"reveal"`      // ディーラーの公開
+	Complaint   *SignedMessage      `json:"complaint"`
+	Fault       *Fault              `json:"fault"`
+}
+
+// Verify checks both signatures and that the complaint proves e.Fault.
+func (e *KeygenEvidence) Verify() error {
+	if e.Reveal == nil || e.Complaint == nil || e.Fault == nil {
+		return errors.New("evidence is incomplete")
+	}
+	if e.Reveal.Session != e.SessionID || e.Reveal.Round != KeygenRoundReveal ||
+		e.Complaint.Session != e.SessionID || e.Complaint.Round != KeygenRoundComplaint {
+		return errors.New("messages do not belong to the complaint")
+	}
+	for _, m := range []*SignedMessage{e.Reveal, e.Complaint} {
+		if err := m.Verify(); err != nil {
+			return err
+		}
+	}
+	var r KeygenReveal
+	if err := json.Unmarshal([]byte(e.Reveal.Payload), &r); err != nil {
+		return err
+	}
+	var c KeygenComplaint
+	if err := json.Unmarshal([]byte(e.Complaint.Payload), &c); err != nil {
+		return err
+	}
+	if !strings.EqualFold(c.Dealer, e.Reveal.From) || e.Fault.Culprit != e.Reveal.From {
+		return errors.New("complaint does not name the culprit")
+	}
+	c.Dealer = e.Reveal.From
+	s := &Session{
+		ID:           e.SessionID,
+		Parties:      e.Parties,
+		Threshold:    e.Threshold,
+		PaillierKeys: map[string]*paillier.PublicKey{e.Complaint.From: e.PaillierKey},
+	}
+	return s.VerifyKeygenComplaint(e.Complaint.From, &r, &c)
+}
+
+func commitmentCount(r *KeygenReveal) int {
+	if r == nil {
+		return 0
+	}
+	return len(r.Commitments)
+}
+
+// CombineKeygen returns the public key Q = ΣA_i0 and every participant's public
+// share S_j from all participants' reveals.
+func (s *Session) CombineKeygen(reveals map[string]*KeygenReveal) (*Point, map[string]*Point, error) {
+	if len(reveals) != len(s.Parties) {
+		return nil, nil, errors.New("reveals are missing")
+	}
+	constants := make([]*Point, 0, len(s.Parties))
+	for _, i := range s.Parties {
+		if reveals[i] == nil {
+			return nil, nil, fmt.Errorf("reveal of %s is missing", i)
+		}
+		constants = append(constants, reveals[i].Commitments[0])
+	}
+	Q := SumPoints(constants...)
+	if Q.IsIdentity() {
+		return nil, nil, errors.New("public key is the point at infinity")
+	}
+	shares := make(map[string]*Point, len(s.Parties))
+	for idx, j := range s.Parties {
+		S := new(Point)
+		for _, i := range s.Parties {
+			S = S.Add(evalCommitments(reveals[i].Commitments, idx+1))
+		}
+		shares[j] = S
+	}
+	return Q, shares, nil
+}
+
+// evalCommitments returns F(x) = Σ_k A_k·x^k, the public image of f(x).
+func evalCommitments(commitments []*Point, x int) *Point {
+	// ホーナー法で評価する
+	X := big.NewInt(int64(x))
+	acc := new(Point)
+	for k := len(commitments) - 1; k >= 0; k-- {
+		acc = acc.ScalarMult(X).Add(commitments[k])
+	}
+	return acc
+}
+
+// KeyShare is a participant's result of key generation.
+type KeyShare struct {
+	Self         string
+	Parties      []string          // 鍵生成の参加者（順序がShamirの評価点を定める）
+	Threshold    int               // 署名に必要な人数 t
+	Secret       *big.Int          // Shamirシェア s_i
+	PublicKey    *Point            // Q
+	PublicShares map[string]*Point // 各参加者の S_j = s_j·G
+}
+
+// Index returns the Shamir evaluation point of addr, or 0 if addr is not a
+// participant.
+func (k *KeyShare) Index(addr string) int {
+	return partyIndex(k.Parties, addr)
+}
+
+func partyIndex(parties []string, addr string) int {
+	for i, p := range parties {
+		if p == addr {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// AdditiveShare returns w_i = λ_i·s_i, where λ_i is the Lagrange coefficient
+// of this participant over signers, so that Σ w_i = x for any t signers.
+func (k *KeyShare) AdditiveShare(signers []string) (*big.Int, error) {
+	lambda, err := lagrange(k.Parties, signers, k.Self)
+	if err != nil {
+		return nil, err
+	}
+	w := new(big.Int).Mul(lambda, k.Secret)
+	return w.Mod(w, q), nil
+}
+
+// lagrange returns λ_self = Π_{j≠self} j / (j - self) mod q over the
+// evaluation points of signers.
+func lagrange(parties, signers []string, self string) (*big.Int, error) {
+	i := partyIndex(parties, self)
+	if i == 0 {
+		return nil, fmt.Errorf("%s is not a participant", self)
+	}
+	num, den := big.NewInt(1), big.NewInt(1)
+	seen := make(map[int]bool, len(signers))
+	for _, s := range signers {
+		j := partyIndex(parties, s)
+		if j == 0 || seen[j] {
+			return nil, fmt.Errorf("invalid signer %s", s)
+		}
+		seen[j] = true
+		if j == i {
+			continue
+		}
+		num.Mul(num, big.NewInt(int64(j)))
+		den.Mul(den, big.NewInt(int64(j-i)))
+	}
+	if !seen[i] {
+		return nil, fmt.Errorf("%s is not a signer", self)
+	}
+	den.Mod(den, q)
+	num.Mul(num, den.ModInverse(den, q))
+	return num.Mod(num, q), nil
+}
+
+// KeygenParty runs the participant side of key generation.
+type KeygenParty struct {
+	session *Session
+	self    string
+	priv    *paillier.PrivateKey
+	coeffs  []*big.Int // f_i の係数（coeffs[0] = x_i）
+	reveal  *KeygenReveal
+}
+
+// NewKeygenParty samples the polynomial of participant self. priv is the
+// participant's Paillier key, used to decrypt the shares dealt to it.
+func NewKeygenParty(session *Session, self string, priv *paillier.PrivateKey) (*KeygenParty, error) {
+	if err := session.Validate(); err != nil {
+		return nil, err
+	}
+	if partyIndex(session.Parties, self) == 0 {
+		return nil, errors.New("self is not a participant")
+	}
+	coeffs := make([]*big.Int, session.Threshold)
+	commitments := make([]*Point, session.Threshold)
+	for k := range coeffs {
+		a, err := randomScalar()
+		if err != nil {
+			return nil, err
+		}
+		coeffs[k], commitments[k] = a, ScalarBaseMult(a)
+	}
+	proof, err := ProveSchnorr(coeffs[0], keygenContext(session.ID, self))
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	shares, err := session.dealShares(self, coeffs)
+	if err != nil {
+		return nil, err
+	}
+	return &KeygenParty{
+		session: session,
+		self:    self,
+		priv:    priv,
+		coeffs:  coeffs,
+		reveal:  &KeygenReveal{Commitments: commitments, Nonce: nonce, Proof: proof, Shares: shares},
+	}, nil
+}
+
+// evalPolynomial returns f(x) mod q.
+func evalPolynomial(coeffs []*big.Int, x int) *big.Int {
+	X := big.NewInt(int64(x))
+	acc := new(big.Int)
+	for k := len(coeffs) - 1; k >= 0; k-- {
+		acc.Mul(acc, X).Add(acc, coeffs[k]).Mod(acc, q)
+	}
+	return acc
+}
+
+// Commit returns the round 1 message.
+func (p *KeygenParty) Commit() *KeygenCommit {
+	return &KeygenCommit{Commitment: keygenCommitment(p.session.ID, p.self, p.reveal.Commitments, p.reveal.Nonce)}
+}
+
+// Reveal returns the round 2 message. It must only be sent after every
+// participant's commitment has been received.
+func (p *KeygenParty) Reveal() *KeygenReveal {
+	return p.reveal
+}
+
+// Finalize checks the other participants' messages, decrypts and verifies
+// the shares dealt to this participant against their Feldman commitments and
+// returns the key share. A share inconsistent with its commitments is
+// reported as a *Fault naming the dealer, against whom the participant sends
+// a complaint instead of a confirmation.
+func (p *KeygenParty) Finalize(commits map[string]*KeygenCommit, reveals map[string]*KeygenReveal) (*KeyShare, error) {
+	idx := partyIndex(p.session.Parties, p.self)
+	secret := evalPolynomial(p.coeffs, idx)
+	for _, i := range p.session.Parties {
+		if i == p.self {
+			continue
+		}
+		r := reveals[i]
+		if err := p.session.VerifyKeygenReveal(i, commits[i], r); err != nil {
+			return nil, err
+		}
+		share, err := receiveShare(p.priv, i, r.Shares[p.self], r.Commitments, idx)
+		if err != nil {
+			return nil, err
+		}
+		secret.Add(secret, share)
+	}
+	secret.Mod(secret, q)
+
+	own := reveals[p.self]
+	if own == nil || len(own.Commitments) == 0 || !own.Commitments[0].Equal(p.reveal.Commitments[0]) {
+		return nil, errors.New("own reveal is missing or altered")
+	}
+	Q, shares, err := p.session.CombineKeygen(reveals)
+	if err != nil {
+		return nil, err
+	}
+	if !ScalarBaseMult(secret).Equal(shares[p.self]) {
+		return nil, errors.New("own share does not match the public share")
+	}
+	return &KeyShare{
+		Self:         p.self,
+		Parties:      p.session.Parties,
+		Threshold:    p.session.Threshold,
+		Secret:       secret,
+		PublicKey:    Q,
+		PublicShares: shares,
+	}, nil
+}
+
+// Confirm returns the round 3 message for the key share returned by
+// Finalize.
+func (p *KeygenParty) Confirm(share *KeyShare) (*KeygenConfirm, error) {
+	proof, err := ProveSchnorr(share.Secret, keygenConfirmContext(p.session.ID, p.self))
+	if err != nil {
+		return nil, err
+	}
+	return &KeygenConfirm{Proof: proof}, nil
+}
+
+// Complain returns the round 3 complaint against dealer, whose reveal r was
+// named by the *Fault that Finalize returned. It opens the share dealt to
+// this participant, which is of no use once key generation is aborted.
+func (p *KeygenParty) Complain(dealer string, r *KeygenReveal) (*KeygenComplaint, error) {
+	if r == nil || r.Shares[p.self] == nil {
+		return nil, fmt.Errorf("%s dealt no share to %s", dealer, p.self)
+	}
+	share, err := openCiphertext(p.priv, r.Shares[p.self])
+	if err != nil {
+		return nil, err
+	}
+	return &KeygenComplaint{Dealer: dealer, Share: share}, nil
+}
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,341 @@
+package additive
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"multisigservice/paillier"
+)
+
+// keygenSession は署名テスト用のPaillier鍵で鍵生成セッションを用意します。
+func keygenSession(t *testing.T, sessionID string, parties []string, threshold int) *Session {
+	t.Helper()
+	keys, _ := paillierFixture(t)
+	pubs := make(map[string]*paillier.PublicKey)
+	for _, addr := range parties {
+		pubs[addr] = &keys[addr].PublicKey
+	}
+	return &Session{ID: sessionID, Parties: parties, Threshold: threshold, PaillierKeys: pubs}
+}
+
+// runKeygen は参加者全員の鍵生成をメモリ上で実行します。
+func runKeygen(t *testing.T, sessionID string, parties []string, threshold int) map[string]*KeyShare {
+	t.Helper()
+	keys, _ := paillierFixture(t)
+	session := keygenSession(t, sessionID, parties, threshold)
+	players := make(map[string]*KeygenParty)
+	commits := make(map[string]*KeygenCommit)
+	for _, addr := range parties {
+		p, err := NewKeygenParty(session, addr, keys[addr])
+		if err != nil {
+			t.Fatal(err)
+		}
+		players[addr] = p
+		commits[addr] = p.Commit()
+	}
+	reveals := make(map[string]*KeygenReveal)
+	for addr, p := range players {
+		// サーバー側の検証
+		if err := session.VerifyKeygenReveal(addr, commits[addr], p.Reveal()); err != nil {
+			t.Fatal(err)
+		}
+		reveals[addr] = relay(t, p.Reveal())
+	}
+	shares := make(map[string]*KeyShare)
+	for addr, p := range players {
+		share, err := p.Finalize(commits, reveals)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// サーバー側で受領の確認を検証
+		confirm, err := p.Confirm(share)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := session.VerifyKeygenConfirm(addr, share.PublicShares[addr], relay(t, confirm)); err != nil {
+			t.Fatal(err)
+		}
+		shares[addr] = share
+	}
+	return shares
+}
+
+var testParties = []string{
+	"0x1111111111111111111111111111111111111111",
+	"0x2222222222222222222222222222222222222222",
+}
+
+func TestKeygen(t *testing.T) {
+	shares := runKeygen(t, "session-1", signParties, 2)
+	Q := shares[signParties[0]].PublicKey
+	if EthereumAddress(Q) == "" {
+		t.Fatal("empty address")
+	}
+
+	// 全員が同じ公開鍵と、自分のシェアに一致する公開シェアを得ること
+	for addr, s := range shares {
+		if !s.PublicKey.Equal(Q) {
+			t.Errorf("%s: got %x\nwant %x", addr, s.PublicKey.Bytes(), Q.Bytes())
+		}
+		if !ScalarBaseMult(s.Secret).Equal(s.PublicShares[addr]) {
+			t.Errorf("%s: public share does not match the secret share", addr)
+		}
+	}
+
+	// 任意の t 人の加法シェアの和が秘密鍵になること
+	for _, signers := range [][]string{
+		{signParties[0], signParties[1]},
+		{signParties[0], signParties[2]},
+		{signParties[1], signParties[2]},
+		signParties,
+	} {
+		x := new(big.Int)
+		for _, addr := range signers {
+			w, err := shares[addr].AdditiveShare(signers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			x.Add(x, w)
+		}
+		if got := ScalarBaseMult(x.Mod(x, q)); !got.Equal(Q) {
+			t.Errorf("signers %v: got %x\nwant %x", signers, got.Bytes(), Q.Bytes())
+		}
+	}
+	if _, err := shares[signParties[0]].AdditiveShare([]string{signParties[1], signParties[2]}); err == nil {
+		t.Error("additive share of a non-signer was returned")
+	}
+}
+
+func TestSessionValidate(t *testing.T) {
+	tests := []struct {
+		parties   []string
+		threshold int
+		ok        bool
+	}{
+		{testParties, 1, true},
+		{testParties, 2, true},
+		{testParties, 0, false},
+		{testParties, 3, false},
+		{[]string{testParties[0], testParties[0]}, 1, false},
+	}
+	for _, tt := range tests {
+		s := keygenSession(t, "session", tt.parties, tt.threshold)
+		if err := s.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%d-of-%v: got %v\nwant ok=%v", tt.threshold, tt.parties, err, tt.ok)
+		}
+	}
+}
+
+func TestKeygenRejectsBadReveal(t *testing.T) {
+	const sid = "session-2"
+	keys, _ := paillierFixture(t)
+	session := keygenSession(t, sid, testParties, 2)
+	a, err := NewKeygenParty(session, testParties[0], keys[testParties[0]])
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewKeygenParty(session, testParties[1], keys[testParties[1]])
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := a.Commit()
+
+	// 他人のコミットメントへのすり替え
+	if err := session.VerifyKeygenReveal(testParties[0], commit, b.Reveal()); err == nil {
+		t.Error("reveal of another party was accepted")
+	}
+	// 別セッションへのリプレイ
+	other := keygenSession(t, "other", testParties, 2)
+	if err := other.VerifyKeygenReveal(testParties[0], commit, a.Reveal()); err == nil {
+		t.Error("reveal from another session was accepted")
+	}
+	// 証明の改ざん
+	forged := *a.Reveal()
+	forged.Proof = b.Reveal().Proof
+	if err := session.VerifyKeygenReveal(testParties[0], commit, &forged); err == nil {
+		t.Error("forged proof was accepted")
+	}
+	// シェアの欠落
+	forged = *a.Reveal()
+	forged.Shares = nil
+	if err := session.VerifyKeygenReveal(testParties[0], commit, &forged); err == nil {
+		t.Error("reveal without shares was accepted")
+	}
+	if err := session.VerifyKeygenReveal(testParties[0], nil, a.Reveal()); err == nil {
+		t.Error("reveal without commitment was accepted")
+	}
+}
+
+func TestKeygenRejectsInconsistentShare(t *testing.T) {
+	const sid = "session-3"
+	keys, _ := paillierFixture(t)
+	session := keygenSession(t, sid, testParties, 2)
+	a, err := NewKeygenParty(session, testParties[0], keys[testParties[0]])
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewKeygenParty(session, testParties[1], keys[testParties[1]])
+	if err != nil {
+		t.Fatal(err)
+	}
+	commits := map[string]*KeygenCommit{testParties[0]: a.Commit(), testParties[1]: b.Commit()}
+
+	// a が b 宛てに多項式と一致しないシェアを配る。サーバーには検出できない
+	ct, err := session.PaillierKeys[testParties[1]].Encrypt(big.NewInt(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := *a.Reveal()
+	forged.Shares = map[string]*paillier.Ciphertext{testParties[1]: ct}
+	if err := session.VerifyKeygenReveal(testParties[0], commits[testParties[0]], &forged); err != nil {
+		t.Fatal(err)
+	}
+	reveals := map[string]*KeygenReveal{testParties[0]: &forged, testParties[1]: b.Reveal()}
+	if _, err := b.Finalize(commits, reveals); err == nil {
+		t.Error("inconsistent share was accepted")
+	}
+}
+
+// signAs は key で署名した鍵生成のメッセージを返します。
+func signAs(t *testing.T, key *ecdsa.PrivateKey, sid string, round int, payload interface{}) *SignedMessage {
+	t.Helper()
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &SignedMessage{Session: sid, Round: round, From: crypto.PubkeyToAddress(key.PublicKey).Hex(), Payload: string(raw)}
+	statement := m.Statement()
+	hash := crypto.Keccak256([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(statement), statement)))
+	if m.Signature, err = crypto.Sign(hash, key); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestKeygenComplaint(t *testing.T) {
+	const sid = "session-4"
+	keys, _ := paillierFixture(t)
+	// 証拠の署名を検証するため、参加者はEthereum鍵のアドレスとする
+	ethKeys := make([]*ecdsa.PrivateKey, 2)
+	parties := make([]string, 2)
+	for i := range ethKeys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ethKeys[i], parties[i] = key, crypto.PubkeyToAddress(key.PublicKey).Hex()
+	}
+	a, b := parties[0], parties[1]
+	privs := map[string]*paillier.PrivateKey{a: keys[testParties[0]], b: keys[testParties[1]]}
+	session := &Session{ID: sid, Parties: parties, Threshold: 2, PaillierKeys: map[string]*paillier.PublicKey{
+		a: &privs[a].PublicKey,
+		b: &privs[b].PublicKey,
+	}}
+	pa, err := NewKeygenParty(session, a, privs[a])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, err := NewKeygenParty(session, b, privs[b])
+	if err != nil {
+		t.Fatal(err)
+	}
+	commits := map[string]*KeygenCommit{a: pa.Commit(), b: pb.Commit()}
+
+	// a が b 宛てに多項式と一致しないシェアを配る
+	ct, err := session.PaillierKeys[b].Encrypt(big.NewInt(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := *pa.Reveal()
+	forged.Shares = map[string]*paillier.Ciphertext{b: ct}
+	reveals := map[string]*KeygenReveal{a: relay(t, &forged), b: relay(t, pb.Reveal())}
+	_, err = pb.Finalize(commits, reveals)
+	var fault *Fault
+	if !errors.As(err, &fault) || fault.Culprit != a {
+		t.Fatalf("got %v\nwant a fault of %s", err, a)
+	}
+
+	complaint, err := pb.Complain(a, reveals[a])
+	if err != nil {
+		t.Fatal(err)
+	}
+	complaint = relay(t, complaint)
+	if err := session.VerifyKeygenComplaint(b, reveals[a], complaint); err != nil {
+		t.Fatalf("valid complaint rejected: %v", err)
+	}
+
+	// 証拠は署名済みのメッセージと苦情を送った参加者の公開鍵から誰でも再検証できること
+	ev := &KeygenEvidence{
+		SessionID:   sid,
+		Parties:     parties,
+		Threshold:   2,
+		PaillierKey: session.PaillierKeys[b],
+		Reveal:      signAs(t, ethKeys[0], sid, KeygenRoundReveal, reveals[a]),
+		Complaint:   signAs(t, ethKeys[1], sid, KeygenRoundComplaint, complaint),
+		Fault:       &Fault{Culprit: a, Reason: "dealt a share inconsistent with its commitments"},
+	}
+	if err := relay(t, ev).Verify(); err != nil {
+		t.Fatalf("valid evidence rejected: %v", err)
+	}
+	blamed := *ev
+	blamed.Fault = &Fault{Culprit: b}
+	if err := blamed.Verify(); err == nil {
+		t.Error("evidence blaming the complainant was accepted")
+	}
+
+	// 正しく配られたシェアへの苦情と、書き換えた開示は拒否されること
+	honest, err := pa.Complain(b, reveals[b])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := session.VerifyKeygenComplaint(a, reveals[b], honest); err == nil {
+		t.Error("complaint against a consistent share was accepted")
+	}
+	tampered := *complaint
+	tampered.Share = &Opening{M: big.NewInt(43).Bytes(), R: complaint.Share.R}
+	if err := session.VerifyKeygenComplaint(b, reveals[a], &tampered); err == nil {
+		t.Error("complaint with a forged opening was accepted")
+	}
+	if err := session.VerifyKeygenComplaint(a, reveals[a], complaint); err == nil {
+		t.Error("complaint against oneself was accepted")
+	}
+}
+
+func TestPointEncoding(t *testing.T) {
+	k, err := randomScalar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := ScalarBaseMult(k)
+	text, err := p.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Point
+	if err := decoded.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(p) {
+		t.Errorf("got %s\nwant %s", mustText(&decoded), text)
+	}
+	if !p.Add(p.Neg()).IsIdentity() {
+		t.Error("p + (-p) is not the identity")
+	}
+	if !p.Add(p).Equal(p.ScalarMult(big.NewInt(2))) {
+		t.Error("p + p != 2p")
+	}
+	if _, err := new(Point).MarshalText(); err == nil {
+		t.Error("identity should not be encodable")
+	}
+}
+
+func mustText(p *Point) string {
+	b, _ := p.MarshalText()
+	return string(b)
+}
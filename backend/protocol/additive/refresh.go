@@ -0,0 +1,238 @@
+package additive
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"multisigservice/paillier"
+)
+
+// 鍵更新（プロアクティブリフレッシュ）は3ラウンドで行います。
+//   1. 各参加者 i は g_i(0) = 0 となる次数 t-1 の多項式 g_i を選び、
+//      係数のコミットメント B_i1..B_i(t-1) へのハッシュコミットメントを送る
+//   2. B_ik・nonce と、自分を含む各参加者 j 宛ての g_i(j) を j のPaillier公開鍵で暗号化したものを公開する
+//   3. 新しいシェア s_j' の知識のSchnorr証明を送り、受領を確認する
+// 参加者 j の新しいシェアは s_j' = s_j + Σ_i g_i(j) です。Σ_i g_i(0) = 0 なので
+// 秘密鍵と公開鍵 Q は変わらず、古いシェアは新しいシェアと組み合わせられなくなります。
+// 自分宛ての g_i(i) も暗号化して公開するため、新しいシェアは公開された記録と古いシェアから復元できます。
+// サーバーは全員の確認が揃ってから、公開シェアを S_j' = S_j + Σ_i Σ_k B_ik·j^k に更新します。
+
+// RefreshReveal is a participant's second refresh message, opening the
+// commitment to the Feldman commitments of its zero-sharing and dealing the
+// shares.
+type RefreshReveal struct {
+	Commitments []*Point                        `json:"commitments"` // B_i1..B_i(t-1)（B_i0 は単位元）
+	Nonce       hexutil.Bytes                   `json:"nonce"`
+	Shares      map[string]*paillier.Ciphertext `json:"shares"` // 自分を含む参加者 j 宛ての Enc_j(g_i(j))
+}
+
+// RefreshConfirm is a participant's third refresh message, proving knowledge
+// of its refreshed share s_j' for the public share S_j'.
+type RefreshConfirm struct {
+	Proof *SchnorrProof `json:"proof"`
+}
+
+// refreshCommitment computes H(session, from, B_i1, ..., B_i(t-1), nonce).
+func refreshCommitment(sessionID, from string, commitments []*Point, nonce []byte) []byte {
+	parts := [][]byte{keygenContext(sessionID, from)}
+	for _, B := range commitments {
+		parts = append(parts, B.Bytes())
+	}
+	return hashParts("additive/refresh-commit", append(parts, nonce)...)
+}
+
+// refreshConfirmContext binds a confirmation to the refresh session and
+// participant, separately from the keygen proofs.
+func refreshConfirmContext(sessionID, from string) []byte {
+	return []byte("refresh-confirm/" + sessionID + "/" + from)
+}
+
+// zeroCommitments prepends the identity, the commitment to g_i(0) = 0.
+func zeroCommitments(commitments []*Point) []*Point {
+	return append([]*Point{new(Point)}, commitments...)
+}
+
+// ValidateRefresh checks the session for a refresh. A refresh needs t >= 2,
+// since with t = 1 every participant holds the whole key.
+func (s *Session) ValidateRefresh() error {
+	if s.Threshold < 2 {
+		return errors.New("refresh requires a threshold of at least 2")
+	}
+	return s.Validate()
+}
+
+// VerifyRefreshReveal checks that r opens from's commitment c and carries a
+// valid encrypted share for every participant, including from. The server
+// calls it before storing a reveal.
+func (s *Session) VerifyRefreshReveal(from string, c *KeygenCommit, r *RefreshReveal) error {
+	if c == nil {
+		return fmt.Errorf("%s has not committed", from)
+	}
+	if r == nil || len(r.Commitments) != s.Threshold-1 {
+		return fmt.Errorf("%s revealed an invalid number of commitments, expected %d", from, s.Threshold-1)
+	}
+	for _, B := range r.Commitments {
+		if B == nil || B.IsIdentity() {
+			return fmt.Errorf("%s revealed an invalid commitment", from)
+		}
+	}
+	want := refreshCommitment(s.ID, from, r.Commitments, r.Nonce)
+	if subtle.ConstantTimeCompare(want, c.Commitment) != 1 {
+		return fmt.Errorf("%s revealed commitments that do not match its commitment", from)
+	}
+	return s.checkShares(from, "", r.Shares)
+}
+
+// RefreshPublicShares returns the public shares after applying every
+// participant's zero-sharing to shares.
+func (s *Session) RefreshPublicShares(shares map[string]*Point, reveals map[string]*RefreshReveal) (map[string]*Point, error) {
+	if len(reveals) != len(s.Parties) {
+		return nil, errors.New("reveals are missing")
+	}
+	refreshed := make(map[string]*Point, len(s.Parties))
+	for idx, j := range s.Parties {
+		S := shares[j]
+		if S == nil {
+			return nil, fmt.Errorf("public share of %s is missing", j)
+		}
+		for _, i := range s.Parties {
+			if reveals[i] == nil {
+				return nil, fmt.Errorf("reveal of %s is missing", i)
+			}
+			S = S.Add(evalCommitments(zeroCommitments(reveals[i].Commitments), idx+1))
+		}
+		if S.IsIdentity() {
+			return nil, fmt.Errorf("public share of %s is the point at infinity", j)
+		}
+		refreshed[j] = S
+	}
+	return refreshed, nil
+}
+
+// VerifyRefreshConfirm checks that from proved knowledge of the refreshed
+// share behind its public share S. The server calls it with the public shares
+// returned by RefreshPublicShares before storing a confirmation, and only
+// replaces the public shares once every participant has confirmed.
+func (s *Session) VerifyRefreshConfirm(from string, S *Point, c *RefreshConfirm) error {
+	if c == nil || !c.Proof.Verify(S, refreshConfirmContext(s.ID, from)) {
+		return fmt.Errorf("%s sent an invalid proof of its refreshed share", from)
+	}
+	return nil
+}
+
+// RefreshParty runs the participant side of a refresh.
+type RefreshParty struct {
+	session *Session
+	share   *KeyShare
+	priv    *paillier.PrivateKey
+	reveal  *RefreshReveal
+}
+
+// NewRefreshParty samples the zero-sharing of the participant holding share.
+// priv is the participant's Paillier key, used to decrypt the shares dealt to
+// it.
+func NewRefreshParty(session *Session, share *KeyShare, priv *paillier.PrivateKey) (*RefreshParty, error) {
+	if err := session.ValidateRefresh(); err != nil {
+		return nil, err
+	}
+	if share.Threshold != session.Threshold || len(share.Parties) != len(session.Parties) {
+		return nil, errors.New("key share does not belong to the session's participants")
+	}
+	for i, p := range session.Parties {
+		if share.Parties[i] != p {
+			return nil, errors.New("key share does not belong to the session's participants")
+		}
+	}
+	coeffs := make([]*big.Int, session.Threshold)
+	coeffs[0] = new(big.Int)
+	commitments := make([]*Point, session.Threshold-1)
+	for k := 1; k < len(coeffs); k++ {
+		b, err := randomScalar()
+		if err != nil {
+			return nil, err
+		}
+		coeffs[k], commitments[k-1] = b, ScalarBaseMult(b)
+	}
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	shares, err := session.dealShares("", coeffs)
+	if err != nil {
+		return nil, err
+	}
+	return &RefreshParty{
+		session: session,
+		share:   share,
+		priv:    priv,
+		reveal:  &RefreshReveal{Commitments: commitments, Nonce: nonce, Shares: shares},
+	}, nil
+}
+
+// Commit returns the round 1 message.
+func (p *RefreshParty) Commit() *KeygenCommit {
+	return &KeygenCommit{Commitment: refreshCommitment(p.session.ID, p.share.Self, p.reveal.Commitments, p.reveal.Nonce)}
+}
+
+// Reveal returns the round 2 message. It must only be sent after every
+// participant's commitment has been received.
+func (p *RefreshParty) Reveal() *RefreshReveal {
+	return p.reveal
+}
+
+// Finalize checks every participant's messages, adds the shares dealt to
+// this participant and returns the refreshed key share. The old share must
+// be discarded once the server has completed the refresh.
+func (p *RefreshParty) Finalize(commits map[string]*KeygenCommit, reveals map[string]*RefreshReveal) (*KeyShare, error) {
+	self := p.share.Self
+	idx := partyIndex(p.session.Parties, self)
+	// 自分宛ての g_i(i) も記録から受け取るため、自分の公開が改ざんされていないことを確認する
+	own := reveals[self]
+	if own == nil || len(own.Commitments) != len(p.reveal.Commitments) {
+		return nil, errors.New("own reveal is missing or altered")
+	}
+	for k, B := range p.reveal.Commitments {
+		if own.Commitments[k] == nil || !own.Commitments[k].Equal(B) {
+			return nil, errors.New("own reveal is missing or altered")
+		}
+	}
+	secret := new(big.Int).Set(p.share.Secret)
+	for _, i := range p.session.Parties {
+		r := reveals[i]
+		if err := p.session.VerifyRefreshReveal(i, commits[i], r); err != nil {
+			return nil, err
+		}
+		delta, err := receiveShare(p.priv, i, r.Shares[self], zeroCommitments(r.Commitments), idx)
+		if err != nil {
+			return nil, err
+		}
+		secret.Add(secret, delta)
+	}
+	secret.Mod(secret, q)
+
+	shares, err := p.session.RefreshPublicShares(p.share.PublicShares, reveals)
+	if err != nil {
+		return nil, err
+	}
+	if !ScalarBaseMult(secret).Equal(shares[self]) {
+		return nil, errors.New("own share does not match the public share")
+	}
+	refreshed := *p.share
+	refreshed.Secret = secret
+	refreshed.PublicShares = shares
+	return &refreshed, nil
+}
+
+// Confirm returns the round 3 message for the share returned by Finalize.
+func (p *RefreshParty) Confirm(refreshed *KeyShare) (*RefreshConfirm, error) {
+	proof, err := ProveSchnorr(refreshed.Secret, refreshConfirmContext(p.session.ID, refreshed.Self))
+	if err != nil {
+		return nil, err
+	}
+	return &RefreshConfirm{Proof: proof}, nil
+}
@@ -0,0 +1,192 @@
+package additive
+
+import (
+	"math/big"
+	"testing"
+
+	"multisigservice/paillier"
+)
+
+// runRefresh は参加者全員の鍵更新をメモリ上で実行します。
+func runRefresh(t *testing.T, sessionID string, shares map[string]*KeyShare) map[string]*KeyShare {
+	t.Helper()
+	keys, _ := paillierFixture(t)
+	var first *KeyShare
+	for _, s := range shares {
+		first = s
+		break
+	}
+	session := keygenSession(t, sessionID, first.Parties, first.Threshold)
+	players := make(map[string]*RefreshParty)
+	commits := make(map[string]*KeygenCommit)
+	for addr, share := range shares {
+		p, err := NewRefreshParty(session, share, keys[addr])
+		if err != nil {
+			t.Fatal(err)
+		}
+		players[addr] = p
+		commits[addr] = p.Commit()
+	}
+	reveals := make(map[string]*RefreshReveal)
+	for addr, p := range players {
+		// サーバー側の検証
+		if err := session.VerifyRefreshReveal(addr, commits[addr], p.Reveal()); err != nil {
+			t.Fatal(err)
+		}
+		reveals[addr] = relay(t, p.Reveal())
+	}
+	refreshed := make(map[string]*KeyShare)
+	for addr, p := range players {
+		share, err := p.Finalize(commits, reveals)
+		if err != nil {
+			t.Fatal(err)
+		}
+		confirm, err := p.Confirm(share)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// サーバー側の検証（全員の確認が揃ってから公開シェアを更新する）
+		if err := session.VerifyRefreshConfirm(addr, share.PublicShares[addr], relay(t, confirm)); err != nil {
+			t.Fatal(err)
+		}
+		refreshed[addr] = share
+	}
+	return refreshed
+}
+
+// combine は signers の加法シェアの和から公開鍵を求めます。
+func combine(t *testing.T, shares map[string]*KeyShare, signers []string) *Point {
+	t.Helper()
+	x := new(big.Int)
+	for _, addr := range signers {
+		w, err := shares[addr].AdditiveShare(signers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x.Add(x, w)
+	}
+	return ScalarBaseMult(x.Mod(x, q))
+}
+
+func TestRefresh(t *testing.T) {
+	shares := runKeygen(t, "keygen", signParties, 2)
+	Q := shares[signParties[0]].PublicKey
+	refreshed := runRefresh(t, "refresh-1", shares)
+
+	for addr, s := range refreshed {
+		if !s.PublicKey.Equal(Q) {
+			t.Errorf("%s: public key changed", addr)
+		}
+		if s.Secret.Cmp(shares[addr].Secret) == 0 {
+			t.Errorf("%s: share was not refreshed", addr)
+		}
+		if !ScalarBaseMult(s.Secret).Equal(s.PublicShares[addr]) {
+			t.Errorf("%s: public share does not match the secret share", addr)
+		}
+	}
+	signers := []string{signParties[1], signParties[2]}
+	if got := combine(t, refreshed, signers); !got.Equal(Q) {
+		t.Errorf("got %x\nwant %x", got.Bytes(), Q.Bytes())
+	}
+
+	// 古いシェアと新しいシェアは組み合わせられないこと
+	mixed := map[string]*KeyShare{signParties[1]: shares[signParties[1]], signParties[2]: refreshed[signParties[2]]}
+	if combine(t, mixed, signers).Equal(Q) {
+		t.Error("old and refreshed shares combined to the key")
+	}
+}
+
+func TestRefreshRejectsBadReveal(t *testing.T) {
+	shares := runKeygen(t, "keygen", testParties, 2)
+	keys, _ := paillierFixture(t)
+	const sid = "refresh-2"
+	session := keygenSession(t, sid, testParties, 2)
+	a, err := NewRefreshParty(session, shares[testParties[0]], keys[testParties[0]])
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewRefreshParty(session, shares[testParties[1]], keys[testParties[1]])
+	if err != nil {
+		t.Fatal(err)
+	}
+	commits := map[string]*KeygenCommit{testParties[0]: a.Commit(), testParties[1]: b.Commit()}
+
+	if err := session.VerifyRefreshReveal(testParties[0], commits[testParties[0]], b.Reveal()); err == nil {
+		t.Error("reveal of another party was accepted")
+	}
+	// 鍵生成のコミットメントとは区別されること
+	keygenCommit := &KeygenCommit{Commitment: keygenCommitment(sid, testParties[0], a.Reveal().Commitments, a.Reveal().Nonce)}
+	if err := session.VerifyRefreshReveal(testParties[0], keygenCommit, a.Reveal()); err == nil {
+		t.Error("keygen commitment was accepted")
+	}
+
+	// g_i(j) と一致しないシェアは受け取り側で検出されること
+	ct, err := session.PaillierKeys[testParties[1]].Encrypt(big.NewInt(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := *a.Reveal()
+	forged.Shares = map[string]*paillier.Ciphertext{testParties[0]: a.Reveal().Shares[testParties[0]], testParties[1]: ct}
+	if err := session.VerifyRefreshReveal(testParties[0], commits[testParties[0]], &forged); err != nil {
+		t.Fatal(err)
+	}
+	reveals := map[string]*RefreshReveal{testParties[0]: &forged, testParties[1]: b.Reveal()}
+	if _, err := b.Finalize(commits, reveals); err == nil {
+		t.Error("inconsistent share was accepted")
+	}
+
+	// 自分宛てのシェアを省いた公開は拒否されること
+	partial := *a.Reveal()
+	partial.Shares = map[string]*paillier.Ciphertext{testParties[1]: a.Reveal().Shares[testParties[1]]}
+	if err := session.VerifyRefreshReveal(testParties[0], commits[testParties[0]], &partial); err == nil {
+		t.Error("reveal without the dealer's own share was accepted")
+	}
+
+	if err := keygenSession(t, sid, testParties, 1).ValidateRefresh(); err == nil {
+		t.Error("refresh of a 1-of-n key was accepted")
+	}
+}
+
+func TestRefreshConfirm(t *testing.T) {
+	shares := runKeygen(t, "keygen", testParties, 2)
+	keys, _ := paillierFixture(t)
+	const sid = "refresh-3"
+	session := keygenSession(t, sid, testParties, 2)
+	players := make(map[string]*RefreshParty)
+	commits := make(map[string]*KeygenCommit)
+	reveals := make(map[string]*RefreshReveal)
+	for _, addr := range testParties {
+		p, err := NewRefreshParty(session, shares[addr], keys[addr])
+		if err != nil {
+			t.Fatal(err)
+		}
+		players[addr], commits[addr], reveals[addr] = p, p.Commit(), p.Reveal()
+	}
+	a := testParties[0]
+	refreshed, err := players[a].Finalize(commits, reveals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirm, err := players[a].Confirm(refreshed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	S := refreshed.PublicShares[a]
+	if err := session.VerifyRefreshConfirm(a, S, confirm); err != nil {
+		t.Fatalf("valid confirmation rejected: %v", err)
+	}
+
+	// 公開シェアは公開された記録から計算できるため、秘密のシェアの知識がなければ確認できないこと
+	if err := session.VerifyRefreshConfirm(a, shares[a].PublicShares[a], confirm); err == nil {
+		t.Error("confirmation accepted for the old public share")
+	}
+	if err := session.VerifyRefreshConfirm(testParties[1], S, confirm); err == nil {
+		t.Error("confirmation accepted for another participant")
+	}
+	if err := keygenSession(t, "refresh-4", testParties, 2).VerifyRefreshConfirm(a, S, confirm); err == nil {
+		t.Error("confirmation replayed in another session")
+	}
+	if err := session.VerifyRefreshConfirm(a, S, &RefreshConfirm{}); err == nil {
+		t.Error("confirmation without a proof accepted")
+	}
+}
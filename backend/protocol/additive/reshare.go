@@ -0,0 +1,255 @@
+package additive
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"multisigservice/paillier"
+)
+
+// 再共有（リシェア）は、旧参加者の t 人以上（ディーラー）から新しい参加者集合へ、
+// 公開鍵 Q を変えずに秘密鍵を移します。新しい集合の人数と閾値 t' は旧集合と異なってよく、
+// 両方の集合に属する参加者もいます。
+//   1. ディーラー i は加法シェア w_i = λ_i·s_i を定数項とする次数 t'-1 の多項式 h_i を選び、
+//      係数のコミットメント C_ik と、新しい参加者 j 宛ての h_i(j) を j のPaillier公開鍵で
+//      暗号化したものを送る。サーバーは C_i0 = λ_i·S_i を旧公開シェアで検証する
+//   2. 新しい参加者 j は受け取ったシェアを検証し、新しいシェア s_j' = Σ_i h_i(j) の
+//      公開シェア S_j' と s_j' の知識のSchnorr証明を確認として送る
+// C_i0 が旧公開シェアで固定されるため、ディーラーは Q を変えられません。
+// 受け取ったシェアが C_ik と一致しない場合は、新しい参加者が確認を送らないことで検出されます。
+// S_j' は公開された配布から誰でも計算できるため、受領は証明によって確認します。
+
+// ReshareDeal is a dealer's message to the new participants.
+type ReshareDeal struct {
+	Commitments []*Point                        `json:"commitments"` // C_i0..C_i(t'-1)（C_i0 = λ_i·S_i）
+	Shares      map[string]*paillier.Ciphertext `json:"shares"`      // 新しい参加者 j 宛ての Enc_j(h_i(j))
+}
+
+// ReshareConfirm is a new participant's confirmation that the shares dealt to
+// it are consistent, carrying its new public share and a proof of knowledge of
+// the new share.
+type ReshareConfirm struct {
+	PublicShare *Point        `json:"publicShare"`
+	Proof       *SchnorrProof `json:"proof"`
+}
+
+// reshareConfirmContext binds a confirmation to the resharing session and new
+// participant.
+func reshareConfirmContext(sessionID, from string) []byte {
+	return []byte("reshare-confirm/" + sessionID + "/" + from)
+}
+
+// ReshareSession describes a resharing from the old participants to New.
+type ReshareSession struct {
+	OldParties      []string          // 旧参加者（順序が旧シェアの評価点を定める）
+	OldThreshold    int               // 旧閾値 t
+	OldPublicShares map[string]*Point // 旧参加者の公開シェア S_i
+	Dealers         []string          // シェアを配る旧参加者（t 人以上）
+	New             *Session          // 新しい参加者・閾値・Paillier公開鍵
+}
+
+// Validate checks the old and new participant sets.
+func (rs *ReshareSession) Validate() error {
+	if err := rs.New.Validate(); err != nil {
+		return err
+	}
+	if len(rs.Dealers) < rs.OldThreshold {
+		return fmt.Errorf("at least %d dealers are required", rs.OldThreshold)
+	}
+	seen := make(map[string]bool, len(rs.Dealers))
+	for _, d := range rs.Dealers {
+		if partyIndex(rs.OldParties, d) == 0 || seen[d] {
+			return fmt.Errorf("invalid dealer %s", d)
+		}
+		seen[d] = true
+		if rs.OldPublicShares[d] == nil {
+			return fmt.Errorf("public share of %s is missing", d)
+		}
+	}
+	return nil
+}
+
+// dealerCommitment returns λ_i·S_i, the commitment to dealer i's additive
+// share over the dealers.
+func (rs *ReshareSession) dealerCommitment(dealer string) (*Point, error) {
+	lambda, err := lagrange(rs.OldParties, rs.Dealers, dealer)
+	if err != nil {
+		return nil, err
+	}
+	return rs.OldPublicShares[dealer].ScalarMult(lambda), nil
+}
+
+// VerifyDeal checks that d commits to from's additive share and carries a
+// valid encrypted share for every new participant. The server calls it
+// before storing a deal.
+func (rs *ReshareSession) VerifyDeal(from string, d *ReshareDeal) error {
+	C0, err := rs.dealerCommitment(from)
+	if err != nil {
+		return err
+	}
+	if d == nil || len(d.Commitments) != rs.New.Threshold {
+		return fmt.Errorf("%s dealt an invalid number of commitments, expected %d", from, rs.New.Threshold)
+	}
+	for _, C := range d.Commitments {
+		if C == nil || C.IsIdentity() {
+			return fmt.Errorf("%s dealt an invalid commitment", from)
+		}
+	}
+	if !d.Commitments[0].Equal(C0) {
+		return fmt.Errorf("%s dealt a secret that does not match its key share", from)
+	}
+	return rs.New.checkShares(from, from, d.Shares)
+}
+
+// VerifyConfirm checks that c carries from's public share S, as returned by
+// Combine, and proves knowledge of the new share behind it. The server calls
+// it before storing a confirmation.
+func (rs *ReshareSession) VerifyConfirm(from string, S *Point, c *ReshareConfirm) error {
+	if c == nil || c.PublicShare == nil || S == nil || !c.PublicShare.Equal(S) {
+		return fmt.Errorf("public share of %s does not match the dealt shares", from)
+	}
+	if !c.Proof.Verify(S, reshareConfirmContext(rs.New.ID, from)) {
+		return fmt.Errorf("%s sent an invalid proof of its new share", from)
+	}
+	return nil
+}
+
+// Combine returns the public key Q = ΣC_i0 and every new participant's
+// public share S_j' from all dealers' deals.
+func (rs *ReshareSession) Combine(deals map[string]*ReshareDeal) (*Point, map[string]*Point, error) {
+	if len(deals) != len(rs.Dealers) {
+		return nil, nil, errors.New("deals are missing")
+	}
+	constants := make([]*Point, 0, len(rs.Dealers))
+	for _, i := range rs.Dealers {
+		if deals[i] == nil {
+			return nil, nil, fmt.Errorf("deal of %s is missing", i)
+		}
+		constants = append(constants, deals[i].Commitments[0])
+	}
+	shares := make(map[string]*Point, len(rs.New.Parties))
+	for idx, j := range rs.New.Parties {
+		S := new(Point)
+		for _, i := range rs.Dealers {
+			S = S.Add(evalCommitments(deals[i].Commitments, idx+1))
+		}
+		shares[j] = S
+	}
+	return SumPoints(constants...), shares, nil
+}
+
+// ReshareParty runs one participant's side of a resharing, as a dealer, a
+// new participant, or both.
+type ReshareParty struct {
+	session *ReshareSession
+	self    string
+	priv    *paillier.PrivateKey
+	coeffs  []*big.Int // h_i の係数（ディーラーのみ）
+	deal    *ReshareDeal
+}
+
+// NewReshareParty prepares participant self. share is self's old key share
+// and is required if self is a dealer; priv is self's Paillier key and is
+// required if self is a new participant.
+func NewReshareParty(session *ReshareSession, self string, share *KeyShare, priv *paillier.PrivateKey) (*ReshareParty, error) {
+	if err := session.Validate(); err != nil {
+		return nil, err
+	}
+	p := &ReshareParty{session: session, self: self, priv: priv}
+	isNew := partyIndex(session.New.Parties, self) != 0
+	if isNew && priv == nil {
+		return nil, errors.New("paillier key is required to receive shares")
+	}
+	if partyIndex(session.Dealers, self) == 0 {
+		if !isNew {
+			return nil, errors.New("self is neither a dealer nor a new participant")
+		}
+		return p, nil
+	}
+
+	if share == nil || share.Self != self {
+		return nil, errors.New("key share of the dealer is required")
+	}
+	w, err := share.AdditiveShare(session.Dealers)
+	if err != nil {
+		return nil, err
+	}
+	p.coeffs = make([]*big.Int, session.New.Threshold)
+	commitments := make([]*Point, session.New.Threshold)
+	p.coeffs[0], commitments[0] = w, ScalarBaseMult(w)
+	for k := 1; k < len(p.coeffs); k++ {
+		c, err := randomScalar()
+		if err != nil {
+			return nil, err
+		}
+		p.coeffs[k], commitments[k] = c, ScalarBaseMult(c)
+	}
+	shares, err := session.New.dealShares(self, p.coeffs)
+	if err != nil {
+		return nil, err
+	}
+	p.deal = &ReshareDeal{Commitments: commitments, Shares: shares}
+	return p, nil
+}
+
+// Deal returns the dealer's message, or nil if self is not a dealer.
+func (p *ReshareParty) Deal() *ReshareDeal {
+	return p.deal
+}
+
+// Finalize checks every dealer's deal, decrypts and verifies the shares
+// dealt to this new participant and returns the new key share. Its receipt is
+// sent back with Confirm.
+func (p *ReshareParty) Finalize(deals map[string]*ReshareDeal) (*KeyShare, error) {
+	idx := partyIndex(p.session.New.Parties, p.self)
+	if idx == 0 {
+		return nil, errors.New("self is not a new participant")
+	}
+	secret := new(big.Int)
+	for _, i := range p.session.Dealers {
+		d := deals[i]
+		if err := p.session.VerifyDeal(i, d); err != nil {
+			return nil, err
+		}
+		if i == p.self {
+			if p.deal == nil || !d.Commitments[0].Equal(p.deal.Commitments[0]) {
+				return nil, errors.New("own deal is missing or altered")
+			}
+			secret.Add(secret, evalPolynomial(p.coeffs, idx))
+			continue
+		}
+		share, err := receiveShare(p.priv, i, d.Shares[p.self], d.Commitments, idx)
+		if err != nil {
+			return nil, err
+		}
+		secret.Add(secret, share)
+	}
+	secret.Mod(secret, q)
+
+	Q, shares, err := p.session.Combine(deals)
+	if err != nil {
+		return nil, err
+	}
+	if !ScalarBaseMult(secret).Equal(shares[p.self]) {
+		return nil, errors.New("own share does not match the public share")
+	}
+	return &KeyShare{
+		Self:         p.self,
+		Parties:      p.session.New.Parties,
+		Threshold:    p.session.New.Threshold,
+		Secret:       secret,
+		PublicKey:    Q,
+		PublicShares: shares,
+	}, nil
+}
+
+// Confirm returns the new participant's confirmation for the share returned
+// by Finalize.
+func (p *ReshareParty) Confirm(share *KeyShare) (*ReshareConfirm, error) {
+	proof, err := ProveSchnorr(share.Secret, reshareConfirmContext(p.session.New.ID, p.self))
+	if err != nil {
+		return nil, err
+	}
+	return &ReshareConfirm{PublicShare: share.PublicShares[p.self], Proof: proof}, nil
+}
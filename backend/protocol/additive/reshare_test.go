@@ -0,0 +1,183 @@
+package additive
+
+import (
+	"math/big"
+	"testing"
+
+	"multisigservice/paillier"
+)
+
+// reshareSession は旧シェアから再共有セッションを用意します。
+func reshareSession(t *testing.T, old map[string]*KeyShare, dealers, parties []string, threshold int) *ReshareSession {
+	t.Helper()
+	share := old[dealers[0]]
+	return &ReshareSession{
+		OldParties:      share.Parties,
+		OldThreshold:    share.Threshold,
+		OldPublicShares: share.PublicShares,
+		Dealers:         dealers,
+		New:             keygenSession(t, "reshare", parties, threshold),
+	}
+}
+
+// runReshare はディーラーと新しい参加者の再共有をメモリ上で実行します。
+func runReshare(t *testing.T, session *ReshareSession, old map[string]*KeyShare) map[string]*KeyShare {
+	t.Helper()
+	keys, _ := paillierFixture(t)
+	players := make(map[string]*ReshareParty)
+	for _, addr := range append(append([]string{}, session.Dealers...), session.New.Parties...) {
+		if players[addr] != nil {
+			continue
+		}
+		var priv *paillier.PrivateKey
+		if partyIndex(session.New.Parties, addr) != 0 {
+			priv = keys[addr]
+		}
+		p, err := NewReshareParty(session, addr, old[addr], priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		players[addr] = p
+	}
+	deals := make(map[string]*ReshareDeal)
+	for _, addr := range session.Dealers {
+		// サーバー側の検証
+		if err := session.VerifyDeal(addr, players[addr].Deal()); err != nil {
+			t.Fatal(err)
+		}
+		deals[addr] = relay(t, players[addr].Deal())
+	}
+	_, public, err := session.Combine(deals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares := make(map[string]*KeyShare)
+	for _, addr := range session.New.Parties {
+		share, err := players[addr].Finalize(deals)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ScalarBaseMult(share.Secret).Equal(public[addr]) {
+			t.Errorf("%s: public share does not match the server's", addr)
+		}
+		confirm, err := players[addr].Confirm(share)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// サーバー側の検証
+		if err := session.VerifyConfirm(addr, public[addr], relay(t, confirm)); err != nil {
+			t.Fatal(err)
+		}
+		shares[addr] = share
+	}
+	return shares
+}
+
+func TestReshare(t *testing.T) {
+	// 2-of-2 から参加者を1人加えて 2-of-3 に
+	old := runKeygen(t, "keygen", testParties, 2)
+	Q := old[testParties[0]].PublicKey
+	grown := runReshare(t, reshareSession(t, old, testParties, signParties, 2), old)
+	for addr, s := range grown {
+		if !s.PublicKey.Equal(Q) {
+			t.Errorf("%s: public key changed", addr)
+		}
+	}
+	if got := combine(t, grown, []string{signParties[0], signParties[2]}); !got.Equal(Q) {
+		t.Errorf("got %x\nwant %x", got.Bytes(), Q.Bytes())
+	}
+
+	// 1人目を除き、残りの2人で 1-of-2 に。ディーラーは1人目と3人目
+	remaining := []string{signParties[1], signParties[2]}
+	shrunk := runReshare(t, reshareSession(t, grown, []string{signParties[0], signParties[2]}, remaining, 1), grown)
+	for _, addr := range remaining {
+		if got := combine(t, shrunk, []string{addr}); !got.Equal(Q) {
+			t.Errorf("%s: got %x\nwant %x", addr, got.Bytes(), Q.Bytes())
+		}
+	}
+}
+
+func TestReshareRejectsBadDeal(t *testing.T) {
+	old := runKeygen(t, "keygen", signParties, 2)
+	keys, _ := paillierFixture(t)
+	dealers := []string{signParties[0], signParties[1]}
+	session := reshareSession(t, old, dealers, testParties, 2)
+
+	if err := reshareSession(t, old, dealers[:1], testParties, 2).Validate(); err == nil {
+		t.Error("fewer dealers than the old threshold were accepted")
+	}
+
+	a, err := NewReshareParty(session, dealers[0], old[dealers[0]], keys[dealers[0]])
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewReshareParty(session, dealers[1], old[dealers[1]], keys[dealers[1]])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 自分のシェアと異なる秘密を配るディーラーはサーバーが検出する
+	if err := session.VerifyDeal(dealers[0], b.Deal()); err == nil {
+		t.Error("deal of another dealer was accepted")
+	}
+	forged := *a.Deal()
+	forged.Commitments = append([]*Point{ScalarBaseMult(big.NewInt(1))}, forged.Commitments[1:]...)
+	if err := session.VerifyDeal(dealers[0], &forged); err == nil {
+		t.Error("deal of a different secret was accepted")
+	}
+
+	// h_i(j) と一致しないシェアは受け取り側で検出されること
+	ct, err := session.New.PaillierKeys[testParties[1]].Encrypt(big.NewInt(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged = *a.Deal()
+	forged.Shares = map[string]*paillier.Ciphertext{testParties[1]: ct}
+	if err := session.VerifyDeal(dealers[0], &forged); err != nil {
+		t.Fatal(err)
+	}
+	deals := map[string]*ReshareDeal{dealers[0]: &forged, dealers[1]: b.Deal()}
+	if _, err := b.Finalize(deals); err == nil {
+		t.Error("inconsistent share was accepted")
+	}
+}
+
+func TestReshareConfirm(t *testing.T) {
+	old := runKeygen(t, "keygen", testParties, 2)
+	keys, _ := paillierFixture(t)
+	session := reshareSession(t, old, testParties, testParties, 2)
+	deals := make(map[string]*ReshareDeal)
+	players := make(map[string]*ReshareParty)
+	for _, addr := range testParties {
+		p, err := NewReshareParty(session, addr, old[addr], keys[addr])
+		if err != nil {
+			t.Fatal(err)
+		}
+		players[addr], deals[addr] = p, p.Deal()
+	}
+	a := testParties[0]
+	share, err := players[a].Finalize(deals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirm, err := players[a].Confirm(share)
+	if err != nil {
+		t.Fatal(err)
+	}
+	S := share.PublicShares[a]
+	if err := session.VerifyConfirm(a, S, confirm); err != nil {
+		t.Fatalf("valid confirmation rejected: %v", err)
+	}
+
+	// 公開シェアは配布から誰でも計算できるため、証明のない確認は拒否されること
+	if err := session.VerifyConfirm(a, S, &ReshareConfirm{PublicShare: S}); err == nil {
+		t.Error("confirmation without a proof accepted")
+	}
+	if err := session.VerifyConfirm(testParties[1], share.PublicShares[testParties[1]], confirm); err == nil {
+		t.Error("confirmation accepted for another participant")
+	}
+	other := reshareSession(t, old, testParties, testParties, 2)
+	other.New.ID = "reshare-other"
+	if err := other.VerifyConfirm(a, S, confirm); err == nil {
+		t.Error("confirmation replayed in another session")
+	}
+}
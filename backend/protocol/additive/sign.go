@@ -0,0 +1,706 @@
+package additive
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"google.golang.org/protobuf/proto"
+
+	"multisigservice/mta"
+	"multisigservice/paillier"
+	pb "multisigservice/proto/paillierpb"
+)
+
+// 署名は t 人以上の署名者で5ラウンドで行います（k = Σk_i, γ = Σγ_i, x = Σw_i）。
+// w_i = λ_i·s_i は署名者集合に対するラグランジュ係数を掛けた加法シェアです。
+//   1. Γ_i = γ_i·G へのコミットメントと、Enc_i(k_i) および他の署名者毎の範囲証明をブロードキャスト
+//   2. 各ペアでMtAを行い、k_i·γ_j と k_i·w_j の加法シェアを得る（相手宛ての個別メッセージ）
+//      応答側はマスクの公開値 β'·G, ν'·G も送る（不正者の特定に用いる）
+//   3. δ_i（δ = kγ のシェア）と Γ_i の公開、γ_i の知識のSchnorr証明をブロードキャスト
+//      サーバーは R = δ⁻¹·ΣΓ_i = k⁻¹·G と r = R.x mod q を導出する
+//   4. k_i·R と σ_i·R（σ = kx のシェア）をブロードキャスト
+//      サーバーは Σk_i·R = G と Σσ_i·R = Q を確認する
+//   5. 部分署名 s_i = m·k_i + r·σ_i をブロードキャスト
+//      サーバーは署名者毎に s_i·R = m·(k_i·R) + r·(σ_i·R) を確認する
+// サーバーは s = Σs_i を求め、(r, s, v) をマルチシグの公開鍵に対して検証します。
+// ラウンド4の確認が通れば、ラウンド5で式を満たさない署名者が不正者です。
+// ラウンド3・4で失敗した場合の不正者の特定は blame.go を参照してください。
+// ラウンド1〜4は署名対象に依存しないため、事前に実行して (R, k_i, σ_i) を
+// Presignature として保存しておけば、署名時はラウンド5の1回だけで済みます。
+// サーバーに預ける場合、k_i と σ_i は各自のPaillier公開鍵で暗号化します（PresignShares）。
+// Enc_i(k_i) の範囲証明は、検証する署名者 j が公開鍵と共に登録したリングPedersenパラメータ
+// （Π-prm で証明済み）に対して j 毎に作成します。パラメータのトラップドアを知るのは j だけなので、
+// サーバーを含む他の誰も証明を偽造できません。
+// MtAの応答 Enc_i(k_i·b + β') には、i のリングPedersenパラメータに対するアフィン証明（Π-aff）を付けます。
+// i は復号する前に証明を検証するため、範囲外の b や β' で i の復号結果を探る選択的失敗攻撃はできません。
+
+// SignRound1 is a participant's broadcast nonce commitment and Enc_i(k_i),
+// with a range proof for every other signer against that signer's
+// ring-Pedersen parameters.
+type SignRound1 struct {
+	Commitment hexutil.Bytes     `json:"commitment"`
+	EncK       []byte            `json:"encK"`   // pb.Ciphertext
+	Proofs     map[string][]byte `json:"proofs"` // 宛先 → pb.EncProof
+}
+
+// SignRound2 is sent by participant j to participant i and answers i's
+// Enc_i(k_i) for both products, with the masks β' and ν' that j added to
+// them committed as points.
+type SignRound2 struct {
+	Gamma     []byte `json:"gamma"`     // pb.MtARound2 for k_i·γ_j
+	W         []byte `json:"w"`         // pb.MtARound2 for k_i·w_j
+	GammaMask *Point `json:"gammaMask"` // β'·G
+	WMask     *Point `json:"wMask"`     // ν'·G
+}
+
+// SignRound3 is a participant's broadcast δ_i and the opening of Γ_i.
+type SignRound3 struct {
+	Delta *hexutil.Big  `json:"delta"`
+	Gamma *Point        `json:"gamma"`
+	Nonce hexutil.Bytes `json:"nonce"`
+	Proof *SchnorrProof `json:"proof"`
+}
+
+// PresignRound3 is the round 3 message of a presigning session: SignRound3
+// together with the sender's sealed k_i and σ_i.
+type PresignRound3 struct {
+	SignRound3
+	Shares *PresignShares `json:"shares"`
+}
+
+// SignRound4 is a participant's broadcast k_i·R and σ_i·R, against which
+// its partial signature is checked.
+type SignRound4 struct {
+	KR     *Point `json:"kR"`
+	SigmaR *Point `json:"sigmaR"`
+}
+
+// SignRound5 is a participant's partial signature.
+type SignRound5 struct {
+	S *hexutil.Big `json:"s"`
+}
+
+// Signature is an Ethereum-style ECDSA signature.
+type Signature struct {
+	R *hexutil.Big `json:"r"`
+	S *hexutil.Big `json:"s"`
+	V uint8        `json:"v"` // リカバリID（0 または 1）
+}
+
+// Bytes returns the 65-byte r || s || v encoding used by go-ethereum.
+func (sig *Signature) Bytes() []byte {
+	out := make([]byte, 65)
+	sig.R.ToInt().FillBytes(out[:32])
+	sig.S.ToInt().FillBytes(out[32:64])
+	out[64] = sig.V
+	return out
+}
+
+// SignConfig holds what a participant needs to take part in signing.
+type SignConfig struct {
+	SessionID    string
+	Self         string
+	Parties      []string // 署名者全員のアドレス（自分を含む）
+	Share        *KeyShare
+	Hash         []byte                              // 署名対象の32バイトのハッシュ（事前署名では nil）
+	Paillier     *paillier.PrivateKey                // 自分のPaillier秘密鍵
+	PaillierKeys map[string]*paillier.PublicKey      // 他の署名者のPaillier公開鍵
+	Pedersen     map[string]*paillier.PedersenParams // 登録済みリングPedersenパラメータ（署名者全員と、ラウンド1を検証しうる参加者の分）
+}
+
+// SignParty runs the participant side of signing.
+type SignParty struct {
+	cfg   SignConfig
+	k     *big.Int
+	gamma *big.Int
+	open  *SignRound3
+
+	w      *big.Int             // 加法シェア w_i = λ_i·s_i
+	encK   *paillier.Ciphertext // 自分の Enc_i(k_i)
+	round1 map[string]*SignRound1
+	betas  map[string]*big.Int // k_j·γ_i に対する自分のシェア
+	nus    map[string]*big.Int // k_j·w_i に対する自分のシェア
+	inbox  map[string]*SignRound2
+	sigma  *big.Int
+	R      *Point
+}
+
+// NewSignParty samples the participant's nonce shares k_i and γ_i. Leave
+// cfg.Hash nil to produce a Presignature instead of a signature.
+func NewSignParty(cfg SignConfig) (*SignParty, error) {
+	if cfg.Hash != nil && len(cfg.Hash) != 32 {
+		return nil, errors.New("hash must be 32 bytes")
+	}
+	if cfg.Share == nil || cfg.Paillier == nil {
+		return nil, errors.New("incomplete signing configuration")
+	}
+	found := false
+	for _, p := range cfg.Parties {
+		if cfg.Pedersen[p] == nil {
+			return nil, fmt.Errorf("missing pedersen parameters of %s", p)
+		}
+		if p == cfg.Self {
+			found = true
+		} else if cfg.PaillierKeys[p] == nil {
+			return nil, fmt.Errorf("missing paillier key of %s", p)
+		}
+	}
+	if !found {
+		return nil, errors.New("self is not a signer")
+	}
+	if len(cfg.Parties) < cfg.Share.Threshold {
+		return nil, fmt.Errorf("at least %d signers are required", cfg.Share.Threshold)
+	}
+	w, err := cfg.Share.AdditiveShare(cfg.Parties)
+	if err != nil {
+		return nil, err
+	}
+
+	k, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	gamma, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	ctx := signContext(cfg.SessionID, cfg.Self)
+	proof, err := ProveSchnorr(gamma, ctx)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &SignParty{
+		cfg:   cfg,
+		k:     k,
+		gamma: gamma,
+		w:     w,
+		open:  &SignRound3{Gamma: ScalarBaseMult(gamma), Nonce: nonce, Proof: proof},
+	}, nil
+}
+
+// signContext binds commitments and proofs to a signing session and participant.
+func signContext(sessionID, from string) []byte {
+	return []byte("sign/" + sessionID + "/" + from)
+}
+
+// signCommitment computes H(session, from, Γ_i, nonce).
+func signCommitment(sessionID, from string, Gamma *Point, nonce []byte) []byte {
+	return hashParts("additive/sign-commit", signContext(sessionID, from), Gamma.Bytes(), nonce)
+}
+
+// Round1 returns the participant's broadcast for round 1.
+func (p *SignParty) Round1() (*SignRound1, error) {
+	pub := &p.cfg.Paillier.PublicKey
+	// 全員に同じ暗号文を送り、証明だけを宛先のパラメータ毎に作るため nonce を明示的に生成する
+	rho, err := paillier.SampleUnit(pub.N)
+	if err != nil {
+		return nil, err
+	}
+	// 不正者の特定で開示できるよう自分の暗号文を保持する
+	if p.encK, err = pub.EncryptWithNonce(p.k, rho); err != nil {
+		return nil, err
+	}
+	encK, err := proto.Marshal(p.encK.ToProto())
+	if err != nil {
+		return nil, err
+	}
+	// 署名者はラウンド1のコミットで決まるため、署名者になりうる全員に証明を作る
+	proofs := make(map[string][]byte)
+	for _, j := range sortedKeys(p.cfg.Pedersen) {
+		if j == p.cfg.Self {
+			continue
+		}
+		proof, err := paillier.ProveEnc(pub, p.cfg.Pedersen[j], p.encK, p.k, rho, mta.RangeBits)
+		if err != nil {
+			return nil, err
+		}
+		if proofs[j], err = proto.Marshal(proof.ToProto()); err != nil {
+			return nil, err
+		}
+	}
+	return &SignRound1{
+		Commitment: signCommitment(p.cfg.SessionID, p.cfg.Self, p.open.Gamma, p.open.Nonce),
+		EncK:       encK,
+		Proofs:     proofs,
+	}, nil
+}
+
+// Round2 answers every other signer's Enc_j(k_j) and returns the messages
+// keyed by recipient.
+func (p *SignParty) Round2(round1 map[string]*SignRound1) (map[string]*SignRound2, error) {
+	p.round1 = round1
+	p.betas = make(map[string]*big.Int)
+	p.nus = make(map[string]*big.Int)
+	out := make(map[string]*SignRound2)
+	for _, j := range p.others() {
+		pub := p.cfg.PaillierKeys[j]
+		if round1[j] == nil {
+			return nil, fmt.Errorf("missing round 1 message from %s", j)
+		}
+		// 自分のパラメータに対する証明を自分で検証する
+		msg, err := round1[j].mtaRound1(p.cfg.Self)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", j, err)
+		}
+		pp, alicePP := p.cfg.Pedersen[p.cfg.Self], p.cfg.Pedersen[j]
+		gammaMsg, beta, err := bobRound2(pub, pp, alicePP, p.gamma, msg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", j, err)
+		}
+		wMsg, nu, err := bobRound2(pub, pp, alicePP, p.w, msg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", j, err)
+		}
+		p.betas[j], p.nus[j] = beta, nu
+		// β = −β' mod q なので β'·G = −β·G
+		out[j] = &SignRound2{
+			Gamma:     gammaMsg,
+			W:         wMsg,
+			GammaMask: ScalarBaseMult(new(big.Int).Sub(q, beta)),
+			WMask:     ScalarBaseMult(new(big.Int).Sub(q, nu)),
+		}
+	}
+	return out, nil
+}
+
+func bobRound2(pub *paillier.PublicKey, pp, alicePP *paillier.PedersenParams, b *big.Int, msg *pb.MtARound1) ([]byte, *big.Int, error) {
+	bob, err := mta.NewBob(pub, pp, alicePP, b)
+	if err != nil {
+		return nil, nil, err
+	}
+	reply, share, err := bob.Round2(msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw, err := proto.Marshal(reply)
+	if err != nil {
+		return nil, nil, err
+	}
+	return raw, share, nil
+}
+
+// Round3 finishes the MtA instances with the messages addressed to this
+// participant (keyed by sender) and returns δ_i with the opening of Γ_i.
+func (p *SignParty) Round3(round2 map[string]*SignRound2) (*SignRound3, error) {
+	if p.encK == nil {
+		return nil, errors.New("round 1 has not been run")
+	}
+	p.inbox = round2
+	// δ_i = k_i·γ_i + Σ_j (α_ij + β_ji), σ_i = k_i·w_i + Σ_j (μ_ij + ν_ji)
+	delta := new(big.Int).Mul(p.k, p.gamma)
+	sigma := new(big.Int).Mul(p.k, p.w)
+	for _, j := range p.others() {
+		msg := round2[j]
+		if msg == nil {
+			return nil, fmt.Errorf("missing round 2 message from %s", j)
+		}
+		alpha, err := p.aliceFinalize(msg.Gamma)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", j, err)
+		}
+		mu, err := p.aliceFinalize(msg.W)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", j, err)
+		}
+		delta.Add(delta, alpha).Add(delta, p.betas[j])
+		sigma.Add(sigma, mu).Add(sigma, p.nus[j])
+	}
+	p.sigma = sigma.Mod(sigma, q)
+	open := *p.open
+	open.Delta = (*hexutil.Big)(delta.Mod(delta, q))
+	return &open, nil
+}
+
+// aliceFinalize verifies a reply to Enc_i(k_i) against the participant's
+// own ring-Pedersen parameters and decrypts it.
+func (p *SignParty) aliceFinalize(raw []byte) (*big.Int, error) {
+	var msg pb.MtARound2
+	if err := proto.Unmarshal(raw, &msg); err != nil {
+		return nil, err
+	}
+	return mta.OpenRound2(p.cfg.Paillier, p.cfg.Pedersen[p.cfg.Self], p.encK, &msg)
+}
+
+// Round4 checks the other signers' openings, derives R and returns k_i·R
+// and σ_i·R.
+func (p *SignParty) Round4(round3 map[string]*SignRound3) (*SignRound4, error) {
+	if p.sigma == nil {
+		return nil, errors.New("round 3 has not been run")
+	}
+	for _, j := range p.cfg.Parties {
+		if err := VerifySignRound3(p.cfg.SessionID, j, p.round1[j], round3[j]); err != nil {
+			return nil, err
+		}
+	}
+	R, _, err := ComputeR(round3)
+	if err != nil {
+		return nil, err
+	}
+	p.R = R
+	return &SignRound4{KR: R.ScalarMult(p.k), SigmaR: R.ScalarMult(p.sigma)}, nil
+}
+
+// Round5 checks the round 4 messages and returns the partial signature
+// s_i = m·k_i + r·σ_i.
+func (p *SignParty) Round5(round4 map[string]*SignRound4) (*SignRound5, error) {
+	if p.cfg.Hash == nil {
+		return nil, errors.New("hash is required to sign")
+	}
+	ps, err := p.Presign(round4)
+	if err != nil {
+		return nil, err
+	}
+	return ps.Sign(p.cfg.Hash)
+}
+
+// Presignature is a participant's message-independent signing material: the
+// nonce point R with its shares k_i and σ_i of k and k·x. It is secret,
+// bound to the signer set, and must be used for at most one signature, since
+// two signatures with the same R reveal the private key.
+type Presignature struct {
+	R     *Point       `json:"R"`
+	K     *hexutil.Big `json:"k"`
+	Sigma *hexutil.Big `json:"sigma"`
+}
+
+// Presign checks the round 4 messages and returns the presignature.
+func (p *SignParty) Presign(round4 map[string]*SignRound4) (*Presignature, error) {
+	if p.R == nil {
+		return nil, errors.New("round 4 has not been run")
+	}
+	if len(round4) != len(p.cfg.Parties) {
+		return nil, errors.New("round 4 messages are missing")
+	}
+	if err := CheckSignRound4(p.cfg.Share.PublicKey, round4); err != nil {
+		return nil, err
+	}
+	return &Presignature{R: p.R, K: (*hexutil.Big)(p.k), Sigma: (*hexutil.Big)(p.sigma)}, nil
+}
+
+// Sign returns the partial signature s_i = m·k_i + r·σ_i for hash.
+func (ps *Presignature) Sign(hash []byte) (*SignRound5, error) {
+	if len(hash) != 32 {
+		return nil, errors.New("hash must be 32 bytes")
+	}
+	if ps.R == nil || ps.R.IsIdentity() || ps.K == nil || ps.Sigma == nil {
+		return nil, errors.New("incomplete presignature")
+	}
+	r := new(big.Int).Mod(ps.R.X(), q)
+	// s_i = m·k_i + r·σ_i mod q
+	s := new(big.Int).Mul(hashToInt(hash), ps.K.ToInt())
+	s.Add(s, new(big.Int).Mul(r, ps.Sigma.ToInt())).Mod(s, q)
+	return &SignRound5{S: (*hexutil.Big)(s)}, nil
+}
+
+// PresignShares is a participant's k_i and σ_i encrypted under its own
+// Paillier key, so that the server can keep them until the online round
+// without learning them.
+type PresignShares struct {
+	K     *paillier.Ciphertext `json:"k"`
+	Sigma *paillier.Ciphertext `json:"sigma"`
+}
+
+// SealPresignShares encrypts k_i and σ_i under the participant's Paillier
+// key. It is sent along with the round 3 message of a presigning session.
+func (p *SignParty) SealPresignShares() (*PresignShares, error) {
+	if p.sigma == nil {
+		return nil, errors.New("round 3 has not been run")
+	}
+	pub := &p.cfg.Paillier.PublicKey
+	k, err := pub.Encrypt(p.k)
+	if err != nil {
+		return nil, err
+	}
+	sigma, err := pub.Encrypt(p.sigma)
+	if err != nil {
+		return nil, err
+	}
+	return &PresignShares{K: k, Sigma: sigma}, nil
+}
+
+// VerifyPresignShares checks that s holds two valid ciphertexts under the
+// sender's Paillier key. The server calls it before storing s.
+func VerifyPresignShares(pub *paillier.PublicKey, s *PresignShares) error {
+	if s == nil || s.K == nil || s.Sigma == nil || !pub.IsValidCiphertext(s.K) || !pub.IsValidCiphertext(s.Sigma) {
+		return errors.New("invalid presignature shares")
+	}
+	return nil
+}
+
+// OpenPresignature decrypts the participant's shares of a stored
+// presignature with nonce point R.
+func OpenPresignature(priv *paillier.PrivateKey, R *Point, s *PresignShares) (*Presignature, error) {
+	if err := VerifyPresignShares(&priv.PublicKey, s); err != nil {
+		return nil, err
+	}
+	k, err := priv.Decrypt(s.K)
+	if err != nil {
+		return nil, err
+	}
+	sigma, err := priv.Decrypt(s.Sigma)
+	if err != nil {
+		return nil, err
+	}
+	if k.Sign() == 0 || k.Cmp(q) >= 0 || sigma.Cmp(q) >= 0 {
+		return nil, errors.New("invalid presignature shares")
+	}
+	return &Presignature{R: R, K: (*hexutil.Big)(k), Sigma: (*hexutil.Big)(sigma)}, nil
+}
+
+// others returns the signers other than self.
+func (p *SignParty) others() []string {
+	var out []string
+	for _, j := range p.cfg.Parties {
+		if j != p.cfg.Self {
+			out = append(out, j)
+		}
+	}
+	return out
+}
+
+// hashToInt converts a 32-byte message hash to the integer m used by ECDSA.
+func hashToInt(hash []byte) *big.Int {
+	return new(big.Int).Mod(new(big.Int).SetBytes(hash), q)
+}
+
+// VerifySignRound1 checks from's Enc(k_i) under its Paillier key and, for
+// every other party j in pps, the range proof against j's ring-Pedersen
+// parameters pps[j]. Proofs for parties outside pps are ignored. The server
+// calls it before storing a round 1 message.
+func VerifySignRound1(pub *paillier.PublicKey, pps map[string]*paillier.PedersenParams, from string, msg *SignRound1) error {
+	if msg == nil || len(msg.Commitment) == 0 {
+		return errors.New("commitment is missing")
+	}
+	for _, j := range sortedKeys(pps) {
+		if j == from {
+			continue
+		}
+		encK, err := msg.mtaRound1(j)
+		if err != nil {
+			return err
+		}
+		if _, err := mta.VerifyRound1(pub, pps[j], encK); err != nil {
+			return fmt.Errorf("range proof for %s: %v", j, err)
+		}
+	}
+	return nil
+}
+
+// mtaRound1 returns Enc(k_i) with the range proof for signer j as the MtA
+// message j answers.
+func (m *SignRound1) mtaRound1(j string) (*pb.MtARound1, error) {
+	raw, ok := m.Proofs[j]
+	if !ok {
+		return nil, fmt.Errorf("range proof for %s is missing", j)
+	}
+	msg := &pb.MtARound1{CA: &pb.Ciphertext{}, RangeProof: &pb.EncProof{}}
+	if err := proto.Unmarshal(m.EncK, msg.CA); err != nil {
+		return nil, err
+	}
+	if err := proto.Unmarshal(raw, msg.RangeProof); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// VerifySignRound2 checks that both MtA replies answer the recipient's
+// Enc_i(k_i) from r1 with a valid affine proof against the recipient's
+// ring-Pedersen parameters, and that the masks are committed. The server
+// calls it before relaying a round 2 message.
+func VerifySignRound2(recipient *paillier.PublicKey, recipientPP *paillier.PedersenParams, r1 *SignRound1, msg *SignRound2) error {
+	if msg == nil {
+		return errors.New("round 2 message is missing")
+	}
+	if msg.GammaMask == nil || msg.WMask == nil {
+		return errors.New("masks are missing")
+	}
+	if r1 == nil {
+		return errors.New("recipient's round 1 message is missing")
+	}
+	encK, err := encKCiphertext(recipient, r1)
+	if err != nil {
+		return err
+	}
+	for _, raw := range [][]byte{msg.Gamma, msg.W} {
+		var reply pb.MtARound2
+		if err := proto.Unmarshal(raw, &reply); err != nil {
+			return err
+		}
+		if _, err := mta.VerifyRound2(recipient, recipientPP, encK, &reply); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifySignRound3 checks that from's round 3 message opens its round 1
+// commitment and proves knowledge of γ_i.
+func VerifySignRound3(sessionID, from string, r1 *SignRound1, r3 *SignRound3) error {
+	if r1 == nil {
+		return fmt.Errorf("%s has not committed", from)
+	}
+	if r3 == nil || r3.Gamma == nil || r3.Delta == nil {
+		return fmt.Errorf("%s sent an incomplete round 3 message", from)
+	}
+	if d := r3.Delta.ToInt(); d.Sign() < 0 || d.Cmp(q) >= 0 {
+		return fmt.Errorf("%s sent δ out of range", from)
+	}
+	want := signCommitment(sessionID, from, r3.Gamma, r3.Nonce)
+	if subtle.ConstantTimeCompare(want, r1.Commitment) != 1 {
+		return fmt.Errorf("%s opened Γ that does not match its commitment", from)
+	}
+	if !r3.Proof.Verify(r3.Gamma, signContext(sessionID, from)) {
+		return fmt.Errorf("%s sent an invalid proof of knowledge of γ", from)
+	}
+	return nil
+}
+
+// ComputeR returns R = δ⁻¹·ΣΓ_i and r = R.x mod q.
+func ComputeR(round3 map[string]*SignRound3) (*Point, *big.Int, error) {
+	delta := new(big.Int)
+	points := make([]*Point, 0, len(round3))
+	for _, from := range sortedKeys(round3) {
+		delta.Add(delta, round3[from].Delta.ToInt())
+		points = append(points, round3[from].Gamma)
+	}
+	delta.Mod(delta, q)
+	if delta.Sign() == 0 {
+		return nil, nil, errors.New("δ is zero")
+	}
+	R := SumPoints(points...).ScalarMult(new(big.Int).ModInverse(delta, q))
+	if R.IsIdentity() {
+		return nil, nil, errors.New("R is the point at infinity")
+	}
+	r := new(big.Int).Mod(R.X(), q)
+	if r.Sign() == 0 {
+		return nil, nil, errors.New("r is zero")
+	}
+	return R, r, nil
+}
+
+// VerifySignRound4 checks that a round 4 message carries both points. The
+// server calls it before storing the message.
+func VerifySignRound4(msg *SignRound4) error {
+	if msg == nil || msg.KR == nil || msg.KR.IsIdentity() || msg.SigmaR == nil || msg.SigmaR.IsIdentity() {
+		return errors.New("round 4 message is incomplete")
+	}
+	return nil
+}
+
+// CheckSignRound4 checks Σk_i·R = G and Σσ_i·R = Q over all signers'
+// round 4 messages. If it fails, the participants open their nonces to find
+// the culprit (see SignTranscript.Blame).
+func CheckSignRound4(Q *Point, round4 map[string]*SignRound4) error {
+	kr := make([]*Point, 0, len(round4))
+	sigmaR := make([]*Point, 0, len(round4))
+	for _, from := range sortedKeys(round4) {
+		if err := VerifySignRound4(round4[from]); err != nil {
+			return fmt.Errorf("%s: %v", from, err)
+		}
+		kr = append(kr, round4[from].KR)
+		sigmaR = append(sigmaR, round4[from].SigmaR)
+	}
+	if !SumPoints(kr...).Equal(ScalarBaseMult(big.NewInt(1))) {
+		return errors.New("Σk_i·R does not equal G")
+	}
+	if !SumPoints(sigmaR...).Equal(Q) {
+		return errors.New("Σσ_i·R does not equal the public key")
+	}
+	return nil
+}
+
+// VerifySignRound5 checks a partial signature against the sender's round 4
+// message: s_i·R = m·(k_i·R) + r·(σ_i·R). Once CheckSignRound4 has passed,
+// the partial signatures sum to a valid signature if and only if every one
+// of them passes this check.
+func VerifySignRound5(hash []byte, R *Point, check *SignRound4, msg *SignRound5) error {
+	if err := VerifySignRound4(check); err != nil {
+		return err
+	}
+	if msg == nil || msg.S == nil || msg.S.ToInt().Sign() < 0 || msg.S.ToInt().Cmp(q) >= 0 {
+		return errors.New("partial signature is out of range")
+	}
+	r := new(big.Int).Mod(R.X(), q)
+	want := check.KR.ScalarMult(hashToInt(hash)).Add(check.SigmaR.ScalarMult(r))
+	if !R.ScalarMult(msg.S.ToInt()).Equal(want) {
+		return errors.New("partial signature does not match k_i·R and σ_i·R")
+	}
+	return nil
+}
+
+// CombineSignature sums the partial signatures and returns the signature
+// (r, s, v), checked against the public key Q.
+func CombineSignature(hash []byte, Q, R *Point, round5 map[string]*SignRound5) (*Signature, error) {
+	s := new(big.Int)
+	for _, msg := range round5 {
+		if msg == nil || msg.S == nil {
+			return nil, errors.New("partial signature is missing")
+		}
+		s.Add(s, msg.S.ToInt())
+	}
+	return NewSignature(hash, Q, R, s.Mod(s, q))
+}
+
+// NewSignature builds the Ethereum signature for the nonce point R and s,
+// normalizing s to the lower half of the group order, and checks that it
+// recovers to the public key Q.
+func NewSignature(hash []byte, Q, R *Point, s *big.Int) (*Signature, error) {
+	if len(hash) != 32 {
+		return nil, errors.New("hash must be 32 bytes")
+	}
+	if R == nil || R.IsIdentity() {
+		return nil, errors.New("R is missing")
+	}
+	s = new(big.Int).Mod(s, q)
+	if s.Sign() == 0 {
+		return nil, errors.New("s is zero")
+	}
+
+	// リカバリIDは R.y の偶奇。s を q - s に置き換えると R が -R に対応するため反転する
+	v := uint8(R.Bytes()[0] - 2)
+	if s.Cmp(new(big.Int).Rsh(q, 1)) > 0 {
+		s.Sub(q, s)
+		v ^= 1
+	}
+	r := new(big.Int).Mod(R.X(), q)
+	sig := &Signature{R: (*hexutil.Big)(r), S: (*hexutil.Big)(s), V: v}
+
+	pub, err := crypto.SigToPub(hash, sig.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if crypto.PubkeyToAddress(*pub).Hex() != EthereumAddress(Q) {
+		return nil, errors.New("signature does not verify under the multisig public key")
+	}
+	return sig, nil
+}
+
+// ParseSignature decodes a 65-byte r || s || v signature (v may be 0/1 or
+// 27/28) and checks it against the public key Q.
+func ParseSignature(hash []byte, Q *Point, raw []byte) (*Signature, error) {
+	if len(raw) != 65 {
+		return nil, errors.New("signature must be 65 bytes")
+	}
+	v := raw[64]
+	if v >= 27 {
+		v -= 27
+	}
+	if v > 1 {
+		return nil, errors.New("invalid recovery id")
+	}
+	// R は x = r と y の偶奇 v から復元する
+	R, err := PointFromBytes(append([]byte{2 + v}, raw[:32]...))
+	if err != nil {
+		return nil, err
+	}
+	return NewSignature(hash, Q, R, new(big.Int).SetBytes(raw[32:64]))
+}
@@ -0,0 +1,380 @@
+package additive
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"multisigservice/paillier"
+)
+
+var signParties = []string{
+	"0x1111111111111111111111111111111111111111",
+	"0x2222222222222222222222222222222222222222",
+	"0x3333333333333333333333333333333333333333",
+}
+
+// signFixture はテスト間で使い回すPaillier鍵と、各自の鍵から作ったリングPedersenパラメータです。
+var signFixture struct {
+	once sync.Once
+	keys map[string]*paillier.PrivateKey
+	pp   map[string]*paillier.PedersenParams
+	err  error
+}
+
+func paillierFixture(t *testing.T) (map[string]*paillier.PrivateKey, map[string]*paillier.PedersenParams) {
+	t.Helper()
+	signFixture.once.Do(func() {
+		signFixture.keys = make(map[string]*paillier.PrivateKey)
+		signFixture.pp = make(map[string]*paillier.PedersenParams)
+		for _, addr := range signParties {
+			_, sk, err := paillier.GenerateKey(2048)
+			if err != nil {
+				signFixture.err = err
+				return
+			}
+			signFixture.keys[addr] = sk
+			if signFixture.pp[addr], signFixture.err = paillier.NewPedersenParams(sk); signFixture.err != nil {
+				return
+			}
+		}
+	})
+	if signFixture.err != nil {
+		t.Fatal(signFixture.err)
+	}
+	return signFixture.keys, signFixture.pp
+}
+
+// pedersenOf は署名者 subset のリングPedersenパラメータを返します。
+func pedersenOf(t *testing.T, subset []string) map[string]*paillier.PedersenParams {
+	t.Helper()
+	_, pp := paillierFixture(t)
+	out := make(map[string]*paillier.PedersenParams, len(subset))
+	for _, addr := range subset {
+		out[addr] = pp[addr]
+	}
+	return out
+}
+
+// relay はサーバー経由の中継を模してJSONで往復させます。
+func relay[T any](t *testing.T, msg *T) *T {
+	t.Helper()
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out T
+	if err := json.Unmarshal(raw, &out); err != nil {
+		t.Fatal(err)
+	}
+	return &out
+}
+
+// newSigners は鍵生成の結果から signers の署名者を用意します。
+func newSigners(t *testing.T, sessionID string, shares map[string]*KeyShare, signers []string, hash []byte) map[string]*SignParty {
+	t.Helper()
+	keys, _ := paillierFixture(t)
+	pubs := make(map[string]*paillier.PublicKey)
+	for addr, sk := range keys {
+		pubs[addr] = &sk.PublicKey
+	}
+	parties := make(map[string]*SignParty)
+	for _, addr := range signers {
+		p, err := NewSignParty(SignConfig{
+			SessionID:    sessionID,
+			Self:         addr,
+			Parties:      signers,
+			Share:        shares[addr],
+			Hash:         hash,
+			Paillier:     keys[addr],
+			PaillierKeys: pubs,
+			Pedersen:     pedersenOf(t, signers),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		parties[addr] = p
+	}
+	return parties
+}
+
+// signRun はサーバーが中継したラウンド1〜4のメッセージです。
+type signRun struct {
+	round1 map[string]*SignRound1
+	round2 map[string]map[string]*SignRound2 // 送信者 → 宛先
+	round3 map[string]*SignRound3
+	round4 map[string]*SignRound4
+}
+
+// runSignRounds はラウンド1〜3をメモリ上で実行し、サーバー側の検証を経たメッセージを返します。
+// tamper を指定すると、ラウンド3のメッセージを中継前に書き換えます。
+func runSignRounds(t *testing.T, sid string, signers map[string]*SignParty, subset []string, tamper func(from string, msg *SignRound3)) *signRun {
+	t.Helper()
+	keys, _ := paillierFixture(t)
+	run := &signRun{
+		round1: make(map[string]*SignRound1),
+		round2: make(map[string]map[string]*SignRound2),
+		round3: make(map[string]*SignRound3),
+	}
+	// ラウンド1：サーバーは範囲証明を検証してから保存する
+	for addr, p := range signers {
+		msg, err := p.Round1()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifySignRound1(&keys[addr].PublicKey, pedersenOf(t, subset), addr, msg); err != nil {
+			t.Fatal(err)
+		}
+		run.round1[addr] = relay(t, msg)
+	}
+
+	// ラウンド2：宛先毎の個別メッセージ
+	inbox := make(map[string]map[string]*SignRound2)
+	for _, addr := range subset {
+		inbox[addr] = make(map[string]*SignRound2)
+	}
+	for from, p := range signers {
+		out, err := p.Round2(run.round1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		run.round2[from] = make(map[string]*SignRound2)
+		for to, msg := range out {
+			if err := VerifySignRound2(&keys[to].PublicKey, pedersenOf(t, subset)[to], run.round1[to], msg); err != nil {
+				t.Fatal(err)
+			}
+			inbox[to][from] = relay(t, msg)
+			run.round2[from][to] = inbox[to][from]
+		}
+	}
+
+	// ラウンド3：サーバーが R を導出する
+	for addr, p := range signers {
+		msg, err := p.Round3(inbox[addr])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tamper != nil {
+			tamper(addr, msg)
+		}
+		if err := VerifySignRound3(sid, addr, run.round1[addr], msg); err != nil {
+			t.Fatal(err)
+		}
+		run.round3[addr] = relay(t, msg)
+	}
+	return run
+}
+
+// runCheckRound はラウンド4を実行し、k_i·R と σ_i·R を run に記録します。
+func runCheckRound(t *testing.T, signers map[string]*SignParty, run *signRun) {
+	t.Helper()
+	run.round4 = make(map[string]*SignRound4)
+	for addr, p := range signers {
+		msg, err := p.Round4(run.round3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifySignRound4(msg); err != nil {
+			t.Fatal(err)
+		}
+		run.round4[addr] = relay(t, msg)
+	}
+}
+
+func TestSign(t *testing.T) {
+	// 2-of-3 の鍵を、1人目と3人目の2人で署名する
+	shares := runKeygen(t, "keygen", signParties, 2)
+	Q := shares[signParties[0]].PublicKey
+	hash := sha256.Sum256([]byte("transfer 1 ETH"))
+	const sid = "sign-1"
+	subset := []string{signParties[0], signParties[2]}
+	signers := newSigners(t, sid, shares, subset, hash[:])
+	run := runSignRounds(t, sid, signers, subset, nil)
+	R, _, err := ComputeR(run.round3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ラウンド4：サーバーは Σk_i·R = G と Σσ_i·R = Q を確認する
+	runCheckRound(t, signers, run)
+	if err := CheckSignRound4(Q, run.round4); err != nil {
+		t.Fatal(err)
+	}
+
+	// ラウンド5：部分署名を検証してから合算する
+	round5 := make(map[string]*SignRound5)
+	for addr, p := range signers {
+		msg, err := p.Round5(run.round4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifySignRound5(hash[:], R, run.round4[addr], msg); err != nil {
+			t.Fatal(err)
+		}
+		round5[addr] = relay(t, msg)
+	}
+	sig, err := CombineSignature(hash[:], Q, R, round5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig.S.ToInt().Cmp(new(big.Int).Rsh(q, 1)) > 0 {
+		t.Error("s is not normalized to the lower half")
+	}
+	if !crypto.VerifySignature(Q.Bytes(), hash[:], sig.Bytes()[:64]) {
+		t.Error("signature does not verify")
+	}
+	parsed, err := ParseSignature(hash[:], Q, sig.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.V != sig.V || parsed.S.ToInt().Cmp(sig.S.ToInt()) != 0 {
+		t.Errorf("got %x\nwant %x", parsed.Bytes(), sig.Bytes())
+	}
+
+	// 部分署名の改ざんは送信者を特定して検出されること
+	bad := *round5[subset[1]]
+	bad.S = (*hexutil.Big)(new(big.Int).Add(bad.S.ToInt(), big.NewInt(1)))
+	if err := VerifySignRound5(hash[:], R, run.round4[subset[1]], &bad); err == nil {
+		t.Error("tampered partial signature passed the check")
+	}
+	round5[subset[1]] = &bad
+	if _, err := CombineSignature(hash[:], Q, R, round5); err == nil {
+		t.Error("tampered partial signature was accepted")
+	}
+}
+
+func TestPresign(t *testing.T) {
+	keys, _ := paillierFixture(t)
+	shares := runKeygen(t, "keygen", signParties, 2)
+	Q := shares[signParties[0]].PublicKey
+	const sid = "presign-1"
+	subset := []string{signParties[1], signParties[2]}
+	signers := newSigners(t, sid, shares, subset, nil)
+	run := runSignRounds(t, sid, signers, subset, nil)
+	R, _, err := ComputeR(run.round3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runCheckRound(t, signers, run)
+	if err := CheckSignRound4(Q, run.round4); err != nil {
+		t.Fatal(err)
+	}
+
+	// k_i と σ_i は自分の鍵で暗号化してサーバーに預ける
+	sealed := make(map[string]*PresignShares)
+	for addr, p := range signers {
+		if _, err := p.Round5(run.round4); err == nil {
+			t.Error("signing without a hash was accepted")
+		}
+		if _, err := p.Presign(run.round4); err != nil {
+			t.Fatal(err)
+		}
+		s, err := p.SealPresignShares()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifyPresignShares(&keys[addr].PublicKey, s); err != nil {
+			t.Fatal(err)
+		}
+		sealed[addr] = relay(t, s)
+	}
+	if err := VerifyPresignShares(&keys[subset[0]].PublicKey, &PresignShares{K: sealed[subset[0]].K}); err == nil {
+		t.Error("incomplete shares were accepted")
+	}
+
+	// 署名時は部分署名を1回送るだけ
+	hash := sha256.Sum256([]byte("transfer 2 ETH"))
+	round5 := make(map[string]*SignRound5)
+	for _, addr := range subset {
+		ps, err := OpenPresignature(keys[addr], R, sealed[addr])
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, err := ps.Sign(hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifySignRound5(hash[:], R, run.round4[addr], msg); err != nil {
+			t.Fatal(err)
+		}
+		round5[addr] = relay(t, msg)
+	}
+	sig, err := CombineSignature(hash[:], Q, R, round5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !crypto.VerifySignature(Q.Bytes(), hash[:], sig.Bytes()[:64]) {
+		t.Error("signature does not verify")
+	}
+
+	// 他人の鍵では開けないこと
+	if _, err := OpenPresignature(keys[subset[0]], R, sealed[subset[1]]); err == nil {
+		t.Error("shares of another participant were opened")
+	}
+}
+
+func TestVerifySignRound3RejectsSubstitutedGamma(t *testing.T) {
+	shares := runKeygen(t, "keygen", signParties, len(signParties))
+	hash := sha256.Sum256([]byte("message"))
+	const sid = "sign-2"
+	signers := newSigners(t, sid, shares, signParties, hash[:])
+
+	a, b := signers[signParties[0]], signers[signParties[1]]
+	r1, err := a.Round1()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 他人の Γ とのすり替え
+	forged := *b.open
+	forged.Delta = (*hexutil.Big)(big.NewInt(1))
+	if err := VerifySignRound3(sid, signParties[0], r1, &forged); err == nil {
+		t.Error("substituted Γ was accepted")
+	}
+	// 正しい公開でも別セッションでは拒否されること
+	open := *a.open
+	open.Delta = (*hexutil.Big)(big.NewInt(1))
+	if err := VerifySignRound3(sid, signParties[0], r1, &open); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySignRound3("other", signParties[0], r1, &open); err == nil {
+		t.Error("opening from another session was accepted")
+	}
+}
+
+func TestVerifySignRound1RangeProofs(t *testing.T) {
+	keys, pp := paillierFixture(t)
+	shares := runKeygen(t, "keygen", signParties, 2)
+	const sid = "sign-round1"
+	subset := []string{signParties[0], signParties[1]}
+	signers := newSigners(t, sid, shares, subset, nil)
+	sender, verifier := subset[0], subset[1]
+	msg, err := signers[sender].Round1()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &keys[sender].PublicKey
+	if err := VerifySignRound1(pub, pedersenOf(t, subset), sender, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	// 検証者自身のパラメータ以外（サーバーや第三者のもの）に対する証明は受け付けないこと
+	swapped := pedersenOf(t, subset)
+	swapped[verifier] = pp[signParties[2]]
+	if err := VerifySignRound1(pub, swapped, sender, msg); err == nil {
+		t.Error("range proof against another party's parameters was accepted")
+	}
+	// 署名者の誰かに宛てた証明が欠けていれば拒否すること
+	if err := VerifySignRound1(pub, pedersenOf(t, signParties), sender, msg); err == nil {
+		t.Error("missing range proof was accepted")
+	}
+	forged := *msg
+	forged.Proofs = map[string][]byte{signParties[2]: msg.Proofs[verifier]}
+	if err := VerifySignRound1(pub, pedersenOf(t, subset), sender, &forged); err == nil {
+		t.Error("range proof addressed to another party was accepted")
+	}
+}
@@ -0,0 +1,280 @@
+// Package cmp adapts the CMP threshold ECDSA protocols of
+// github.com/taurusgroup/multi-party-sig to the backend's relay. Participants
+// drive a protocol.Handler through Party and exchange the marshalled
+// protocol.Message values via the server, which only reads the message
+// headers (ParseMessage) to route and check them. Party IDs are the
+// participants' Ethereum addresses.
+package cmp
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/taurusgroup/multi-party-sig/pkg/ecdsa"
+	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
+	"github.com/taurusgroup/multi-party-sig/pkg/math/polynomial"
+	"github.com/taurusgroup/multi-party-sig/pkg/party"
+	"github.com/taurusgroup/multi-party-sig/pkg/pool"
+	"github.com/taurusgroup/multi-party-sig/pkg/protocol"
+	mpscmp "github.com/taurusgroup/multi-party-sig/protocols/cmp"
+
+	"multisigservice/protocol/additive"
+)
+
+// Protocol IDs carried in the headers of relayed messages.
+const (
+	ProtocolKeygen        = "cmp/keygen-threshold"
+	ProtocolRefresh       = "cmp/refresh-threshold"
+	ProtocolPresign       = "cmp/presign-offline"
+	ProtocolPresignOnline = "cmp/presign-online"
+	ProtocolSign          = "cmp/sign"
+)
+
+// Config is a participant's secret key material after keygen or refresh.
+type Config = mpscmp.Config
+
+// EmptyConfig returns a Config ready for UnmarshalBinary.
+func EmptyConfig() *Config {
+	return mpscmp.EmptyConfig(curve.Secp256k1{})
+}
+
+// Party is one participant's execution of a CMP protocol.
+type Party struct {
+	handler protocol.Handler
+	done    bool
+}
+
+func newParty(start protocol.StartFunc, sessionID string) (*Party, error) {
+	h, err := protocol.NewMultiHandler(start, []byte(sessionID))
+	if err != nil {
+		return nil, err
+	}
+	return &Party{handler: h}, nil
+}
+
+func partyIDs(addresses []string) []party.ID {
+	ids := make([]party.ID, len(addresses))
+	for i, a := range addresses {
+		ids[i] = party.ID(a)
+	}
+	return ids
+}
+
+// Keygen starts key generation. Any threshold+1 participants can sign with
+// the resulting key.
+func Keygen(sessionID, self string, participants []string, threshold int, pl *pool.Pool) (*Party, error) {
+	return newParty(mpscmp.Keygen(curve.Secp256k1{}, party.ID(self), partyIDs(participants), threshold, pl), sessionID)
+}
+
+// Refresh starts a refresh of cfg. The public key is unchanged.
+func Refresh(sessionID string, cfg *Config, pl *pool.Pool) (*Party, error) {
+	return newParty(mpscmp.Refresh(cfg, pl), sessionID)
+}
+
+// Sign starts interactive signing of hash among signers.
+func Sign(sessionID string, cfg *Config, signers []string, hash []byte, pl *pool.Pool) (*Party, error) {
+	return newParty(mpscmp.Sign(cfg, partyIDs(signers), hash, pl), sessionID)
+}
+
+// Presign starts the message-independent part of signing among signers.
+func Presign(sessionID string, cfg *Config, signers []string, pl *pool.Pool) (*Party, error) {
+	return newParty(mpscmp.Presign(cfg, partyIDs(signers), pl), sessionID)
+}
+
+// PresignOnline finishes signing hash with a presignature.
+func PresignOnline(sessionID string, cfg *Config, presig *ecdsa.PreSignature, hash []byte, pl *pool.Pool) (*Party, error) {
+	return newParty(mpscmp.PresignOnline(cfg, presig, hash, pl), sessionID)
+}
+
+// Outgoing returns the marshalled messages produced so far, to be posted to
+// the relay. It never blocks.
+func (p *Party) Outgoing() ([][]byte, error) {
+	var out [][]byte
+	for {
+		select {
+		case msg, ok := <-p.handler.Listen():
+			if !ok {
+				p.done = true
+				return out, nil
+			}
+			raw, err := msg.MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, raw)
+		default:
+			return out, nil
+		}
+	}
+}
+
+// Deliver passes a relayed message to the protocol. Messages not addressed
+// to this participant or for another execution are ignored.
+func (p *Party) Deliver(raw []byte) error {
+	var msg protocol.Message
+	if err := msg.UnmarshalBinary(raw); err != nil {
+		return err
+	}
+	if p.handler.CanAccept(&msg) {
+		p.handler.Accept(&msg)
+	}
+	return nil
+}
+
+// Done reports whether the protocol has finished, successfully or not.
+// It is updated by Outgoing.
+func (p *Party) Done() bool {
+	return p.done
+}
+
+// Result returns the protocol output: *Config for keygen and refresh,
+// *ecdsa.PreSignature for presign and *ecdsa.Signature for signing.
+func (p *Party) Result() (interface{}, error) {
+	return p.handler.Result()
+}
+
+// Header is the routing information the server reads from a relayed message.
+type Header struct {
+	From      string
+	To        string // 空の場合はブロードキャスト
+	Round     int    // 0 は中断の通知
+	Broadcast bool
+	Protocol  string
+}
+
+// ParseMessage decodes the header of a relayed message.
+func ParseMessage(raw []byte) (*Header, error) {
+	var msg protocol.Message
+	// UnmarshalBinary はCBORのエラーを返さないため、必須フィールドで判定する
+	if err := msg.UnmarshalBinary(raw); err != nil {
+		return nil, err
+	}
+	if msg.From == "" || msg.Protocol == "" || len(msg.SSID) == 0 {
+		return nil, errors.New("malformed protocol message")
+	}
+	return &Header{
+		From:      string(msg.From),
+		To:        string(msg.To),
+		Round:     int(msg.RoundNumber),
+		Broadcast: msg.Broadcast,
+		Protocol:  msg.Protocol,
+	}, nil
+}
+
+// Check verifies that the message belongs to protocolID, was sent by from
+// and is addressed to participants.
+func (h *Header) Check(protocolID, from string, participants []string) error {
+	if h.Protocol != protocolID {
+		return fmt.Errorf("message belongs to %s, expected %s", h.Protocol, protocolID)
+	}
+	if h.From != from {
+		return errors.New("message sender does not match")
+	}
+	if h.To == "" {
+		return nil
+	}
+	if h.To == from {
+		return errors.New("message is addressed to its sender")
+	}
+	for _, p := range participants {
+		if p == h.To {
+			return nil
+		}
+	}
+	return errors.New("message recipient is not a participant")
+}
+
+// PublicKey returns the group public key of cfg.
+func PublicKey(cfg *Config) (*additive.Point, error) {
+	return toPoint(cfg.PublicPoint())
+}
+
+// PublicShares returns every participant's public key share in cfg.
+func PublicShares(cfg *Config) (map[string]*additive.Point, error) {
+	shares := make(map[string]*additive.Point, len(cfg.Public))
+	for id, pub := range cfg.Public {
+		p, err := toPoint(pub.ECDSA)
+		if err != nil {
+			return nil, err
+		}
+		shares[string(id)] = p
+	}
+	return shares, nil
+}
+
+// CheckPublicShares checks that shares holds a public share for exactly the
+// participants and that the shares are evaluations of one polynomial of degree
+// threshold whose constant term is Q, so that any threshold+1 of them
+// interpolate to Q. Participants must agree on the shares before the server
+// relies on them, but agreement alone does not show that they fit the key.
+func CheckPublicShares(Q *additive.Point, shares map[string]*additive.Point, participants []string, threshold int) error {
+	if threshold < 0 || threshold >= len(participants) {
+		return errors.New("invalid threshold")
+	}
+	if len(shares) != len(participants) {
+		return errors.New("public shares do not match the participants")
+	}
+	group := curve.Secp256k1{}
+	target, err := fromPoint(group, Q)
+	if err != nil {
+		return err
+	}
+	points := make(map[party.ID]curve.Point, len(participants))
+	for _, addr := range participants {
+		share, ok := shares[addr]
+		if !ok || share == nil || share.IsIdentity() {
+			return fmt.Errorf("public share of %s is missing", addr)
+		}
+		if points[party.ID(addr)], err = fromPoint(group, share); err != nil {
+			return err
+		}
+	}
+
+	// 先頭の threshold 人に残りの1人ずつを加えた threshold+1 人で、定数項が Q になるか確認する。
+	// 全員の点が同じ次数 threshold の多項式に乗っていれば、どの組でも Q に一致する。
+	base := partyIDs(participants[:threshold])
+	for _, addr := range participants[threshold:] {
+		subset := append(append([]party.ID{}, base...), party.ID(addr))
+		sum := group.NewPoint()
+		for id, lambda := range polynomial.Lagrange(group, subset) {
+			sum = sum.Add(lambda.Act(points[id]))
+		}
+		if !sum.Equal(target) {
+			return fmt.Errorf("public share of %s is inconsistent with the public key", addr)
+		}
+	}
+	return nil
+}
+
+// EthereumSignature converts sig to an Ethereum signature, checked against Q.
+func EthereumSignature(hash []byte, Q *additive.Point, sig *ecdsa.Signature) (*additive.Signature, error) {
+	if sig == nil || sig.R == nil || sig.S == nil {
+		return nil, errors.New("signature is missing")
+	}
+	R, err := toPoint(sig.R)
+	if err != nil {
+		return nil, err
+	}
+	s, err := sig.S.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return additive.NewSignature(hash, Q, R, new(big.Int).SetBytes(s))
+}
+
+func fromPoint(group curve.Curve, p *additive.Point) (curve.Point, error) {
+	q := group.NewPoint()
+	if err := q.UnmarshalBinary(p.Bytes()); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func toPoint(p curve.Point) (*additive.Point, error) {
+	raw, err := p.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return additive.PointFromBytes(raw)
+}
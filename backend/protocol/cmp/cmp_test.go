@@ -0,0 +1,213 @@
+package cmp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/taurusgroup/multi-party-sig/pkg/ecdsa"
+	"github.com/taurusgroup/multi-party-sig/pkg/math/curve"
+	"github.com/taurusgroup/multi-party-sig/pkg/math/polynomial"
+	"github.com/taurusgroup/multi-party-sig/pkg/math/sample"
+	"github.com/taurusgroup/multi-party-sig/pkg/party"
+	"github.com/taurusgroup/multi-party-sig/pkg/pool"
+
+	"multisigservice/protocol/additive"
+)
+
+var testParties = []string{
+	"0x1111111111111111111111111111111111111111",
+	"0x2222222222222222222222222222222222222222",
+}
+
+// run はサーバーの中継を模して、ヘッダを検証しながら全員のメッセージを配送します。
+func run(t *testing.T, protocolID string, parties map[string]*Party) map[string]interface{} {
+	t.Helper()
+	var relayed [][]byte
+	for {
+		progressed := false
+		for from, p := range parties {
+			out, err := p.Outgoing()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, raw := range out {
+				h, err := ParseMessage(raw)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if h.Round == 0 {
+					t.Fatalf("%s aborted", from)
+				}
+				if err := h.Check(protocolID, from, testParties); err != nil {
+					t.Fatal(err)
+				}
+				relayed = append(relayed, raw)
+				progressed = true
+			}
+		}
+		for _, raw := range relayed {
+			for _, p := range parties {
+				if err := p.Deliver(raw); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		relayed = nil
+		done := true
+		for _, p := range parties {
+			done = done && p.Done()
+		}
+		if done {
+			break
+		}
+		if !progressed {
+			t.Fatal("protocol stalled")
+		}
+	}
+	results := make(map[string]interface{})
+	for addr, p := range parties {
+		r, err := p.Result()
+		if err != nil {
+			t.Fatalf("%s: %v", addr, err)
+		}
+		results[addr] = r
+	}
+	return results
+}
+
+func TestKeygenAndSign(t *testing.T) {
+	if testing.Short() {
+		t.Skip("CMP key generation is slow")
+	}
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+
+	// n-of-n なので threshold は n-1
+	keygen := make(map[string]*Party)
+	for _, addr := range testParties {
+		p, err := Keygen("keygen-1", addr, testParties, len(testParties)-1, pl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keygen[addr] = p
+	}
+	configs := make(map[string]*Config)
+	for addr, r := range run(t, ProtocolKeygen, keygen) {
+		configs[addr] = r.(*Config)
+	}
+	Q, err := PublicKey(configs[testParties[0]])
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares, err := PublicShares(configs[testParties[1]])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != len(testParties) {
+		t.Errorf("got %d public shares\nwant %d", len(shares), len(testParties))
+	}
+	if err := CheckPublicShares(Q, shares, testParties, len(testParties)-1); err != nil {
+		t.Errorf("public shares of keygen rejected: %v", err)
+	}
+
+	// 設定はシリアライズして保存・復元できること
+	raw, err := configs[testParties[0]].MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := EmptyConfig()
+	if err := restored.UnmarshalBinary(raw); err != nil {
+		t.Fatal(err)
+	}
+	configs[testParties[0]] = restored
+
+	hash := sha256.Sum256([]byte("transfer 1 ETH"))
+	signers := make(map[string]*Party)
+	for addr, cfg := range configs {
+		p, err := Sign("sign-1", cfg, testParties, hash[:], pl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers[addr] = p
+	}
+	for addr, r := range run(t, ProtocolSign, signers) {
+		sig, err := EthereumSignature(hash[:], Q, r.(*ecdsa.Signature))
+		if err != nil {
+			t.Fatalf("%s: %v", addr, err)
+		}
+		if sig.S.ToInt().Sign() == 0 {
+			t.Error("empty signature")
+		}
+	}
+	if additive.EthereumAddress(Q) == "" {
+		t.Fatal("empty address")
+	}
+}
+
+func TestHeaderCheck(t *testing.T) {
+	h := &Header{From: testParties[0], To: testParties[1], Round: 2, Protocol: ProtocolSign}
+	tests := []struct {
+		protocol, from string
+		ok             bool
+	}{
+		{ProtocolSign, testParties[0], true},
+		{ProtocolKeygen, testParties[0], false},
+		{ProtocolSign, testParties[1], false},
+	}
+	for _, tt := range tests {
+		if err := h.Check(tt.protocol, tt.from, testParties); (err == nil) != tt.ok {
+			t.Errorf("Check(%s, %s): got %v\nwant ok=%v", tt.protocol, tt.from, err, tt.ok)
+		}
+	}
+	h.To = "0x3333333333333333333333333333333333333333"
+	if err := h.Check(ProtocolSign, testParties[0], testParties); err == nil {
+		t.Error("message to a non-participant was accepted")
+	}
+	if _, err := ParseMessage([]byte{0xa0}); err == nil {
+		t.Error("malformed message was accepted")
+	}
+}
+
+func TestCheckPublicShares(t *testing.T) {
+	group := curve.Secp256k1{}
+	parties := append(append([]string{}, testParties...), "0x3333333333333333333333333333333333333333")
+
+	// 2-of-3（次数1の多項式）の公開シェアを作る
+	poly := polynomial.NewPolynomial(group, 1, sample.Scalar(rand.Reader, group))
+	Q, err := toPoint(poly.Constant().ActOnBase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares := make(map[string]*additive.Point)
+	for _, addr := range parties {
+		if shares[addr], err = toPoint(poly.Evaluate(party.ID(addr).Scalar(group)).ActOnBase()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := CheckPublicShares(Q, shares, parties, 1); err != nil {
+		t.Fatalf("valid shares rejected: %v", err)
+	}
+
+	// 他の鍵、次数の違い、1人分の改ざん・欠落は拒否される
+	other := additive.ScalarBaseMult(big.NewInt(7))
+	if err := CheckPublicShares(other, shares, parties, 1); err == nil {
+		t.Error("shares accepted for another public key")
+	}
+	if err := CheckPublicShares(Q, shares, parties, 0); err == nil {
+		t.Error("shares accepted with the wrong threshold")
+	}
+	tampered := make(map[string]*additive.Point)
+	for addr, p := range shares {
+		tampered[addr] = p
+	}
+	tampered[parties[2]] = shares[parties[2]].Add(other)
+	if err := CheckPublicShares(Q, tampered, parties, 1); err == nil {
+		t.Error("tampered share accepted")
+	}
+	delete(tampered, parties[2])
+	if err := CheckPublicShares(Q, tampered, parties, 1); err == nil {
+		t.Error("missing share accepted")
+	}
+}
@@ -1,15 +1,58 @@
 import React, { useState } from 'react';
-import { createMultiSig } from '../services/api';
+import { createMultiSig, getChallenge, getKeygenSession, startKeygen, MultiSigMembers } from '../services/api';
+import { signWithMetamask } from '../services/metamask';
 
 const MultiSigCreate: React.FC = () => {
   const [owner, setOwner] = useState<string>(''); // ログイン済みユーザーのEthereumアドレス
-  const [participant1, setParticipant1] = useState<string>('');
-  const [participant2, setParticipant2] = useState<string>('');
+  const [participants, setParticipants] = useState<string[]>(['', '']);
+  const [includeOwner, setIncludeOwner] = useState<boolean>(true);
+  const [threshold, setThreshold] = useState<number>(2);
+  const [scheme, setScheme] = useState<'additive' | 'cmp'>('additive');
+  const [session, setSession] = useState<string>('');
   const [message, setMessage] = useState<string>('');
 
+  // 参加者数 n（作成者を含める場合は作成者も数える）
+  const total = participants.length + (includeOwner ? 1 : 0);
+
+  const members = (): MultiSigMembers => ({ owner, participants, includeOwner, threshold });
+
+  const updateParticipant = (index: number, value: string) => {
+    setParticipants(participants.map((p, i) => (i === index ? value : p)));
+  };
+
+  const removeParticipant = (index: number) => {
+    setParticipants(participants.filter((_, i) => i !== index));
+  };
+
+  const handleKeygen = async () => {
+    if (threshold < 1 || threshold > total) {
+      setMessage(`Threshold must be between 1 and ${total}`);
+      return;
+    }
+    const result = await startKeygen(members(), scheme);
+    if (result.session) {
+      setSession(result.session.sessionId);
+    }
+    setMessage(result.message);
+  };
+
   const handleCreate = async () => {
-    // 例として、ownerはログイン済みのaddressとする
-    const result = await createMultiSig({ owner, participants: [participant1, participant2] });
+    // 参加者全員がcommit/revealを終えると、サーバーが公開鍵とアドレスを導出する
+    const keygen = await getKeygenSession(session);
+    if (keygen.session?.status !== 'completed') {
+      setMessage('Key generation is not completed yet');
+      return;
+    }
+    // 例として、ownerはログイン済みのaddressとする。ownerはチャレンジ付きの作成メッセージに署名する
+    const address = keygen.session.result.address;
+    const challenge = await getChallenge(owner);
+    const signature = await signWithMetamask(`Create multisig ${address}\nSession: ${session}\nChallenge: ${challenge}`);
+    const result = await createMultiSig({
+      ...members(),
+      address,
+      session,
+      signature,
+    });
     setMessage(result.message);
   };
 
@@ -22,19 +65,46 @@ const MultiSigCreate: React.FC = () => {
         value={owner}
         onChange={(e) => setOwner(e.target.value)}
       />
-      <input
-        type="text"
-        placeholder="Participant 1 Ethereum Address"
-        value={participant1}
-        onChange={(e) => setParticipant1(e.target.value)}
-      />
-      <input
-        type="text"
-        placeholder="Participant 2 Ethereum Address"
-        value={participant2}
-        onChange={(e) => setParticipant2(e.target.value)}
-      />
-      <button onClick={handleCreate}>Create</button>
+      <label>
+        <input
+          type="checkbox"
+          checked={includeOwner}
+          onChange={(e) => setIncludeOwner(e.target.checked)}
+        />
+        Include me as a participant
+      </label>
+      {participants.map((p, i) => (
+        <div key={i}>
+          <input
+            type="text"
+            placeholder={`Participant ${i + 1} Ethereum Address`}
+            value={p}
+            onChange={(e) => updateParticipant(i, e.target.value)}
+          />
+          <button onClick={() => removeParticipant(i)} disabled={participants.length <= 1}>
+            Remove
+          </button>
+        </div>
+      ))}
+      <button onClick={() => setParticipants([...participants, ''])}>Add Participant</button>
+      <label>
+        Threshold
+        <input
+          type="number"
+          min={1}
+          max={total}
+          value={threshold}
+          onChange={(e) => setThreshold(Number(e.target.value))}
+        />
+        of {total}
+      </label>
+      <select value={scheme} onChange={(e) => setScheme(e.target.value as 'additive' | 'cmp')}>
+        <option value="additive">Additive ECDSA</option>
+        <option value="cmp">CMP</option>
+      </select>
+      <button onClick={handleKeygen}>Start Key Generation</button>
+      {session && <p>Keygen session: {session}</p>}
+      <button onClick={handleCreate} disabled={!session}>Create</button>
       {message && <p>{message}</p>}
     </div>
   );
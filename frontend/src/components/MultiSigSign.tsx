@@ -1,31 +1,51 @@
 import React, { useState } from 'react';
-import { signMultiSigData } from '../services/api';
-import { signWithMetamask } from '../services/metamask';
+import { getSigningSession, startSigning } from '../services/api';
 
 const MultiSigSign: React.FC = () => {
-  const [multiSigId, setMultiSigId] = useState<string>('');
+  const [address, setAddress] = useState<string>('');
+  const [hash, setHash] = useState<string>('');
+  const [session, setSession] = useState<string>('');
   const [message, setMessage] = useState<string>('');
 
-  const handleSign = async () => {
-    // GETで署名用のデータを取得（プロトコルの状態に応じた処理）
-    const dataToSign = await signMultiSigData(multiSigId, 'get');
-    // Metamaskの署名機能で署名
-    const signature = await signWithMetamask(dataToSign);
-    // 署名をPOSTで送信して状態を更新
-    const result = await signMultiSigData(multiSigId, 'post', { signature });
+  const handleStart = async () => {
+    // 署名セッションを開始（参加者はセッションIDを使って各ラウンドのメッセージを送信する）
+    const result = await startSigning(address, hash);
+    if (result.session) {
+      setSession(result.session.sessionId);
+    }
     setMessage(result.message);
   };
 
+  const handleRefresh = async () => {
+    // セッションの状態を取得し、完了していれば署名を表示
+    const result = await getSigningSession(address, session);
+    if (result.session?.status === 'completed') {
+      setMessage(`Signature: ${result.session.result.encoded}`);
+    } else if (result.session) {
+      setMessage(`Status: ${result.session.status} (round ${result.session.round})`);
+    } else {
+      setMessage(result.message);
+    }
+  };
+
   return (
     <div>
       <h2>MultiSig Sign</h2>
       <input
         type="text"
-        placeholder="MultiSig ID"
-        value={multiSigId}
-        onChange={(e) => setMultiSigId(e.target.value)}
+        placeholder="MultiSig Address"
+        value={address}
+        onChange={(e) => setAddress(e.target.value)}
+      />
+      <input
+        type="text"
+        placeholder="Hash to sign (0x...)"
+        value={hash}
+        onChange={(e) => setHash(e.target.value)}
       />
-      <button onClick={handleSign}>Sign and Update</button>
+      <button onClick={handleStart}>Start Signing</button>
+      {session && <p>Signing session: {session}</p>}
+      <button onClick={handleRefresh} disabled={!session}>Refresh Status</button>
       {message && <p>{message}</p>}
     </div>
   );
@@ -2,9 +2,46 @@ import axios from 'axios';
 
 const API_URL = 'http://localhost:8080/api';
 
-interface CreateMultiSigData {
+// マルチシグの参加者と閾値の指定
+export interface MultiSigMembers {
   owner: string;
   participants: string[];
+  includeOwner: boolean; // 作成者を参加者に含めるか
+  threshold: number; // 署名に必要な人数 t（1 ≤ t ≤ n）
+}
+
+interface CreateMultiSigData extends MultiSigMembers {
+  address: string; // 鍵生成で導出されたマルチシグのアドレス
+  session: string; // 完了した鍵生成セッションのID
+  signature: string; // 作成メッセージとチャレンジへの所有者の personal_sign 署名
+}
+
+// 署名付きの操作に使うチャレンジを取得する。チャレンジは1回の検証で消費される
+export async function getChallenge(address: string): Promise<string> {
+  const res = await axios.get(`${API_URL}/auth/challenge`, { params: { address } });
+  return res.data.challenge;
+}
+
+// 鍵生成セッションを開始する。各参加者はセッションIDを使ってcommit/reveal/confirm（CMPではメッセージの中継）を行う
+// 受け取ったシェアがコミットメントと一致しない場合は confirm の代わりに complaint を送り、鍵生成を中断する
+export async function startKeygen(members: MultiSigMembers, scheme: 'additive' | 'cmp' = 'additive') {
+  try {
+    const res = await axios.post(`${API_URL}/multisig/keygen`, { ...members, scheme });
+    return res.data;
+  } catch (error) {
+    console.error(error);
+    return { message: 'Error starting key generation' };
+  }
+}
+
+export async function getKeygenSession(session: string) {
+  try {
+    const res = await axios.get(`${API_URL}/multisig/keygen/${session}`);
+    return res.data;
+  } catch (error) {
+    console.error(error);
+    return { message: 'Error fetching key generation session' };
+  }
 }
 
 export async function createMultiSig(data: CreateMultiSigData) {
@@ -27,17 +64,82 @@ export async function getMultiSigList() {
   }
 }
 
-export async function signMultiSigData(id: string, method: 'get' | 'post', payload?: any) {
+// 署名セッションを開始する。各参加者はセッションIDを使って commit/mta/reveal/check/partial を順に送信する
+// 加法的方式の各メッセージには送信者の personal_sign 署名を付け、確認に失敗した場合は open で開示する
+// 不正があったセッションは aborted となり、culprit に不正者のアドレス、evidence に再検証できる証拠が記録される
+// 加法的方式では最初にコミットした t 人が署名者となる。CMPでは signers で署名者を指定できる
+// presign に事前署名のIDを指定すると、事前署名の署名者が partial を1回送るだけで署名できる
+// 事前署名を使う場合は、署名者の1人が "Sign <hash> with presignature <presign> of multisig <address>" と
+// チャレンジに署名し、initiator と signature に指定する
+export async function startSigning(
+  address: string,
+  hash: string,
+  signers?: string[],
+  presign?: string,
+  initiator?: string,
+  signature?: string,
+) {
   try {
-    if (method === 'get') {
-      const res = await axios.get(`${API_URL}/multisig/${id}/data`);
-      return res.data.dataToSign;
-    } else {
-      const res = await axios.post(`${API_URL}/multisig/${id}/data`, payload);
-      return res.data;
-    }
+    const res = await axios.post(`${API_URL}/multisig/${address}/sign`, { hash, signers, presign, initiator, signature });
+    return res.data;
+  } catch (error) {
+    console.error(error);
+    return { message: 'Error starting signing session' };
+  }
+}
+
+export async function getSigningSession(address: string, session: string) {
+  try {
+    const res = await axios.get(`${API_URL}/multisig/${address}/sign/${session}`);
+    return res.data;
   } catch (error) {
     console.error(error);
     return { message: 'Error during signing process' };
   }
 }
+
+// 事前署名セッションを開始する。各参加者はセッションIDを使って commit/mta/reveal/check を送信する
+// reveal には自分のPaillier公開鍵で暗号化した k_i, σ_i を含め、完了すると事前署名として保存される
+export async function startPresign(address: string, signers?: string[]) {
+  try {
+    const res = await axios.post(`${API_URL}/multisig/${address}/presign`, { signers });
+    return res.data;
+  } catch (error) {
+    console.error(error);
+    return { message: 'Error starting presigning session' };
+  }
+}
+
+export async function getPresignatures(address: string, status = 'available') {
+  try {
+    const res = await axios.get(`${API_URL}/multisig/${address}/presignatures`, { params: { status } });
+    return res.data.presignatures;
+  } catch (error) {
+    console.error(error);
+    return [];
+  }
+}
+
+// 鍵更新セッションを開始する。公開鍵とアドレスは変わらず、完了すると各参加者のシェアが更新される
+// 各参加者はセッションIDを使って commit/reveal を送信する（CMPではメッセージの中継）
+export async function startRefresh(address: string) {
+  try {
+    const res = await axios.post(`${API_URL}/multisig/${address}/refresh`);
+    return res.data;
+  } catch (error) {
+    console.error(error);
+    return { message: 'Error starting refresh session' };
+  }
+}
+
+// 参加者と閾値を変更する再共有セッションを開始する。公開鍵とアドレスは変わらない
+// 旧参加者はセッションの承認メッセージに署名して approve を送り、承認した旧参加者が deal、新しい参加者が confirm を送信する
+export async function startReshare(address: string, proposer: string, participants: string[], threshold: number) {
+  try {
+    const res = await axios.post(`${API_URL}/multisig/${address}/reshare`, { proposer, participants, threshold });
+    return res.data;
+  } catch (error) {
+    console.error(error);
+    return { message: 'Error starting reshare session' };
+  }
+}